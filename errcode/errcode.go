@@ -152,11 +152,9 @@ func FromError(err error) (*Err, bool) {
 	}
 
 	if s, ok := status.FromError(err); ok {
-		code, msg := s.Code(), s.Message()
-		if code > GrpcMaxCode {
-			if e, ok := FromMessage(msg); ok {
-				return e, true
-			}
+		// 业务错误可能以业务状态码或 grpc 标准状态码（如限流时的 ResourceExhausted）返回，均从消息中解析
+		if e, ok := FromMessage(s.Message()); ok {
+			return e, true
 		}
 	}
 
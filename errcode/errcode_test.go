@@ -8,6 +8,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
@@ -181,6 +182,8 @@ func TestFromError(t *testing.T) {
 		{err: errors.New("test"), expect: false},
 		{err: stderrors.New("test"), expect: false},
 		{err: New(101, "101 error"), expect: true},
+		{err: status.Error(codes.ResourceExhausted, New(101, "101 error").Error()), expect: true},
+		{err: status.Error(codes.ResourceExhausted, "test"), expect: false},
 	}
 
 	for _, c := range cases {
@@ -115,4 +115,17 @@ var (
 	ErrAPINotAllowed = errcode.New(154, "暂不支持该 API")
 	// ErrRPCNotAllowed 暂不支持该 RPC 错误
 	ErrRPCNotAllowed = errcode.New(155, "暂不支持该 RPC")
+
+	// ErrTooManyRequests 请求过于频繁错误
+	ErrTooManyRequests = errcode.New(156, "请求过于频繁，请稍后再试", http.StatusTooManyRequests)
+)
+
+// oss 包预定义错误
+var (
+	// ErrFileTypeNotAllowed 文件类型不允许上传错误
+	ErrFileTypeNotAllowed = errcode.New(160, "不支持上传该类型的文件", http.StatusUnsupportedMediaType)
+	// ErrFileTooLarge 文件过大错误
+	ErrFileTooLarge = errcode.New(161, "文件过大", http.StatusRequestEntityTooLarge)
+	// ErrFileInfected 文件未通过安全扫描错误
+	ErrFileInfected = errcode.New(162, "文件未通过安全扫描", http.StatusUnprocessableEntity)
 )
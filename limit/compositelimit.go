@@ -0,0 +1,139 @@
+package limit
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+const (
+	// compositeScript 多规则时间段限流 lua 脚本，KEYS 为各规则的键，ARGV 依次为每条规则的时间段和配额，
+	// 全部规则均未超出配额时才为每条规则拿取配额，返回首个拒绝的规则序号（从 1 开始，全部通过时为 0）和各规则剩余配额
+	compositeScript = `local remains = {0};
+for i = 1, #KEYS do
+    local quota = tonumber(ARGV[i * 2]);
+    local current = tonumber(redis.call("GET", KEYS[i]) or "0") + 1;
+    if current > quota then
+        return {i};
+    end
+    remains[i + 1] = quota - current;
+end
+for i = 1, #KEYS do
+    if redis.call("INCRBY", KEYS[i], 1) == 1 then
+        redis.call("EXPIRE", KEYS[i], tonumber(ARGV[i * 2 - 1]));
+    end
+end
+return remains;`
+)
+
+// Rule 时间段限流规则
+type Rule struct {
+	Key    string // 键
+	Period int    // 时间段（秒）
+	Quota  int    // 时间段内配额
+}
+
+// CompositeResult 多规则限流结果
+type CompositeResult struct {
+	Allowed   bool  // 是否放行
+	Rejected  int   // 首个拒绝请求的规则序号（从 0 开始），放行时为 -1
+	Remaining []int // 放行时各规则的剩余配额
+}
+
+// CompositeLimit 多规则时间段限流器，在一个 lua 脚本中原子地检查多条规则，
+// 全部规则均未超出配额时才为每条规则拿取配额，避免靠后的规则拒绝请求时靠前的规则已被计数，
+// 为保证原子性，多条规则的键（含键前缀）需要包含相同的 hashtag，如 {tag}，且不能重复，
+// 全部规则的计数键均保存在首条规则的键路由到的 redis 节点上
+type CompositeLimit struct {
+	keyPrefix string
+	store     *xkv.Store
+}
+
+// NewCompositeLimit 新建多规则时间段限流器
+func NewCompositeLimit(keyPrefix string, store *xkv.Store) (*CompositeLimit, error) {
+	if store == nil {
+		return nil, errors.New("limit: illegal composite limit config")
+	}
+
+	return &CompositeLimit{keyPrefix: keyPrefix, store: store}, nil
+}
+
+// MustNewCompositeLimit 新建多规则时间段限流器
+func MustNewCompositeLimit(keyPrefix string, store *xkv.Store) *CompositeLimit {
+	cl, err := NewCompositeLimit(keyPrefix, store)
+	if err != nil {
+		panic(err)
+	}
+
+	return cl
+}
+
+// Allow 访问多规则时间段限流器，全部规则均允许放行时为每条规则拿取配额，否则不拿取任何配额并返回首个拒绝的规则序号
+func (cl *CompositeLimit) Allow(rules ...Rule) (*CompositeResult, error) {
+	if len(rules) == 0 {
+		return &CompositeResult{Allowed: true, Rejected: -1}, nil
+	}
+
+	keys := make([]string, 0, len(rules))
+	args := make([]any, 0, len(rules)*2)
+	seen := make(map[string]struct{}, len(rules))
+	for _, r := range rules {
+		if r.Key == "" || r.Period <= 0 || r.Quota <= 0 {
+			return nil, errors.Errorf("limit: illegal composite limit rule: %+v", r)
+		}
+
+		key := cl.keyPrefix + r.Key
+		if _, ok := seen[key]; ok {
+			return nil, errors.Errorf("limit: duplicate composite limit rule key: %s", key)
+		}
+		if len(keys) > 0 && (hashTag(key) == "" || hashTag(key) != hashTag(keys[0])) {
+			return nil, errors.Errorf("limit: composite limit rule keys must share the same hashtag: %s, %s", keys[0], key)
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+		args = append(args, r.Period, r.Quota)
+	}
+
+	resp, err := cl.store.EvalKeys(compositeScript, keys, args...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "store eval script err")
+	}
+
+	values, ok := resp.([]any)
+	if !ok || len(values) == 0 {
+		return nil, ErrUnexpectedType
+	}
+	ints := make([]int, len(values))
+	for i, v := range values {
+		n, ok := v.(int64)
+		if !ok {
+			return nil, ErrUnexpectedType
+		}
+		ints[i] = int(n)
+	}
+
+	if ints[0] > 0 {
+		return &CompositeResult{Rejected: ints[0] - 1}, nil
+	}
+	if len(ints) != len(rules)+1 {
+		return nil, ErrUnexpectedType
+	}
+
+	return &CompositeResult{Allowed: true, Rejected: -1, Remaining: ints[1:]}, nil
+}
+
+// hashTag 获取键的 hashtag，即首个 { 与之后首个 } 之间的非空内容，不存在时返回空字符串
+func hashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return ""
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return ""
+	}
+
+	return key[start+1 : start+1+end]
+}
@@ -0,0 +1,162 @@
+package limit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+func runOnCompositeLimit(fn func(cl *CompositeLimit)) {
+	s1.FlushAll()
+	s2.FlushAll()
+
+	store := xkv.NewStore([]cache.NodeConf{
+		{
+			RedisConf: redis.RedisConf{
+				Host: s1.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: 100,
+		},
+		{
+			RedisConf: redis.RedisConf{
+				Host: s2.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: 100,
+		},
+	})
+
+	fn(MustNewCompositeLimit("composite_limit:", store))
+}
+
+func TestNewCompositeLimit(t *testing.T) {
+	_, err := NewCompositeLimit("", nil)
+	require.Error(t, err)
+	assert.Panics(t, func() {
+		MustNewCompositeLimit("", nil)
+	})
+}
+
+func TestCompositeLimit_Allow(t *testing.T) {
+	runOnCompositeLimit(func(cl *CompositeLimit) {
+		rules := []Rule{
+			{Key: "{user}:provider", Period: 10, Quota: 5},
+			{Key: "{user}:ip", Period: 10, Quota: 3},
+			{Key: "{user}:receiver", Period: 10, Quota: 2},
+		}
+
+		r, err := cl.Allow(rules...)
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, -1, r.Rejected)
+		assert.Equal(t, []int{4, 2, 1}, r.Remaining)
+
+		r, err = cl.Allow(rules...)
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, []int{3, 1, 0}, r.Remaining)
+
+		// 靠后的规则拒绝时，靠前的规则不计数
+		for i := 0; i < 3; i++ {
+			r, err = cl.Allow(rules...)
+			require.NoError(t, err)
+			assert.False(t, r.Allowed)
+			assert.Equal(t, 2, r.Rejected)
+			assert.Empty(t, r.Remaining)
+		}
+
+		// 更换接收方后，IP 规则在其计数达到配额后拒绝
+		rules[2].Key = "{user}:receiver2"
+		r, err = cl.Allow(rules...)
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, []int{2, 0, 1}, r.Remaining)
+		r, err = cl.Allow(rules...)
+		require.NoError(t, err)
+		assert.False(t, r.Allowed)
+		assert.Equal(t, 1, r.Rejected)
+
+		// 全部计数键保存在首条规则的键路由到的节点上
+		s := s1
+		if !s.Exists("composite_limit:{user}:provider") {
+			s = s2
+		}
+		v, err := s.Get("composite_limit:{user}:provider")
+		require.NoError(t, err)
+		assert.Equal(t, "3", v)
+		v, err = s.Get("composite_limit:{user}:receiver")
+		require.NoError(t, err)
+		assert.Equal(t, "2", v)
+		assert.Greater(t, s.TTL("composite_limit:{user}:ip").Seconds(), float64(0))
+
+		r, err = cl.Allow()
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		_, err = cl.Allow(Rule{Key: "key", Period: 0, Quota: 1})
+		require.Error(t, err)
+
+		// 单条规则不要求 hashtag
+		r, err = cl.Allow(Rule{Key: "key", Period: 10, Quota: 1})
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+	})
+}
+
+func TestCompositeLimit_Allow_HashTag(t *testing.T) {
+	runOnCompositeLimit(func(cl *CompositeLimit) {
+		// 多条规则的键需要包含相同的 hashtag
+		_, err := cl.Allow(Rule{Key: "provider", Period: 10, Quota: 5}, Rule{Key: "ip", Period: 10, Quota: 5})
+		require.Error(t, err)
+		_, err = cl.Allow(Rule{Key: "{user}:provider", Period: 10, Quota: 5}, Rule{Key: "ip", Period: 10, Quota: 5})
+		require.Error(t, err)
+		_, err = cl.Allow(Rule{Key: "provider", Period: 10, Quota: 5}, Rule{Key: "{user}:ip", Period: 10, Quota: 5})
+		require.Error(t, err)
+		_, err = cl.Allow(Rule{Key: "{user}:provider", Period: 10, Quota: 5}, Rule{Key: "{user2}:ip", Period: 10, Quota: 5})
+		require.Error(t, err)
+		_, err = cl.Allow(Rule{Key: "{}:provider", Period: 10, Quota: 5}, Rule{Key: "{}:ip", Period: 10, Quota: 5})
+		require.Error(t, err)
+		assert.False(t, s1.Exists("composite_limit:{user}:provider") || s2.Exists("composite_limit:{user}:provider"))
+
+		// 键前缀中的 hashtag 同样有效
+		cl.keyPrefix = "composite_limit:{user}:"
+		r, err := cl.Allow(Rule{Key: "provider", Period: 10, Quota: 5}, Rule{Key: "ip", Period: 10, Quota: 5})
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+	})
+}
+
+func TestCompositeLimit_Allow_Duplicate(t *testing.T) {
+	runOnCompositeLimit(func(cl *CompositeLimit) {
+		// 重复的键会在一次调用中被计数两次，直接拒绝
+		_, err := cl.Allow(
+			Rule{Key: "{user}:receiver", Period: 10, Quota: 5},
+			Rule{Key: "{user}:receiver", Period: 20, Quota: 3},
+		)
+		require.Error(t, err)
+		assert.False(t, s1.Exists("composite_limit:{user}:receiver") || s2.Exists("composite_limit:{user}:receiver"))
+	})
+}
+
+func TestHashTag(t *testing.T) {
+	cases := []struct {
+		key    string
+		expect string
+	}{
+		{key: "user", expect: ""},
+		{key: "{user}:ip", expect: "user"},
+		{key: "prefix:{user}:ip", expect: "user"},
+		{key: "{}:{user}", expect: ""},
+		{key: "{user:ip", expect: ""},
+		{key: "{a}{b}", expect: "a"},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expect, hashTag(c.key), c.key)
+	}
+}
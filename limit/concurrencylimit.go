@@ -0,0 +1,206 @@
+package limit
+
+import (
+	"context"
+	stderrors "errors"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zeromicro/go-zero/core/stringx"
+
+	"github.com/sliveryou/go-tool/v2/timex"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+const (
+	// acquireScript 获取并发租约 lua 脚本，有序集合中记录租约令牌及其过期时间（毫秒），
+	// 令牌格式为 占用数量:随机串，清理过期租约后统计占用数量，不足时返回 0
+	acquireScript = `local limit = tonumber(ARGV[1]);
+local n = tonumber(ARGV[2]);
+local now = tonumber(ARGV[3]);
+local ttl = tonumber(ARGV[4]);
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now);
+local used = 0;
+for _, token in ipairs(redis.call("ZRANGE", KEYS[1], 0, -1)) do
+    used = used + tonumber(string.match(token, "^(%d+):"));
+end
+if used + n > limit then
+    return 0;
+end
+redis.call("ZADD", KEYS[1], now + ttl, ARGV[5]);
+if redis.call("PTTL", KEYS[1]) < ttl then
+    redis.call("PEXPIRE", KEYS[1], ttl);
+end
+return 1;`
+
+	// refreshScript 续期并发租约 lua 脚本，租约不存在或已过期时返回 0
+	refreshScript = `local now = tonumber(ARGV[1]);
+local ttl = tonumber(ARGV[2]);
+local expire = tonumber(redis.call("ZSCORE", KEYS[1], ARGV[3]));
+if expire == nil or expire <= now then
+    redis.call("ZREM", KEYS[1], ARGV[3]);
+    return 0;
+end
+redis.call("ZADD", KEYS[1], now + ttl, ARGV[3]);
+if redis.call("PTTL", KEYS[1]) < ttl then
+    redis.call("PEXPIRE", KEYS[1], ttl);
+end
+return 1;`
+
+	// releaseScript 释放并发租约 lua 脚本，删除租约令牌，租约不存在或已过期时返回 0
+	releaseScript = `local now = tonumber(ARGV[1]);
+local expire = tonumber(redis.call("ZSCORE", KEYS[1], ARGV[2]));
+if expire == nil then
+    return 0;
+end
+redis.call("ZREM", KEYS[1], ARGV[2]);
+if expire <= now then
+    return 0;
+end
+return 1;`
+
+	// inFlightScript 统计并发占用数量 lua 脚本
+	inFlightScript = `local used = 0;
+for _, token in ipairs(redis.call("ZRANGEBYSCORE", KEYS[1], "(" .. ARGV[1], "+inf")) do
+    used = used + tonumber(string.match(token, "^(%d+):"));
+end
+return used;`
+
+	// minAcquireBackoff 阻塞获取并发租约的最小重试间隔
+	minAcquireBackoff = 10 * time.Millisecond
+	// maxAcquireBackoff 阻塞获取并发租约的最大重试间隔
+	maxAcquireBackoff = 500 * time.Millisecond
+)
+
+// ErrLeaseNotFound 并发租约不存在或已过期错误
+var ErrLeaseNotFound = stderrors.New("lease not found")
+
+// ConcurrencyLimit 并发限流器，即基于 redis 的分布式信号量，限制全部服务实例对同一个键的同时占用数量，
+// 租约在过期时间后自动释放，避免持有者崩溃导致占用泄漏
+type ConcurrencyLimit struct {
+	limit     int           // 最大并发占用数量
+	ttl       time.Duration // 租约过期时间
+	keyPrefix string        // 键前缀
+	store     *xkv.Store
+}
+
+// NewConcurrencyLimit 新建并发限流器，limit：最大并发占用数量，ttl：租约过期时间（毫秒精度）
+func NewConcurrencyLimit(limit int, ttl time.Duration, keyPrefix string, store *xkv.Store) (*ConcurrencyLimit, error) {
+	if store == nil || limit <= 0 || ttl < time.Millisecond {
+		return nil, errors.New("limit: illegal concurrency limit config")
+	}
+
+	return &ConcurrencyLimit{limit: limit, ttl: ttl, keyPrefix: keyPrefix, store: store}, nil
+}
+
+// MustNewConcurrencyLimit 新建并发限流器
+func MustNewConcurrencyLimit(limit int, ttl time.Duration, keyPrefix string, store *xkv.Store) *ConcurrencyLimit {
+	cl, err := NewConcurrencyLimit(limit, ttl, keyPrefix, store)
+	if err != nil {
+		panic(err)
+	}
+
+	return cl
+}
+
+// Acquire 阻塞获取 n 个并发占用，返回用于释放和续期的租约令牌，上下文结束时返回上下文错误
+func (cl *ConcurrencyLimit) Acquire(ctx context.Context, key string, n int) (string, error) {
+	backoff := minAcquireBackoff
+	for {
+		token, ok, err := cl.TryAcquire(ctx, key, n)
+		if err != nil || ok {
+			return token, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", errors.WithMessage(ctx.Err(), "limit: acquire concurrency err")
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > maxAcquireBackoff {
+			backoff = maxAcquireBackoff
+		}
+	}
+}
+
+// TryAcquire 尝试获取 n 个并发占用，占用数量不足时立即返回 false
+func (cl *ConcurrencyLimit) TryAcquire(ctx context.Context, key string, n int) (string, bool, error) {
+	if n <= 0 || n > cl.limit {
+		return "", false, errors.Errorf("limit: illegal concurrency acquire n: %d", n)
+	}
+
+	token := strconv.Itoa(n) + ":" + stringx.Randn(16)
+	resp, err := cl.store.EvalCtx(ctx, acquireScript, cl.keyPrefix+key,
+		cl.limit, n, timex.Now().UnixMilli(), cl.ttl.Milliseconds(), token,
+	)
+	if err != nil {
+		return "", false, errors.WithMessage(err, "store eval script err")
+	}
+
+	code, ok := resp.(int64)
+	if !ok {
+		return "", false, ErrUnexpectedType
+	}
+	if code != 1 {
+		return "", false, nil
+	}
+
+	return token, true, nil
+}
+
+// Release 释放租约令牌对应的并发占用，租约不存在或已过期时返回 ErrLeaseNotFound
+func (cl *ConcurrencyLimit) Release(ctx context.Context, key, token string) error {
+	resp, err := cl.store.EvalCtx(ctx, releaseScript, cl.keyPrefix+key, timex.Now().UnixMilli(), token)
+	if err != nil {
+		return errors.WithMessage(err, "store eval script err")
+	}
+
+	code, ok := resp.(int64)
+	if !ok {
+		return ErrUnexpectedType
+	}
+	if code != 1 {
+		return ErrLeaseNotFound
+	}
+
+	return nil
+}
+
+// Refresh 将租约的过期时间重置为 ttl 之后，用于执行时间超过 ttl 的持有者续期，租约不存在或已过期时返回 ErrLeaseNotFound
+func (cl *ConcurrencyLimit) Refresh(ctx context.Context, key, token string) error {
+	resp, err := cl.store.EvalCtx(ctx, refreshScript, cl.keyPrefix+key,
+		timex.Now().UnixMilli(), cl.ttl.Milliseconds(), token,
+	)
+	if err != nil {
+		return errors.WithMessage(err, "store eval script err")
+	}
+
+	code, ok := resp.(int64)
+	if !ok {
+		return ErrUnexpectedType
+	}
+	if code != 1 {
+		return ErrLeaseNotFound
+	}
+
+	return nil
+}
+
+// InFlight 获取当前未过期的并发占用数量
+func (cl *ConcurrencyLimit) InFlight(ctx context.Context, key string) (int, error) {
+	resp, err := cl.store.EvalCtx(ctx, inFlightScript, cl.keyPrefix+key, timex.Now().UnixMilli())
+	if err != nil {
+		return 0, errors.WithMessage(err, "store eval script err")
+	}
+
+	used, ok := resp.(int64)
+	if !ok {
+		return 0, ErrUnexpectedType
+	}
+
+	return int(used), nil
+}
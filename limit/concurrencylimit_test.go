@@ -0,0 +1,171 @@
+package limit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+func runOnConcurrencyLimit(fn func(cl *ConcurrencyLimit)) {
+	s1.FlushAll()
+	s2.FlushAll()
+
+	store := xkv.NewStore([]cache.NodeConf{
+		{
+			RedisConf: redis.RedisConf{
+				Host: s1.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: 100,
+		},
+		{
+			RedisConf: redis.RedisConf{
+				Host: s2.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: 100,
+		},
+	})
+
+	// 同时最多占用 3 个，租约 200ms 后过期
+	fn(MustNewConcurrencyLimit(3, 200*time.Millisecond, "concurrency_limit:", store))
+}
+
+func TestNewConcurrencyLimit(t *testing.T) {
+	_, err := NewConcurrencyLimit(0, time.Second, "", &xkv.Store{})
+	require.Error(t, err)
+	_, err = NewConcurrencyLimit(3, 0, "", &xkv.Store{})
+	require.Error(t, err)
+	_, err = NewConcurrencyLimit(3, time.Second, "", nil)
+	require.Error(t, err)
+	assert.Panics(t, func() {
+		MustNewConcurrencyLimit(3, time.Second, "", nil)
+	})
+}
+
+func TestConcurrencyLimit_TryAcquire(t *testing.T) {
+	runOnConcurrencyLimit(func(cl *ConcurrencyLimit) {
+		ctx := context.Background()
+		key := "test_key_for_concurrency_limit_try_acquire"
+
+		t1, ok, err := cl.TryAcquire(ctx, key, 2)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		_, ok, err = cl.TryAcquire(ctx, key, 2)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		t2, ok, err := cl.TryAcquire(ctx, key, 1)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		inFlight, err := cl.InFlight(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, 3, inFlight)
+
+		require.NoError(t, cl.Release(ctx, key, t1))
+		require.ErrorIs(t, cl.Release(ctx, key, t1), ErrLeaseNotFound)
+		inFlight, err = cl.InFlight(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, 1, inFlight)
+		require.NoError(t, cl.Release(ctx, key, t2))
+
+		_, _, err = cl.TryAcquire(ctx, key, 0)
+		require.Error(t, err)
+		_, _, err = cl.TryAcquire(ctx, key, 4)
+		require.Error(t, err)
+	})
+}
+
+func TestConcurrencyLimit_Acquire(t *testing.T) {
+	runOnConcurrencyLimit(func(cl *ConcurrencyLimit) {
+		ctx := context.Background()
+		key := "test_key_for_concurrency_limit_acquire"
+
+		var running, maxRunning int32
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				token, err := cl.Acquire(ctx, key, 1)
+				if !assert.NoError(t, err) {
+					return
+				}
+				cur := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if cur <= m || atomic.CompareAndSwapInt32(&maxRunning, m, cur) {
+						break
+					}
+				}
+				time.Sleep(20 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				assert.NoError(t, cl.Release(ctx, key, token))
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(3), maxRunning)
+
+		// 占用已满时等待至上下文结束
+		token, err := cl.Acquire(ctx, key, 3)
+		require.NoError(t, err)
+		tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err = cl.Acquire(tctx, key, 1)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.NoError(t, cl.Release(ctx, key, token))
+	})
+}
+
+func TestConcurrencyLimit_Expire(t *testing.T) {
+	runOnConcurrencyLimit(func(cl *ConcurrencyLimit) {
+		ctx := context.Background()
+		key := "test_key_for_concurrency_limit_expire"
+
+		crashed, err := cl.Acquire(ctx, key, 2)
+		require.NoError(t, err)
+		alive, err := cl.Acquire(ctx, key, 1)
+		require.NoError(t, err)
+
+		// 续期的租约保留，未续期的租约过期后自动释放
+		time.Sleep(120 * time.Millisecond)
+		require.NoError(t, cl.Refresh(ctx, key, alive))
+		time.Sleep(120 * time.Millisecond)
+
+		inFlight, err := cl.InFlight(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, 1, inFlight)
+		_, ok, err := cl.TryAcquire(ctx, key, 2)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		require.ErrorIs(t, cl.Refresh(ctx, key, crashed), ErrLeaseNotFound)
+		require.NoError(t, cl.Release(ctx, key, alive))
+	})
+}
+
+func TestConcurrencyLimit_ReleaseExpired(t *testing.T) {
+	runOnConcurrencyLimit(func(cl *ConcurrencyLimit) {
+		ctx := context.Background()
+		key := "test_key_for_concurrency_limit_release_expired"
+
+		// 已过期但尚未被清理的租约释放时返回 ErrLeaseNotFound，并删除租约令牌
+		token, err := cl.Acquire(ctx, key, 1)
+		require.NoError(t, err)
+		time.Sleep(250 * time.Millisecond)
+		require.ErrorIs(t, cl.Release(ctx, key, token), ErrLeaseNotFound)
+		n, err := cl.store.ZcardCtx(ctx, cl.keyPrefix+key)
+		require.NoError(t, err)
+		assert.Equal(t, 0, n)
+		require.ErrorIs(t, cl.Release(ctx, key, token), ErrLeaseNotFound)
+	})
+}
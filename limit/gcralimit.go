@@ -0,0 +1,201 @@
+package limit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sliveryou/go-tool/v2/timex"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+const (
+	// gcraScript GCRA 限流 lua 脚本，键中记录理论到达时间（毫秒），
+	// 返回是否放行、剩余配额、重试等待时间（毫秒）和配额完全恢复时间（毫秒）
+	gcraScript = `local burst = tonumber(ARGV[1]);
+local emission_interval = tonumber(ARGV[2]);
+local now = tonumber(ARGV[3]);
+local requested = tonumber(ARGV[4]);
+local dvt = emission_interval * burst;
+local increment = emission_interval * requested;
+local tat = tonumber(redis.call("GET", KEYS[1]));
+if tat == nil or tat < now then
+    tat = now;
+end
+local new_tat = tat + increment;
+local diff = now - (new_tat - dvt);
+if diff < 0 then
+    local retry_after = -1;
+    if increment <= dvt then
+        retry_after = math.ceil(-diff);
+    end
+    return {0, math.floor((now - (tat - dvt)) / emission_interval), retry_after, math.ceil(tat - now)};
+end
+local reset_after = math.ceil(new_tat - now);
+if reset_after > 0 then
+    redis.call("SET", KEYS[1], tostring(new_tat), "PX", reset_after);
+end
+return {1, math.floor(diff / emission_interval), 0, reset_after};`
+)
+
+// GCRAResult GCRA 限流结果
+type GCRAResult struct {
+	Allowed    bool          // 是否放行
+	Limit      int           // 突发容量，即配额完全恢复时可连续放行的请求数量
+	Remaining  int           // 剩余配额
+	ResetAfter time.Duration // 配额完全恢复所需时间
+	RetryAfter time.Duration // 被拒绝时距可再次放行的等待时间，放行时为 0，请求数量超过突发容量时为 -1
+}
+
+// SetHeader 设置 RateLimit-Limit、RateLimit-Remaining、RateLimit-Reset 和被拒绝时的 Retry-After 响应头（单位为秒，向上取整）
+func (r *GCRAResult) SetHeader(header http.Header) {
+	header.Set("RateLimit-Limit", strconv.Itoa(r.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(r.Remaining))
+	header.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(r.ResetAfter), 10))
+	if !r.Allowed && r.RetryAfter >= 0 {
+		header.Set("Retry-After", strconv.FormatInt(ceilSeconds(r.RetryAfter), 10))
+	}
+}
+
+// gcraOption GCRA 限流器配置
+type gcraOption struct {
+	rate      int           // 速率，即每个时间段放行的请求数量
+	period    time.Duration // 时间段
+	burst     int           // 突发容量
+	keyPrefix string        // 键前缀
+}
+
+// clone 克隆 GCRA 限流器配置
+func (o gcraOption) clone() *gcraOption {
+	return &gcraOption{
+		rate:      o.rate,
+		period:    o.period,
+		burst:     o.burst,
+		keyPrefix: o.keyPrefix,
+	}
+}
+
+// GCRAOption GCRA 限流器可选配置
+type GCRAOption func(op *gcraOption)
+
+// WithRatePer 指定 GCRA 限流器速率，即每 period 放行 rate 个请求
+func WithRatePer(rate int, period time.Duration) GCRAOption {
+	return func(op *gcraOption) {
+		op.rate = rate
+		op.period = period
+	}
+}
+
+// WithBurst 指定 GCRA 限流器突发容量
+func WithBurst(burst int) GCRAOption {
+	return func(op *gcraOption) {
+		op.burst = burst
+	}
+}
+
+// WithGCRAKeyPrefix 指定 GCRA 限流器键前缀
+func WithGCRAKeyPrefix(keyPrefix string) GCRAOption {
+	return func(op *gcraOption) {
+		op.keyPrefix = keyPrefix
+	}
+}
+
+// GCRALimit GCRA（通用信元速率算法）限流器，以毫秒精度匀速放行请求，
+// 并返回剩余配额、配额恢复时间和重试等待时间
+type GCRALimit struct {
+	option gcraOption
+	store  *xkv.Store
+}
+
+// NewGCRALimit 新建 GCRA 限流器，rate：每个时间段放行的请求数量，period：时间段，burst：突发容量
+func NewGCRALimit(rate int, period time.Duration, burst int, keyPrefix string, store *xkv.Store) (*GCRALimit, error) {
+	if store == nil || rate <= 0 || period < time.Millisecond || burst <= 0 {
+		return nil, errors.New("limit: illegal gcra limit config")
+	}
+
+	limiter := &GCRALimit{
+		option: gcraOption{
+			rate:      rate,
+			period:    period,
+			burst:     burst,
+			keyPrefix: keyPrefix,
+		},
+		store: store,
+	}
+
+	return limiter, nil
+}
+
+// MustNewGCRALimit 新建 GCRA 限流器
+func MustNewGCRALimit(rate int, period time.Duration, burst int, keyPrefix string, store *xkv.Store) *GCRALimit {
+	gl, err := NewGCRALimit(rate, period, burst, keyPrefix, store)
+	if err != nil {
+		panic(err)
+	}
+
+	return gl
+}
+
+// Allow 访问 GCRA 限流器拿取 1 个配额，并返回限流结果
+func (gl *GCRALimit) Allow(key string, opts ...GCRAOption) (*GCRAResult, error) {
+	return gl.AllowN(timex.Now(), key, 1, opts...)
+}
+
+// AllowN 访问 GCRA 限流器在 now 时刻拿取 n 个配额，并返回限流结果，被拒绝时不消耗配额
+func (gl *GCRALimit) AllowN(now time.Time, key string, n int, opts ...GCRAOption) (*GCRAResult, error) {
+	op := gl.option.clone()
+	for _, opt := range opts {
+		opt(op)
+	}
+	if op.rate <= 0 || op.period < time.Millisecond || op.burst <= 0 {
+		return nil, errors.New("limit: illegal gcra limit option")
+	}
+	if n <= 0 {
+		return nil, errors.Errorf("limit: illegal gcra limit n: %d", n)
+	}
+
+	emissionInterval := float64(op.period.Milliseconds()) / float64(op.rate)
+	resp, err := gl.store.Eval(gcraScript, op.keyPrefix+key,
+		op.burst, strconv.FormatFloat(emissionInterval, 'f', -1, 64), now.UnixMilli(), n,
+	)
+	if err != nil {
+		return nil, errors.WithMessage(err, "store eval script err")
+	}
+
+	values, ok := resp.([]any)
+	if !ok || len(values) != 4 {
+		return nil, ErrUnexpectedType
+	}
+	ints := make([]int64, len(values))
+	for i, v := range values {
+		if ints[i], ok = v.(int64); !ok {
+			return nil, ErrUnexpectedType
+		}
+	}
+
+	result := &GCRAResult{
+		Allowed:    ints[0] == 1,
+		Limit:      op.burst,
+		Remaining:  int(ints[1]),
+		ResetAfter: time.Duration(ints[3]) * time.Millisecond,
+		RetryAfter: time.Duration(ints[2]) * time.Millisecond,
+	}
+	if result.RetryAfter < 0 {
+		result.RetryAfter = -1
+	}
+
+	return result, nil
+}
+
+// ceilSeconds 将时间间隔向上取整为秒数
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,156 @@
+package limit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+func runOnGCRALimit(fn func(gl *GCRALimit)) {
+	s1.FlushAll()
+	s2.FlushAll()
+
+	store := xkv.NewStore([]cache.NodeConf{
+		{
+			RedisConf: redis.RedisConf{
+				Host: s1.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: 100,
+		},
+		{
+			RedisConf: redis.RedisConf{
+				Host: s2.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: 100,
+		},
+	})
+
+	// 每秒放行 10 个请求，突发容量为 5
+	fn(MustNewGCRALimit(10, time.Second, 5, "gcra_limit:", store))
+}
+
+func TestNewGCRALimit(t *testing.T) {
+	_, err := NewGCRALimit(0, time.Second, 5, "", &xkv.Store{})
+	require.Error(t, err)
+	_, err = NewGCRALimit(10, 0, 5, "", &xkv.Store{})
+	require.Error(t, err)
+	_, err = NewGCRALimit(10, time.Second, 0, "", &xkv.Store{})
+	require.Error(t, err)
+	_, err = NewGCRALimit(10, time.Second, 5, "", nil)
+	require.Error(t, err)
+	assert.Panics(t, func() {
+		MustNewGCRALimit(10, time.Second, 5, "", nil)
+	})
+}
+
+func TestGCRALimit_AllowN(t *testing.T) {
+	runOnGCRALimit(func(gl *GCRALimit) {
+		testKey := "test_key_for_gcra_limit_allow_n"
+		now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		// 突发容量内连续放行
+		for i := 0; i < 5; i++ {
+			r, err := gl.AllowN(now, testKey, 1)
+			require.NoError(t, err)
+			assert.True(t, r.Allowed)
+			assert.Equal(t, 5, r.Limit)
+			assert.Equal(t, 4-i, r.Remaining)
+			assert.Equal(t, time.Duration(i+1)*100*time.Millisecond, r.ResetAfter)
+			assert.Zero(t, r.RetryAfter)
+		}
+
+		r, err := gl.AllowN(now, testKey, 1)
+		require.NoError(t, err)
+		assert.False(t, r.Allowed)
+		assert.Equal(t, 0, r.Remaining)
+		assert.Equal(t, 100*time.Millisecond, r.RetryAfter)
+		assert.Equal(t, 500*time.Millisecond, r.ResetAfter)
+
+		// 毫秒精度，同一秒内按发射间隔恢复配额
+		r, err = gl.AllowN(now.Add(99*time.Millisecond), testKey, 1)
+		require.NoError(t, err)
+		assert.False(t, r.Allowed)
+		assert.Equal(t, time.Millisecond, r.RetryAfter)
+		r, err = gl.AllowN(now.Add(100*time.Millisecond), testKey, 1)
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 0, r.Remaining)
+
+		// 剩余配额不足时拒绝且不消耗配额
+		r, err = gl.AllowN(now.Add(300*time.Millisecond), testKey, 3)
+		require.NoError(t, err)
+		assert.False(t, r.Allowed)
+		assert.Equal(t, 2, r.Remaining)
+		assert.Equal(t, 100*time.Millisecond, r.RetryAfter)
+		r, err = gl.AllowN(now.Add(300*time.Millisecond), testKey, 2)
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 0, r.Remaining)
+
+		// 请求数量超过突发容量时无法放行
+		r, err = gl.AllowN(now.Add(time.Hour), testKey, 6)
+		require.NoError(t, err)
+		assert.False(t, r.Allowed)
+		assert.Equal(t, 5, r.Remaining)
+		assert.Equal(t, time.Duration(-1), r.RetryAfter)
+
+		// 可选配置覆盖速率和突发容量
+		r, err = gl.AllowN(now, "test_key_for_gcra_limit_option", 1, WithRatePer(1, time.Minute), WithBurst(1))
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		r, err = gl.AllowN(now.Add(time.Second), "test_key_for_gcra_limit_option", 1, WithRatePer(1, time.Minute), WithBurst(1))
+		require.NoError(t, err)
+		assert.False(t, r.Allowed)
+		assert.Equal(t, 59*time.Second, r.RetryAfter)
+
+		_, err = gl.AllowN(now, testKey, 1, WithBurst(0))
+		require.Error(t, err)
+
+		// 请求数量必须大于 0，负数会回拨 TAT 从而获得额外的突发容量
+		_, err = gl.AllowN(now, testKey, 0)
+		require.Error(t, err)
+		_, err = gl.AllowN(now, testKey, -5)
+		require.Error(t, err)
+	})
+}
+
+func TestGCRALimit_Allow(t *testing.T) {
+	runOnGCRALimit(func(gl *GCRALimit) {
+		testKey := "test_key_for_gcra_limit_allow"
+		var allowed int
+		for i := 0; i < 10; i++ {
+			r, err := gl.Allow(testKey)
+			require.NoError(t, err)
+			if r.Allowed {
+				allowed++
+			}
+		}
+
+		assert.Equal(t, 5, allowed)
+	})
+}
+
+func TestGCRAResult_SetHeader(t *testing.T) {
+	header := http.Header{}
+	r := &GCRAResult{Allowed: true, Limit: 5, Remaining: 3, ResetAfter: 200 * time.Millisecond}
+	r.SetHeader(header)
+	assert.Equal(t, "5", header.Get("RateLimit-Limit"))
+	assert.Equal(t, "3", header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", header.Get("RateLimit-Reset"))
+	assert.Empty(t, header.Get("Retry-After"))
+
+	r = &GCRAResult{Limit: 5, ResetAfter: 3 * time.Second, RetryAfter: 1500 * time.Millisecond}
+	r.SetHeader(header)
+	assert.Equal(t, "0", header.Get("RateLimit-Remaining"))
+	assert.Equal(t, "3", header.Get("RateLimit-Reset"))
+	assert.Equal(t, "2", header.Get("Retry-After"))
+}
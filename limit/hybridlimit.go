@@ -0,0 +1,438 @@
+package limit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+	xrate "golang.org/x/time/rate"
+
+	"github.com/sliveryou/go-tool/v2/timex"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+const (
+	// ModeRemote 混合限流器模式：使用 redis 限流
+	ModeRemote = "remote"
+	// ModeLocal 混合限流器模式：redis 不可用，使用进程内限流
+	ModeLocal = "local"
+
+	// defaultProbeInterval 默认 redis 探测间隔
+	defaultProbeInterval = time.Second
+	// sweepInterval 进程内限流状态清理间隔
+	sweepInterval = time.Minute
+)
+
+var (
+	// hybridModeGauge 混合限流器模式指标，0 为 redis 限流，1 为进程内限流
+	hybridModeGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "limit",
+		Subsystem: "hybrid",
+		Name:      "mode",
+		Help:      "Hybrid limiter mode, 0 for remote (redis) and 1 for local (in-process).",
+	}, []string{"name"})
+	// hybridModeGaugeOnce 首次新建混合限流器时才注册模式指标
+	hybridModeGaugeOnce sync.Once
+)
+
+// HybridOption 混合限流器可选配置
+type HybridOption func(h *hybrid)
+
+// WithName 指定混合限流器名称，用作模式指标的 name 标签，默认为键前缀或键
+func WithName(name string) HybridOption {
+	return func(h *hybrid) {
+		h.name = name
+	}
+}
+
+// WithInstances 指定预期的服务实例数量，进程内限流时速率、容量和配额按实例数量均分（向上取整），默认为 1
+func WithInstances(instances int) HybridOption {
+	return func(h *hybrid) {
+		h.instances = instances
+	}
+}
+
+// WithProbeInterval 指定进程内限流时探测 redis 是否恢复的间隔，默认为 1s
+func WithProbeInterval(interval time.Duration) HybridOption {
+	return func(h *hybrid) {
+		h.interval = interval
+	}
+}
+
+// hybrid 混合限流器模式切换器，redis 出错时切换为进程内限流并在后台探测，redis 恢复后切换回 redis 限流
+type hybrid struct {
+	name      string        // 名称
+	instances int           // 预期的服务实例数量
+	interval  time.Duration // 探测间隔
+	store     *xkv.Store    // 键值存取器
+	reset     func()        // 切换回 redis 限流时清理进程内限流状态
+
+	local     atomic.Bool            // 是否使用进程内限流
+	failedKey atomic.Pointer[string] // 最近出错的键
+	done      chan struct{}          // 停止后台探测信号
+	closeOnce sync.Once
+}
+
+// newHybrid 新建混合限流器模式切换器
+func newHybrid(store *xkv.Store, name string, reset func(), opts ...HybridOption) (*hybrid, error) {
+	h := &hybrid{
+		name:      name,
+		instances: 1,
+		interval:  defaultProbeInterval,
+		store:     store,
+		reset:     reset,
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	if h.instances <= 0 || h.interval <= 0 {
+		return nil, errors.New("limit: illegal hybrid limit config")
+	}
+	hybridModeGaugeOnce.Do(func() {
+		prometheus.MustRegister(hybridModeGauge)
+	})
+	hybridModeGauge.WithLabelValues(h.name).Set(0)
+
+	return h, nil
+}
+
+// mode 获取当前模式
+func (h *hybrid) mode() string {
+	if h.local.Load() {
+		return ModeLocal
+	}
+
+	return ModeRemote
+}
+
+// split 按实例数量均分 n，向上取整
+func (h *hybrid) split(n int) int {
+	return (n + h.instances - 1) / h.instances
+}
+
+// isFailure 判断错误是否为 redis 故障
+func (h *hybrid) isFailure(err error) bool {
+	return err != nil && !errors.Is(err, ErrUnexpectedType) && !errors.Is(err, ErrUnknownCode)
+}
+
+// fallback 切换为进程内限流并启动后台探测，key：出错的键，用于探测其所在的 redis 节点，
+// 返回是否使用进程内限流，关闭后不再切换并返回 false
+func (h *hybrid) fallback(key string, err error) bool {
+	if h.closed() {
+		return false
+	}
+	h.failedKey.Store(&key)
+	if !h.local.CompareAndSwap(false, true) {
+		return true
+	}
+
+	logx.Errorf("limit: hybrid limit %s switch to local mode, err: %v", h.name, err)
+	hybridModeGauge.WithLabelValues(h.name).Set(1)
+	threading.GoSafe(h.probe)
+
+	return true
+}
+
+// remoteOnly 判断是否使用 redis 限流，关闭后总是使用 redis 限流
+func (h *hybrid) remoteOnly() bool {
+	return !h.local.Load() || h.closed()
+}
+
+// probe 定期探测 redis，恢复后切换回 redis 限流
+func (h *hybrid) probe() {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.done:
+			// 关闭后不再探测，切换回 redis 限流，避免一直停留在进程内限流
+			h.local.Store(false)
+			hybridModeGauge.WithLabelValues(h.name).Set(0)
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), h.interval)
+			_, err := h.store.ExistsCtx(ctx, *h.failedKey.Load())
+			cancel()
+			if err != nil {
+				continue
+			}
+
+			h.reset()
+			h.local.Store(false)
+			hybridModeGauge.WithLabelValues(h.name).Set(0)
+			logx.Infof("limit: hybrid limit %s switch back to remote mode", h.name)
+			return
+		}
+	}
+}
+
+// close 停止后台探测
+func (h *hybrid) close() {
+	h.closeOnce.Do(func() {
+		close(h.done)
+	})
+}
+
+// closed 判断是否已关闭
+func (h *hybrid) closed() bool {
+	select {
+	case <-h.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// HybridLimit 混合令牌桶限流器，redis 不可用时使用进程内令牌桶限流，redis 恢复后自动切换回 redis 限流
+type HybridLimit struct {
+	*hybrid
+	remote *TokenLimit // redis 令牌桶限流器
+
+	mu        sync.Mutex                // 保护进程内令牌桶
+	limiters  map[string]*xrate.Limiter // 进程内令牌桶
+	nextSweep time.Time                 // 下次清理已填满令牌桶的时间
+}
+
+// NewHybridLimit 新建混合令牌桶限流器
+func NewHybridLimit(tl *TokenLimit, opts ...HybridOption) (*HybridLimit, error) {
+	if tl == nil {
+		return nil, errors.New("limit: illegal hybrid limit config")
+	}
+
+	hl := &HybridLimit{remote: tl, limiters: make(map[string]*xrate.Limiter)}
+	h, err := newHybrid(tl.store, tl.option.key, hl.reset, opts...)
+	if err != nil {
+		return nil, err
+	}
+	hl.hybrid = h
+
+	return hl, nil
+}
+
+// MustNewHybridLimit 新建混合令牌桶限流器
+func MustNewHybridLimit(tl *TokenLimit, opts ...HybridOption) *HybridLimit {
+	hl, err := NewHybridLimit(tl, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return hl
+}
+
+// Mode 获取当前模式，ModeRemote 或 ModeLocal
+func (hl *HybridLimit) Mode() string {
+	return hl.mode()
+}
+
+// Close 停止后台探测，关闭后 redis 出错时不再切换为进程内限流
+func (hl *HybridLimit) Close() {
+	hl.close()
+}
+
+// Allow 访问混合令牌桶限流器拿取令牌，并判断是否允许拿取
+func (hl *HybridLimit) Allow(opts ...TokenOption) (bool, error) {
+	return hl.AllowN(timex.Now(), 1, opts...)
+}
+
+// AllowN 访问混合令牌桶限流器拿取 n 个令牌，并判断是否允许拿取，redis 出错时切换为进程内限流并返回进程内限流结果，
+// 关闭后 redis 出错时返回错误
+func (hl *HybridLimit) AllowN(now time.Time, n int, opts ...TokenOption) (bool, error) {
+	op := hl.remote.option.clone()
+	for _, opt := range opts {
+		opt(op)
+	}
+
+	if hl.remoteOnly() {
+		allowed, err := hl.remote.AllowN(now, n, opts...)
+		if !hl.isFailure(err) || !hl.fallback(op.key, err) {
+			return allowed, err
+		}
+	}
+
+	return hl.limiter(now, op).AllowN(now, n), nil
+}
+
+// limiter 获取键对应的进程内令牌桶
+func (hl *HybridLimit) limiter(now time.Time, op *tokenOption) *xrate.Limiter {
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+
+	if now.After(hl.nextSweep) {
+		for key, l := range hl.limiters {
+			if l.TokensAt(now) >= float64(l.Burst()) {
+				delete(hl.limiters, key)
+			}
+		}
+		hl.nextSweep = now.Add(sweepInterval)
+	}
+
+	limit := xrate.Limit(float64(op.rate) / float64(hl.instances))
+	burst := hl.split(op.capacity)
+	l, ok := hl.limiters[op.key]
+	if !ok {
+		l = xrate.NewLimiter(limit, burst)
+		hl.limiters[op.key] = l
+	} else if l.Limit() != limit || l.Burst() != burst {
+		l.SetLimitAt(now, limit)
+		l.SetBurstAt(now, burst)
+	}
+
+	return l
+}
+
+// reset 清理进程内令牌桶
+func (hl *HybridLimit) reset() {
+	hl.mu.Lock()
+	defer hl.mu.Unlock()
+
+	hl.limiters = make(map[string]*xrate.Limiter)
+}
+
+// localWindow 进程内时间段计数窗口
+type localWindow struct {
+	expireAt time.Time // 窗口过期时间
+	count    int       // 窗口内请求数量
+}
+
+// HybridPeriodLimit 混合时间段限流器，redis 不可用时使用进程内时间段限流，redis 恢复后自动切换回 redis 限流
+type HybridPeriodLimit struct {
+	*hybrid
+	remote *PeriodLimit // redis 时间段限流器
+
+	mu        sync.Mutex              // 保护进程内计数窗口
+	windows   map[string]*localWindow // 进程内计数窗口
+	nextSweep time.Time               // 下次清理过期计数窗口的时间
+}
+
+// NewHybridPeriodLimit 新建混合时间段限流器
+func NewHybridPeriodLimit(pl *PeriodLimit, opts ...HybridOption) (*HybridPeriodLimit, error) {
+	if pl == nil {
+		return nil, errors.New("limit: illegal hybrid period limit config")
+	}
+
+	hpl := &HybridPeriodLimit{remote: pl, windows: make(map[string]*localWindow)}
+	h, err := newHybrid(pl.store, pl.option.keyPrefix, hpl.reset, opts...)
+	if err != nil {
+		return nil, err
+	}
+	hpl.hybrid = h
+
+	return hpl, nil
+}
+
+// MustNewHybridPeriodLimit 新建混合时间段限流器
+func MustNewHybridPeriodLimit(pl *PeriodLimit, opts ...HybridOption) *HybridPeriodLimit {
+	hpl, err := NewHybridPeriodLimit(pl, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return hpl
+}
+
+// Mode 获取当前模式，ModeRemote 或 ModeLocal
+func (hpl *HybridPeriodLimit) Mode() string {
+	return hpl.mode()
+}
+
+// Close 停止后台探测，关闭后 redis 出错时不再切换为进程内限流
+func (hpl *HybridPeriodLimit) Close() {
+	hpl.close()
+}
+
+// Take 访问混合时间段限流器拿取配额，并返回相关状态码，关闭后 redis 出错时返回错误
+func (hpl *HybridPeriodLimit) Take(key string, opts ...Option) (int, error) {
+	op := hpl.remote.option.clone()
+	for _, opt := range opts {
+		opt(op)
+	}
+
+	if hpl.remoteOnly() {
+		code, err := hpl.remote.Take(key, opts...)
+		if !hpl.isFailure(err) || !hpl.fallback(op.keyPrefix+key, err) {
+			return code, err
+		}
+	}
+
+	remain := hpl.take(timex.Now(), op.keyPrefix+key, op)
+	switch {
+	case remain > 0:
+		return Allowed, nil
+	case remain == 0:
+		return Reached, nil
+	default:
+		return Overflowed, nil
+	}
+}
+
+// Get 访问混合时间段限流器拿取配额，并返回剩余配额，返回值含义同 PeriodLimit.Get，关闭后 redis 出错时返回错误
+func (hpl *HybridPeriodLimit) Get(key string, opts ...Option) (int, error) {
+	op := hpl.remote.option.clone()
+	for _, opt := range opts {
+		opt(op)
+	}
+
+	if hpl.remoteOnly() {
+		remain, err := hpl.remote.Get(key, opts...)
+		if !hpl.isFailure(err) || !hpl.fallback(op.keyPrefix+key, err) {
+			return remain, err
+		}
+	}
+
+	return hpl.take(timex.Now(), op.keyPrefix+key, op), nil
+}
+
+// Allow 访问混合时间段限流器拿取配额，并判断是否允许放行
+func (hpl *HybridPeriodLimit) Allow(key string, opts ...Option) (bool, error) {
+	code, err := hpl.Take(key, opts...)
+	if err != nil {
+		return false, err
+	}
+
+	if code == Allowed || code == Reached {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// take 在进程内拿取配额，并返回剩余配额，key：包含键前缀的完整键
+func (hpl *HybridPeriodLimit) take(now time.Time, key string, op *option) int {
+	hpl.mu.Lock()
+	defer hpl.mu.Unlock()
+
+	if now.After(hpl.nextSweep) {
+		for k, w := range hpl.windows {
+			if !now.Before(w.expireAt) {
+				delete(hpl.windows, k)
+			}
+		}
+		hpl.nextSweep = now.Add(sweepInterval)
+	}
+
+	w, ok := hpl.windows[key]
+	if !ok || !now.Before(w.expireAt) {
+		w = &localWindow{expireAt: now.Add(time.Duration(op.period) * time.Second)}
+		hpl.windows[key] = w
+	}
+	w.count++
+
+	return hpl.split(op.quota) - w.count
+}
+
+// reset 清理进程内计数窗口
+func (hpl *HybridPeriodLimit) reset() {
+	hpl.mu.Lock()
+	defer hpl.mu.Unlock()
+
+	hpl.windows = make(map[string]*localWindow)
+}
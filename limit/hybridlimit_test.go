@@ -0,0 +1,183 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+func newHybridStore(t *testing.T) (*miniredis.Miniredis, *xkv.Store) {
+	t.Helper()
+
+	m := miniredis.RunT(t)
+	store := xkv.NewStore([]cache.NodeConf{
+		{
+			RedisConf: redis.RedisConf{
+				Host: m.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: 100,
+		},
+	})
+
+	return m, store
+}
+
+func TestNewHybridLimit(t *testing.T) {
+	_, err := NewHybridLimit(nil)
+	require.Error(t, err)
+	_, err = NewHybridPeriodLimit(nil)
+	require.Error(t, err)
+
+	_, store := newHybridStore(t)
+	tl := MustNewTokenLimit(10, 5, "hybrid_token", store)
+	_, err = NewHybridLimit(tl, WithInstances(0))
+	require.Error(t, err)
+	_, err = NewHybridLimit(tl, WithProbeInterval(0))
+	require.Error(t, err)
+	assert.Panics(t, func() {
+		MustNewHybridPeriodLimit(nil)
+	})
+}
+
+func TestHybridLimit(t *testing.T) {
+	m, store := newHybridStore(t)
+	hl := MustNewHybridLimit(MustNewTokenLimit(10, 5, "hybrid_token", store),
+		WithName("test_hybrid_token"), WithInstances(2), WithProbeInterval(50*time.Millisecond))
+	defer hl.Close()
+	gauge := hybridModeGauge.WithLabelValues("test_hybrid_token")
+
+	ok, err := hl.Allow()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, ModeRemote, hl.Mode())
+	assert.Equal(t, float64(0), testutil.ToFloat64(gauge))
+
+	// redis 不可用时切换为进程内限流，容量按实例数量均分
+	m.Close()
+	now := time.Now()
+	var allowed int
+	for i := 0; i < 5; i++ {
+		ok, err := hl.AllowN(now, 1)
+		require.NoError(t, err)
+		if ok {
+			allowed++
+		}
+	}
+	assert.Equal(t, 3, allowed)
+	assert.Equal(t, ModeLocal, hl.Mode())
+	assert.Equal(t, float64(1), testutil.ToFloat64(gauge))
+
+	// 进程内令牌桶按均分后的速率恢复
+	ok, err = hl.AllowN(now.Add(200*time.Millisecond), 1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// redis 恢复后切换回 redis 限流
+	require.NoError(t, m.Restart())
+	require.Eventually(t, func() bool {
+		return hl.Mode() == ModeRemote
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, float64(0), testutil.ToFloat64(gauge))
+
+	ok, err = hl.Allow()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, m.Exists("hybrid_token"))
+}
+
+func TestHybridLimit_Close(t *testing.T) {
+	m, store := newHybridStore(t)
+	hl := MustNewHybridLimit(MustNewTokenLimit(10, 5, "hybrid_token", store),
+		WithName("test_hybrid_close"), WithProbeInterval(time.Hour))
+	gauge := hybridModeGauge.WithLabelValues("test_hybrid_close")
+
+	// 进程内限流时关闭，停止探测并切换回 redis 限流
+	m.Close()
+	_, err := hl.Allow()
+	require.NoError(t, err)
+	assert.Equal(t, ModeLocal, hl.Mode())
+	hl.Close()
+	require.Eventually(t, func() bool {
+		return hl.Mode() == ModeRemote
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, float64(0), testutil.ToFloat64(gauge))
+
+	// 关闭后 redis 出错时不再切换为进程内限流，返回 redis 错误
+	_, err = hl.Allow()
+	require.Error(t, err)
+	assert.Equal(t, ModeRemote, hl.Mode())
+	assert.Equal(t, float64(0), testutil.ToFloat64(gauge))
+}
+
+func TestHybridPeriodLimit_Close(t *testing.T) {
+	m, store := newHybridStore(t)
+	hpl := MustNewHybridPeriodLimit(MustNewPeriodLimit(10, 5, "hybrid_period:", store),
+		WithName("test_hybrid_period_close"), WithProbeInterval(time.Hour))
+	gauge := hybridModeGauge.WithLabelValues("test_hybrid_period_close")
+
+	// 关闭后 redis 出错时不再切换为进程内限流，返回 redis 错误
+	hpl.Close()
+	m.Close()
+	_, err := hpl.Take("key")
+	require.Error(t, err)
+	_, err = hpl.Get("key")
+	require.Error(t, err)
+	ok, err := hpl.Allow("key")
+	require.Error(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, ModeRemote, hpl.Mode())
+	assert.Equal(t, float64(0), testutil.ToFloat64(gauge))
+}
+
+func TestHybridPeriodLimit(t *testing.T) {
+	m, store := newHybridStore(t)
+	hpl := MustNewHybridPeriodLimit(MustNewPeriodLimit(10, 5, "hybrid_period:", store),
+		WithName("test_hybrid_period"), WithInstances(2), WithProbeInterval(50*time.Millisecond))
+	defer hpl.Close()
+	gauge := hybridModeGauge.WithLabelValues("test_hybrid_period")
+
+	remain, err := hpl.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, 4, remain)
+	assert.Equal(t, ModeRemote, hpl.Mode())
+
+	// redis 不可用时切换为进程内限流，配额按实例数量均分
+	m.Close()
+	var codes []int
+	for i := 0; i < 4; i++ {
+		code, err := hpl.Take("key")
+		require.NoError(t, err)
+		codes = append(codes, code)
+	}
+	assert.Equal(t, []int{Allowed, Allowed, Reached, Overflowed}, codes)
+	assert.Equal(t, ModeLocal, hpl.Mode())
+	assert.Equal(t, float64(1), testutil.ToFloat64(gauge))
+
+	ok, err := hpl.Allow("other")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	remain, err = hpl.Get("other", WithQuota(10))
+	require.NoError(t, err)
+	assert.Equal(t, 3, remain)
+
+	// redis 恢复后切换回 redis 限流，继续使用 redis 中的计数
+	require.NoError(t, m.Restart())
+	require.Eventually(t, func() bool {
+		return hpl.Mode() == ModeRemote
+	}, 5*time.Second, 20*time.Millisecond)
+	assert.Equal(t, float64(0), testutil.ToFloat64(gauge))
+
+	// 重启后首次请求可能因连接池中的失效连接被客户端重试，仅校验计数未被重置
+	remain, err = hpl.Get("key")
+	require.NoError(t, err)
+	assert.LessOrEqual(t, remain, 3)
+}
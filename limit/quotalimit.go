@@ -0,0 +1,290 @@
+package limit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+
+	"github.com/sliveryou/go-tool/v2/timex"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+const (
+	// consumeScript 消耗日历周期配额 lua 脚本，有序集合中记录当前周期内各键的已用配额，
+	// 超出配额时不消耗配额，返回是否放行和已用配额
+	consumeScript = `local quota = tonumber(ARGV[1]);
+local n = tonumber(ARGV[2]);
+local used = tonumber(redis.call("ZSCORE", KEYS[1], ARGV[3]) or "0");
+if used + n > quota then
+    return {0, used};
+end
+used = tonumber(redis.call("ZINCRBY", KEYS[1], n, ARGV[3]));
+redis.call("PEXPIREAT", KEYS[1], ARGV[4]);
+return {1, used};`
+
+	// refundScript 退还日历周期配额 lua 脚本，最多退还至已用配额为 0，返回已用配额
+	refundScript = `local n = tonumber(ARGV[1]);
+local used = tonumber(redis.call("ZSCORE", KEYS[1], ARGV[2]) or "0");
+if used <= 0 then
+    return used;
+end
+used = math.max(used - n, 0);
+redis.call("ZADD", KEYS[1], used, ARGV[2]);
+return used;`
+
+	// creditScript 补充日历周期配额 lua 脚本，已用配额可以小于 0，即当前周期内可用配额超过原配额，返回已用配额
+	creditScript = `local used = tonumber(redis.call("ZINCRBY", KEYS[1], -tonumber(ARGV[1]), ARGV[2]));
+redis.call("PEXPIREAT", KEYS[1], ARGV[3]);
+return used;`
+)
+
+// QuotaWindow 日历周期
+type QuotaWindow string
+
+const (
+	// WindowDay 自然日，零点重置
+	WindowDay QuotaWindow = "day"
+	// WindowWeek 自然周，周一零点重置
+	WindowWeek QuotaWindow = "week"
+	// WindowMonth 自然月，每月一日零点重置
+	WindowMonth QuotaWindow = "month"
+)
+
+// QuotaResult 日历周期配额结果
+type QuotaResult struct {
+	Allowed   bool      // 是否放行，查询时为是否仍有剩余配额
+	Limit     int       // 周期内配额
+	Used      int       // 周期内已用配额，补充配额后可能小于 0
+	Remaining int       // 周期内剩余配额
+	ResetAt   time.Time // 配额重置时间，即当前周期结束时间
+}
+
+// QuotaUsage 配额用量
+type QuotaUsage struct {
+	Key  string // 键
+	Used int    // 周期内已用配额
+}
+
+// QuotaLimit 日历周期配额限流器，配额在指定时区的自然日、自然周或自然月开始时统一重置，
+// 同一周期内全部键的已用配额记录在同一个有序集合中，支持用量查询、配额退还与补充和用量排行
+type QuotaLimit struct {
+	window   QuotaWindow
+	location *time.Location
+	option   option
+	store    *xkv.Store
+}
+
+// NewQuotaLimit 新建日历周期配额限流器，window：日历周期，quota：周期内配额，location：时区，为 nil 时使用本地时区
+func NewQuotaLimit(window QuotaWindow, quota int, keyPrefix string, location *time.Location, store *xkv.Store) (*QuotaLimit, error) {
+	if store == nil || quota <= 0 {
+		return nil, errors.New("limit: illegal quota limit config")
+	}
+	switch window {
+	case WindowDay, WindowWeek, WindowMonth:
+	default:
+		return nil, errors.Errorf("limit: illegal quota limit window: %s", window)
+	}
+	if location == nil {
+		location = time.Local
+	}
+
+	limiter := &QuotaLimit{
+		window:   window,
+		location: location,
+		option: option{
+			quota:     quota,
+			keyPrefix: keyPrefix,
+		},
+		store: store,
+	}
+
+	return limiter, nil
+}
+
+// MustNewQuotaLimit 新建日历周期配额限流器
+func MustNewQuotaLimit(window QuotaWindow, quota int, keyPrefix string, location *time.Location, store *xkv.Store) *QuotaLimit {
+	ql, err := NewQuotaLimit(window, quota, keyPrefix, location, store)
+	if err != nil {
+		panic(err)
+	}
+
+	return ql
+}
+
+// Window 获取 t 时刻所在日历周期的开始时间和结束时间
+func (ql *QuotaLimit) Window(t time.Time) (start, end time.Time) {
+	t = t.In(ql.location)
+	y, m, d := t.Date()
+
+	switch ql.window {
+	case WindowWeek:
+		// 以周一作为一周的开始
+		offset := (int(t.Weekday()) + 6) % 7
+		start = time.Date(y, m, d-offset, 0, 0, 0, 0, ql.location)
+		end = start.AddDate(0, 0, 7)
+	case WindowMonth:
+		start = time.Date(y, m, 1, 0, 0, 0, 0, ql.location)
+		end = start.AddDate(0, 1, 0)
+	default:
+		start = time.Date(y, m, d, 0, 0, 0, 0, ql.location)
+		end = start.AddDate(0, 0, 1)
+	}
+
+	return start, end
+}
+
+// Consume 消耗 n 个配额，超出配额时不消耗配额，并返回配额结果
+func (ql *QuotaLimit) Consume(ctx context.Context, key string, n int, opts ...Option) (*QuotaResult, error) {
+	return ql.consume(ctx, timex.Now(), key, n, opts...)
+}
+
+// Usage 查询当前周期内的配额用量，不消耗配额
+func (ql *QuotaLimit) Usage(ctx context.Context, key string, opts ...Option) (*QuotaResult, error) {
+	return ql.usage(ctx, timex.Now(), key, opts...)
+}
+
+// Refund 退还 n 个已消耗的配额，例如请求处理失败时，最多退还至已用配额为 0，并返回配额结果
+func (ql *QuotaLimit) Refund(ctx context.Context, key string, n int, opts ...Option) (*QuotaResult, error) {
+	return ql.adjust(ctx, timex.Now(), refundScript, key, n, opts...)
+}
+
+// Credit 为当前周期补充 n 个配额，补充的配额在周期结束时失效，并返回配额结果
+func (ql *QuotaLimit) Credit(ctx context.Context, key string, n int, opts ...Option) (*QuotaResult, error) {
+	return ql.adjust(ctx, timex.Now(), creditScript, key, n, opts...)
+}
+
+// Top 获取当前周期内已用配额最多的 n 个键，按已用配额降序排列
+func (ql *QuotaLimit) Top(ctx context.Context, n int, opts ...Option) ([]QuotaUsage, error) {
+	return ql.top(ctx, timex.Now(), n, opts...)
+}
+
+// consume 在 now 时刻消耗 n 个配额
+func (ql *QuotaLimit) consume(ctx context.Context, now time.Time, key string, n int, opts ...Option) (*QuotaResult, error) {
+	op, err := ql.buildOption(n, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	windowKey, end := ql.windowKey(now, op.keyPrefix)
+	resp, err := ql.store.EvalCtx(ctx, consumeScript, windowKey, op.quota, n, key, end.UnixMilli())
+	if err != nil {
+		return nil, errors.WithMessage(err, "store eval script err")
+	}
+
+	values, ok := resp.([]any)
+	if !ok || len(values) != 2 {
+		return nil, ErrUnexpectedType
+	}
+	allowed, ok1 := values[0].(int64)
+	used, ok2 := values[1].(int64)
+	if !ok1 || !ok2 {
+		return nil, ErrUnexpectedType
+	}
+
+	result := newQuotaResult(op.quota, int(used), end)
+	result.Allowed = allowed == 1
+
+	return result, nil
+}
+
+// usage 查询 now 时刻所在周期内的配额用量
+func (ql *QuotaLimit) usage(ctx context.Context, now time.Time, key string, opts ...Option) (*QuotaResult, error) {
+	op, err := ql.buildOption(1, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	windowKey, end := ql.windowKey(now, op.keyPrefix)
+	used, err := ql.store.ZscoreCtx(ctx, windowKey, key)
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, errors.WithMessage(err, "limit: get quota usage err")
+	}
+
+	return newQuotaResult(op.quota, int(used), end), nil
+}
+
+// adjust 在 now 时刻执行退还或补充配额脚本
+func (ql *QuotaLimit) adjust(ctx context.Context, now time.Time, script, key string, n int, opts ...Option) (*QuotaResult, error) {
+	op, err := ql.buildOption(n, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	windowKey, end := ql.windowKey(now, op.keyPrefix)
+	resp, err := ql.store.EvalCtx(ctx, script, windowKey, n, key, end.UnixMilli())
+	if err != nil {
+		return nil, errors.WithMessage(err, "store eval script err")
+	}
+
+	used, ok := resp.(int64)
+	if !ok {
+		return nil, ErrUnexpectedType
+	}
+
+	return newQuotaResult(op.quota, int(used), end), nil
+}
+
+// top 获取 now 时刻所在周期内已用配额最多的 n 个键
+func (ql *QuotaLimit) top(ctx context.Context, now time.Time, n int, opts ...Option) ([]QuotaUsage, error) {
+	op, err := ql.buildOption(n, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	windowKey, _ := ql.windowKey(now, op.keyPrefix)
+	pairs, err := ql.store.ZrevrangebyscoreWithScoresAndLimitCtx(ctx, windowKey, 1, math.MaxInt64, 0, n)
+	if err != nil {
+		return nil, errors.WithMessage(err, "limit: get quota top usages err")
+	}
+
+	usages := make([]QuotaUsage, 0, len(pairs))
+	for _, p := range pairs {
+		usages = append(usages, QuotaUsage{Key: p.Key, Used: int(p.Score)})
+	}
+
+	return usages, nil
+}
+
+// buildOption 构建配置并校验数量
+func (ql *QuotaLimit) buildOption(n int, opts ...Option) (*option, error) {
+	op := ql.option.clone()
+	for _, opt := range opts {
+		opt(op)
+	}
+	if n <= 0 || op.quota <= 0 {
+		return nil, errors.Errorf("limit: illegal quota limit n: %d or quota: %d", n, op.quota)
+	}
+
+	return op, nil
+}
+
+// windowKey 获取 now 时刻所在周期的有序集合键和周期结束时间
+func (ql *QuotaLimit) windowKey(now time.Time, keyPrefix string) (string, time.Time) {
+	start, end := ql.Window(now)
+	layout := "20060102"
+	if ql.window == WindowMonth {
+		layout = "200601"
+	}
+
+	return keyPrefix + string(ql.window) + ":" + start.Format(layout), end
+}
+
+// newQuotaResult 新建配额结果
+func newQuotaResult(quota, used int, resetAt time.Time) *QuotaResult {
+	remaining := quota - used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &QuotaResult{
+		Allowed:   remaining > 0,
+		Limit:     quota,
+		Used:      used,
+		Remaining: remaining,
+		ResetAt:   resetAt,
+	}
+}
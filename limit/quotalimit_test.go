@@ -0,0 +1,228 @@
+package limit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+
+	"github.com/sliveryou/go-tool/v2/timex"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+func runOnQuotaLimit(window QuotaWindow, fn func(ql *QuotaLimit)) {
+	s1.FlushAll()
+	s2.FlushAll()
+
+	store := xkv.NewStore([]cache.NodeConf{
+		{
+			RedisConf: redis.RedisConf{
+				Host: s1.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: 100,
+		},
+		{
+			RedisConf: redis.RedisConf{
+				Host: s2.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: 100,
+		},
+	})
+
+	// 每个周期配额为 5，使用上海时区
+	fn(MustNewQuotaLimit(window, 5, "quota_limit:", timex.Shanghai(), store))
+}
+
+func TestNewQuotaLimit(t *testing.T) {
+	_, err := NewQuotaLimit(WindowDay, 5, "", nil, nil)
+	require.Error(t, err)
+	_, err = NewQuotaLimit(WindowDay, 0, "", nil, &xkv.Store{})
+	require.Error(t, err)
+	_, err = NewQuotaLimit("year", 5, "", nil, &xkv.Store{})
+	require.Error(t, err)
+	assert.Panics(t, func() {
+		MustNewQuotaLimit(WindowDay, 5, "", nil, nil)
+	})
+
+	ql, err := NewQuotaLimit(WindowDay, 5, "", nil, &xkv.Store{})
+	require.NoError(t, err)
+	assert.Equal(t, time.Local, ql.location)
+}
+
+func TestQuotaLimit_Window(t *testing.T) {
+	sh := timex.Shanghai()
+	// 2026-10-15 17:30 UTC 即上海时区 2026-10-16 01:30（周五）
+	now := time.Date(2026, 10, 15, 17, 30, 0, 0, time.UTC)
+
+	cases := []struct {
+		window QuotaWindow
+		start  time.Time
+		end    time.Time
+		key    string
+	}{
+		{
+			window: WindowDay,
+			start:  time.Date(2026, 10, 16, 0, 0, 0, 0, sh),
+			end:    time.Date(2026, 10, 17, 0, 0, 0, 0, sh),
+			key:    "quota_limit:day:20261016",
+		},
+		{
+			window: WindowWeek,
+			start:  time.Date(2026, 10, 12, 0, 0, 0, 0, sh),
+			end:    time.Date(2026, 10, 19, 0, 0, 0, 0, sh),
+			key:    "quota_limit:week:20261012",
+		},
+		{
+			window: WindowMonth,
+			start:  time.Date(2026, 10, 1, 0, 0, 0, 0, sh),
+			end:    time.Date(2026, 11, 1, 0, 0, 0, 0, sh),
+			key:    "quota_limit:month:202610",
+		},
+	}
+
+	for _, c := range cases {
+		runOnQuotaLimit(c.window, func(ql *QuotaLimit) {
+			start, end := ql.Window(now)
+			assert.True(t, c.start.Equal(start), c.window)
+			assert.True(t, c.end.Equal(end), c.window)
+			key, _ := ql.windowKey(now, "quota_limit:")
+			assert.Equal(t, c.key, key)
+		})
+	}
+
+	// 周日属于上一周
+	runOnQuotaLimit(WindowWeek, func(ql *QuotaLimit) {
+		start, _ := ql.Window(time.Date(2026, 10, 18, 23, 0, 0, 0, sh))
+		assert.True(t, time.Date(2026, 10, 12, 0, 0, 0, 0, sh).Equal(start))
+	})
+}
+
+func TestQuotaLimit_Consume(t *testing.T) {
+	runOnQuotaLimit(WindowDay, func(ql *QuotaLimit) {
+		ctx := context.Background()
+		// 键在周期结束时过期，因此使用当前周期结束前 1 分钟作为测试时间
+		_, end := ql.Window(timex.Now())
+		now := end.Add(-time.Minute)
+		key := "test_key_for_quota_limit_consume"
+
+		r, err := ql.consume(ctx, now, key, 3)
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 3, r.Used)
+		assert.Equal(t, 2, r.Remaining)
+		assert.True(t, end.Equal(r.ResetAt))
+
+		// 超出配额时不消耗配额
+		r, err = ql.consume(ctx, now, key, 3)
+		require.NoError(t, err)
+		assert.False(t, r.Allowed)
+		assert.Equal(t, 3, r.Used)
+		r, err = ql.consume(ctx, now, key, 2)
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 0, r.Remaining)
+
+		// 临时提高配额
+		r, err = ql.consume(ctx, now, key, 1, WithQuota(10))
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 6, r.Used)
+
+		// 零点后进入新的周期，配额重置
+		r, err = ql.consume(ctx, now.Add(time.Minute), key, 5)
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 5, r.Used)
+
+		_, err = ql.consume(ctx, now, key, 0)
+		require.Error(t, err)
+		_, err = ql.consume(ctx, now, key, 1, WithQuota(0))
+		require.Error(t, err)
+
+		// 使用当前时间消耗配额
+		r, err = ql.Consume(ctx, "other", 1)
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.True(t, end.Equal(r.ResetAt))
+	})
+}
+
+func TestQuotaLimit_Adjust(t *testing.T) {
+	runOnQuotaLimit(WindowMonth, func(ql *QuotaLimit) {
+		ctx := context.Background()
+		key := "test_key_for_quota_limit_adjust"
+
+		r, err := ql.Usage(ctx, key)
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 0, r.Used)
+		assert.Equal(t, 5, r.Remaining)
+
+		_, err = ql.Consume(ctx, key, 4)
+		require.NoError(t, err)
+		r, err = ql.Usage(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, 4, r.Used)
+
+		// 最多退还至已用配额为 0
+		r, err = ql.Refund(ctx, key, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 3, r.Used)
+		r, err = ql.Refund(ctx, key, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 0, r.Used)
+		r, err = ql.Refund(ctx, "none", 1)
+		require.NoError(t, err)
+		assert.Equal(t, 0, r.Used)
+
+		// 补充配额后当前周期可用配额超过原配额
+		r, err = ql.Credit(ctx, key, 3)
+		require.NoError(t, err)
+		assert.Equal(t, -3, r.Used)
+		assert.Equal(t, 8, r.Remaining)
+		r, err = ql.Consume(ctx, key, 8)
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		r, err = ql.Consume(ctx, key, 1)
+		require.NoError(t, err)
+		assert.False(t, r.Allowed)
+
+		_, err = ql.Credit(ctx, key, 0)
+		require.Error(t, err)
+	})
+}
+
+func TestQuotaLimit_Top(t *testing.T) {
+	runOnQuotaLimit(WindowWeek, func(ql *QuotaLimit) {
+		ctx := context.Background()
+
+		for key, n := range map[string]int{"a": 1, "b": 5, "c": 3, "d": 2} {
+			_, err := ql.Consume(ctx, key, n)
+			require.NoError(t, err)
+		}
+		_, err := ql.Credit(ctx, "e", 2)
+		require.NoError(t, err)
+
+		usages, err := ql.Top(ctx, 3)
+		require.NoError(t, err)
+		assert.Equal(t, []QuotaUsage{{Key: "b", Used: 5}, {Key: "c", Used: 3}, {Key: "d", Used: 2}}, usages)
+
+		// 未使用配额的键不参与排行
+		usages, err = ql.Top(ctx, 10)
+		require.NoError(t, err)
+		assert.Len(t, usages, 4)
+
+		usages, err = ql.Top(ctx, 10, WithKeyPrefix("other:"))
+		require.NoError(t, err)
+		assert.Empty(t, usages)
+		_, err = ql.Top(ctx, 0)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,171 @@
+package limit
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+const (
+	// KeyByIP 按客户端 IP 限流
+	KeyByIP = "ip"
+	// KeyByJWT 按 JWT 载荷字段（如用户标识）限流
+	KeyByJWT = "jwt"
+	// KeyByAppKey 按签名校验后的应用 AppKey 限流
+	KeyByAppKey = "appkey"
+	// KeyByRoute 按路由限流，即全部客户端共享同一路由的配额
+	KeyByRoute = "route"
+
+	// DefaultRouteLimitKeyPrefix 默认路由限流键前缀
+	DefaultRouteLimitKeyPrefix = "micro.pkg:limit:route:"
+)
+
+// pathParamRegex 路径参数正则，匹配经 regexp.QuoteMeta 转义后的 {param}
+var pathParamRegex = regexp.MustCompile(`\\\{[^/]+?\\\}`)
+
+// RouteLimitConf 路由限流配置
+type RouteLimitConf struct {
+	KeyPrefix string      `json:",default=micro.pkg:limit:route:"` // 限流键前缀
+	Rules     []RouteRule `json:",optional"`                       // 路由限流规则，按顺序匹配，首个匹配的规则生效
+}
+
+// RouteRule 路由限流规则
+type RouteRule struct {
+	// 路由，为空时匹配全部路由，常见形式：
+	//   GET:/api/user/{id}
+	//   /api/file/*
+	//   /user.User/GetUser
+	Route    string        `json:",optional"`
+	KeyBy    string        `json:",default=ip,options=[ip,jwt,appkey,route]"` // 限流键来源（枚举 ip、jwt、appkey 和 route），默认为 ip
+	JWTField string        `json:",default=sub"`                              // 限流键来源为 jwt 时使用的 JWT 载荷字段，默认为 sub
+	Rate     int           // 速率，即每个时间段放行的请求数量
+	Period   time.Duration `json:",default=1s"` // 时间段，默认为 1s
+	Burst    int           `json:",optional"`   // 突发容量，默认与速率相同
+}
+
+// routeRule 编译后的路由限流规则
+type routeRule struct {
+	RouteRule
+	method  string         // 请求方法，为空时匹配全部请求方法
+	pattern *regexp.Regexp // 路径匹配正则，为 nil 时匹配全部路径
+}
+
+// RouteKey 获取按路由限流时的限流键，即规则的请求方法和路由，匹配该规则的全部请求共享同一限流键
+func (r *RouteRule) RouteKey() string {
+	if r.Route == "" {
+		return "*"
+	}
+
+	return r.Route
+}
+
+// match 判断请求方法和路径是否匹配该规则
+func (r *routeRule) match(method, path string) bool {
+	if r.method != "" && !strings.EqualFold(r.method, method) {
+		return false
+	}
+
+	return r.pattern == nil || r.pattern.MatchString(path)
+}
+
+// RouteLimit 路由限流器，按请求方法和路径匹配限流规则，并使用 GCRA 限流器对规则内的限流键限流
+type RouteLimit struct {
+	gl    *GCRALimit
+	rules []*routeRule
+}
+
+// NewRouteLimit 新建路由限流器
+func NewRouteLimit(c RouteLimitConf, store *xkv.Store) (*RouteLimit, error) {
+	if store == nil {
+		return nil, errors.New("limit: illegal route limit config")
+	}
+
+	keyPrefix := c.KeyPrefix
+	if keyPrefix == "" {
+		keyPrefix = DefaultRouteLimitKeyPrefix
+	}
+	gl, err := NewGCRALimit(1, time.Second, 1, keyPrefix, store)
+	if err != nil {
+		return nil, err
+	}
+
+	rl := &RouteLimit{gl: gl, rules: make([]*routeRule, 0, len(c.Rules))}
+	for _, rule := range c.Rules {
+		r, err := compileRouteRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		rl.rules = append(rl.rules, r)
+	}
+
+	return rl, nil
+}
+
+// MustNewRouteLimit 新建路由限流器
+func MustNewRouteLimit(c RouteLimitConf, store *xkv.Store) *RouteLimit {
+	rl, err := NewRouteLimit(c, store)
+	if err != nil {
+		panic(err)
+	}
+
+	return rl
+}
+
+// Match 获取请求方法和路径匹配的首个限流规则，gRPC 请求的 method 为空，path 为完整方法名
+func (rl *RouteLimit) Match(method, path string) (*RouteRule, bool) {
+	for _, r := range rl.rules {
+		if r.match(method, path) {
+			return &r.RouteRule, true
+		}
+	}
+
+	return nil, false
+}
+
+// Allow 访问路由限流器，为规则内的限流键拿取 1 个配额，并返回限流结果
+func (rl *RouteLimit) Allow(rule *RouteRule, key string) (*GCRAResult, error) {
+	return rl.gl.Allow(rule.Route+":"+rule.KeyBy+":"+key,
+		WithRatePer(rule.Rate, rule.Period), WithBurst(rule.Burst))
+}
+
+// compileRouteRule 校验并编译路由限流规则
+func compileRouteRule(rule RouteRule) (*routeRule, error) {
+	if rule.KeyBy == "" {
+		rule.KeyBy = KeyByIP
+	}
+	if rule.KeyBy == KeyByJWT && rule.JWTField == "" {
+		rule.JWTField = "sub"
+	}
+	if rule.Period == 0 {
+		rule.Period = time.Second
+	}
+	if rule.Burst == 0 {
+		rule.Burst = rule.Rate
+	}
+
+	switch rule.KeyBy {
+	case KeyByIP, KeyByJWT, KeyByAppKey, KeyByRoute:
+	default:
+		return nil, errors.Errorf("limit: illegal route rule key by: %s", rule.KeyBy)
+	}
+	if rule.Rate <= 0 || rule.Period < time.Millisecond || rule.Burst <= 0 {
+		return nil, errors.Errorf("limit: illegal route rule: %+v", rule)
+	}
+
+	r := &routeRule{RouteRule: rule}
+	path := rule.Route
+	if parts := strings.SplitN(path, ":", 2); len(parts) == 2 {
+		r.method, path = parts[0], parts[1]
+	}
+	if path != "" {
+		expr := strings.ReplaceAll(regexp.QuoteMeta(path), `/\*`, "/.*")
+		expr = pathParamRegex.ReplaceAllString(expr, "[^/]+")
+		r.pattern = regexp.MustCompile("^" + expr + "$")
+	}
+
+	return r, nil
+}
@@ -0,0 +1,129 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+func runOnRouteLimit(c RouteLimitConf, fn func(rl *RouteLimit)) {
+	s1.FlushAll()
+	s2.FlushAll()
+
+	store := xkv.NewStore([]cache.NodeConf{
+		{
+			RedisConf: redis.RedisConf{
+				Host: s1.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: 100,
+		},
+		{
+			RedisConf: redis.RedisConf{
+				Host: s2.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: 100,
+		},
+	})
+
+	fn(MustNewRouteLimit(c, store))
+}
+
+func TestNewRouteLimit(t *testing.T) {
+	_, err := NewRouteLimit(RouteLimitConf{}, nil)
+	require.Error(t, err)
+	assert.Panics(t, func() {
+		MustNewRouteLimit(RouteLimitConf{}, nil)
+	})
+
+	store := &xkv.Store{}
+	_, err = NewRouteLimit(RouteLimitConf{Rules: []RouteRule{{Rate: 0}}}, store)
+	require.Error(t, err)
+	_, err = NewRouteLimit(RouteLimitConf{Rules: []RouteRule{{KeyBy: "header", Rate: 1}}}, store)
+	require.Error(t, err)
+	_, err = NewRouteLimit(RouteLimitConf{Rules: []RouteRule{{Rate: 1, Burst: -1}}}, store)
+	require.Error(t, err)
+}
+
+func TestRouteLimit_Match(t *testing.T) {
+	runOnRouteLimit(RouteLimitConf{
+		Rules: []RouteRule{
+			{Route: "POST:/api/user/{id}", KeyBy: KeyByJWT, Rate: 1},
+			{Route: "/api/file/*", KeyBy: KeyByAppKey, Rate: 2},
+			{Route: "/user.User/GetUser", KeyBy: KeyByRoute, Rate: 3},
+			{Rate: 4},
+		},
+	}, func(rl *RouteLimit) {
+		cases := []struct {
+			method string
+			path   string
+			expect int
+		}{
+			{method: "POST", path: "/api/user/1", expect: 1},
+			{method: "post", path: "/api/user/1", expect: 1},
+			{method: "GET", path: "/api/user/1", expect: 4},
+			{method: "POST", path: "/api/user/1/avatar", expect: 4},
+			{method: "GET", path: "/api/file/a/b.png", expect: 2},
+			{method: "", path: "/user.User/GetUser", expect: 3},
+			{method: "", path: "/userXUser/GetUser", expect: 4},
+		}
+
+		for _, c := range cases {
+			rule, ok := rl.Match(c.method, c.path)
+			require.True(t, ok)
+			assert.Equal(t, c.expect, rule.Rate, c)
+		}
+
+		rule, _ := rl.Match("POST", "/api/user/1")
+		assert.Equal(t, "sub", rule.JWTField)
+		assert.Equal(t, time.Second, rule.Period)
+		assert.Equal(t, 1, rule.Burst)
+		assert.Equal(t, "POST:/api/user/{id}", rule.RouteKey())
+		rule, _ = rl.Match("GET", "/")
+		assert.Equal(t, KeyByIP, rule.KeyBy)
+		assert.Equal(t, "*", rule.RouteKey())
+	})
+
+	runOnRouteLimit(RouteLimitConf{}, func(rl *RouteLimit) {
+		_, ok := rl.Match("GET", "/api/user/1")
+		assert.False(t, ok)
+	})
+}
+
+func TestRouteLimit_Allow(t *testing.T) {
+	runOnRouteLimit(RouteLimitConf{
+		Rules: []RouteRule{
+			{Route: "GET:/api/user", Rate: 2, Period: time.Minute},
+			{Rate: 1, Period: time.Minute, Burst: 3},
+		},
+	}, func(rl *RouteLimit) {
+		rule, _ := rl.Match("GET", "/api/user")
+		for i := 0; i < 2; i++ {
+			r, err := rl.Allow(rule, "127.0.0.1")
+			require.NoError(t, err)
+			assert.True(t, r.Allowed)
+			assert.Equal(t, 2, r.Limit)
+		}
+		r, err := rl.Allow(rule, "127.0.0.1")
+		require.NoError(t, err)
+		assert.False(t, r.Allowed)
+
+		// 不同限流键和不同规则的配额互不影响
+		r, err = rl.Allow(rule, "127.0.0.2")
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		rule, _ = rl.Match("GET", "/api/file")
+		r, err = rl.Allow(rule, "127.0.0.1")
+		require.NoError(t, err)
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 3, r.Limit)
+		assert.Equal(t, 2, r.Remaining)
+	})
+}
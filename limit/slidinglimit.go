@@ -0,0 +1,126 @@
+package limit
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zeromicro/go-zero/core/stringx"
+
+	"github.com/sliveryou/go-tool/v2/timex"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+const (
+	// slidingScript 滑动窗口限流 lua 脚本，有序集合中记录窗口内已放行请求的时间戳（毫秒），
+	// 被拒绝的请求不计入窗口，返回拿取配额后的剩余配额
+	slidingScript = `local limit = tonumber(ARGV[1]);
+local window = tonumber(ARGV[2]) * 1000;
+local now = tonumber(ARGV[3]);
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window);
+local current = redis.call("ZCARD", KEYS[1]) + 1;
+if current <= limit then
+    redis.call("ZADD", KEYS[1], now, ARGV[4]);
+    redis.call("PEXPIRE", KEYS[1], window);
+end
+return limit - current;`
+)
+
+// SlidingLimit 滑动窗口限流器，统计截至当前时刻的一个时间段内已放行的请求数，
+// 避免时间段限流器在窗口边界前后各用满配额导致的两倍突发
+type SlidingLimit struct {
+	option option
+	store  *xkv.Store
+}
+
+// NewSlidingLimit 新建滑动窗口限流器
+func NewSlidingLimit(period, quota int, keyPrefix string, store *xkv.Store) (*SlidingLimit, error) {
+	if store == nil || period <= 0 || quota <= 0 {
+		return nil, errors.New("limit: illegal sliding limit config")
+	}
+
+	limiter := &SlidingLimit{
+		option: option{
+			period:    period,
+			quota:     quota,
+			keyPrefix: keyPrefix,
+		},
+		store: store,
+	}
+
+	return limiter, nil
+}
+
+// MustNewSlidingLimit 新建滑动窗口限流器
+func MustNewSlidingLimit(period, quota int, keyPrefix string, store *xkv.Store) *SlidingLimit {
+	sl, err := NewSlidingLimit(period, quota, keyPrefix, store)
+	if err != nil {
+		panic(err)
+	}
+
+	return sl
+}
+
+// Take 访问滑动窗口限流器拿取配额，并返回相关状态码
+func (sl *SlidingLimit) Take(key string, opts ...Option) (int, error) {
+	remain, err := sl.take(timex.Now(), key, opts...)
+	if err != nil {
+		return Unknown, err
+	}
+
+	switch {
+	case remain > 0:
+		return Allowed, nil
+	case remain == 0:
+		return Reached, nil
+	default:
+		return Overflowed, nil
+	}
+}
+
+// Get 访问滑动窗口限流器拿取配额，并返回剩余配额，返回值含义
+//
+//	> 0：剩余配额大于 0，Allowed
+//	= 0：剩余配额等于 0，Reached
+//	< 0：剩余配额小于 0，Overflowed
+func (sl *SlidingLimit) Get(key string, opts ...Option) (int, error) {
+	return sl.take(timex.Now(), key, opts...)
+}
+
+// Allow 访问滑动窗口限流器拿取配额，并判断是否允许放行
+func (sl *SlidingLimit) Allow(key string, opts ...Option) (bool, error) {
+	code, err := sl.Take(key, opts...)
+	if err != nil {
+		return false, err
+	}
+
+	if code == Allowed || code == Reached {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// take 在 now 时刻拿取配额，并返回剩余配额
+func (sl *SlidingLimit) take(now time.Time, key string, opts ...Option) (int, error) {
+	op := sl.option.clone()
+	for _, opt := range opts {
+		opt(op)
+	}
+
+	ms := now.UnixMilli()
+	resp, err := sl.store.Eval(slidingScript, op.keyPrefix+key,
+		op.quota, op.period, ms, strconv.FormatInt(ms, 10)+":"+stringx.Randn(8),
+	)
+	if err != nil {
+		return InvalidQuota, errors.WithMessage(err, "store eval script err")
+	}
+
+	remain, ok := resp.(int64)
+	if !ok {
+		return InvalidQuota, ErrUnexpectedType
+	}
+
+	return int(remain), nil
+}
@@ -0,0 +1,137 @@
+package limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+func runOnSlidingLimit(fn func(sl *SlidingLimit)) {
+	s1.FlushAll()
+	s2.FlushAll()
+
+	store := xkv.NewStore([]cache.NodeConf{
+		{
+			RedisConf: redis.RedisConf{
+				Host: s1.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: 100,
+		},
+		{
+			RedisConf: redis.RedisConf{
+				Host: s2.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: 100,
+		},
+	})
+
+	// 10s 内允许请求 5 次
+	fn(MustNewSlidingLimit(10, 5, "sliding_limit:", store))
+}
+
+func TestNewSlidingLimit(t *testing.T) {
+	_, err := NewSlidingLimit(0, 5, "", &xkv.Store{})
+	require.Error(t, err)
+	_, err = NewSlidingLimit(10, 0, "", &xkv.Store{})
+	require.Error(t, err)
+	_, err = NewSlidingLimit(10, 5, "", nil)
+	require.Error(t, err)
+	assert.Panics(t, func() {
+		MustNewSlidingLimit(10, 5, "", nil)
+	})
+}
+
+func TestSlidingLimit_Take(t *testing.T) {
+	runOnSlidingLimit(func(sl *SlidingLimit) {
+		testKey := "test_key_for_sliding_limit_take"
+		var allowed, reached, overflowed int
+		for i := 0; i < 20; i++ {
+			code, err := sl.Take(testKey, WithQuota(10))
+			require.NoError(t, err)
+			switch code {
+			case Allowed:
+				allowed++
+			case Reached:
+				reached++
+			case Overflowed:
+				overflowed++
+			}
+		}
+
+		assert.Equal(t, 9, allowed)
+		assert.Equal(t, 1, reached)
+		assert.Equal(t, 10, overflowed)
+	})
+}
+
+func TestSlidingLimit_Get(t *testing.T) {
+	runOnSlidingLimit(func(sl *SlidingLimit) {
+		testKey := "test_key_for_sliding_limit_get"
+		for i := 4; i >= -3; i-- {
+			remain, err := sl.Get(testKey)
+			require.NoError(t, err)
+			if i >= 0 {
+				assert.Equal(t, i, remain)
+			} else {
+				// 被拒绝的请求不计入窗口
+				assert.Equal(t, -1, remain)
+			}
+		}
+	})
+}
+
+func TestSlidingLimit_Allow(t *testing.T) {
+	runOnSlidingLimit(func(sl *SlidingLimit) {
+		testKey := "test_key_for_sliding_limit_allow"
+		var allowed int
+		for i := 0; i < 10; i++ {
+			ok, err := sl.Allow(testKey)
+			require.NoError(t, err)
+			if ok {
+				allowed++
+			}
+		}
+
+		assert.Equal(t, 5, allowed)
+	})
+}
+
+func TestSlidingLimit_Window(t *testing.T) {
+	runOnSlidingLimit(func(sl *SlidingLimit) {
+		testKey := "test_key_for_sliding_limit_window"
+		start := time.Date(2024, 1, 1, 0, 0, 9, 0, time.UTC)
+
+		// 窗口末尾用满配额
+		for i := 0; i < 5; i++ {
+			remain, err := sl.take(start.Add(time.Duration(i)*100*time.Millisecond), testKey)
+			require.NoError(t, err)
+			assert.Equal(t, 4-i, remain)
+		}
+
+		// 固定窗口在 10s 处重置，滑动窗口在最早的请求移出窗口前仍拒绝
+		for _, d := range []time.Duration{time.Second, 5 * time.Second, 9999 * time.Millisecond} {
+			remain, err := sl.take(start.Add(d), testKey)
+			require.NoError(t, err)
+			assert.Equal(t, -1, remain, d)
+		}
+
+		// 最早的请求移出窗口后逐个释放配额
+		remain, err := sl.take(start.Add(10*time.Second), testKey)
+		require.NoError(t, err)
+		assert.Equal(t, 0, remain)
+		remain, err = sl.take(start.Add(10*time.Second+50*time.Millisecond), testKey)
+		require.NoError(t, err)
+		assert.Equal(t, -1, remain)
+		remain, err = sl.take(start.Add(10*time.Second+100*time.Millisecond), testKey)
+		require.NoError(t, err)
+		assert.Equal(t, 0, remain)
+	})
+}
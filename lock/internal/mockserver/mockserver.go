@@ -15,14 +15,12 @@
 package mockserver
 
 import (
-	"context"
 	"fmt"
 	"net"
 	"os"
 	"sync"
 
 	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
-	"go.etcd.io/etcd/api/v3/mvccpb"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/resolver"
 )
@@ -51,6 +49,7 @@ type MockServers struct {
 	mu      sync.RWMutex
 	Servers []*MockServer
 	wg      sync.WaitGroup
+	store   *store
 }
 
 // StartMockServers creates the desired count of mock servers
@@ -101,6 +100,7 @@ func startMockServers(network string, addrs []string) (ms *MockServers, err erro
 	ms = &MockServers{
 		Servers: make([]*MockServer, len(addrs)),
 		wg:      sync.WaitGroup{},
+		store:   newStore(),
 	}
 	defer func() {
 		if err != nil {
@@ -131,8 +131,9 @@ func (ms *MockServers) StartAt(idx int) (err error) {
 	}
 
 	svr := grpc.NewServer()
-	pb.RegisterKVServer(svr, &mockKVServer{})
-	pb.RegisterLeaseServer(svr, &mockLeaseServer{})
+	pb.RegisterKVServer(svr, &mockKVServer{s: ms.store})
+	pb.RegisterLeaseServer(svr, &mockLeaseServer{s: ms.store})
+	pb.RegisterWatchServer(svr, &mockWatchServer{s: ms.store})
 	ms.Servers[idx].GrpcServer = svr
 
 	ms.wg.Add(1)
@@ -160,94 +161,10 @@ func (ms *MockServers) StopAt(idx int) {
 // Stop stops the mock server, immediately closing all open connections and listeners.
 func (ms *MockServers) Stop() {
 	for idx := range ms.Servers {
-		ms.StopAt(idx)
+		if ms.Servers[idx] != nil {
+			ms.StopAt(idx)
+		}
 	}
 	ms.wg.Wait()
-}
-
-type mockKVServer struct{}
-
-func (m *mockKVServer) Range(context.Context, *pb.RangeRequest) (*pb.RangeResponse, error) {
-	return &pb.RangeResponse{
-		Header: &pb.ResponseHeader{},
-	}, nil
-}
-
-func (m *mockKVServer) Put(context.Context, *pb.PutRequest) (*pb.PutResponse, error) {
-	return &pb.PutResponse{
-		Header: &pb.ResponseHeader{},
-	}, nil
-}
-
-func (m *mockKVServer) DeleteRange(context.Context, *pb.DeleteRangeRequest) (*pb.DeleteRangeResponse, error) {
-	return &pb.DeleteRangeResponse{
-		Header: &pb.ResponseHeader{},
-	}, nil
-}
-
-func (m *mockKVServer) Txn(context.Context, *pb.TxnRequest) (*pb.TxnResponse, error) {
-	return &pb.TxnResponse{
-		Header:    &pb.ResponseHeader{},
-		Succeeded: true,
-		Responses: []*pb.ResponseOp{
-			{
-				Response: &pb.ResponseOp_ResponseRange{
-					ResponseRange: &pb.RangeResponse{
-						Header: &pb.ResponseHeader{},
-						Kvs:    []*mvccpb.KeyValue{{}},
-					},
-				},
-			},
-			{
-				Response: &pb.ResponseOp_ResponseRange{
-					ResponseRange: &pb.RangeResponse{
-						Header: &pb.ResponseHeader{},
-						Kvs:    []*mvccpb.KeyValue{{}},
-					},
-				},
-			},
-		},
-	}, nil
-}
-
-func (m *mockKVServer) Compact(context.Context, *pb.CompactionRequest) (*pb.CompactionResponse, error) {
-	return &pb.CompactionResponse{
-		Header: &pb.ResponseHeader{},
-	}, nil
-}
-
-func (m *mockKVServer) Lease(context.Context, *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
-	return &pb.LeaseGrantResponse{
-		Header: &pb.ResponseHeader{},
-	}, nil
-}
-
-type mockLeaseServer struct{}
-
-func (s mockLeaseServer) LeaseGrant(context.Context, *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
-	return &pb.LeaseGrantResponse{
-		Header: &pb.ResponseHeader{},
-	}, nil
-}
-
-func (s *mockLeaseServer) LeaseRevoke(context.Context, *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
-	return &pb.LeaseRevokeResponse{
-		Header: &pb.ResponseHeader{},
-	}, nil
-}
-
-func (s *mockLeaseServer) LeaseKeepAlive(pb.Lease_LeaseKeepAliveServer) error {
-	return nil
-}
-
-func (s *mockLeaseServer) LeaseTimeToLive(context.Context, *pb.LeaseTimeToLiveRequest) (*pb.LeaseTimeToLiveResponse, error) {
-	return &pb.LeaseTimeToLiveResponse{
-		Header: &pb.ResponseHeader{},
-	}, nil
-}
-
-func (s *mockLeaseServer) LeaseLeases(context.Context, *pb.LeaseLeasesRequest) (*pb.LeaseLeasesResponse, error) {
-	return &pb.LeaseLeasesResponse{
-		Header: &pb.ResponseHeader{},
-	}, nil
+	ms.store.close()
 }
@@ -0,0 +1,606 @@
+package mockserver
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+// store is a minimal in-memory mvcc store shared by all mock servers of a cluster.
+// It keeps the latest version of each key, a full event history for watches and
+// leases with expiry, which is enough for the etcd concurrency recipes.
+type store struct {
+	mu      sync.Mutex
+	rev     int64
+	kvs     map[string]*mvccpb.KeyValue
+	events  []*mvccpb.Event
+	leases  map[int64]*lease
+	leaseID int64
+	changed chan struct{}
+	stop    chan struct{}
+}
+
+type lease struct {
+	id     int64
+	ttl    int64
+	expiry time.Time
+	keys   map[string]struct{}
+}
+
+func newStore() *store {
+	s := &store{
+		rev:     1,
+		kvs:     make(map[string]*mvccpb.KeyValue),
+		leases:  make(map[int64]*lease),
+		changed: make(chan struct{}),
+		stop:    make(chan struct{}),
+	}
+	go s.expireLoop()
+
+	return s
+}
+
+func (s *store) close() {
+	close(s.stop)
+}
+
+func (s *store) header() *pb.ResponseHeader {
+	return &pb.ResponseHeader{ClusterId: 1, MemberId: 1, Revision: s.rev, RaftTerm: 1}
+}
+
+// notify wakes up all watchers, must be called with s.mu held.
+func (s *store) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *store) expireLoop() {
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for id, l := range s.leases {
+				if now.After(l.expiry) {
+					s.revokeLocked(id)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// revokeLocked deletes the lease and all keys attached to it, must be called with s.mu held.
+func (s *store) revokeLocked(id int64) bool {
+	l, ok := s.leases[id]
+	if !ok {
+		return false
+	}
+	delete(s.leases, id)
+
+	if len(l.keys) > 0 {
+		rev := s.rev + 1
+		for key := range l.keys {
+			s.deleteKey(key, rev)
+		}
+		s.rev = rev
+		s.notify()
+	}
+
+	return true
+}
+
+// match reports whether key is in the range described by key and rangeEnd.
+func match(key, start, end []byte) bool {
+	switch {
+	case len(end) == 0:
+		return bytes.Equal(key, start)
+	case len(end) == 1 && end[0] == 0:
+		return bytes.Compare(key, start) >= 0
+	default:
+		return bytes.Compare(key, start) >= 0 && bytes.Compare(key, end) < 0
+	}
+}
+
+func (s *store) rangeKeys(r *pb.RangeRequest) *pb.RangeResponse {
+	var kvs []*mvccpb.KeyValue
+	for _, kv := range s.kvs {
+		if !match(kv.Key, r.Key, r.RangeEnd) {
+			continue
+		}
+		if (r.MinModRevision > 0 && kv.ModRevision < r.MinModRevision) ||
+			(r.MaxModRevision > 0 && kv.ModRevision > r.MaxModRevision) ||
+			(r.MinCreateRevision > 0 && kv.CreateRevision < r.MinCreateRevision) ||
+			(r.MaxCreateRevision > 0 && kv.CreateRevision > r.MaxCreateRevision) {
+			continue
+		}
+		kvs = append(kvs, kv)
+	}
+
+	less := func(a, b *mvccpb.KeyValue) bool { return bytes.Compare(a.Key, b.Key) < 0 }
+	switch r.SortTarget {
+	case pb.RangeRequest_VERSION:
+		less = func(a, b *mvccpb.KeyValue) bool { return a.Version < b.Version }
+	case pb.RangeRequest_CREATE:
+		less = func(a, b *mvccpb.KeyValue) bool { return a.CreateRevision < b.CreateRevision }
+	case pb.RangeRequest_MOD:
+		less = func(a, b *mvccpb.KeyValue) bool { return a.ModRevision < b.ModRevision }
+	case pb.RangeRequest_VALUE:
+		less = func(a, b *mvccpb.KeyValue) bool { return bytes.Compare(a.Value, b.Value) < 0 }
+	}
+	sort.Slice(kvs, func(i, j int) bool {
+		if r.SortOrder == pb.RangeRequest_DESCEND {
+			return less(kvs[j], kvs[i])
+		}
+		return less(kvs[i], kvs[j])
+	})
+
+	resp := &pb.RangeResponse{Header: s.header(), Count: int64(len(kvs))}
+	if r.CountOnly {
+		return resp
+	}
+	if r.Limit > 0 && int64(len(kvs)) > r.Limit {
+		kvs, resp.More = kvs[:r.Limit], true
+	}
+	for _, kv := range kvs {
+		c := *kv
+		if r.KeysOnly {
+			c.Value = nil
+		}
+		resp.Kvs = append(resp.Kvs, &c)
+	}
+
+	return resp
+}
+
+func (s *store) putKey(r *pb.PutRequest, rev int64) (*pb.PutResponse, error) {
+	if r.Lease != 0 {
+		if _, ok := s.leases[r.Lease]; !ok {
+			return nil, rpctypes.ErrGRPCLeaseNotFound
+		}
+	}
+
+	key := string(r.Key)
+	kv := &mvccpb.KeyValue{Key: r.Key, Value: r.Value, Lease: r.Lease, CreateRevision: rev, ModRevision: rev, Version: 1}
+	resp := &pb.PutResponse{}
+	if prev, ok := s.kvs[key]; ok {
+		kv.CreateRevision, kv.Version = prev.CreateRevision, prev.Version+1
+		if r.IgnoreValue {
+			kv.Value = prev.Value
+		}
+		if r.IgnoreLease {
+			kv.Lease = prev.Lease
+		}
+		if l, ok := s.leases[prev.Lease]; ok {
+			delete(l.keys, key)
+		}
+		if r.PrevKv {
+			c := *prev
+			resp.PrevKv = &c
+		}
+	}
+	if l, ok := s.leases[kv.Lease]; ok {
+		l.keys[key] = struct{}{}
+	}
+
+	s.kvs[key] = kv
+	s.events = append(s.events, &mvccpb.Event{Type: mvccpb.PUT, Kv: kv})
+
+	return resp, nil
+}
+
+func (s *store) deleteKey(key string, rev int64) *mvccpb.KeyValue {
+	prev, ok := s.kvs[key]
+	if !ok {
+		return nil
+	}
+	if l, ok := s.leases[prev.Lease]; ok {
+		delete(l.keys, key)
+	}
+	delete(s.kvs, key)
+	s.events = append(s.events, &mvccpb.Event{
+		Type:   mvccpb.DELETE,
+		Kv:     &mvccpb.KeyValue{Key: prev.Key, ModRevision: rev},
+		PrevKv: prev,
+	})
+
+	return prev
+}
+
+func (s *store) deleteRange(r *pb.DeleteRangeRequest, rev int64) *pb.DeleteRangeResponse {
+	resp := &pb.DeleteRangeResponse{}
+	for key, kv := range s.kvs {
+		if match(kv.Key, r.Key, r.RangeEnd) {
+			prev := s.deleteKey(key, rev)
+			resp.Deleted++
+			if r.PrevKv {
+				resp.PrevKvs = append(resp.PrevKvs, prev)
+			}
+		}
+	}
+
+	return resp
+}
+
+func (s *store) compare(c *pb.Compare) bool {
+	kv, ok := s.kvs[string(c.Key)]
+	if !ok {
+		if c.Target == pb.Compare_VALUE {
+			return false
+		}
+		kv = &mvccpb.KeyValue{}
+	}
+
+	var result int
+	switch c.Target {
+	case pb.Compare_VERSION:
+		result = compareInt(kv.Version, c.GetVersion())
+	case pb.Compare_CREATE:
+		result = compareInt(kv.CreateRevision, c.GetCreateRevision())
+	case pb.Compare_MOD:
+		result = compareInt(kv.ModRevision, c.GetModRevision())
+	case pb.Compare_VALUE:
+		result = bytes.Compare(kv.Value, c.GetValue())
+	case pb.Compare_LEASE:
+		result = compareInt(kv.Lease, c.GetLease())
+	}
+
+	switch c.Result {
+	case pb.Compare_EQUAL:
+		return result == 0
+	case pb.Compare_GREATER:
+		return result > 0
+	case pb.Compare_LESS:
+		return result < 0
+	default:
+		return result != 0
+	}
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// txn executes the request ops, all writes of a txn share the same revision.
+func (s *store) txn(r *pb.TxnRequest, rev int64) (*pb.TxnResponse, bool, error) {
+	succeeded := true
+	for _, c := range r.Compare {
+		if !s.compare(c) {
+			succeeded = false
+			break
+		}
+	}
+
+	ops := r.Success
+	if !succeeded {
+		ops = r.Failure
+	}
+
+	resp := &pb.TxnResponse{Succeeded: succeeded}
+	wrote := false
+	for _, op := range ops {
+		switch req := op.Request.(type) {
+		case *pb.RequestOp_RequestRange:
+			resp.Responses = append(resp.Responses, &pb.ResponseOp{
+				Response: &pb.ResponseOp_ResponseRange{ResponseRange: s.rangeKeys(req.RequestRange)},
+			})
+		case *pb.RequestOp_RequestPut:
+			pr, err := s.putKey(req.RequestPut, rev)
+			if err != nil {
+				return nil, false, err
+			}
+			wrote = true
+			resp.Responses = append(resp.Responses, &pb.ResponseOp{
+				Response: &pb.ResponseOp_ResponsePut{ResponsePut: pr},
+			})
+		case *pb.RequestOp_RequestDeleteRange:
+			dr := s.deleteRange(req.RequestDeleteRange, rev)
+			wrote = wrote || dr.Deleted > 0
+			resp.Responses = append(resp.Responses, &pb.ResponseOp{
+				Response: &pb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: dr},
+			})
+		case *pb.RequestOp_RequestTxn:
+			tr, w, err := s.txn(req.RequestTxn, rev)
+			if err != nil {
+				return nil, false, err
+			}
+			wrote = wrote || w
+			resp.Responses = append(resp.Responses, &pb.ResponseOp{
+				Response: &pb.ResponseOp_ResponseTxn{ResponseTxn: tr},
+			})
+		}
+	}
+
+	return resp, wrote, nil
+}
+
+// write runs fn as a single write at the next revision and returns the response header.
+func (s *store) write(fn func(rev int64) (bool, error)) (*pb.ResponseHeader, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wrote, err := fn(s.rev + 1)
+	if err != nil {
+		return nil, err
+	}
+	if wrote {
+		s.rev++
+		s.notify()
+	}
+
+	return s.header(), nil
+}
+
+type mockKVServer struct {
+	pb.UnimplementedKVServer
+	s *store
+}
+
+func (m *mockKVServer) Range(_ context.Context, r *pb.RangeRequest) (*pb.RangeResponse, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	return m.s.rangeKeys(r), nil
+}
+
+func (m *mockKVServer) Put(_ context.Context, r *pb.PutRequest) (resp *pb.PutResponse, err error) {
+	header, err := m.s.write(func(rev int64) (bool, error) {
+		resp, err = m.s.putKey(r, rev)
+		return err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Header = header
+
+	return resp, nil
+}
+
+func (m *mockKVServer) DeleteRange(_ context.Context, r *pb.DeleteRangeRequest) (resp *pb.DeleteRangeResponse, err error) {
+	header, _ := m.s.write(func(rev int64) (bool, error) {
+		resp = m.s.deleteRange(r, rev)
+		return resp.Deleted > 0, nil
+	})
+	resp.Header = header
+
+	return resp, nil
+}
+
+func (m *mockKVServer) Txn(_ context.Context, r *pb.TxnRequest) (resp *pb.TxnResponse, err error) {
+	header, err := m.s.write(func(rev int64) (wrote bool, err error) {
+		resp, wrote, err = m.s.txn(r, rev)
+		return wrote, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.Header = header
+	fillTxnHeader(resp, resp.Header)
+
+	return resp, nil
+}
+
+func fillTxnHeader(resp *pb.TxnResponse, h *pb.ResponseHeader) {
+	for _, op := range resp.Responses {
+		switch r := op.Response.(type) {
+		case *pb.ResponseOp_ResponseRange:
+			r.ResponseRange.Header = h
+		case *pb.ResponseOp_ResponsePut:
+			r.ResponsePut.Header = h
+		case *pb.ResponseOp_ResponseDeleteRange:
+			r.ResponseDeleteRange.Header = h
+		case *pb.ResponseOp_ResponseTxn:
+			r.ResponseTxn.Header = h
+			fillTxnHeader(r.ResponseTxn, h)
+		}
+	}
+}
+
+func (m *mockKVServer) Compact(context.Context, *pb.CompactionRequest) (*pb.CompactionResponse, error) {
+	return &pb.CompactionResponse{Header: m.s.lockedHeader()}, nil
+}
+
+func (s *store) lockedHeader() *pb.ResponseHeader {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.header()
+}
+
+type mockLeaseServer struct {
+	pb.UnimplementedLeaseServer
+	s *store
+}
+
+func (m *mockLeaseServer) LeaseGrant(_ context.Context, r *pb.LeaseGrantRequest) (*pb.LeaseGrantResponse, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	id := r.ID
+	if id == 0 {
+		m.s.leaseID++
+		id = m.s.leaseID
+	}
+	if _, ok := m.s.leases[id]; ok {
+		return nil, rpctypes.ErrGRPCLeaseExist
+	}
+	m.s.leases[id] = &lease{
+		id:     id,
+		ttl:    r.TTL,
+		expiry: time.Now().Add(time.Duration(r.TTL) * time.Second),
+		keys:   make(map[string]struct{}),
+	}
+
+	return &pb.LeaseGrantResponse{Header: m.s.header(), ID: id, TTL: r.TTL}, nil
+}
+
+func (m *mockLeaseServer) LeaseRevoke(_ context.Context, r *pb.LeaseRevokeRequest) (*pb.LeaseRevokeResponse, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if !m.s.revokeLocked(r.ID) {
+		return nil, rpctypes.ErrGRPCLeaseNotFound
+	}
+
+	return &pb.LeaseRevokeResponse{Header: m.s.header()}, nil
+}
+
+func (m *mockLeaseServer) LeaseKeepAlive(stream pb.Lease_LeaseKeepAliveServer) error {
+	for {
+		r, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		m.s.mu.Lock()
+		resp := &pb.LeaseKeepAliveResponse{Header: m.s.header(), ID: r.ID}
+		if l, ok := m.s.leases[r.ID]; ok {
+			l.expiry = time.Now().Add(time.Duration(l.ttl) * time.Second)
+			resp.TTL = l.ttl
+		}
+		m.s.mu.Unlock()
+
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (m *mockLeaseServer) LeaseTimeToLive(_ context.Context, r *pb.LeaseTimeToLiveRequest) (*pb.LeaseTimeToLiveResponse, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	resp := &pb.LeaseTimeToLiveResponse{Header: m.s.header(), ID: r.ID, TTL: -1}
+	if l, ok := m.s.leases[r.ID]; ok {
+		resp.TTL = int64(time.Until(l.expiry).Seconds())
+		resp.GrantedTTL = l.ttl
+		if r.Keys {
+			for key := range l.keys {
+				resp.Keys = append(resp.Keys, []byte(key))
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+func (m *mockLeaseServer) LeaseLeases(context.Context, *pb.LeaseLeasesRequest) (*pb.LeaseLeasesResponse, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	resp := &pb.LeaseLeasesResponse{Header: m.s.header()}
+	for id := range m.s.leases {
+		resp.Leases = append(resp.Leases, &pb.LeaseStatus{ID: id})
+	}
+
+	return resp, nil
+}
+
+type mockWatchServer struct {
+	pb.UnimplementedWatchServer
+	s *store
+}
+
+func (m *mockWatchServer) Watch(stream pb.Watch_WatchServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	var (
+		sendMu  sync.Mutex
+		watchID int64
+		cancels = make(map[int64]context.CancelFunc)
+	)
+	send := func(resp *pb.WatchResponse) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.Send(resp)
+	}
+
+	for {
+		r, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+
+		switch req := r.RequestUnion.(type) {
+		case *pb.WatchRequest_CreateRequest:
+			cr := req.CreateRequest
+			id := watchID
+			watchID++
+			wctx, wcancel := context.WithCancel(ctx)
+			cancels[id] = wcancel
+
+			m.s.mu.Lock()
+			start := cr.StartRevision
+			if start == 0 {
+				start = m.s.rev + 1
+			}
+			header := m.s.header()
+			m.s.mu.Unlock()
+
+			if err := send(&pb.WatchResponse{Header: header, WatchId: id, Created: true}); err != nil {
+				return err
+			}
+			go m.watch(wctx, id, cr, start, send)
+		case *pb.WatchRequest_CancelRequest:
+			id := req.CancelRequest.WatchId
+			if c, ok := cancels[id]; ok {
+				c()
+				delete(cancels, id)
+			}
+			if err := send(&pb.WatchResponse{Header: m.s.lockedHeader(), WatchId: id, Canceled: true}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watch sends the events of the watched range from start revision until ctx is done.
+func (m *mockWatchServer) watch(ctx context.Context, id int64, cr *pb.WatchCreateRequest, start int64, send func(*pb.WatchResponse) error) {
+	next := 0
+	for {
+		m.s.mu.Lock()
+		var events []*mvccpb.Event
+		for ; next < len(m.s.events); next++ {
+			ev := m.s.events[next]
+			if ev.Kv.ModRevision >= start && match(ev.Kv.Key, cr.Key, cr.RangeEnd) {
+				c := *ev
+				if !cr.PrevKv {
+					c.PrevKv = nil
+				}
+				events = append(events, &c)
+			}
+		}
+		header, changed := m.s.header(), m.s.changed
+		m.s.mu.Unlock()
+
+		if len(events) > 0 {
+			if err := send(&pb.WatchResponse{Header: header, WatchId: id, Events: events}); err != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		}
+	}
+}
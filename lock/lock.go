@@ -3,11 +3,13 @@ package lock
 import (
 	"context"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/zeromicro/go-zero/core/logx"
 	"github.com/zeromicro/go-zero/core/threading"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.etcd.io/etcd/client/v3/concurrency"
 )
@@ -25,8 +27,18 @@ etcd 加锁解锁过程：
   2. 执行 put 操作，将创建的 key 绑定租约写入 etcd，客户端需记录 Revision 以便下一步判断自己是否获得锁；
   3. 通过前缀查询键值对列表，如果自己的 Revision 为当前列表中最小的则认为获得锁；否则监听列表中前一个 Revision 比自己小的 key 的删除事件，一旦监听到 pre-key 则自己获得锁；
   4. 完成业务流程后，删除对应的 key 释放锁。
+
+fencing token：
+  持有者可能因 GC 停顿或网络分区等原因在租约过期后仍认为自己持有锁，此时其它客户端已经获得锁，
+  持有者的写入可能覆盖新持有者的写入。锁键的创建版本号（CreateRevision）随每次上锁单调递增，
+  可以作为 fencing token 随写入请求一起发送，由存储服务拒绝 token 小于已见过的最大 token 的写入。
 */
 
+var (
+	_ Locker = (*EtcdLocker)(nil)
+	_ Lock   = (*EtcdLock)(nil)
+)
+
 // Config 分布式锁配置
 type Config struct {
 	Prefix    string   `json:",optional"` // 锁前缀，如 /xlock/
@@ -36,26 +48,27 @@ type Config struct {
 }
 
 // Option 可选配置
-type Option func(l *Locker)
+type Option func(l *EtcdLocker)
 
 // WithClient 使用指定 etcd 客户端
 func WithClient(client *clientv3.Client) Option {
-	return func(l *Locker) {
+	return func(l *EtcdLocker) {
 		if client != nil {
 			l.client = client
 		}
 	}
 }
 
-// Locker 分布式锁客户端
-type Locker struct {
+// EtcdLocker etcd 分布式锁客户端
+type EtcdLocker struct {
 	prefix string
 	client *clientv3.Client
+	registry
 }
 
-// NewLocker 新建分布式锁客户端
-func NewLocker(c Config, opts ...Option) (*Locker, error) {
-	l := &Locker{
+// NewLocker 新建 etcd 分布式锁客户端
+func NewLocker(c Config, opts ...Option) (*EtcdLocker, error) {
+	l := &EtcdLocker{
 		prefix: strings.TrimRight(c.Prefix, "/") + "/",
 	}
 	for _, opt := range opts {
@@ -79,8 +92,8 @@ func NewLocker(c Config, opts ...Option) (*Locker, error) {
 	return l, nil
 }
 
-// MustNewLocker 新建分布式锁客户端
-func MustNewLocker(c Config, opts ...Option) *Locker {
+// MustNewLocker 新建 etcd 分布式锁客户端
+func MustNewLocker(c Config, opts ...Option) *EtcdLocker {
 	l, err := NewLocker(c, opts...)
 	if err != nil {
 		return nil
@@ -89,43 +102,79 @@ func MustNewLocker(c Config, opts ...Option) *Locker {
 	return l
 }
 
-// Lock 分布式锁
-type Lock struct {
-	lockKey string
-	session *concurrency.Session
-	mutex   *concurrency.Mutex
+// EtcdLock etcd 分布式锁
+type EtcdLock struct {
+	locker    *EtcdLocker
+	lockKey   string
+	owner     string
+	session   *concurrency.Session
+	mutex     *concurrency.Mutex
+	token     int64
+	holder    Lock // 可重入上锁时实际持有锁的 Lock
+	closeOnce sync.Once
 }
 
 // NewLock 新建分布式锁
 //
 // key 为将要上锁的键，ttl 为键的租约到期时间，默认为 10s
-func (l *Locker) NewLock(key string, ttl ...int) (*Lock, error) {
-	t := 10
-	if len(ttl) > 0 {
-		t = ttl[0]
+func (l *EtcdLocker) NewLock(key string, ttl ...int) (Lock, error) {
+	return l.newLock(key, "", ttl...)
+}
+
+// NewReentrantLock 新建可重入分布式锁
+//
+// owner 为持有者标识，当前进程内同一持有者已经持有 key 对应的锁时，上锁会直接成功并增加持有次数，
+// 解锁会减少持有次数，持有次数为 0 时才真正释放锁，ttl 为键的租约到期时间，默认为 10s
+func (l *EtcdLocker) NewReentrantLock(key, owner string, ttl ...int) (Lock, error) {
+	if owner == "" {
+		return nil, errors.New("xlock: illegal reentrant lock owner")
 	}
 
-	// session 可以创建租约和自动续约
-	session, err := concurrency.NewSession(l.client, concurrency.WithTTL(t))
+	return l.newLock(key, owner, ttl...)
+}
+
+// WithLock 阻塞获取 key 对应的分布式锁后执行 fn，执行完毕后解锁
+//
+// 持有锁期间会话丢失时，会取消 fn 的上下文，并在 fn 未返回错误时返回 ErrLockLost
+func (l *EtcdLocker) WithLock(ctx context.Context, key string, fn func(ctx context.Context) error, ttl ...int) error {
+	lock, err := l.NewLock(key, ttl...)
 	if err != nil {
-		return nil, errors.WithMessage(err, "new concurrency session err")
+		return err
+	}
+	if err := lock.Lock(ctx); err != nil {
+		return err
+	}
+
+	return runWithLock(ctx, lock, fn)
+}
+
+// newLock 新建分布式锁
+func (l *EtcdLocker) newLock(key, owner string, ttl ...int) (*EtcdLock, error) {
+	session, err := l.newSession(ttl...)
+	if err != nil {
+		return nil, err
 	}
 
 	lockKey := l.prefix + strings.Trim(key, "/")
 	mutex := concurrency.NewMutex(session, lockKey)
 
-	return &Lock{lockKey: lockKey, session: session, mutex: mutex}, nil
+	return &EtcdLock{locker: l, lockKey: lockKey, owner: owner, session: session, mutex: mutex}, nil
 }
 
 // TryLock 尝试上锁，若不能获取锁会立刻返回
 //
 // ctx 最好带有 timeout
-func (l *Lock) TryLock(ctx context.Context) error {
+func (l *EtcdLock) TryLock(ctx context.Context) error {
+	if l.reenter() {
+		return nil
+	}
+
 	err := l.mutex.TryLock(ctx)
 	if err != nil {
 		l.Close()
 		return errors.WithMessage(err, "mutex try lock err")
 	}
+	l.acquired()
 
 	return nil
 }
@@ -133,12 +182,17 @@ func (l *Lock) TryLock(ctx context.Context) error {
 // Lock 上锁，若不能获取锁会阻塞并等待获取锁
 //
 // ctx 最好带有 timeout
-func (l *Lock) Lock(ctx context.Context) error {
+func (l *EtcdLock) Lock(ctx context.Context) error {
+	if l.reenter() {
+		return nil
+	}
+
 	err := l.mutex.Lock(ctx)
 	if err != nil {
 		l.Close()
 		return errors.WithMessage(err, "mutex lock err")
 	}
+	l.acquired()
 
 	return nil
 }
@@ -146,10 +200,18 @@ func (l *Lock) Lock(ctx context.Context) error {
 // Unlock 解锁
 //
 // ctx 最好带有 timeout
-func (l *Lock) Unlock(ctx context.Context) error {
-	defer l.Close()
+func (l *EtcdLock) Unlock(ctx context.Context) error {
+	target := l
+	if l.owner != "" {
+		holder, last := l.locker.release(l.lockKey, l.owner, l, l.holder)
+		if !last {
+			return nil
+		}
+		target = holder.(*EtcdLock)
+	}
+	defer target.Close()
 
-	err := l.mutex.Unlock(ctx)
+	err := target.mutex.Unlock(ctx)
 	if err != nil {
 		return errors.WithMessage(err, "mutex unlock err")
 	}
@@ -157,6 +219,20 @@ func (l *Lock) Unlock(ctx context.Context) error {
 	return nil
 }
 
+// Token 获取 fencing token，即锁键的创建版本号，每次上锁单调递增，上锁成功后有效
+func (l *EtcdLock) Token() int64 {
+	return l.token
+}
+
+// Done 获取会话结束通道，持有锁期间会话租约过期或被撤销导致锁丢失时，以及锁关闭后，该通道会被关闭
+func (l *EtcdLock) Done() <-chan struct{} {
+	if l.holder != nil {
+		return l.holder.Done()
+	}
+
+	return l.session.Done()
+}
+
 // Close 关闭锁
 //
 // 注意：不关闭会导致 session 内存泄漏
@@ -167,10 +243,108 @@ func (l *Lock) Unlock(ctx context.Context) error {
 //  3. 执行 Unlock 时
 //
 // 其余情况需要自行显示调用 Close 方法
-func (l *Lock) Close() {
-	threading.GoSafe(func() {
-		if err := l.session.Close(); err != nil {
-			logx.Errorf("xlock: concurrency session close err: %v", err)
-		}
+func (l *EtcdLock) Close() {
+	l.closeOnce.Do(func() {
+		threading.GoSafe(func() {
+			if err := l.session.Close(); err != nil {
+				logx.Errorf("xlock: concurrency session close err: %v", err)
+			}
+		})
 	})
 }
+
+// acquired 上锁成功后记录 fencing token 和可重入锁的持有信息
+func (l *EtcdLock) acquired() {
+	// 锁键的创建版本号即 mutex 判断持有者时使用的版本号
+	if cmp, ok := l.mutex.IsOwner().TargetUnion.(*pb.Compare_CreateRevision); ok {
+		l.token = cmp.CreateRevision
+	}
+	if l.owner != "" {
+		l.locker.hold(l.lockKey, l.owner, l)
+	}
+}
+
+// reenter 当前进程内同一持有者已经持有锁时增加持有次数，并关闭当前锁的会话
+func (l *EtcdLock) reenter() bool {
+	if l.owner == "" {
+		return false
+	}
+	holder, ok := l.locker.reenter(l.lockKey, l.owner)
+	if !ok {
+		return false
+	}
+	l.holder = holder
+	l.token = holder.Token()
+	l.Close()
+
+	return true
+}
+
+// newSession 新建会话，ttl 为会话租约到期时间，默认为 10s
+func (l *EtcdLocker) newSession(ttl ...int) (*concurrency.Session, error) {
+	t := 10
+	if len(ttl) > 0 {
+		t = ttl[0]
+	}
+
+	// session 可以创建租约和自动续约
+	session, err := concurrency.NewSession(l.client, concurrency.WithTTL(t))
+	if err != nil {
+		return nil, errors.WithMessage(err, "new concurrency session err")
+	}
+
+	return session, nil
+}
+
+// createKey 创建绑定会话租约的键，键已存在时沿用，返回键的创建版本号
+func createKey(ctx context.Context, session *concurrency.Session, key string) (int64, error) {
+	client := session.Client()
+	cmp := clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+	put := clientv3.OpPut(key, "", clientv3.WithLease(session.Lease()))
+	get := clientv3.OpGet(key)
+
+	resp, err := client.Txn(ctx).If(cmp).Then(put).Else(get).Commit()
+	if err != nil {
+		return 0, err
+	}
+	if !resp.Succeeded {
+		if kvs := resp.Responses[0].GetResponseRange().Kvs; len(kvs) > 0 {
+			return kvs[0].CreateRevision, nil
+		}
+	}
+
+	return resp.Header.Revision, nil
+}
+
+// deleteKey 使用独立的上下文删除键，用于上锁失败时撤销排队
+func deleteKey(session *concurrency.Session, key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+	defer cancel()
+
+	if _, err := session.Client().Delete(ctx, key); err != nil {
+		logx.Errorf("xlock: delete key %s err: %v", key, err)
+	}
+}
+
+// waitDelete 等待 rev 版本之后 key 的删除事件，opts 可以指定 clientv3.WithPrefix 等待前缀下任意键的删除事件
+func waitDelete(ctx context.Context, client *clientv3.Client, key string, rev int64, opts ...clientv3.OpOption) error {
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	opts = append(opts, clientv3.WithRev(rev+1))
+	for wr := range client.Watch(wctx, key, opts...) {
+		if err := wr.Err(); err != nil {
+			return err
+		}
+		for _, ev := range wr.Events {
+			if ev.Type == clientv3.EventTypeDelete {
+				return nil
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return errors.New("lost watcher waiting for delete")
+}
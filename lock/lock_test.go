@@ -2,6 +2,7 @@ package lock
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 	"testing"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
 
 	"github.com/sliveryou/micro-pkg/lock/internal/mockserver"
 )
@@ -45,7 +47,7 @@ func teardown() {
 	ms.Stop()
 }
 
-func getLocker() *Locker {
+func getLocker() *EtcdLocker {
 	c := Config{
 		Prefix:    "/xlock/",
 		Endpoints: endpoints,
@@ -83,7 +85,7 @@ func TestLocker_NewLock(t *testing.T) {
 	lock, err := l.NewLock(key)
 	require.NoError(t, err)
 	assert.NotNil(t, lock)
-	assert.Equal(t, "/xlock/test-key", lock.lockKey)
+	assert.Equal(t, "/xlock/test-key", lock.(*EtcdLock).lockKey)
 }
 
 func TestLock_Lock(t *testing.T) {
@@ -125,3 +127,157 @@ func TestLock_TryLock(t *testing.T) {
 
 	cancel()
 }
+
+func TestLock_Token(t *testing.T) {
+	l := getLocker()
+	key := "test-token-key"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var last int64
+	for i := 0; i < 3; i++ {
+		lock, err := l.NewLock(key)
+		require.NoError(t, err)
+		require.NoError(t, lock.Lock(ctx))
+		assert.Greater(t, lock.Token(), last)
+		last = lock.Token()
+		require.NoError(t, lock.Unlock(ctx))
+	}
+}
+
+func TestLock_Contention(t *testing.T) {
+	l := getLocker()
+	key := "test-contention-key"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	lock1, err := l.NewLock(key)
+	require.NoError(t, err)
+	require.NoError(t, lock1.Lock(ctx))
+
+	lock2, err := l.NewLock(key)
+	require.NoError(t, err)
+	require.ErrorIs(t, lock2.TryLock(ctx), ErrLocked)
+
+	// 锁释放后阻塞等待的客户端获得锁，且 fencing token 更大
+	lock3, err := l.NewLock(key)
+	require.NoError(t, err)
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- lock3.Lock(ctx)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired while held by another session")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.NoError(t, lock1.Unlock(ctx))
+	require.NoError(t, <-acquired)
+	assert.Greater(t, lock3.Token(), lock1.Token())
+	require.NoError(t, lock3.Unlock(ctx))
+}
+
+func TestLock_Done(t *testing.T) {
+	l := getLocker()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	lock, err := l.NewLock("test-done-key", 1)
+	require.NoError(t, err)
+	require.NoError(t, lock.Lock(ctx))
+	assert.False(t, isDone(lock.Done()))
+
+	// 租约被撤销后会话结束
+	_, err = l.client.Revoke(ctx, lock.(*EtcdLock).session.Lease())
+	require.NoError(t, err)
+	select {
+	case <-lock.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock done channel not closed after lease revoked")
+	}
+	lock.Close()
+}
+
+func TestLocker_NewReentrantLock(t *testing.T) {
+	l := getLocker()
+	key := "test-reentrant-key"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err := l.NewReentrantLock(key, "")
+	require.Error(t, err)
+
+	lock1, err := l.NewReentrantLock(key, "owner1")
+	require.NoError(t, err)
+	require.NoError(t, lock1.Lock(ctx))
+
+	// 同一持有者可以重复上锁
+	lock2, err := l.NewReentrantLock(key, "owner1")
+	require.NoError(t, err)
+	require.NoError(t, lock2.TryLock(ctx))
+	assert.Equal(t, lock1.Token(), lock2.Token())
+
+	other, err := l.NewReentrantLock(key, "owner2")
+	require.NoError(t, err)
+	require.ErrorIs(t, other.TryLock(ctx), ErrLocked)
+
+	// 持有次数为 0 时才真正释放锁
+	require.NoError(t, lock1.Unlock(ctx))
+	other, err = l.NewReentrantLock(key, "owner2")
+	require.NoError(t, err)
+	require.ErrorIs(t, other.TryLock(ctx), ErrLocked)
+	require.NoError(t, lock2.Unlock(ctx))
+
+	other, err = l.NewReentrantLock(key, "owner2")
+	require.NoError(t, err)
+	require.NoError(t, other.TryLock(ctx))
+	assert.Greater(t, other.Token(), lock1.Token())
+	require.NoError(t, other.Unlock(ctx))
+}
+
+func TestLocker_WithLock(t *testing.T) {
+	l := getLocker()
+	key := "test-with-lock-key"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var run bool
+	err := l.WithLock(ctx, key, func(ctx context.Context) error {
+		run = true
+		lock, err := l.NewLock(key)
+		require.NoError(t, err)
+		require.ErrorIs(t, lock.TryLock(ctx), ErrLocked)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.True(t, run)
+
+	mockErr := errors.New("mock err")
+	err = l.WithLock(ctx, key, func(ctx context.Context) error {
+		return mockErr
+	})
+	require.ErrorIs(t, err, mockErr)
+
+	// 锁丢失时取消 fn 的上下文
+	err = l.WithLock(ctx, key, func(ctx context.Context) error {
+		resp, err := l.client.Get(ctx, "/xlock/"+key+"/", clientv3.WithPrefix())
+		require.NoError(t, err)
+		require.Len(t, resp.Kvs, 1)
+		_, err = l.client.Revoke(ctx, clientv3.LeaseID(resp.Kvs[0].Lease))
+		require.NoError(t, err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(5 * time.Second):
+			return errors.New("context not canceled after lock lost")
+		}
+	})
+	require.ErrorIs(t, err, ErrLockLost)
+
+	// 锁已经释放
+	lock, err := l.NewLock(key)
+	require.NoError(t, err)
+	require.NoError(t, lock.TryLock(ctx))
+	require.NoError(t, lock.Unlock(ctx))
+}
@@ -0,0 +1,143 @@
+package lock
+
+import (
+	"context"
+	stderrors "errors"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+// unlockTimeout WithLock 解锁超时时间
+const unlockTimeout = 5 * time.Second
+
+var (
+	// ErrLocked 锁已被其它持有者持有错误，TryLock 不能获取锁时返回
+	ErrLocked = concurrency.ErrLocked
+	// ErrLockLost 锁丢失错误，即持有锁期间租约过期或被撤销
+	ErrLockLost = stderrors.New("lock lost")
+)
+
+// Locker 分布式锁客户端，etcd 和 redis 分布式锁客户端均实现了该接口
+type Locker interface {
+	// NewLock 新建分布式锁，ttl 为锁的租约到期时间（秒），默认为 10s
+	NewLock(key string, ttl ...int) (Lock, error)
+	// NewReentrantLock 新建可重入分布式锁，当前进程内同一持有者可以重复上锁
+	NewReentrantLock(key, owner string, ttl ...int) (Lock, error)
+	// WithLock 阻塞获取分布式锁后执行 fn，执行完毕后解锁，锁丢失时取消 fn 的上下文
+	WithLock(ctx context.Context, key string, fn func(ctx context.Context) error, ttl ...int) error
+}
+
+// Lock 分布式锁
+type Lock interface {
+	// TryLock 尝试上锁，若不能获取锁会立刻返回 ErrLocked
+	TryLock(ctx context.Context) error
+	// Lock 上锁，若不能获取锁会阻塞并等待获取锁
+	Lock(ctx context.Context) error
+	// Unlock 解锁
+	Unlock(ctx context.Context) error
+	// Token 获取 fencing token，每次上锁单调递增，上锁成功后有效
+	Token() int64
+	// Done 获取锁结束通道，锁丢失或关闭后该通道会被关闭
+	Done() <-chan struct{}
+	// Close 关闭锁，释放相关资源
+	Close()
+}
+
+// heldLock 可重入锁的持有信息
+type heldLock struct {
+	lock  Lock   // 实际持有锁的 Lock
+	owner string // 持有者标识
+	count int    // 持有次数
+}
+
+// registry 可重入锁持有信息登记表
+type registry struct {
+	mu   sync.Mutex
+	held map[string]*heldLock // 可重入锁的持有信息，键为锁名称
+}
+
+// reenter 当前进程内同一持有者已经持有锁时增加持有次数，并返回实际持有锁的 Lock
+func (r *registry) reenter(lockKey, owner string) (Lock, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, ok := r.held[lockKey]
+	if !ok || h.owner != owner || isDone(h.lock.Done()) {
+		return nil, false
+	}
+	h.count++
+
+	return h.lock, true
+}
+
+// hold 记录可重入锁的持有信息
+func (r *registry) hold(lockKey, owner string, lock Lock) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.held == nil {
+		r.held = make(map[string]*heldLock)
+	}
+	r.held[lockKey] = &heldLock{lock: lock, owner: owner, count: 1}
+}
+
+// release 减少可重入锁的持有次数，返回实际持有锁的 Lock 和是否需要真正释放锁，
+// lock 为解锁的 Lock，holder 为其可重入上锁时实际持有锁的 Lock
+func (r *registry) release(lockKey, owner string, lock, holder Lock) (Lock, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, ok := r.held[lockKey]
+	if !ok || h.owner != owner || (holder != nil && holder != h.lock) {
+		return lock, holder == nil
+	}
+	if h.count--; h.count > 0 {
+		return h.lock, false
+	}
+	delete(r.held, lockKey)
+
+	return h.lock, true
+}
+
+// runWithLock 持有锁时执行 fn，执行完毕后解锁，锁丢失时取消 fn 的上下文
+func runWithLock(ctx context.Context, lock Lock, fn func(ctx context.Context) error) error {
+	fctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-lock.Done():
+			cancel()
+		case <-fctx.Done():
+		}
+	}()
+
+	err := fn(fctx)
+	lost := isDone(lock.Done())
+
+	// ctx 可能已经结束，使用独立的上下文解锁
+	uctx, ucancel := context.WithTimeout(context.Background(), unlockTimeout)
+	defer ucancel()
+	uerr := lock.Unlock(uctx)
+
+	switch {
+	case err != nil:
+		return err
+	case lost:
+		return ErrLockLost
+	default:
+		return uerr
+	}
+}
+
+// isDone 判断通道是否已关闭
+func isDone(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
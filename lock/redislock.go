@@ -0,0 +1,487 @@
+package lock
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/stringx"
+	"github.com/zeromicro/go-zero/core/threading"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+/*
+redis 加锁解锁过程：
+  1. 生成随机的持有者令牌，执行 SET prefix{key} token NX PX ttl，写入成功则获得锁，同时递增 prefix{key}:fence 计数器作为 fencing token，
+     锁键与计数器键共享 hashtag，保证两者位于 redis 集群的同一 slot；
+  2. 持有锁期间看门狗每隔 ttl/3 校验持有者令牌并续期，校验失败或持续续期失败超过 ttl 时认为锁已丢失；
+  3. 完成业务流程后，通过 lua 脚本校验持有者令牌后删除 key 释放锁，避免误删其它持有者的锁。
+
+Redlock 模式：
+  在 xkv 集群的每个节点上独立执行上述过程，获得锁的节点权重之和超过总权重的一半，
+  且扣除上锁耗时和时钟漂移后锁仍然有效时，才认为上锁成功，否则释放全部节点上的锁。
+  各节点的 fencing token 计数器相互独立，取获得锁节点中的最大值作为 fencing token，
+  在多数节点的计数器不丢失的前提下单调递增。
+
+fencing token 注意事项：
+  计数器键没有过期时间，redis 需要开启持久化（如 appendonly yes 且 appendfsync always），
+  并使用不会淘汰无过期时间键的淘汰策略（如 noeviction 或 volatile-*），
+  否则计数器因淘汰、重启或主从切换丢失后会重新从 1 开始，fencing token 不再单调递增。
+*/
+
+const (
+	// lockAcquireScript 上锁 lua 脚本，KEYS[1] 为锁键，KEYS[2] 为 fencing token 计数器键，
+	// 上锁成功时返回递增后的 fencing token，否则返回 0
+	lockAcquireScript = `if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+    return redis.call("INCR", KEYS[2]);
+end
+return 0;`
+
+	// lockExtendScript 续期 lua 脚本，持有者令牌不匹配时返回 0
+	lockExtendScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("PEXPIRE", KEYS[1], ARGV[2]);
+end
+return 0;`
+
+	// lockReleaseScript 解锁 lua 脚本，持有者令牌不匹配时返回 0
+	lockReleaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("DEL", KEYS[1]);
+end
+return 0;`
+
+	// fenceKeySuffix fencing token 计数器键后缀
+	fenceKeySuffix = ":fence"
+	// minLockBackoff 阻塞上锁的最小重试间隔
+	minLockBackoff = 10 * time.Millisecond
+	// maxLockBackoff 阻塞上锁的最大重试间隔
+	maxLockBackoff = 500 * time.Millisecond
+	// clockDriftFactor 时钟漂移系数
+	clockDriftFactor = 0.01
+)
+
+var (
+	_ Locker = (*RedisLocker)(nil)
+	_ Lock   = (*RedisLock)(nil)
+)
+
+// RedisOption redis 分布式锁可选配置
+type RedisOption func(l *RedisLocker)
+
+// WithRedlock 使用 Redlock 模式，在 xkv 集群的全部节点上按权重投票上锁
+func WithRedlock() RedisOption {
+	return func(l *RedisLocker) {
+		l.redlock = true
+	}
+}
+
+// redisNode 执行锁脚本的 redis 节点
+type redisNode struct {
+	weight int
+	eval   func(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+// RedisLocker redis 分布式锁客户端
+type RedisLocker struct {
+	prefix  string
+	redlock bool
+	nodes   []redisNode
+	total   int // 节点权重之和
+	registry
+}
+
+// NewRedisLocker 新建 redis 分布式锁客户端，prefix 为锁前缀，如 /xlock/
+func NewRedisLocker(store *xkv.Store, prefix string, opts ...RedisOption) (*RedisLocker, error) {
+	if store == nil {
+		return nil, errors.New("xlock: illegal redis locker config")
+	}
+
+	l := &RedisLocker{prefix: strings.TrimRight(prefix, "/") + "/"}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if l.redlock {
+		nodes, err := store.Nodes()
+		if err != nil {
+			return nil, errors.WithMessage(err, "xlock: get redis nodes err")
+		}
+		for _, n := range nodes {
+			if n.Weight <= 0 {
+				continue
+			}
+			rds := n.Redis
+			l.nodes = append(l.nodes, redisNode{
+				weight: n.Weight,
+				eval: func(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+					return rds.EvalCtx(ctx, script, keys, args...)
+				},
+			})
+		}
+	} else {
+		l.nodes = []redisNode{{weight: 1, eval: store.EvalKeysCtx}}
+	}
+
+	for _, n := range l.nodes {
+		l.total += n.weight
+	}
+	if l.total <= 0 {
+		return nil, errors.New("xlock: illegal redis locker nodes")
+	}
+
+	return l, nil
+}
+
+// MustNewRedisLocker 新建 redis 分布式锁客户端
+func MustNewRedisLocker(store *xkv.Store, prefix string, opts ...RedisOption) *RedisLocker {
+	l, err := NewRedisLocker(store, prefix, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return l
+}
+
+// NewLock 新建分布式锁
+//
+// key 为将要上锁的键，实际的锁键为 prefix{key}，ttl 为键的过期时间，默认为 10s
+func (l *RedisLocker) NewLock(key string, ttl ...int) (Lock, error) {
+	return l.newLock(key, "", ttl...)
+}
+
+// NewReentrantLock 新建可重入分布式锁
+//
+// owner 为持有者标识，当前进程内同一持有者已经持有 key 对应的锁时，上锁会直接成功并增加持有次数，
+// 解锁会减少持有次数，持有次数为 0 时才真正释放锁，ttl 为键的过期时间，默认为 10s
+func (l *RedisLocker) NewReentrantLock(key, owner string, ttl ...int) (Lock, error) {
+	if owner == "" {
+		return nil, errors.New("xlock: illegal reentrant lock owner")
+	}
+
+	return l.newLock(key, owner, ttl...)
+}
+
+// WithLock 阻塞获取 key 对应的分布式锁后执行 fn，执行完毕后解锁
+//
+// 持有锁期间锁丢失时，会取消 fn 的上下文，并在 fn 未返回错误时返回 ErrLockLost
+func (l *RedisLocker) WithLock(ctx context.Context, key string, fn func(ctx context.Context) error, ttl ...int) error {
+	lock, err := l.NewLock(key, ttl...)
+	if err != nil {
+		return err
+	}
+	if err := lock.Lock(ctx); err != nil {
+		return err
+	}
+
+	return runWithLock(ctx, lock, fn)
+}
+
+// newLock 新建分布式锁
+func (l *RedisLocker) newLock(key, owner string, ttl ...int) (*RedisLock, error) {
+	t := 10
+	if len(ttl) > 0 {
+		t = ttl[0]
+	}
+	if t <= 0 {
+		return nil, errors.Errorf("xlock: illegal redis lock ttl: %d", t)
+	}
+
+	return &RedisLock{
+		locker:  l,
+		lockKey: l.prefix + "{" + strings.Trim(key, "/") + "}",
+		owner:   owner,
+		ttl:     time.Duration(t) * time.Second,
+		value:   stringx.Randn(16),
+		done:    make(chan struct{}),
+	}, nil
+}
+
+// acquire 在全部节点上执行上锁脚本，返回 fencing token 和是否上锁成功
+func (l *RedisLocker) acquire(ctx context.Context, key, value string, ttl time.Duration) (int64, bool, error) {
+	start := time.Now()
+
+	var (
+		weight, failed int
+		token          int64
+		lastErr        error
+	)
+	for _, n := range l.nodes {
+		resp, err := n.eval(ctx, lockAcquireScript, []string{key, key + fenceKeySuffix}, value, ttl.Milliseconds())
+		if err != nil {
+			failed += n.weight
+			lastErr = err
+			continue
+		}
+		if fence, ok := resp.(int64); ok && fence > 0 {
+			weight += n.weight
+			if fence > token {
+				token = fence
+			}
+		}
+	}
+
+	// 扣除上锁耗时和时钟漂移后锁仍然有效，且获得锁的节点权重之和超过总权重的一半时上锁成功
+	drift := time.Duration(float64(ttl)*clockDriftFactor) + 2*time.Millisecond
+	if weight*2 > l.total && time.Since(start)+drift < ttl {
+		return token, true, nil
+	}
+
+	if weight > 0 {
+		rctx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+		defer cancel()
+		_, _ = l.unlock(rctx, key, value)
+	}
+	if failed*2 >= l.total {
+		return 0, false, errors.WithMessage(lastErr, "store eval script err")
+	}
+
+	return 0, false, nil
+}
+
+// extend 在全部节点上续期，返回是否仍然持有锁
+func (l *RedisLocker) extend(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return l.vote(ctx, lockExtendScript, key, value, ttl.Milliseconds())
+}
+
+// unlock 在全部节点上解锁，返回解锁前是否仍然持有锁
+func (l *RedisLocker) unlock(ctx context.Context, key, value string) (bool, error) {
+	return l.vote(ctx, lockReleaseScript, key, value)
+}
+
+// vote 在全部节点上执行脚本，脚本返回 1 的节点权重之和超过总权重的一半时返回 true
+func (l *RedisLocker) vote(ctx context.Context, script, key string, args ...any) (bool, error) {
+	var (
+		weight, failed int
+		lastErr        error
+	)
+	for _, n := range l.nodes {
+		resp, err := n.eval(ctx, script, []string{key}, args...)
+		if err != nil {
+			failed += n.weight
+			lastErr = err
+			continue
+		}
+		if code, ok := resp.(int64); ok && code == 1 {
+			weight += n.weight
+		}
+	}
+
+	if weight*2 > l.total {
+		return true, nil
+	}
+	if failed*2 >= l.total {
+		return false, errors.WithMessage(lastErr, "store eval script err")
+	}
+
+	return false, nil
+}
+
+// RedisLock redis 分布式锁
+type RedisLock struct {
+	locker    *RedisLocker
+	lockKey   string
+	owner     string
+	ttl       time.Duration
+	value     string // 持有者令牌，用于校验锁的持有者
+	token     int64
+	holder    Lock // 可重入上锁时实际持有锁的 Lock
+	held      atomic.Bool
+	done      chan struct{}
+	doneOnce  sync.Once
+	closeOnce sync.Once
+}
+
+// TryLock 尝试上锁，若不能获取锁会立刻返回 ErrLocked
+//
+// ctx 最好带有 timeout
+func (l *RedisLock) TryLock(ctx context.Context) error {
+	if l.reenter() {
+		return nil
+	}
+
+	ok, err := l.acquire(ctx)
+	if err == nil && !ok {
+		err = ErrLocked
+	}
+	if err != nil {
+		l.Close()
+		return errors.WithMessage(err, "redis try lock err")
+	}
+
+	return nil
+}
+
+// Lock 上锁，若不能获取锁会以指数退避的间隔重试，直到获取锁或 ctx 结束
+//
+// ctx 最好带有 timeout
+func (l *RedisLock) Lock(ctx context.Context) error {
+	if l.reenter() {
+		return nil
+	}
+
+	backoff := minLockBackoff
+	for {
+		ok, err := l.acquire(ctx)
+		if err != nil {
+			l.Close()
+			return errors.WithMessage(err, "redis lock err")
+		}
+		if ok {
+			return nil
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			l.Close()
+			return errors.WithMessage(ctx.Err(), "redis lock err")
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > maxLockBackoff {
+			backoff = maxLockBackoff
+		}
+	}
+}
+
+// Unlock 解锁，锁在解锁前已经丢失时返回 ErrLockLost
+//
+// ctx 最好带有 timeout
+func (l *RedisLock) Unlock(ctx context.Context) error {
+	target := l
+	if l.owner != "" {
+		holder, last := l.locker.release(l.lockKey, l.owner, l, l.holder)
+		if !last {
+			return nil
+		}
+		target = holder.(*RedisLock)
+	}
+	defer target.Close()
+
+	if !target.held.Swap(false) {
+		return errors.New("xlock: redis lock is not held")
+	}
+
+	ok, err := target.locker.unlock(ctx, target.lockKey, target.value)
+	if err != nil {
+		return errors.WithMessage(err, "redis unlock err")
+	}
+	if !ok {
+		return errors.WithMessage(ErrLockLost, "redis unlock err")
+	}
+
+	return nil
+}
+
+// Token 获取 fencing token，即 prefix{key}:fence 计数器的值，在计数器不丢失的前提下每次上锁单调递增，上锁成功后有效
+func (l *RedisLock) Token() int64 {
+	return l.token
+}
+
+// Done 获取锁结束通道，持有锁期间锁被删除、过期或持续续期失败导致锁丢失时，以及锁关闭后，该通道会被关闭
+func (l *RedisLock) Done() <-chan struct{} {
+	if l.holder != nil {
+		return l.holder.Done()
+	}
+
+	return l.done
+}
+
+// Close 关闭锁，停止看门狗，仍然持有锁时会释放锁
+//
+// 在以下几种情况会自动调用 Close 方法：
+//  1. TryLock 发生失败时
+//  2. Lock 发生失败时
+//  3. 执行 Unlock 时
+//
+// 其余情况需要自行显示调用 Close 方法
+func (l *RedisLock) Close() {
+	l.closeOnce.Do(func() {
+		if l.held.Swap(false) {
+			threading.GoSafe(func() {
+				ctx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+				defer cancel()
+				if _, err := l.locker.unlock(ctx, l.lockKey, l.value); err != nil {
+					logx.Errorf("xlock: redis lock release err: %v", err)
+				}
+			})
+		}
+		l.closeDone()
+	})
+}
+
+// acquire 执行一次上锁，上锁成功后记录 fencing token 和可重入锁的持有信息，并启动看门狗
+func (l *RedisLock) acquire(ctx context.Context) (bool, error) {
+	token, ok, err := l.locker.acquire(ctx, l.lockKey, l.value, l.ttl)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	l.token = token
+	l.held.Store(true)
+	if l.owner != "" {
+		l.locker.hold(l.lockKey, l.owner, l)
+	}
+	threading.GoSafe(l.watchdog)
+
+	return true, nil
+}
+
+// reenter 当前进程内同一持有者已经持有锁时增加持有次数，并关闭当前锁
+func (l *RedisLock) reenter() bool {
+	if l.owner == "" {
+		return false
+	}
+	holder, ok := l.locker.reenter(l.lockKey, l.owner)
+	if !ok {
+		return false
+	}
+	l.holder = holder
+	l.token = holder.Token()
+	l.Close()
+
+	return true
+}
+
+// watchdog 看门狗，持有锁期间每隔 ttl/3 续期一次，锁丢失时关闭锁结束通道
+func (l *RedisLock) watchdog() {
+	interval := l.ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	extended := time.Now()
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		ok, err := l.locker.extend(ctx, l.lockKey, l.value, l.ttl)
+		cancel()
+
+		switch {
+		case ok:
+			extended = time.Now()
+		case err == nil || time.Since(extended) >= l.ttl:
+			// 锁已被删除、已过期或已被其它持有者获得
+			logx.Errorf("xlock: redis lock %s lost, err: %v", l.lockKey, err)
+			l.closeDone()
+			return
+		default:
+			logx.Errorf("xlock: redis lock %s extend err: %v", l.lockKey, err)
+		}
+	}
+}
+
+// closeDone 关闭锁结束通道
+func (l *RedisLock) closeDone() {
+	l.doneOnce.Do(func() {
+		close(l.done)
+	})
+}
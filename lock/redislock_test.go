@@ -0,0 +1,269 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+var (
+	s1, _ = miniredis.Run()
+	s2, _ = miniredis.Run()
+)
+
+func getRedisStore(weights ...int) *xkv.Store {
+	s1.FlushAll()
+	s2.FlushAll()
+
+	if len(weights) == 0 {
+		weights = []int{100, 100}
+	}
+
+	return xkv.NewStore([]cache.NodeConf{
+		{
+			RedisConf: redis.RedisConf{
+				Host: s1.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: weights[0],
+		},
+		{
+			RedisConf: redis.RedisConf{
+				Host: s2.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: weights[1],
+		},
+	})
+}
+
+func TestNewRedisLocker(t *testing.T) {
+	_, err := NewRedisLocker(nil, "/xlock/")
+	require.Error(t, err)
+	assert.Panics(t, func() {
+		MustNewRedisLocker(nil, "/xlock/")
+	})
+
+	l, err := NewRedisLocker(getRedisStore(), "/xlock/")
+	require.NoError(t, err)
+	assert.Len(t, l.nodes, 1)
+
+	l, err = NewRedisLocker(getRedisStore(), "/xlock/", WithRedlock())
+	require.NoError(t, err)
+	assert.Len(t, l.nodes, 2)
+	assert.Equal(t, 200, l.total)
+
+	lock, err := l.NewLock("/test-key/")
+	require.NoError(t, err)
+	assert.Equal(t, "/xlock/{test-key}", lock.(*RedisLock).lockKey)
+	_, err = l.NewLock("test-key", 0)
+	require.Error(t, err)
+}
+
+func TestRedisLock_TryLock(t *testing.T) {
+	l := MustNewRedisLocker(getRedisStore(), "/xlock/")
+	ctx := context.Background()
+
+	lock1, err := l.NewLock("test-key")
+	require.NoError(t, err)
+	require.NoError(t, lock1.TryLock(ctx))
+	assert.Equal(t, int64(1), lock1.Token())
+	// 锁键与计数器键共享 hashtag
+	s := s1
+	if !s.Exists("/xlock/{test-key}") {
+		s = s2
+	}
+	v, err := s.Get("/xlock/{test-key}:fence")
+	require.NoError(t, err)
+	assert.Equal(t, "1", v)
+	assert.Equal(t, time.Duration(0), s.TTL("/xlock/{test-key}:fence"))
+
+	// 其它持有者不能获取锁，也不能误删锁
+	lock2, err := l.NewLock("test-key")
+	require.NoError(t, err)
+	require.ErrorIs(t, lock2.TryLock(ctx), ErrLocked)
+	assert.True(t, isDone(lock2.Done()))
+	require.Error(t, lock2.Unlock(ctx))
+	assert.False(t, isDone(lock1.Done()))
+
+	require.NoError(t, lock1.Unlock(ctx))
+	assert.True(t, isDone(lock1.Done()))
+
+	// fencing token 随每次上锁单调递增
+	lock3, err := l.NewLock("test-key")
+	require.NoError(t, err)
+	require.NoError(t, lock3.TryLock(ctx))
+	assert.Equal(t, int64(2), lock3.Token())
+	lock3.Close()
+
+	// 关闭锁时会释放锁
+	lock4, err := l.NewLock("test-key")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return lock4.TryLock(ctx) == nil
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, lock4.Unlock(ctx))
+}
+
+func TestRedisLock_Lock(t *testing.T) {
+	l := MustNewRedisLocker(getRedisStore(), "/xlock/")
+	ctx := context.Background()
+
+	lock1, err := l.NewLock("test-key")
+	require.NoError(t, err)
+	require.NoError(t, lock1.Lock(ctx))
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_ = lock1.Unlock(ctx)
+	}()
+
+	// 阻塞等待直到锁被释放
+	lock2, err := l.NewLock("test-key")
+	require.NoError(t, err)
+	start := time.Now()
+	require.NoError(t, lock2.Lock(ctx))
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	assert.Greater(t, lock2.Token(), lock1.Token())
+
+	// 等待超时
+	lock3, err := l.NewLock("test-key")
+	require.NoError(t, err)
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, lock3.Lock(tctx), context.DeadlineExceeded)
+
+	require.NoError(t, lock2.Unlock(ctx))
+}
+
+func TestRedisLock_Watchdog(t *testing.T) {
+	store := getRedisStore()
+	l := MustNewRedisLocker(store, "/xlock/")
+	ctx := context.Background()
+
+	lock, err := l.NewLock("test-key", 1)
+	require.NoError(t, err)
+	require.NoError(t, lock.Lock(ctx))
+	key := lock.(*RedisLock).lockKey
+
+	// 看门狗续期后锁不会过期
+	s1.FastForward(900 * time.Millisecond)
+	s2.FastForward(900 * time.Millisecond)
+	time.Sleep(400 * time.Millisecond)
+	s1.FastForward(200 * time.Millisecond)
+	s2.FastForward(200 * time.Millisecond)
+	exists, err := store.ExistsCtx(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.False(t, isDone(lock.Done()))
+
+	// 锁被删除后视为锁丢失
+	_, err = store.DelCtx(ctx, key)
+	require.NoError(t, err)
+	select {
+	case <-lock.Done():
+	case <-time.After(time.Second):
+		t.Fatal("lock done channel should be closed")
+	}
+	require.ErrorIs(t, lock.Unlock(ctx), ErrLockLost)
+}
+
+func TestRedisLocker_NewReentrantLock(t *testing.T) {
+	l := MustNewRedisLocker(getRedisStore(), "/xlock/")
+	ctx := context.Background()
+
+	_, err := l.NewReentrantLock("test-key", "")
+	require.Error(t, err)
+
+	outer, err := l.NewReentrantLock("test-key", "owner-1")
+	require.NoError(t, err)
+	require.NoError(t, outer.Lock(ctx))
+	inner, err := l.NewReentrantLock("test-key", "owner-1")
+	require.NoError(t, err)
+	require.NoError(t, inner.TryLock(ctx))
+	assert.Equal(t, outer.Token(), inner.Token())
+
+	other, err := l.NewReentrantLock("test-key", "owner-2")
+	require.NoError(t, err)
+	require.ErrorIs(t, other.TryLock(ctx), ErrLocked)
+
+	// 持有次数为 0 时才真正释放锁
+	require.NoError(t, inner.Unlock(ctx))
+	assert.False(t, isDone(outer.Done()))
+	require.NoError(t, outer.Unlock(ctx))
+
+	other, err = l.NewReentrantLock("test-key", "owner-2")
+	require.NoError(t, err)
+	require.NoError(t, other.TryLock(ctx))
+	require.NoError(t, other.Unlock(ctx))
+}
+
+func TestRedisLocker_WithLock(t *testing.T) {
+	l := MustNewRedisLocker(getRedisStore(), "/xlock/")
+	ctx := context.Background()
+
+	errFn := errors.New("fn err")
+	err := l.WithLock(ctx, "test-key", func(ctx context.Context) error {
+		lock, err := l.NewLock("test-key")
+		require.NoError(t, err)
+		require.ErrorIs(t, lock.TryLock(ctx), ErrLocked)
+		return errFn
+	})
+	require.ErrorIs(t, err, errFn)
+
+	// 锁丢失时取消 fn 的上下文并返回 ErrLockLost
+	err = l.WithLock(ctx, "test-key", func(ctx context.Context) error {
+		s1.FlushAll()
+		s2.FlushAll()
+		<-ctx.Done()
+		return nil
+	}, 1)
+	require.ErrorIs(t, err, ErrLockLost)
+}
+
+func TestRedisLocker_Redlock(t *testing.T) {
+	// s1 权重超过总权重的一半，仅在 s1 上获得锁即可上锁成功
+	l := MustNewRedisLocker(getRedisStore(100, 50), "/xlock/", WithRedlock())
+	ctx := context.Background()
+
+	require.NoError(t, s2.Set("/xlock/{test-key}", "other"))
+	lock, err := l.NewLock("test-key")
+	require.NoError(t, err)
+	require.NoError(t, lock.TryLock(ctx))
+	assert.Equal(t, int64(1), lock.Token())
+	assert.True(t, s1.Exists("/xlock/{test-key}"))
+	require.NoError(t, lock.Unlock(ctx))
+	assert.False(t, s1.Exists("/xlock/{test-key}"))
+	v, err := s2.Get("/xlock/{test-key}")
+	require.NoError(t, err)
+	assert.Equal(t, "other", v)
+
+	// s1 上的锁被其它持有者持有时上锁失败，并释放 s2 上获得的锁
+	s2.FlushAll()
+	require.NoError(t, s1.Set("/xlock/{test-key}", "other"))
+	lock, err = l.NewLock("test-key")
+	require.NoError(t, err)
+	require.ErrorIs(t, lock.TryLock(ctx), ErrLocked)
+	assert.False(t, s2.Exists("/xlock/{test-key}"))
+
+	// 多数节点不可用时返回错误
+	l = MustNewRedisLocker(getRedisStore(), "/xlock/", WithRedlock())
+	s2.Close()
+	defer func() {
+		require.NoError(t, s2.Restart())
+	}()
+	lock, err = l.NewLock("test-key")
+	require.NoError(t, err)
+	err = lock.TryLock(ctx)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrLocked)
+}
@@ -0,0 +1,175 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+/*
+etcd 读写锁加锁解锁过程：
+  1. 读者在 key/read/ 下、写者在 key/write/ 下创建绑定会话租约的键，并记录键的创建版本号；
+  2. 读者查询 key/write/ 下创建版本号比自己小的最后一个键，写者查询 key/ 下创建版本号比自己小的最后一个键，
+     不存在则获得锁，否则监听该键的删除事件，删除后重新查询，即读者只等待排在自己之前的写者，写者等待排在自己之前的全部读者和写者；
+  3. 完成业务流程后，删除自己的键释放锁。
+*/
+
+const (
+	// rwReadPrefix 读者键前缀
+	rwReadPrefix = "read/"
+	// rwWritePrefix 写者键前缀
+	rwWritePrefix = "write/"
+)
+
+// RWMutex etcd 分布式读写锁，允许多个读者或一个写者持有锁
+//
+// 同一 RWMutex 同一时刻只能持有一次读锁或写锁，需要并发持有时请新建多个 RWMutex，
+// 解锁后可以再次上锁，不再使用时需要显式调用 Close 方法
+type RWMutex struct {
+	session   *concurrency.Session
+	pfx       string
+	myKey     string
+	myRev     int64
+	closeOnce sync.Once
+}
+
+// NewRWMutex 新建分布式读写锁
+//
+// key 为将要上锁的键，注意不要与 NewLock 使用相同的键，ttl 为会话租约到期时间，默认为 10s
+func (l *EtcdLocker) NewRWMutex(key string, ttl ...int) (*RWMutex, error) {
+	session, err := l.newSession(ttl...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RWMutex{session: session, pfx: l.prefix + strings.Trim(key, "/") + "/"}, nil
+}
+
+// RLock 上读锁，若存在排在之前的写者会阻塞并等待获取锁
+//
+// ctx 最好带有 timeout
+func (m *RWMutex) RLock(ctx context.Context) error {
+	return m.lock(ctx, rwReadPrefix, false)
+}
+
+// TryRLock 尝试上读锁，若存在排在之前的写者会立刻返回 ErrLocked
+//
+// ctx 最好带有 timeout
+func (m *RWMutex) TryRLock(ctx context.Context) error {
+	return m.lock(ctx, rwReadPrefix, true)
+}
+
+// RUnlock 解读锁
+//
+// ctx 最好带有 timeout
+func (m *RWMutex) RUnlock(ctx context.Context) error {
+	return m.unlock(ctx)
+}
+
+// Lock 上写锁，若存在排在之前的读者或写者会阻塞并等待获取锁
+//
+// ctx 最好带有 timeout
+func (m *RWMutex) Lock(ctx context.Context) error {
+	return m.lock(ctx, rwWritePrefix, false)
+}
+
+// TryLock 尝试上写锁，若存在排在之前的读者或写者会立刻返回 ErrLocked
+//
+// ctx 最好带有 timeout
+func (m *RWMutex) TryLock(ctx context.Context) error {
+	return m.lock(ctx, rwWritePrefix, true)
+}
+
+// Unlock 解写锁
+//
+// ctx 最好带有 timeout
+func (m *RWMutex) Unlock(ctx context.Context) error {
+	return m.unlock(ctx)
+}
+
+// Token 获取 fencing token，即锁键的创建版本号，上锁成功后有效
+func (m *RWMutex) Token() int64 {
+	return m.myRev
+}
+
+// Done 获取会话结束通道，会话租约过期或被撤销导致锁丢失时，以及锁关闭后，该通道会被关闭
+func (m *RWMutex) Done() <-chan struct{} {
+	return m.session.Done()
+}
+
+// Close 关闭锁，会话关闭后持有的锁会被释放
+func (m *RWMutex) Close() {
+	m.closeOnce.Do(func() {
+		threading.GoSafe(func() {
+			if err := m.session.Close(); err != nil {
+				logx.Errorf("xlock: concurrency session close err: %v", err)
+			}
+		})
+	})
+}
+
+// lock 在 kind 前缀下创建键并排队上锁
+func (m *RWMutex) lock(ctx context.Context, kind string, try bool) error {
+	if m.myKey != "" {
+		return errors.New("xlock: rwmutex is already locked")
+	}
+
+	key := fmt.Sprintf("%s%s%x", m.pfx, kind, m.session.Lease())
+	rev, err := createKey(ctx, m.session, key)
+	if err != nil {
+		return errors.WithMessage(err, "rwmutex create key err")
+	}
+
+	// 读者只等待排在之前的写者，写者等待排在之前的全部读者和写者
+	waitPfx := m.pfx + rwWritePrefix
+	if kind == rwWritePrefix {
+		waitPfx = m.pfx
+	}
+	opts := append([]clientv3.OpOption{clientv3.WithMaxCreateRev(rev - 1)}, clientv3.WithLastCreate()...)
+
+	for {
+		resp, err := m.session.Client().Get(ctx, waitPfx, opts...)
+		if err != nil {
+			deleteKey(m.session, key)
+			return errors.WithMessage(err, "rwmutex get keys err")
+		}
+		if len(resp.Kvs) == 0 {
+			break
+		}
+		if try {
+			deleteKey(m.session, key)
+			return errors.WithMessage(ErrLocked, "rwmutex try lock err")
+		}
+
+		err = waitDelete(ctx, m.session.Client(), string(resp.Kvs[0].Key), resp.Header.Revision)
+		if err != nil {
+			deleteKey(m.session, key)
+			return errors.WithMessage(err, "rwmutex lock err")
+		}
+	}
+
+	m.myKey, m.myRev = key, rev
+
+	return nil
+}
+
+// unlock 删除自己的键释放锁
+func (m *RWMutex) unlock(ctx context.Context) error {
+	if m.myKey == "" {
+		return errors.New("xlock: rwmutex is not locked")
+	}
+
+	if _, err := m.session.Client().Delete(ctx, m.myKey); err != nil {
+		return errors.WithMessage(err, "rwmutex unlock err")
+	}
+	m.myKey, m.myRev = "", 0
+
+	return nil
+}
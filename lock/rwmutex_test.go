@@ -0,0 +1,92 @@
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdLocker_NewRWMutex(t *testing.T) {
+	l := getLocker()
+	key := "test-rwmutex-key"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	newRWMutex := func() *RWMutex {
+		m, err := l.NewRWMutex(key)
+		require.NoError(t, err)
+		t.Cleanup(m.Close)
+		return m
+	}
+
+	// 多个读者可以同时持有读锁
+	r1, r2, w := newRWMutex(), newRWMutex(), newRWMutex()
+	require.NoError(t, r1.TryRLock(ctx))
+	require.NoError(t, r2.RLock(ctx))
+	assert.Greater(t, r2.Token(), r1.Token())
+	require.Error(t, r1.RLock(ctx))
+	require.ErrorIs(t, w.TryLock(ctx), ErrLocked)
+
+	// 写者等待全部读者释放读锁
+	locked := make(chan error, 1)
+	go func() {
+		locked <- w.Lock(ctx)
+	}()
+	require.NoError(t, r1.RUnlock(ctx))
+	select {
+	case <-locked:
+		t.Fatal("writer should wait for all readers")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.NoError(t, r2.RUnlock(ctx))
+	require.NoError(t, <-locked)
+
+	// 写者持有写锁时读者需要等待
+	require.ErrorIs(t, r1.TryRLock(ctx), ErrLocked)
+	tctx, tcancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer tcancel()
+	require.ErrorIs(t, r1.RLock(tctx), context.DeadlineExceeded)
+
+	go func() {
+		locked <- r1.RLock(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, w.Unlock(ctx))
+	require.NoError(t, <-locked)
+	require.NoError(t, r1.RUnlock(ctx))
+	require.Error(t, r1.RUnlock(ctx))
+
+	// 等待超时或尝试失败的键已被删除，不会阻塞之后的写者
+	require.NoError(t, w.TryLock(ctx))
+	require.NoError(t, w.Unlock(ctx))
+}
+
+func TestRWMutex_Done(t *testing.T) {
+	l := getLocker()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	m, err := l.NewRWMutex("test-rwmutex-done-key", 1)
+	require.NoError(t, err)
+	require.NoError(t, m.Lock(ctx))
+	assert.False(t, isDone(m.Done()))
+
+	// 会话关闭后写锁被释放
+	m.Close()
+	select {
+	case <-m.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("rwmutex done channel not closed after close")
+	}
+
+	other, err := l.NewRWMutex("test-rwmutex-done-key")
+	require.NoError(t, err)
+	defer other.Close()
+	require.Eventually(t, func() bool {
+		return other.TryRLock(ctx) == nil
+	}, 5*time.Second, 20*time.Millisecond)
+	require.NoError(t, other.RUnlock(ctx))
+}
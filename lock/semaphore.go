@@ -0,0 +1,138 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+/*
+etcd 信号量获取释放过程：
+  1. 在 key/ 下创建绑定会话租约的键，并记录键的创建版本号；
+  2. 统计 key/ 下创建版本号比自己小的键的数量，小于 limit 则获得信号量，
+     否则监听 key/ 下任意键的删除事件，删除后重新统计，即按创建顺序排队，最多 limit 个持有者；
+  3. 完成业务流程后，删除自己的键释放信号量。
+*/
+
+// Semaphore etcd 分布式计数信号量，最多允许 limit 个持有者同时持有
+//
+// 使用同一个键的全部 Semaphore 需要使用相同的 limit，
+// 同一 Semaphore 同一时刻只能持有一次，需要并发持有时请新建多个 Semaphore，
+// 释放后可以再次获取，不再使用时需要显式调用 Close 方法
+type Semaphore struct {
+	session   *concurrency.Session
+	pfx       string
+	limit     int
+	myKey     string
+	closeOnce sync.Once
+}
+
+// NewSemaphore 新建分布式计数信号量
+//
+// key 为信号量的键，注意不要与 NewLock 和 NewRWMutex 使用相同的键，
+// limit 为最大持有者数量，ttl 为会话租约到期时间，默认为 10s
+func (l *EtcdLocker) NewSemaphore(key string, limit int, ttl ...int) (*Semaphore, error) {
+	if limit <= 0 {
+		return nil, errors.Errorf("xlock: illegal semaphore limit: %d", limit)
+	}
+
+	session, err := l.newSession(ttl...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Semaphore{session: session, pfx: l.prefix + strings.Trim(key, "/") + "/", limit: limit}, nil
+}
+
+// Acquire 获取信号量，若持有者数量已满会阻塞并等待获取信号量
+//
+// ctx 最好带有 timeout
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	return s.acquire(ctx, false)
+}
+
+// TryAcquire 尝试获取信号量，若持有者数量已满会立刻返回 ErrLocked
+//
+// ctx 最好带有 timeout
+func (s *Semaphore) TryAcquire(ctx context.Context) error {
+	return s.acquire(ctx, true)
+}
+
+// Release 释放信号量
+//
+// ctx 最好带有 timeout
+func (s *Semaphore) Release(ctx context.Context) error {
+	if s.myKey == "" {
+		return errors.New("xlock: semaphore is not acquired")
+	}
+
+	if _, err := s.session.Client().Delete(ctx, s.myKey); err != nil {
+		return errors.WithMessage(err, "semaphore release err")
+	}
+	s.myKey = ""
+
+	return nil
+}
+
+// Done 获取会话结束通道，会话租约过期或被撤销导致信号量丢失时，以及信号量关闭后，该通道会被关闭
+func (s *Semaphore) Done() <-chan struct{} {
+	return s.session.Done()
+}
+
+// Close 关闭信号量，会话关闭后持有的信号量会被释放
+func (s *Semaphore) Close() {
+	s.closeOnce.Do(func() {
+		threading.GoSafe(func() {
+			if err := s.session.Close(); err != nil {
+				logx.Errorf("xlock: concurrency session close err: %v", err)
+			}
+		})
+	})
+}
+
+// acquire 创建键并排队获取信号量
+func (s *Semaphore) acquire(ctx context.Context, try bool) error {
+	if s.myKey != "" {
+		return errors.New("xlock: semaphore is already acquired")
+	}
+
+	key := fmt.Sprintf("%s%x", s.pfx, s.session.Lease())
+	rev, err := createKey(ctx, s.session, key)
+	if err != nil {
+		return errors.WithMessage(err, "semaphore create key err")
+	}
+
+	for {
+		resp, err := s.session.Client().Get(ctx, s.pfx,
+			clientv3.WithPrefix(), clientv3.WithMaxCreateRev(rev-1), clientv3.WithCountOnly())
+		if err != nil {
+			deleteKey(s.session, key)
+			return errors.WithMessage(err, "semaphore count keys err")
+		}
+		if resp.Count < int64(s.limit) {
+			break
+		}
+		if try {
+			deleteKey(s.session, key)
+			return errors.WithMessage(ErrLocked, "semaphore try acquire err")
+		}
+
+		// 排在之前的任意持有者释放后重新统计
+		err = waitDelete(ctx, s.session.Client(), s.pfx, resp.Header.Revision, clientv3.WithPrefix())
+		if err != nil {
+			deleteKey(s.session, key)
+			return errors.WithMessage(err, "semaphore acquire err")
+		}
+	}
+
+	s.myKey = key
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtcdLocker_NewSemaphore(t *testing.T) {
+	l := getLocker()
+	key := "test-semaphore-key"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err := l.NewSemaphore(key, 0)
+	require.Error(t, err)
+
+	newSemaphore := func() *Semaphore {
+		s, err := l.NewSemaphore(key, 2)
+		require.NoError(t, err)
+		t.Cleanup(s.Close)
+		return s
+	}
+
+	// 最多允许 2 个持有者
+	s1, s2, s3 := newSemaphore(), newSemaphore(), newSemaphore()
+	require.NoError(t, s1.TryAcquire(ctx))
+	require.NoError(t, s2.Acquire(ctx))
+	require.Error(t, s1.Acquire(ctx))
+	require.ErrorIs(t, s3.TryAcquire(ctx), ErrLocked)
+	tctx, tcancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer tcancel()
+	require.ErrorIs(t, s3.Acquire(tctx), context.DeadlineExceeded)
+
+	// 任意持有者释放后等待者获得信号量
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- s3.Acquire(ctx)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("semaphore should be full")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.NoError(t, s2.Release(ctx))
+	require.NoError(t, <-acquired)
+	require.Error(t, s2.Release(ctx))
+	require.ErrorIs(t, s2.TryAcquire(ctx), ErrLocked)
+
+	// 会话关闭后信号量被释放
+	s1.Close()
+	require.Eventually(t, func() bool {
+		return s2.TryAcquire(ctx) == nil
+	}, 5*time.Second, 20*time.Millisecond)
+	require.NoError(t, s2.Release(ctx))
+	require.NoError(t, s3.Release(ctx))
+}
@@ -32,11 +32,12 @@ type Config struct {
 
 // Notify 通知服务
 type Notify struct {
-	c            Config                        // 配置
-	smsClients   notifytypes.SmsClientPicker   // 短信客户端选取器
-	emailClients notifytypes.EmailClientPicker // 邮件客户端选取器
-	kvStore      *xkv.Store                    // 键值存取器
-	periodLimit  *limit.PeriodLimit            // 通知限流器
+	c              Config                        // 配置
+	smsClients     notifytypes.SmsClientPicker   // 短信客户端选取器
+	emailClients   notifytypes.EmailClientPicker // 邮件客户端选取器
+	kvStore        *xkv.Store                    // 键值存取器
+	periodLimit    *limit.PeriodLimit            // 通知限流器
+	compositeLimit *limit.CompositeLimit         // 通知发送多规则限流器
 }
 
 // NewNotify 新建通知服务
@@ -54,13 +55,18 @@ func NewNotify(c Config, smsClients notifytypes.SmsClientPicker, emailClients no
 	if err != nil {
 		return nil, errors.WithMessage(err, "notify: new period limit err")
 	}
+	compositeLimit, err := limit.NewCompositeLimit("", kvStore)
+	if err != nil {
+		return nil, errors.WithMessage(err, "notify: new composite limit err")
+	}
 
 	return &Notify{
-		c:            c,
-		smsClients:   smsClients,
-		emailClients: emailClients,
-		kvStore:      kvStore,
-		periodLimit:  periodLimit,
+		c:              c,
+		smsClients:     smsClients,
+		emailClients:   emailClients,
+		kvStore:        kvStore,
+		periodLimit:    periodLimit,
+		compositeLimit: compositeLimit,
 	}, nil
 }
 
@@ -134,47 +140,31 @@ func (n *Notify) handleSendParams(p *notifytypes.SendParams) (*notifytypes.CodeP
 	return cp, nil
 }
 
-// checkSend 检查给定参数条件是否允许发送
+// checkSend 检查给定参数条件是否允许发送，全部限制条件均满足时才计数
 func (n *Notify) checkSend(p notifytypes.CommonParams) error {
-	// 生成提供方限制缓存 key
-	providerKey := notifytypes.GenProviderLimitKey(p)
-	ok, err := n.periodLimit.Allow(providerKey, limit.WithQuota(n.c.ProviderQuota))
-	if err != nil {
-		return errors.Wrap(err, "provider limit allow err")
-	}
-	if !ok {
-		return notifytypes.ErrProviderOverQuota
-	}
-
-	// 生成IP地址来源限制缓存 key
-	ipSourceKey := notifytypes.GenIPSourceLimitKey(p)
-	ok, err = n.periodLimit.Allow(ipSourceKey, limit.WithQuota(n.c.IPSourceQuota))
-	if err != nil {
-		return errors.Wrap(err, "ip source limit allow err")
-	}
-	if !ok {
-		return notifytypes.ErrIPSourceOverQuota
-	}
-
-	// 生成接收方限制缓存 key
-	receiverKey := notifytypes.GenReceiverLimitKey(p)
-	ok, err = n.periodLimit.Allow(receiverKey, limit.WithQuota(n.c.ReceiverQuota))
+	day := 24 * 3600
+	rejectErrs := []error{
+		notifytypes.ErrProviderOverQuota,
+		notifytypes.ErrIPSourceOverQuota,
+		notifytypes.ErrReceiverOverQuota,
+		notifytypes.ErrSendTooFrequently,
+	}
+
+	result, err := n.compositeLimit.Allow(
+		// 提供方限制
+		limit.Rule{Key: notifytypes.GenProviderLimitKey(p), Period: day, Quota: n.c.ProviderQuota},
+		// IP地址来源限制
+		limit.Rule{Key: notifytypes.GenIPSourceLimitKey(p), Period: day, Quota: n.c.IPSourceQuota},
+		// 接收方限制
+		limit.Rule{Key: notifytypes.GenReceiverLimitKey(p), Period: day, Quota: n.c.ReceiverQuota},
+		// 发送通知限制
+		limit.Rule{Key: notifytypes.GenSendLimitKey(p), Period: n.c.SendPeriod, Quota: n.c.SendQuota},
+	)
 	if err != nil {
-		return errors.Wrap(err, "receiver limit allow err")
-	}
-	if !ok {
-		return notifytypes.ErrReceiverOverQuota
+		return errors.Wrap(err, "composite limit allow err")
 	}
-
-	// 生成发送通知限制缓存 key
-	sendKey := notifytypes.GenSendLimitKey(p)
-	ok, err = n.periodLimit.Allow(sendKey,
-		limit.WithPeriod(n.c.SendPeriod), limit.WithQuota(n.c.SendQuota))
-	if err != nil {
-		return errors.Wrap(err, "send limit allow err")
-	}
-	if !ok {
-		return notifytypes.ErrSendTooFrequently
+	if !result.Allowed {
+		return rejectErrs[result.Rejected]
 	}
 
 	return nil
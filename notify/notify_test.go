@@ -1,6 +1,7 @@
 package notify
 
 import (
+	"strconv"
 	"testing"
 	"time"
 
@@ -139,3 +140,46 @@ func TestNotify_VerifyEmailCode(t *testing.T) {
 	err = n.VerifyEmailCode(p)
 	require.NoError(t, err)
 }
+
+func TestNotify_checkSend(t *testing.T) {
+	n, err := getNotify()
+	require.NoError(t, err)
+
+	p := notifytypes.CommonParams{}
+	p.NotifyMethod = notifytypes.Sms
+	p.IP = "127.0.0.2"
+	p.Provider = "test"
+	p.Receiver = "13000000001"
+	p.TemplateID = "login"
+
+	keys := []string{
+		notifytypes.GenProviderLimitKey(p),
+		notifytypes.GenIPSourceLimitKey(p),
+		notifytypes.GenReceiverLimitKey(p),
+	}
+	// 计数键均保存在多规则限流器路由到的节点上
+	getCount := func(key string) int {
+		for _, s := range []*miniredis.Miniredis{s1, s2} {
+			if v, err := s.Get(key); err == nil {
+				count, err := strconv.Atoi(v)
+				require.NoError(t, err)
+				return count
+			}
+		}
+		return 0
+	}
+	counts := make([]int, len(keys))
+	for i, key := range keys {
+		counts[i] = getCount(key)
+	}
+
+	require.NoError(t, n.checkSend(p))
+
+	// 发送过于频繁时，其他限制条件不计数
+	for i := 0; i < 3; i++ {
+		require.ErrorIs(t, n.checkSend(p), notifytypes.ErrSendTooFrequently)
+	}
+	for i, key := range keys {
+		require.Equal(t, counts[i]+1, getCount(key), key)
+	}
+}
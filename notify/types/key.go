@@ -23,9 +23,9 @@ func GenCodeKey(p CommonParams) string {
 		p.Provider, p.NotifyMethod, p.TemplateID, p.Receiver)
 }
 
-// GenSendLimitKey 生成发送通知限制缓存 key
+// GenSendLimitKey 生成发送通知限制缓存 key，以提供方作为 hashtag
 func GenSendLimitKey(p CommonParams) string {
-	return fmt.Sprintf("%s%s:%s:%s:%s", KeyPrefixSendLimit,
+	return fmt.Sprintf("%s{%s}:%s:%s:%s", KeyPrefixSendLimit,
 		p.Provider, p.NotifyMethod, p.TemplateID, p.Receiver)
 }
 
@@ -35,20 +35,20 @@ func GenVerifyLimitKey(p CommonParams) string {
 		p.Provider, p.NotifyMethod, p.TemplateID, p.Receiver)
 }
 
-// GenReceiverLimitKey 生成接收方限制缓存 key
+// GenReceiverLimitKey 生成接收方限制缓存 key，以提供方作为 hashtag
 func GenReceiverLimitKey(p CommonParams) string {
-	return fmt.Sprintf("%s%s:%s:%s", KeyPrefixReceiverLimit,
+	return fmt.Sprintf("%s{%s}:%s:%s", KeyPrefixReceiverLimit,
 		p.Provider, p.NotifyMethod, p.Receiver)
 }
 
-// GenIPSourceLimitKey 生成IP地址来源限制缓存 key
+// GenIPSourceLimitKey 生成IP地址来源限制缓存 key，以提供方作为 hashtag
 func GenIPSourceLimitKey(p CommonParams) string {
-	return fmt.Sprintf("%s%s:%s", KeyPrefixIPSourceLimit,
+	return fmt.Sprintf("%s{%s}:%s", KeyPrefixIPSourceLimit,
 		p.Provider, p.IP)
 }
 
-// GenProviderLimitKey 生成提供方限制缓存 key
+// GenProviderLimitKey 生成提供方限制缓存 key，以提供方作为 hashtag
 func GenProviderLimitKey(p CommonParams) string {
-	return fmt.Sprintf("%s%s", KeyPrefixProviderLimit,
+	return fmt.Sprintf("%s{%s}", KeyPrefixProviderLimit,
 		p.Provider)
 }
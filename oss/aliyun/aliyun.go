@@ -1,12 +1,23 @@
 package aliyun
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
 	"io"
+	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 	"github.com/pkg/errors"
+
+	"github.com/sliveryou/micro-pkg/oss/internal/imaging"
+	"github.com/sliveryou/micro-pkg/oss/internal/policy"
+	"github.com/sliveryou/micro-pkg/oss/internal/progress"
+	"github.com/sliveryou/micro-pkg/oss/internal/util"
+	"github.com/sliveryou/micro-pkg/oss/types"
+	"github.com/sliveryou/micro-pkg/xhttp"
 )
 
 const (
@@ -92,7 +103,12 @@ func (o *OSS) GetURL(key string) string {
 
 // GetObject 获取对象在阿里云 OSS 的存储数据
 func (o *OSS) GetObject(key string) (io.ReadCloser, error) {
-	obj, err := o.bucket.GetObject(key)
+	return o.GetObjectCtx(context.Background(), key)
+}
+
+// GetObjectCtx 获取对象在阿里云 OSS 的存储数据
+func (o *OSS) GetObjectCtx(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := o.bucket.GetObject(key, oss.WithContext(ctx))
 	if err != nil {
 		return nil, errors.WithMessage(err, "aliyun: oss get object err")
 	}
@@ -102,7 +118,19 @@ func (o *OSS) GetObject(key string) (io.ReadCloser, error) {
 
 // PutObject 上传对象至阿里云 OSS
 func (o *OSS) PutObject(key string, reader io.Reader) (string, error) {
-	err := o.bucket.PutObject(key, reader)
+	return o.PutObjectCtx(context.Background(), key, reader)
+}
+
+// PutObjectCtx 上传对象至阿里云 OSS，opts：对象可选配置
+func (o *OSS) PutObjectCtx(ctx context.Context, key string, reader io.Reader, opts ...types.ObjectOption) (string, error) {
+	oo := types.NewObjectOptions(key, opts...)
+	options := toOptions(ctx, oo)
+	total, _ := xhttp.GetReaderLen(reader)
+	if t := progress.New(ctx, total, oo); t != nil {
+		options = append(options, oss.Progress(&progressListener{t: t}))
+	}
+
+	err := o.bucket.PutObject(key, reader, options...)
 	if err != nil {
 		return "", errors.WithMessage(err, "aliyun: oss put object err")
 	}
@@ -112,16 +140,36 @@ func (o *OSS) PutObject(key string, reader io.Reader) (string, error) {
 
 // DeleteObjects 批量删除阿里云 OSS 上的对象
 func (o *OSS) DeleteObjects(keys ...string) error {
-	_, err := o.bucket.DeleteObjects(keys)
+	return o.DeleteObjectsCtx(context.Background(), keys...)
+}
+
+// DeleteObjectsCtx 批量删除阿里云 OSS 上的对象
+func (o *OSS) DeleteObjectsCtx(ctx context.Context, keys ...string) error {
+	_, err := o.bucket.DeleteObjects(keys, oss.WithContext(ctx))
 
 	return errors.WithMessage(err, "aliyun: oss delete objects err")
 }
 
 // UploadFile 上传文件至阿里云 OSS，filePath：文件路径，partSize：分块大小（字节），routines：并发数
 func (o *OSS) UploadFile(key, filePath string, partSize int64, routines int) (string, error) {
-	err := o.bucket.UploadFile(key, filePath, partSize,
-		oss.Routines(routines),
-		oss.Checkpoint(true, ""))
+	return o.UploadFileCtx(context.Background(), key, filePath, partSize, routines)
+}
+
+// UploadFileCtx 上传文件至阿里云 OSS，filePath：文件路径，partSize：分块大小（字节），routines：并发数，opts：对象可选配置
+func (o *OSS) UploadFileCtx(ctx context.Context, key, filePath string, partSize int64, routines int, opts ...types.ObjectOption) (string, error) {
+	oo := types.NewObjectOptions(key, opts...)
+	t := progress.New(ctx, progress.FileSize(filePath), oo)
+	if t.Limited() {
+		return o.uploadFileLimited(ctx, key, filePath, partSize, t, oo)
+	}
+
+	options := toOptions(ctx, oo)
+	options = append(options, oss.Routines(routines), oss.Checkpoint(true, ""))
+	if t != nil {
+		options = append(options, oss.Progress(&progressListener{t: t}))
+	}
+
+	err := o.bucket.UploadFile(key, filePath, partSize, options...)
 	if err != nil {
 		return "", errors.WithMessage(err, "aliyun: oss upload file err")
 	}
@@ -129,6 +177,101 @@ func (o *OSS) UploadFile(key, filePath string, partSize int64, routines int) (st
 	return o.GetURL(key), nil
 }
 
+// CopyObject 在阿里云 OSS 服务端复制对象，源对象不存在时返回 types.ErrObjectNotFound
+func (o *OSS) CopyObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	_, err := o.bucket.CopyObject(srcKey, dstKey, oss.WithContext(ctx))
+	if err != nil {
+		if isNotFound(err) {
+			err = types.ErrObjectNotFound
+		}
+		return "", errors.WithMessage(err, "aliyun: oss copy object err")
+	}
+
+	return o.GetURL(dstKey), nil
+}
+
+// MoveObject 在阿里云 OSS 服务端移动对象，源对象不存在时返回 types.ErrObjectNotFound
+func (o *OSS) MoveObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	u, err := o.CopyObject(ctx, srcKey, dstKey)
+	if err != nil {
+		return "", err
+	}
+	if srcKey != dstKey {
+		if err := o.DeleteObjectsCtx(ctx, srcKey); err != nil {
+			return "", err
+		}
+	}
+
+	return u, nil
+}
+
+// ListObjects 列举阿里云 OSS 上指定前缀的对象，opts：列举可选配置
+func (o *OSS) ListObjects(ctx context.Context, prefix string, opts ...types.ListOption) (*types.ListObjectsResult, error) {
+	lo := types.NewListOptions(opts...)
+	out, err := o.bucket.ListObjects(oss.WithContext(ctx), oss.Prefix(prefix),
+		oss.Marker(lo.Marker), oss.Delimiter(lo.Delimiter), oss.MaxKeys(lo.MaxKeys))
+	if err != nil {
+		return nil, errors.WithMessage(err, "aliyun: oss list objects err")
+	}
+
+	result := &types.ListObjectsResult{
+		Objects:        make([]types.ObjectInfo, 0, len(out.Objects)),
+		CommonPrefixes: out.CommonPrefixes,
+		IsTruncated:    out.IsTruncated,
+		NextMarker:     out.NextMarker,
+	}
+	for _, obj := range out.Objects {
+		result.Objects = append(result.Objects, types.ObjectInfo{
+			Key:          obj.Key,
+			Size:         obj.Size,
+			ETag:         util.TrimETag(obj.ETag),
+			LastModified: obj.LastModified,
+		})
+	}
+	util.FillNextMarker(result)
+
+	return result, nil
+}
+
+// StatObject 获取阿里云 OSS 上对象的元信息，对象不存在时返回 types.ErrObjectNotFound
+func (o *OSS) StatObject(ctx context.Context, key string) (*types.ObjectInfo, error) {
+	header, err := o.bucket.GetObjectDetailedMeta(key, oss.WithContext(ctx))
+	if err != nil {
+		if isNotFound(err) {
+			err = types.ErrObjectNotFound
+		}
+		return nil, errors.WithMessage(err, "aliyun: oss stat object err")
+	}
+
+	size, _ := strconv.ParseInt(header.Get(oss.HTTPHeaderContentLength), 10, 64)
+	lastModified, _ := http.ParseTime(header.Get(oss.HTTPHeaderLastModified))
+	metadata := make(map[string]string)
+	for k := range header {
+		if strings.HasPrefix(k, oss.HTTPHeaderOssMetaPrefix) {
+			metadata[k[len(oss.HTTPHeaderOssMetaPrefix):]] = header.Get(k)
+		}
+	}
+
+	return &types.ObjectInfo{
+		Key:          key,
+		Size:         size,
+		ETag:         util.TrimETag(header.Get(oss.HTTPHeaderEtag)),
+		LastModified: lastModified,
+		ContentType:  header.Get(oss.HTTPHeaderContentType),
+		Metadata:     util.LowerKeys(metadata),
+	}, nil
+}
+
+// Exists 判断对象是否存在于阿里云 OSS
+func (o *OSS) Exists(ctx context.Context, key string) (bool, error) {
+	ok, err := o.bucket.IsObjectExist(key, oss.WithContext(ctx))
+	if err != nil {
+		return false, errors.WithMessage(err, "aliyun: oss check object exists err")
+	}
+
+	return ok, nil
+}
+
 // AuthorizedUpload 授权上传至阿里云 OSS，expires：过期时间（秒）
 func (o *OSS) AuthorizedUpload(key string, expires int) (string, error) {
 	signedURL, err := o.bucket.SignURL(key, oss.HTTPPut, int64(expires))
@@ -139,6 +282,134 @@ func (o *OSS) AuthorizedUpload(key string, expires int) (string, error) {
 	return signedURL, nil
 }
 
+// PresignedGetURL 获取阿里云 OSS 上对象的预签名下载 URL，expires：过期时间（秒），opts：预签名可选配置
+func (o *OSS) PresignedGetURL(key string, expires int, opts ...types.PresignOption) (string, error) {
+	po := types.NewPresignOptions(opts...)
+	var options []oss.Option
+	if po.ResponseContentType != "" {
+		options = append(options, oss.ResponseContentType(po.ResponseContentType))
+	}
+	if po.ResponseContentDisposition != "" {
+		options = append(options, oss.ResponseContentDisposition(po.ResponseContentDisposition))
+	}
+	if po.ResponseCacheControl != "" {
+		options = append(options, oss.ResponseCacheControl(po.ResponseCacheControl))
+	}
+
+	signedURL, err := o.bucket.SignURL(key, oss.HTTPGet, int64(expires), options...)
+	if err != nil {
+		return "", errors.WithMessage(err, "aliyun: oss presigned get url err")
+	}
+
+	return o.externalURL(signedURL), nil
+}
+
+// PostPolicy 获取阿里云 OSS 表单上传策略，expires：过期时间（秒），opts：表单上传策略可选配置
+func (o *OSS) PostPolicy(key string, expires int, opts ...types.PostPolicyOption) (*types.PostPolicy, error) {
+	// 参考文档 https://help.aliyun.com/zh/oss/developer-reference/postobject
+	p, err := policy.New(key, expires, types.NewPostPolicyOptions(opts...), true)
+	if err != nil {
+		return nil, errors.WithMessage(err, "aliyun: oss post policy err")
+	}
+	p.Add(map[string]string{"bucket": o.bucket.BucketName})
+
+	_, encoded, err := p.Encode()
+	if err != nil {
+		return nil, errors.WithMessage(err, "aliyun: oss post policy err")
+	}
+
+	conf := o.client.Config
+	return p.PostPolicy(fmt.Sprintf("https://%s.%s", o.bucket.BucketName, o.externalEndpoint), map[string]string{
+		policy.FieldPolicy: encoded,
+		"OSSAccessKeyId":   conf.AccessKeyID,
+		"Signature":        base64.StdEncoding.EncodeToString(policy.HmacSHA1(conf.AccessKeySecret, encoded)),
+	}), nil
+}
+
+// InitiateMultipartUpload 初始化阿里云 OSS 分片上传，返回上传 ID，opts：对象可选配置
+func (o *OSS) InitiateMultipartUpload(ctx context.Context, key string, opts ...types.ObjectOption) (string, error) {
+	imur, err := o.bucket.InitiateMultipartUpload(key, toOptions(ctx, types.NewObjectOptions(key, opts...))...)
+	if err != nil {
+		return "", errors.WithMessage(err, "aliyun: oss initiate multipart upload err")
+	}
+
+	return imur.UploadID, nil
+}
+
+// PresignedUploadPartURL 获取阿里云 OSS 分片上传的预签名 URL，partNumber：分片序号，expires：过期时间（秒）
+func (o *OSS) PresignedUploadPartURL(key, uploadID string, partNumber, expires int) (string, error) {
+	signedURL, err := o.bucket.SignURL(key, oss.HTTPPut, int64(expires),
+		oss.AddParam("partNumber", strconv.Itoa(partNumber)),
+		oss.AddParam("uploadId", uploadID))
+	if err != nil {
+		return "", errors.WithMessage(err, "aliyun: oss presigned upload part url err")
+	}
+
+	return o.externalURL(signedURL), nil
+}
+
+// CompleteMultipartUpload 完成阿里云 OSS 分片上传，parts：已上传的分片列表
+func (o *OSS) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []types.Part) (string, error) {
+	uploadParts := make([]oss.UploadPart, 0, len(parts))
+	for _, p := range util.SortParts(parts) {
+		uploadParts = append(uploadParts, oss.UploadPart{PartNumber: p.PartNumber, ETag: p.ETag})
+	}
+
+	_, err := o.bucket.CompleteMultipartUpload(o.imur(key, uploadID), uploadParts, oss.WithContext(ctx))
+	if err != nil {
+		if isNotFound(err) {
+			err = types.ErrUploadNotFound
+		}
+		return "", errors.WithMessage(err, "aliyun: oss complete multipart upload err")
+	}
+
+	return o.GetURL(key), nil
+}
+
+// AbortMultipartUpload 取消阿里云 OSS 分片上传
+func (o *OSS) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	err := o.bucket.AbortMultipartUpload(o.imur(key, uploadID), oss.WithContext(ctx))
+	if err != nil {
+		if isNotFound(err) {
+			err = types.ErrUploadNotFound
+		}
+		return errors.WithMessage(err, "aliyun: oss abort multipart upload err")
+	}
+
+	return nil
+}
+
+// ListParts 列举阿里云 OSS 分片上传中已上传的分片
+func (o *OSS) ListParts(ctx context.Context, key, uploadID string) ([]types.Part, error) {
+	var parts []types.Part
+	marker := 0
+	for {
+		out, err := o.bucket.ListUploadedParts(o.imur(key, uploadID),
+			oss.WithContext(ctx), oss.PartNumberMarker(marker))
+		if err != nil {
+			if isNotFound(err) {
+				err = types.ErrUploadNotFound
+			}
+			return nil, errors.WithMessage(err, "aliyun: oss list parts err")
+		}
+
+		for _, p := range out.UploadedParts {
+			parts = append(parts, types.Part{
+				PartNumber:   p.PartNumber,
+				ETag:         util.TrimETag(p.ETag),
+				Size:         int64(p.Size),
+				LastModified: p.LastModified,
+			})
+		}
+		if !out.IsTruncated {
+			break
+		}
+		marker, _ = strconv.Atoi(out.NextPartNumberMarker)
+	}
+
+	return parts, nil
+}
+
 // GetThumbnailSuffix 获取缩略图后缀
 func (o *OSS) GetThumbnailSuffix(width, height int, size int64) string {
 	// 参考文档 https://help.aliyun.com/document_detail/44688.html
@@ -158,3 +429,149 @@ func (o *OSS) GetThumbnailSuffix(width, height int, size int64) string {
 
 	return suffix
 }
+
+// ProcessImage 获取阿里云 OSS 上图片处理后的访问 URL，opts：图片处理可选配置
+func (o *OSS) ProcessImage(ctx context.Context, key string, opts ...types.ImageOption) (string, error) {
+	// 参考文档 https://help.aliyun.com/zh/oss/user-guide/img-parameters
+	ip := types.NewImageOptions(opts...)
+	if err := ip.Check(); err != nil {
+		return "", err
+	}
+
+	return o.GetURL(key) + "?x-oss-process=" + imaging.Params(ip), nil
+}
+
+// SetLifecycleRules 设置阿里云 OSS 存储桶的生命周期规则，覆盖已有规则，rules 为空时删除全部规则
+func (o *OSS) SetLifecycleRules(ctx context.Context, rules []types.LifecycleRule) error {
+	rules, err := types.NormalizeLifecycleRules(rules)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		if err := o.client.DeleteBucketLifecycle(o.bucket.BucketName, oss.WithContext(ctx)); err != nil {
+			return errors.WithMessage(err, "aliyun: oss delete lifecycle err")
+		}
+		return nil
+	}
+
+	lrs := make([]oss.LifecycleRule, 0, len(rules))
+	for _, r := range rules {
+		lr := oss.LifecycleRule{ID: r.ID, Prefix: r.Prefix, Status: "Enabled"}
+		if r.Disabled {
+			lr.Status = "Disabled"
+		}
+		if r.ExpirationDays > 0 {
+			lr.Expiration = &oss.LifecycleExpiration{Days: r.ExpirationDays}
+		}
+		if r.AbortMultipartUploadDays > 0 {
+			lr.AbortMultipartUpload = &oss.LifecycleAbortMultipartUpload{Days: r.AbortMultipartUploadDays}
+		}
+		lrs = append(lrs, lr)
+	}
+	if err := o.client.SetBucketLifecycle(o.bucket.BucketName, lrs, oss.WithContext(ctx)); err != nil {
+		return errors.WithMessage(err, "aliyun: oss set lifecycle err")
+	}
+
+	return nil
+}
+
+// GetLifecycleRules 获取阿里云 OSS 存储桶的生命周期规则，仅返回按天数过期删除和取消分片上传的规则
+func (o *OSS) GetLifecycleRules(ctx context.Context) ([]types.LifecycleRule, error) {
+	out, err := o.client.GetBucketLifecycle(o.bucket.BucketName, oss.WithContext(ctx))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.WithMessage(err, "aliyun: oss get lifecycle err")
+	}
+
+	var rules []types.LifecycleRule
+	for _, lr := range out.Rules {
+		r := types.LifecycleRule{ID: lr.ID, Prefix: lr.Prefix, Disabled: lr.Status != "Enabled"}
+		if lr.Expiration != nil {
+			r.ExpirationDays = lr.Expiration.Days
+		}
+		if lr.AbortMultipartUpload != nil {
+			r.AbortMultipartUploadDays = lr.AbortMultipartUpload.Days
+		}
+		if r.ExpirationDays > 0 || r.AbortMultipartUploadDays > 0 {
+			rules = append(rules, r)
+		}
+	}
+
+	return rules, nil
+}
+
+// toOptions 将对象可选配置转换为阿里云 OSS 请求选项
+func toOptions(ctx context.Context, oo *types.ObjectOptions) []oss.Option {
+	options := []oss.Option{oss.WithContext(ctx), oss.ContentType(oo.ContentType)}
+	if oo.ContentDisposition != "" {
+		options = append(options, oss.ContentDisposition(oo.ContentDisposition))
+	}
+	if oo.ContentEncoding != "" {
+		options = append(options, oss.ContentEncoding(oo.ContentEncoding))
+	}
+	if oo.CacheControl != "" {
+		options = append(options, oss.CacheControl(oo.CacheControl))
+	}
+	for k, v := range oo.Metadata {
+		options = append(options, oss.Meta(k, v))
+	}
+
+	return options
+}
+
+// uploadFileLimited 限速时按分片顺序上传文件，SDK 断点续传上传仅在分片完成后回调进度，无法在传输过程中限速
+func (o *OSS) uploadFileLimited(ctx context.Context, key, filePath string, partSize int64, t *progress.Tracker, oo *types.ObjectOptions) (string, error) {
+	imur, err := o.bucket.InitiateMultipartUpload(key, toOptions(ctx, oo)...)
+	if err != nil {
+		return "", errors.WithMessage(err, "aliyun: oss upload file err")
+	}
+
+	parts, err := progress.UploadParts(ctx, t, filePath, partSize, func(partNumber int, r io.Reader, size int64) (string, error) {
+		part, err := o.bucket.UploadPart(imur, r, size, partNumber, oss.WithContext(ctx))
+		return part.ETag, err
+	})
+	if err != nil {
+		_ = o.bucket.AbortMultipartUpload(imur)
+		return "", errors.WithMessage(err, "aliyun: oss upload file err")
+	}
+
+	return o.CompleteMultipartUpload(ctx, key, imur.UploadID, parts)
+}
+
+// externalURL 将预签名 URL 的访问域名替换为外网域名，签名不包含访问域名，替换后签名仍然有效
+func (o *OSS) externalURL(signedURL string) string {
+	if o.uploadInternal {
+		return strings.Replace(signedURL, "-internal", "", 1)
+	}
+
+	return signedURL
+}
+
+// imur 获取分片上传初始化结果
+func (o *OSS) imur(key, uploadID string) oss.InitiateMultipartUploadResult {
+	return oss.InitiateMultipartUploadResult{
+		Bucket:   o.bucket.BucketName,
+		Key:      key,
+		UploadID: uploadID,
+	}
+}
+
+// isNotFound 判断错误是否为对象或分片上传不存在错误
+func isNotFound(err error) bool {
+	var se oss.ServiceError
+	return errors.As(err, &se) && se.StatusCode == http.StatusNotFound
+}
+
+// progressListener 阿里云 OSS 传输进度监听器
+type progressListener struct {
+	t *progress.Tracker
+}
+
+// ProgressChanged 实现 oss.ProgressListener 接口，在监听器中限速等待会阻塞 SDK 读取数据
+func (l *progressListener) ProgressChanged(event *oss.ProgressEvent) {
+	if event.EventType == oss.TransferDataEvent {
+		l.t.Add(event.RwBytes)
+	}
+}
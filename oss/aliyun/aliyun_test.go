@@ -1,10 +1,19 @@
 package aliyun
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
 	"testing"
 
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/internal/progress"
+	"github.com/sliveryou/micro-pkg/oss/types"
 )
 
 var (
@@ -41,3 +50,68 @@ func TestOSS_AuthorizedUpload(t *testing.T) {
 	require.NoError(t, err)
 	t.Log(oss.AuthorizedUpload("test/test.txt", 120))
 }
+
+func TestIsNotFound(t *testing.T) {
+	assert.True(t, isNotFound(oss.ServiceError{StatusCode: 404}))
+	assert.True(t, isNotFound(errors.WithMessage(oss.ServiceError{StatusCode: 404}, "test")))
+	assert.False(t, isNotFound(oss.ServiceError{StatusCode: 403}))
+	assert.False(t, isNotFound(errors.New("test")))
+}
+
+func TestOSS_PresignedGetURL(t *testing.T) {
+	oss, err := NewOSS("oss-cn-hangzhou-internal.aliyuncs.com", accessKeyID, accessKeySecret, bucketName, WithNotSetACL(), WithUploadInternal())
+	require.NoError(t, err)
+	signedURL, err := oss.PresignedGetURL("test/test.pdf", 120,
+		types.WithResponseContentDisposition(`attachment; filename="test.pdf"`))
+	require.NoError(t, err)
+	assert.Contains(t, signedURL, "my-test.oss-cn-hangzhou.aliyuncs.com/")
+	assert.Contains(t, signedURL, "response-content-disposition=")
+}
+
+func TestOSS_PostPolicy(t *testing.T) {
+	oss, err := NewOSS("oss-cn-hangzhou-internal.aliyuncs.com", accessKeyID, accessKeySecret, bucketName, WithNotSetACL(), WithUploadInternal())
+	require.NoError(t, err)
+	pp, err := oss.PostPolicy("", 120, types.WithKeyPrefix("upload/"),
+		types.WithContentLengthRange(0, 1024), types.WithContentTypes("image/png", "image/jpeg"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://my-test.oss-cn-hangzhou.aliyuncs.com", pp.URL)
+	assert.Equal(t, "upload/${filename}", pp.Fields["key"])
+	assert.Equal(t, accessKeyID, pp.Fields["OSSAccessKeyId"])
+
+	h := hmac.New(sha1.New, []byte(accessKeySecret))
+	h.Write([]byte(pp.Fields["policy"]))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(h.Sum(nil)), pp.Fields["Signature"])
+
+	raw, err := base64.StdEncoding.DecodeString(pp.Fields["policy"])
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `["starts-with","$key","upload/"]`)
+	assert.Contains(t, string(raw), `["in","$Content-Type",["image/png","image/jpeg"]]`)
+	assert.Contains(t, string(raw), `{"bucket":"my-test"}`)
+}
+
+func TestOSS_ProcessImage(t *testing.T) {
+	oss, err := NewOSS(endpoint, accessKeyID, accessKeySecret, bucketName, WithNotSetACL())
+	require.NoError(t, err)
+
+	u, err := oss.ProcessImage(context.Background(), "test/test.png",
+		types.WithResize(100, 100, types.ResizeModeFill), types.WithFormat("webp"), types.WithQuality(80))
+	require.NoError(t, err)
+	assert.Equal(t, "https://my-test.oss-cn-hangzhou.aliyuncs.com/test/test.png"+
+		"?x-oss-process=image/resize,m_fill,w_100,h_100,limit_0/format,webp/quality,Q_80", u)
+
+	_, err = oss.ProcessImage(context.Background(), "test/test.png")
+	require.ErrorIs(t, err, types.ErrInvalidImageOptions)
+}
+
+func TestProgressListener(t *testing.T) {
+	var consumed int64
+	tr := progress.New(context.Background(), 100, types.NewObjectOptions("test.txt",
+		types.WithProgress(func(e types.ProgressEvent) { consumed = e.ConsumedBytes })))
+	l := &progressListener{t: tr}
+
+	l.ProgressChanged(&oss.ProgressEvent{EventType: oss.TransferStartedEvent, TotalBytes: 100})
+	l.ProgressChanged(&oss.ProgressEvent{EventType: oss.TransferDataEvent, RwBytes: 40, ConsumedBytes: 40, TotalBytes: 100})
+	l.ProgressChanged(&oss.ProgressEvent{EventType: oss.TransferDataEvent, RwBytes: 60, ConsumedBytes: 100, TotalBytes: 100})
+	l.ProgressChanged(&oss.ProgressEvent{EventType: oss.TransferCompletedEvent, ConsumedBytes: 100, TotalBytes: 100})
+	assert.Equal(t, int64(100), consumed)
+}
@@ -0,0 +1,333 @@
+package oss
+
+import (
+	"context"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+	"github.com/sliveryou/micro-pkg/xhash"
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+const (
+	// decrRefScript 减少引用计数 lua 脚本，引用计数归零时删除计数 key
+	decrRefScript = `local n = redis.call('DECR', KEYS[1]);
+if (n <= 0) then
+    redis.call('DEL', KEYS[1]);
+end
+return n;`
+)
+
+// ErrDedupNotSupported 去重 OSS 客户端不支持该操作错误，签名 URL、表单直传和分片上传等方式无法在上传前计算内容摘要，
+// 移动内容寻址对象或写入内容寻址对象键前缀也会导致引用计数失效
+var ErrDedupNotSupported = errors.New("oss: operation not supported by dedup oss")
+
+// DedupLocker 去重 OSS 客户端使用的分布式锁，用于串行化同一内容寻址对象的上传和删除，
+// 如 lock.RedisLocker 和 lock.EtcdLocker
+type DedupLocker interface {
+	// WithLock 阻塞获取 key 对应的分布式锁后执行 fn，执行完毕后解锁
+	WithLock(ctx context.Context, key string, fn func(ctx context.Context) error, ttl ...int) error
+}
+
+// DedupOption 去重 OSS 客户端可选配置
+type DedupOption func(d *DedupOSS)
+
+// WithDedupDigest 使用内容摘要名称，支持 md5、sha256 和 sm3 等 xhash 摘要，默认为 sha256
+func WithDedupDigest(digest string) DedupOption {
+	return func(d *DedupOSS) {
+		d.digest = strings.ToLower(digest)
+	}
+}
+
+// WithDedupKeyPrefix 使用内容寻址对象键前缀，默认为 dedup/
+func WithDedupKeyPrefix(keyPrefix string) DedupOption {
+	return func(d *DedupOSS) {
+		d.keyPrefix = keyPrefix
+	}
+}
+
+// WithDedupRefKeyPrefix 使用引用计数 key 前缀，默认为 oss:dedup:ref:
+func WithDedupRefKeyPrefix(refKeyPrefix string) DedupOption {
+	return func(d *DedupOSS) {
+		d.refKeyPrefix = refKeyPrefix
+	}
+}
+
+// DedupOSS 内容寻址去重 OSS 客户端，上传时根据内容摘要生成对象键，
+// 内容相同的对象仅存储一份并记录引用计数，删除时仅在最后一个引用被删除后才删除对象，
+// 同一内容寻址对象的上传和删除通过分布式锁串行执行
+type DedupOSS struct {
+	OSS                      // 内部 OSS 客户端
+	store        *xkv.Store  // 键值存取器，用于存储引用计数
+	locker       DedupLocker // 分布式锁，用于串行化同一内容寻址对象的上传和删除
+	digest       string      // 内容摘要名称
+	keyPrefix    string      // 内容寻址对象键前缀
+	refKeyPrefix string      // 引用计数 key 前缀
+}
+
+// NewDedupOSS 新建内容寻址去重 OSS 客户端，locker 用于串行化同一内容寻址对象的上传和删除，如 lock.RedisLocker
+func NewDedupOSS(client OSS, store *xkv.Store, locker DedupLocker, opts ...DedupOption) (*DedupOSS, error) {
+	if client == nil || store == nil || locker == nil {
+		return nil, errors.New("oss: illegal dedup oss config")
+	}
+
+	d := &DedupOSS{
+		OSS:          client,
+		store:        store,
+		locker:       locker,
+		digest:       xhash.SHA256,
+		keyPrefix:    "dedup/",
+		refKeyPrefix: "oss:dedup:ref:",
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	switch d.digest {
+	case xhash.MD5, xhash.SM3, xhash.SHA1, xhash.SHA224, xhash.SHA256, xhash.SHA384, xhash.SHA512:
+	default:
+		return nil, errors.Errorf("oss: illegal dedup digest: %s", d.digest)
+	}
+
+	return d, nil
+}
+
+// MustNewDedupOSS 新建内容寻址去重 OSS 客户端
+func MustNewDedupOSS(client OSS, store *xkv.Store, locker DedupLocker, opts ...DedupOption) *DedupOSS {
+	d, err := NewDedupOSS(client, store, locker, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return d
+}
+
+// PutObject 上传对象至 OSS，key 仅用于推断扩展名和内容类型，返回内容寻址对象的访问 URL
+func (d *DedupOSS) PutObject(key string, reader io.Reader) (string, error) {
+	return d.PutObjectCtx(context.Background(), key, reader)
+}
+
+// PutObjectCtx 上传对象至 OSS，key 仅用于推断扩展名和内容类型，返回内容寻址对象的访问 URL，
+// 数据在流式计算摘要的同时暂存至本地临时文件，对象已存在时跳过上传
+func (d *DedupOSS) PutObjectCtx(ctx context.Context, key string, reader io.Reader, opts ...types.ObjectOption) (string, error) {
+	tmp, err := os.CreateTemp("", "oss-dedup-*")
+	if err != nil {
+		return "", errors.WithMessage(err, "oss: dedup create temp file err")
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	h := d.newHash()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), reader); err != nil {
+		return "", errors.WithMessage(err, "oss: dedup copy reader err")
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", errors.WithMessage(err, "oss: dedup seek temp file err")
+	}
+
+	contentKey := d.ContentKey(fmt.Sprintf("%x", h.Sum(nil)), key)
+
+	return d.put(ctx, contentKey, func(ctx context.Context) (string, error) {
+		return d.OSS.PutObjectCtx(ctx, contentKey, tmp, opts...)
+	})
+}
+
+// UploadFile 上传文件至 OSS，key 仅用于推断扩展名，返回内容寻址对象的访问 URL
+func (d *DedupOSS) UploadFile(key, filePath string, partSize int64, routines int) (string, error) {
+	return d.UploadFileCtx(context.Background(), key, filePath, partSize, routines)
+}
+
+// UploadFileCtx 上传文件至 OSS，key 仅用于推断扩展名，返回内容寻址对象的访问 URL，对象已存在时跳过上传
+func (d *DedupOSS) UploadFileCtx(ctx context.Context, key, filePath string, partSize int64, routines int, opts ...types.ObjectOption) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", errors.WithMessagef(err, "oss: dedup open file path: %s err", filePath)
+	}
+	sum, err := xhash.HashReader(d.newHash(), f)
+	f.Close()
+	if err != nil {
+		return "", errors.WithMessage(err, "oss: dedup hash file err")
+	}
+
+	contentKey := d.ContentKey(sum, key)
+
+	return d.put(ctx, contentKey, func(ctx context.Context) (string, error) {
+		return d.OSS.UploadFileCtx(ctx, contentKey, filePath, partSize, routines, opts...)
+	})
+}
+
+// DeleteObjects 批量删除 OSS 上的对象，内容寻址对象仅在引用计数归零时删除
+func (d *DedupOSS) DeleteObjects(keys ...string) error {
+	return d.DeleteObjectsCtx(context.Background(), keys...)
+}
+
+// DeleteObjectsCtx 批量删除 OSS 上的对象，keys 可为内容寻址对象键或其访问 URL，内容寻址对象仅在引用计数归零时删除，
+// 没有引用计数记录的内容寻址对象不由去重 OSS 客户端管理，不会被删除
+func (d *DedupOSS) DeleteObjectsCtx(ctx context.Context, keys ...string) error {
+	deletes := make([]string, 0, len(keys))
+	for _, key := range keys {
+		key = d.objectKey(key)
+		if !d.isContentKey(key) {
+			deletes = append(deletes, key)
+			continue
+		}
+
+		if err := d.delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	if len(deletes) == 0 {
+		return nil
+	}
+
+	return d.OSS.DeleteObjectsCtx(ctx, deletes...)
+}
+
+// CopyObject 在 OSS 服务端复制对象，目标对象键为内容寻址对象键时返回 ErrDedupNotSupported
+func (d *DedupOSS) CopyObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	if d.isContentKey(dstKey) {
+		return "", ErrDedupNotSupported
+	}
+
+	return d.OSS.CopyObject(ctx, srcKey, dstKey)
+}
+
+// MoveObject 在 OSS 服务端移动对象，源或目标对象键为内容寻址对象键时返回 ErrDedupNotSupported
+func (d *DedupOSS) MoveObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	if d.isContentKey(srcKey) || d.isContentKey(dstKey) {
+		return "", ErrDedupNotSupported
+	}
+
+	return d.OSS.MoveObject(ctx, srcKey, dstKey)
+}
+
+// AuthorizedUpload 去重模式下不支持授权上传，返回 ErrDedupNotSupported
+func (d *DedupOSS) AuthorizedUpload(key string, expires int) (string, error) {
+	return "", ErrDedupNotSupported
+}
+
+// PostPolicy 去重模式下不支持表单直传，返回 ErrDedupNotSupported
+func (d *DedupOSS) PostPolicy(key string, expires int, opts ...types.PostPolicyOption) (*types.PostPolicy, error) {
+	return nil, ErrDedupNotSupported
+}
+
+// InitiateMultipartUpload 去重模式下不支持分片上传会话，返回 ErrDedupNotSupported
+func (d *DedupOSS) InitiateMultipartUpload(ctx context.Context, key string, opts ...types.ObjectOption) (string, error) {
+	return "", ErrDedupNotSupported
+}
+
+// PresignedUploadPartURL 去重模式下不支持分片上传会话，返回 ErrDedupNotSupported
+func (d *DedupOSS) PresignedUploadPartURL(key, uploadID string, partNumber, expires int) (string, error) {
+	return "", ErrDedupNotSupported
+}
+
+// CompleteMultipartUpload 去重模式下不支持分片上传会话，返回 ErrDedupNotSupported
+func (d *DedupOSS) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []types.Part) (string, error) {
+	return "", ErrDedupNotSupported
+}
+
+// ContentKey 获取内容寻址对象键，格式为 前缀+摘要名称/摘要值+扩展名，sum：十六进制摘要值，key：用于推断扩展名的对象键
+func (d *DedupOSS) ContentKey(sum, key string) string {
+	return fmt.Sprintf("%s%s/%s%s", d.keyPrefix, d.digest, sum, strings.ToLower(path.Ext(key)))
+}
+
+// RefCount 获取内容寻址对象的引用计数，key 可为内容寻址对象键或其访问 URL
+func (d *DedupOSS) RefCount(ctx context.Context, key string) (int64, error) {
+	n, err := d.store.GetInt64Ctx(ctx, d.refKeyPrefix+d.objectKey(key))
+	if err != nil {
+		return 0, errors.WithMessage(err, "oss: dedup get ref count err")
+	}
+
+	return n, nil
+}
+
+// put 持有内容寻址对象的锁时增加引用计数并在需要时上传对象，首个引用或对象不存在时执行上传，上传失败时回滚引用计数
+func (d *DedupOSS) put(ctx context.Context, contentKey string, upload func(ctx context.Context) (string, error)) (string, error) {
+	var u string
+	err := d.locker.WithLock(ctx, contentKey, func(ctx context.Context) error {
+		n, err := d.store.IncrCtx(ctx, d.refKeyPrefix+contentKey)
+		if err != nil {
+			return errors.WithMessage(err, "oss: dedup incr ref count err")
+		}
+
+		if n > 1 {
+			exists, err := d.OSS.Exists(ctx, contentKey)
+			if err == nil && exists {
+				u = d.OSS.GetURL(contentKey)
+				return nil
+			}
+		}
+
+		u, err = upload(ctx)
+		if err != nil {
+			_, _ = d.decrRef(context.Background(), contentKey)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return u, nil
+}
+
+// delete 持有内容寻址对象的锁时减少引用计数，引用计数归零时删除对象，没有引用计数记录时不删除对象
+func (d *DedupOSS) delete(ctx context.Context, contentKey string) error {
+	return d.locker.WithLock(ctx, contentKey, func(ctx context.Context) error {
+		n, err := d.decrRef(ctx, contentKey)
+		if err != nil {
+			return err
+		}
+		// n < 0 时没有引用计数记录，对象不由去重 OSS 客户端管理
+		if n != 0 {
+			return nil
+		}
+
+		return d.OSS.DeleteObjectsCtx(ctx, contentKey)
+	})
+}
+
+// decrRef 减少引用计数，返回减少后的引用计数，没有引用计数记录时返回 -1
+func (d *DedupOSS) decrRef(ctx context.Context, contentKey string) (int64, error) {
+	resp, err := d.store.EvalCtx(ctx, decrRefScript, d.refKeyPrefix+contentKey)
+	if err != nil {
+		return 0, errors.WithMessage(err, "oss: dedup decr ref count err")
+	}
+
+	n, ok := resp.(int64)
+	if !ok {
+		return 0, errors.Errorf("oss: dedup decr ref count unexpected type: %T", resp)
+	}
+
+	return n, nil
+}
+
+// newHash 新建内容摘要计算对象
+func (d *DedupOSS) newHash() hash.Hash {
+	return xhash.New(d.digest)
+}
+
+// isContentKey 判断对象键或其访问 URL 是否为内容寻址对象键
+func (d *DedupOSS) isContentKey(key string) bool {
+	return strings.HasPrefix(d.objectKey(key), d.keyPrefix)
+}
+
+// objectKey 获取对象键，去除访问 URL 中的访问地址前缀
+func (d *DedupOSS) objectKey(key string) string {
+	if base := d.OSS.GetURL(""); base != "" {
+		return strings.TrimPrefix(key, base)
+	}
+
+	return key
+}
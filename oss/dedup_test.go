@@ -0,0 +1,247 @@
+package oss
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+
+	"github.com/sliveryou/micro-pkg/lock"
+	"github.com/sliveryou/micro-pkg/oss/local"
+	"github.com/sliveryou/micro-pkg/xhash"
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+func TestDedupOSS(t *testing.T) {
+	d, cleanup := newTestDedupOSS(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	u1, err := d.PutObject("a/test.txt", strings.NewReader("hello world"))
+	require.NoError(t, err)
+	u2, err := d.PutObjectCtx(ctx, "b/other.TXT", strings.NewReader("hello world"))
+	require.NoError(t, err)
+	assert.Equal(t, u1, u2)
+
+	key := d.ContentKey("b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", "test.txt")
+	assert.Equal(t, "http://endpoint/"+key, u1)
+	n, err := d.RefCount(ctx, u1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	// 第一个引用被删除，对象仍存在
+	require.NoError(t, d.DeleteObjects(u1))
+	ok, err := d.Exists(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	n, err = d.RefCount(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// 最后一个引用被删除，对象被删除
+	require.NoError(t, d.DeleteObjectsCtx(ctx, key))
+	ok, err = d.Exists(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	n, err = d.RefCount(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	// 对象被外部删除后重新上传
+	u3, err := d.PutObject("c/test.txt", strings.NewReader("hello world"))
+	require.NoError(t, err)
+	require.NoError(t, d.OSS.DeleteObjects(key))
+	u4, err := d.PutObject("d/test.txt", strings.NewReader("hello world"))
+	require.NoError(t, err)
+	assert.Equal(t, u3, u4)
+	ok, err = d.Exists(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 非内容寻址对象直接删除
+	_, err = d.OSS.PutObject("plain/test.txt", strings.NewReader("test"))
+	require.NoError(t, err)
+	require.NoError(t, d.DeleteObjects("plain/test.txt"))
+	ok, err = d.Exists(ctx, "plain/test.txt")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestDedupOSS_Unmanaged(t *testing.T) {
+	d, cleanup := newTestDedupOSS(t)
+	defer cleanup()
+
+	// 没有引用计数记录的内容寻址对象不会被删除
+	ctx := context.Background()
+	key := d.ContentKey("unmanaged", "test.txt")
+	_, err := d.OSS.PutObject(key, strings.NewReader("test"))
+	require.NoError(t, err)
+	require.NoError(t, d.DeleteObjectsCtx(ctx, key))
+	ok, err := d.Exists(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	n, err := d.RefCount(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestDedupOSS_NotSupported(t *testing.T) {
+	d, cleanup := newTestDedupOSS(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	u, err := d.PutObject("test.txt", strings.NewReader("hello world"))
+	require.NoError(t, err)
+	key := d.objectKey(u)
+
+	// 移动内容寻址对象或写入内容寻址对象键前缀会导致引用计数失效
+	_, err = d.MoveObject(ctx, key, "plain/test.txt")
+	require.ErrorIs(t, err, ErrDedupNotSupported)
+	_, err = d.MoveObject(ctx, "plain/test.txt", key)
+	require.ErrorIs(t, err, ErrDedupNotSupported)
+	_, err = d.CopyObject(ctx, "plain/test.txt", d.ContentKey("other", "test.txt"))
+	require.ErrorIs(t, err, ErrDedupNotSupported)
+	ok, err := d.Exists(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 从内容寻址对象复制出的普通对象与引用计数无关
+	_, err = d.CopyObject(ctx, key, "plain/test.txt")
+	require.NoError(t, err)
+	n, err := d.RefCount(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	_, err = d.MoveObject(ctx, "plain/test.txt", "plain/moved.txt")
+	require.NoError(t, err)
+
+	_, err = d.AuthorizedUpload("test.txt", 60)
+	require.ErrorIs(t, err, ErrDedupNotSupported)
+	_, err = d.PostPolicy("test.txt", 60)
+	require.ErrorIs(t, err, ErrDedupNotSupported)
+	_, err = d.InitiateMultipartUpload(ctx, "test.txt")
+	require.ErrorIs(t, err, ErrDedupNotSupported)
+	_, err = d.PresignedUploadPartURL("test.txt", "upload-id", 1, 60)
+	require.ErrorIs(t, err, ErrDedupNotSupported)
+	_, err = d.CompleteMultipartUpload(ctx, "test.txt", "upload-id", nil)
+	require.ErrorIs(t, err, ErrDedupNotSupported)
+}
+
+func TestDedupOSS_UploadFile(t *testing.T) {
+	d, cleanup := newTestDedupOSS(t, WithDedupDigest(xhash.SM3), WithDedupKeyPrefix("cas/"))
+	defer cleanup()
+
+	filePath := filepath.Join(t.TempDir(), "test.png")
+	require.NoError(t, os.WriteFile(filePath, []byte("test-png"), 0o644))
+
+	ctx := context.Background()
+	u1, err := d.UploadFile("test.png", filePath, 0, 1)
+	require.NoError(t, err)
+	u2, err := d.PutObject("other.png", strings.NewReader("test-png"))
+	require.NoError(t, err)
+	assert.Equal(t, u1, u2)
+	assert.True(t, strings.HasPrefix(u1, "http://endpoint/cas/sm3/"))
+	assert.True(t, strings.HasSuffix(u1, ".png"))
+
+	info, err := d.StatObject(ctx, strings.TrimPrefix(u1, "http://endpoint/"))
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", info.ContentType)
+
+	n, err := d.RefCount(ctx, u1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	_, err = d.UploadFile("none.png", filepath.Join(t.TempDir(), "none.png"), 0, 1)
+	assert.Error(t, err)
+}
+
+func TestNewDedupOSS(t *testing.T) {
+	_, err := NewDedupOSS(nil, nil, nil)
+	assert.Error(t, err)
+	assert.Panics(t, func() {
+		MustNewDedupOSS(nil, nil, nil)
+	})
+
+	lss, err := local.NewLSS("endpoint", "testdata")
+	require.NoError(t, err)
+	defer os.RemoveAll("testdata")
+	_, err = NewDedupOSS(lss, &xkv.Store{}, nil)
+	assert.Error(t, err)
+	_, err = NewDedupOSS(lss, &xkv.Store{}, &lock.RedisLocker{}, WithDedupDigest("crc32"))
+	assert.Error(t, err)
+}
+
+func TestDedupOSS_Concurrent(t *testing.T) {
+	d, cleanup := newTestDedupOSS(t)
+	defer cleanup()
+
+	// 删除对象前等待，扩大删除与上传并发执行的时间窗口
+	deleting := make(chan struct{})
+	d.OSS = &slowDeleteOSS{OSS: d.OSS, deleting: deleting}
+
+	ctx := context.Background()
+	u, err := d.PutObject("test.txt", strings.NewReader("hello world"))
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- d.DeleteObjects(u)
+	}()
+
+	// 最后一个引用删除的同时上传相同内容，上传完成后对象必须存在
+	<-deleting
+	_, err = d.PutObject("other.txt", strings.NewReader("hello world"))
+	require.NoError(t, err)
+	require.NoError(t, <-done)
+
+	n, err := d.RefCount(ctx, u)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	ok, err := d.Exists(ctx, d.objectKey(u))
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+type slowDeleteOSS struct {
+	OSS
+	deleting chan struct{}
+	once     sync.Once
+}
+
+func (o *slowDeleteOSS) DeleteObjectsCtx(ctx context.Context, keys ...string) error {
+	o.once.Do(func() {
+		close(o.deleting)
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	return o.OSS.DeleteObjectsCtx(ctx, keys...)
+}
+
+func newTestDedupOSS(t *testing.T, opts ...DedupOption) (*DedupOSS, func()) {
+	t.Helper()
+
+	s := miniredis.RunT(t)
+	store := xkv.NewStore([]cache.NodeConf{
+		{
+			RedisConf: redis.RedisConf{Host: s.Addr(), Type: "node"},
+			Weight:    100,
+		},
+	})
+
+	bucketName := "testdata"
+	lss, err := local.NewLSS("endpoint", bucketName)
+	require.NoError(t, err)
+
+	d, err := NewDedupOSS(lss, store, lock.MustNewRedisLocker(store, "oss:dedup:lock"), opts...)
+	require.NoError(t, err)
+
+	return d, func() { os.RemoveAll(bucketName) }
+}
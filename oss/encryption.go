@@ -0,0 +1,275 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/sliveryou/micro-pkg/oss/internal/envelope"
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+const (
+	// EncryptionAESGCM AES-GCM 加密算法，密钥长度为 16、24 或 32 字节
+	EncryptionAESGCM = envelope.AlgorithmAESGCM
+	// EncryptionSM4GCM SM4-GCM 加密算法，密钥长度为 16 字节
+	EncryptionSM4GCM = envelope.AlgorithmSM4GCM
+
+	// MetaEncryptionKeyID 用户自定义元数据中的加密密钥 ID 键
+	MetaEncryptionKeyID = "encryption-key-id"
+	// MetaEncryptionAlgorithm 用户自定义元数据中的加密算法键
+	MetaEncryptionAlgorithm = "encryption-algorithm"
+)
+
+var (
+	// ErrEncryptionKeyNotFound 加密密钥不存在错误
+	ErrEncryptionKeyNotFound = errors.New("oss: encryption key not found")
+	// ErrEncryptionNotSupported 加密 OSS 客户端不支持该操作错误，签名 URL 和表单直传等方式无法在上传前加密数据
+	ErrEncryptionNotSupported = errors.New("oss: operation not supported by encrypted oss")
+)
+
+// EncryptionOption 加密 OSS 客户端可选配置
+type EncryptionOption func(e *EncryptedOSS)
+
+// WithEncryptionAlgorithm 使用加密算法，支持 EncryptionAESGCM 和 EncryptionSM4GCM，默认为 EncryptionAESGCM
+func WithEncryptionAlgorithm(algorithm string) EncryptionOption {
+	return func(e *EncryptedOSS) {
+		e.algorithm = algorithm
+	}
+}
+
+// WithEncryptionChunkSize 使用加密分块大小（字节），默认为 64KB，最大为 16MB
+func WithEncryptionChunkSize(chunkSize int) EncryptionOption {
+	return func(e *EncryptedOSS) {
+		e.chunkSize = chunkSize
+	}
+}
+
+// EncryptedOSS 客户端加密 OSS 客户端，上传时在本地使用信封格式分块加密数据，获取对象时透明解密，
+// 每个对象使用随机数据密钥加密，数据密钥由当前密钥 ID 对应的主密钥加密后保存在对象头部，
+// 密钥 ID 同时记录在对象的用户自定义元数据中，轮换密钥后使用旧密钥加密的对象仍可读取
+type EncryptedOSS struct {
+	OSS                         // 内部 OSS 客户端
+	keyID     string            // 当前加密使用的密钥 ID
+	keys      map[string][]byte // 密钥 ID 与主密钥映射
+	algorithm string            // 加密算法
+	chunkSize int               // 加密分块大小
+}
+
+// NewEncryptedOSS 新建客户端加密 OSS 客户端，keyID：当前加密使用的密钥 ID，keys：全部密钥 ID 与主密钥映射，需包含历史密钥以读取旧对象
+func NewEncryptedOSS(client OSS, keyID string, keys map[string][]byte, opts ...EncryptionOption) (*EncryptedOSS, error) {
+	if client == nil || keyID == "" {
+		return nil, errors.New("oss: illegal encrypted oss config")
+	}
+
+	e := &EncryptedOSS{
+		OSS:       client,
+		keyID:     keyID,
+		keys:      make(map[string][]byte, len(keys)),
+		algorithm: EncryptionAESGCM,
+		chunkSize: envelope.DefaultChunkSize,
+	}
+	for id, key := range keys {
+		e.keys[id] = append([]byte(nil), key...)
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	key, ok := e.keys[keyID]
+	if !ok {
+		return nil, errors.WithMessagef(ErrEncryptionKeyNotFound, "key id: %s", keyID)
+	}
+	if err := envelope.CheckKey(e.algorithm, key); err != nil {
+		return nil, errors.WithMessage(err, "oss: illegal encrypted oss config")
+	}
+	if e.chunkSize <= 0 || e.chunkSize > envelope.MaxChunkSize {
+		return nil, errors.New("oss: illegal encrypted oss chunk size")
+	}
+
+	return e, nil
+}
+
+// MustNewEncryptedOSS 新建客户端加密 OSS 客户端
+func MustNewEncryptedOSS(client OSS, keyID string, keys map[string][]byte, opts ...EncryptionOption) *EncryptedOSS {
+	e, err := NewEncryptedOSS(client, keyID, keys, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return e
+}
+
+// KeyID 获取当前加密使用的密钥 ID
+func (e *EncryptedOSS) KeyID() string {
+	return e.keyID
+}
+
+// GetObject 获取对象在 OSS 的存储数据并解密
+func (e *EncryptedOSS) GetObject(key string) (io.ReadCloser, error) {
+	return e.GetObjectCtx(context.Background(), key)
+}
+
+// GetObjectCtx 获取对象在 OSS 的存储数据并解密，根据对象头部中的密钥 ID 选择主密钥，数据被篡改时读取将返回错误
+func (e *EncryptedOSS) GetObjectCtx(ctx context.Context, key string) (io.ReadCloser, error) {
+	rc, err := e.OSS.GetObjectCtx(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	dr, err := envelope.NewDecryptReader(rc, e.key)
+	if err != nil {
+		rc.Close()
+		return nil, errors.WithMessagef(err, "oss: decrypt object %s err", key)
+	}
+
+	return &decryptReadCloser{Reader: dr, Closer: rc}, nil
+}
+
+// PutObject 加密并上传对象至 OSS
+func (e *EncryptedOSS) PutObject(key string, reader io.Reader) (string, error) {
+	return e.PutObjectCtx(context.Background(), key, reader)
+}
+
+// PutObjectCtx 加密并上传对象至 OSS，opts：对象可选配置，密钥 ID 和加密算法会写入用户自定义元数据
+func (e *EncryptedOSS) PutObjectCtx(ctx context.Context, key string, reader io.Reader, opts ...types.ObjectOption) (string, error) {
+	er, err := e.encrypt(reader)
+	if err != nil {
+		return "", err
+	}
+
+	return e.OSS.PutObjectCtx(ctx, key, er, e.objectOptions(opts)...)
+}
+
+// UploadFile 加密并上传文件至 OSS，filePath：文件路径，partSize：分块大小（字节），routines：并发数
+func (e *EncryptedOSS) UploadFile(key, filePath string, partSize int64, routines int) (string, error) {
+	return e.UploadFileCtx(context.Background(), key, filePath, partSize, routines)
+}
+
+// UploadFileCtx 加密并上传文件至 OSS，文件加密至本地临时文件后再分块上传，
+// filePath：文件路径，partSize：分块大小（字节），routines：并发数，opts：对象可选配置
+func (e *EncryptedOSS) UploadFileCtx(ctx context.Context, key, filePath string, partSize int64, routines int, opts ...types.ObjectOption) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", errors.WithMessagef(err, "oss: open file path: %s err", filePath)
+	}
+	defer f.Close()
+
+	er, err := e.encrypt(f)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp("", "oss-encrypt-*")
+	if err != nil {
+		return "", errors.WithMessage(err, "oss: create temp file err")
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+	if _, err := io.Copy(tmp, er); err != nil {
+		return "", errors.WithMessage(err, "oss: encrypt file err")
+	}
+	if err := tmp.Close(); err != nil {
+		return "", errors.WithMessage(err, "oss: close temp file err")
+	}
+
+	return e.OSS.UploadFileCtx(ctx, key, tmp.Name(), partSize, routines, e.objectOptions(opts)...)
+}
+
+// Reencrypt 使用当前密钥重新加密对象，用于密钥轮换后迁移旧对象，对象已使用当前密钥加密时跳过，返回是否重新加密
+func (e *EncryptedOSS) Reencrypt(ctx context.Context, key string) (bool, error) {
+	info, err := e.OSS.StatObject(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if info.Metadata[MetaEncryptionKeyID] == e.keyID && info.Metadata[MetaEncryptionAlgorithm] == e.algorithm {
+		return false, nil
+	}
+
+	rc, err := e.GetObjectCtx(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	defer rc.Close()
+
+	if _, err := e.PutObjectCtx(ctx, key, rc,
+		types.WithContentType(info.ContentType),
+		types.WithMetadata(info.Metadata)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AuthorizedUpload 客户端加密模式下不支持授权上传，返回 ErrEncryptionNotSupported
+func (e *EncryptedOSS) AuthorizedUpload(key string, expires int) (string, error) {
+	return "", ErrEncryptionNotSupported
+}
+
+// PresignedGetURL 客户端加密模式下不支持预签名下载，返回 ErrEncryptionNotSupported
+func (e *EncryptedOSS) PresignedGetURL(key string, expires int, opts ...types.PresignOption) (string, error) {
+	return "", ErrEncryptionNotSupported
+}
+
+// PostPolicy 客户端加密模式下不支持表单直传，返回 ErrEncryptionNotSupported
+func (e *EncryptedOSS) PostPolicy(key string, expires int, opts ...types.PostPolicyOption) (*types.PostPolicy, error) {
+	return nil, ErrEncryptionNotSupported
+}
+
+// InitiateMultipartUpload 客户端加密模式下不支持分片上传会话，返回 ErrEncryptionNotSupported
+func (e *EncryptedOSS) InitiateMultipartUpload(ctx context.Context, key string, opts ...types.ObjectOption) (string, error) {
+	return "", ErrEncryptionNotSupported
+}
+
+// PresignedUploadPartURL 客户端加密模式下不支持分片上传会话，返回 ErrEncryptionNotSupported
+func (e *EncryptedOSS) PresignedUploadPartURL(key, uploadID string, partNumber, expires int) (string, error) {
+	return "", ErrEncryptionNotSupported
+}
+
+// ProcessImage 客户端加密模式下云服务商和纯 Go 渲染均无法处理密文图片，返回 ErrEncryptionNotSupported
+func (e *EncryptedOSS) ProcessImage(ctx context.Context, key string, opts ...types.ImageOption) (string, error) {
+	return "", ErrEncryptionNotSupported
+}
+
+// encrypt 新建使用当前密钥的加密读取器
+func (e *EncryptedOSS) encrypt(reader io.Reader) (io.Reader, error) {
+	er, err := envelope.NewEncryptReader(reader, e.algorithm, e.keyID, e.keys[e.keyID], e.chunkSize)
+	if err != nil {
+		return nil, errors.WithMessage(err, "oss: new encrypt reader err")
+	}
+
+	return er, nil
+}
+
+// key 根据密钥 ID 获取主密钥
+func (e *EncryptedOSS) key(keyID string) ([]byte, error) {
+	key, ok := e.keys[keyID]
+	if !ok {
+		return nil, errors.WithMessagef(ErrEncryptionKeyNotFound, "key id: %s", keyID)
+	}
+
+	return key, nil
+}
+
+// objectOptions 获取追加加密元数据后的对象可选配置，复制 opts 以免修改调用方切片的底层数组
+func (e *EncryptedOSS) objectOptions(opts []types.ObjectOption) []types.ObjectOption {
+	return append(append([]types.ObjectOption(nil), opts...), e.metadata())
+}
+
+// metadata 获取记录密钥 ID 和加密算法的用户自定义元数据可选配置
+func (e *EncryptedOSS) metadata() types.ObjectOption {
+	return types.WithMetadata(map[string]string{
+		MetaEncryptionKeyID:     e.keyID,
+		MetaEncryptionAlgorithm: e.algorithm,
+	})
+}
+
+// decryptReadCloser 解密读取关闭器
+type decryptReadCloser struct {
+	io.Reader
+	io.Closer
+}
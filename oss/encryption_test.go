@@ -0,0 +1,143 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+func TestEncryptedOSS(t *testing.T) {
+	defer os.RemoveAll("testdata")
+	inner := MustNewOSS(Config{Cloud: "local", EndPoint: "endpoint", BucketName: "testdata"})
+
+	keys := map[string][]byte{
+		"v1": []byte("0123456789abcdef0123456789abcdef"),
+		"v2": []byte("fedcba9876543210"),
+	}
+	e1, err := NewEncryptedOSS(inner, "v1", keys)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", e1.KeyID())
+
+	ctx := context.Background()
+	_, err = e1.PutObjectCtx(ctx, "test/test.txt", strings.NewReader("hello world"),
+		types.WithMetadata(map[string]string{"foo": "bar"}))
+	require.NoError(t, err)
+
+	// 内部 OSS 中存储的是密文，元数据中记录密钥 ID
+	assert.Equal(t, "hello world", readObject(t, e1, "test/test.txt"))
+	assert.NotContains(t, readObject(t, inner, "test/test.txt"), "hello world")
+	info, err := inner.StatObject(ctx, "test/test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", info.Metadata[MetaEncryptionKeyID])
+	assert.Equal(t, EncryptionAESGCM, info.Metadata[MetaEncryptionAlgorithm])
+	assert.Equal(t, "bar", info.Metadata["foo"])
+	assert.Equal(t, "text/plain", info.ContentType)
+
+	// 轮换至 SM4 密钥后旧对象仍可读取
+	e2, err := NewEncryptedOSS(inner, "v2", keys, WithEncryptionAlgorithm(EncryptionSM4GCM), WithEncryptionChunkSize(4))
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", readObject(t, e2, "test/test.txt"))
+
+	filePath := filepath.Join(t.TempDir(), "test.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("hello sm4"), 0o644))
+	_, err = e2.UploadFile("test/file.txt", filePath, 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "hello sm4", readObject(t, e2, "test/file.txt"))
+	assert.Equal(t, "hello sm4", readObject(t, e1, "test/file.txt"))
+
+	// 使用当前密钥重新加密旧对象
+	ok, err := e2.Reencrypt(ctx, "test/test.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = e2.Reencrypt(ctx, "test/test.txt")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	info, err = inner.StatObject(ctx, "test/test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", info.Metadata[MetaEncryptionKeyID])
+	assert.Equal(t, EncryptionSM4GCM, info.Metadata[MetaEncryptionAlgorithm])
+	assert.Equal(t, "bar", info.Metadata["foo"])
+
+	// 缺少密钥时无法读取
+	e3, err := NewEncryptedOSS(inner, "v1", map[string][]byte{"v1": keys["v1"]})
+	require.NoError(t, err)
+	_, err = e3.GetObject("test/test.txt")
+	assert.ErrorIs(t, err, ErrEncryptionKeyNotFound)
+
+	// 未加密的对象和被篡改的对象
+	_, err = inner.PutObject("test/plain.txt", strings.NewReader("plain text"))
+	require.NoError(t, err)
+	_, err = e1.GetObject("test/plain.txt")
+	assert.Error(t, err)
+	_, err = e1.GetObject("test/none.txt")
+	assert.Error(t, err)
+
+	// 不支持无法在上传前加密的操作
+	_, err = e1.AuthorizedUpload("test/test.txt", 120)
+	assert.ErrorIs(t, err, ErrEncryptionNotSupported)
+	_, err = e1.PresignedGetURL("test/test.txt", 120)
+	assert.ErrorIs(t, err, ErrEncryptionNotSupported)
+	_, err = e1.PostPolicy("test/test.txt", 120)
+	assert.ErrorIs(t, err, ErrEncryptionNotSupported)
+	_, err = e1.InitiateMultipartUpload(ctx, "test/test.txt")
+	assert.ErrorIs(t, err, ErrEncryptionNotSupported)
+	_, err = e1.PresignedUploadPartURL("test/test.txt", "upload", 1, 120)
+	assert.ErrorIs(t, err, ErrEncryptionNotSupported)
+}
+
+func TestEncryptedOSS_ObjectOptions(t *testing.T) {
+	e, err := NewEncryptedOSS(MustNewOSS(Config{Cloud: "mock"}), "v1",
+		map[string][]byte{"v1": []byte("0123456789abcdef")})
+	require.NoError(t, err)
+
+	// 调用方切片有剩余容量时不修改其底层数组
+	spare := types.WithContentType("text/plain")
+	opts := make([]types.ObjectOption, 1, 2)
+	opts[0] = types.WithMetadata(map[string]string{"foo": "bar"})
+	_ = append(opts, spare)
+
+	got := e.objectOptions(opts)
+	require.Len(t, got, 2)
+	o := types.NewObjectOptions("test.txt", opts[:2]...)
+	assert.Equal(t, "text/plain", o.ContentType)
+	assert.Equal(t, map[string]string{"foo": "bar"}, o.Metadata)
+}
+
+func TestNewEncryptedOSS(t *testing.T) {
+	inner := MustNewOSS(Config{Cloud: "mock"})
+	keys := map[string][]byte{"v1": []byte("0123456789abcdef0123456789abcdef")}
+
+	_, err := NewEncryptedOSS(nil, "v1", keys)
+	assert.Error(t, err)
+	_, err = NewEncryptedOSS(inner, "v2", keys)
+	assert.ErrorIs(t, err, ErrEncryptionKeyNotFound)
+	_, err = NewEncryptedOSS(inner, "v1", keys, WithEncryptionAlgorithm(EncryptionSM4GCM))
+	assert.Error(t, err)
+	_, err = NewEncryptedOSS(inner, "v1", keys, WithEncryptionAlgorithm("des"))
+	assert.Error(t, err)
+	_, err = NewEncryptedOSS(inner, "v1", keys, WithEncryptionChunkSize(-1))
+	assert.Error(t, err)
+	assert.Panics(t, func() {
+		MustNewEncryptedOSS(inner, "", keys)
+	})
+}
+
+func readObject(t *testing.T, o OSS, key string) string {
+	t.Helper()
+
+	rc, err := o.GetObject(key)
+	require.NoError(t, err)
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+
+	return string(data)
+}
@@ -1,13 +1,23 @@
 package huawei
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
 	"io"
+	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/huaweicloud/huaweicloud-sdk-go-obs/obs"
 	"github.com/pkg/errors"
 
+	"github.com/sliveryou/micro-pkg/oss/internal/imaging"
+	"github.com/sliveryou/micro-pkg/oss/internal/policy"
+	"github.com/sliveryou/micro-pkg/oss/internal/progress"
+	"github.com/sliveryou/micro-pkg/oss/internal/util"
+	"github.com/sliveryou/micro-pkg/oss/internal/xio"
+	"github.com/sliveryou/micro-pkg/oss/types"
 	"github.com/sliveryou/micro-pkg/xhttp"
 )
 
@@ -34,6 +44,8 @@ type OBS struct {
 	notSetACL  bool
 	endpoint   string
 	bucketName string
+	ak         string
+	sk         string
 	client     *obs.ObsClient
 }
 
@@ -44,7 +56,7 @@ func NewOBS(endpoint, accessKeyID, accessKeySecret, bucketName string, opts ...O
 		return nil, errors.WithMessage(err, "huawei: new obs err")
 	}
 
-	o := &OBS{endpoint: endpoint, bucketName: bucketName, client: client}
+	o := &OBS{endpoint: endpoint, bucketName: bucketName, ak: accessKeyID, sk: accessKeySecret, client: client}
 	for _, opt := range opts {
 		opt(o)
 	}
@@ -75,6 +87,16 @@ func (o *OBS) GetURL(key string) string {
 
 // GetObject 获取对象在华为云 OBS 的存储数据
 func (o *OBS) GetObject(key string) (io.ReadCloser, error) {
+	return o.GetObjectCtx(context.Background(), key)
+}
+
+// GetObjectCtx 获取对象在华为云 OBS 的存储数据
+func (o *OBS) GetObjectCtx(ctx context.Context, key string) (io.ReadCloser, error) {
+	// 华为云 OBS SDK 仅支持客户端级别的上下文，此处在请求前检查上下文并使用感知上下文的读取器
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithMessage(err, "huawei: obs get object err")
+	}
+
 	input := &obs.GetObjectInput{}
 	input.Bucket = o.bucketName
 	input.Key = key
@@ -84,19 +106,35 @@ func (o *OBS) GetObject(key string) (io.ReadCloser, error) {
 		return nil, errors.WithMessage(err, "huawei: obs get object err")
 	}
 
-	return obj.Body, nil
+	return xio.NewCtxReadCloser(ctx, obj.Body), nil
 }
 
 // PutObject 上传对象至华为云 OBS
 func (o *OBS) PutObject(key string, reader io.Reader) (string, error) {
+	return o.PutObjectCtx(context.Background(), key, reader)
+}
+
+// PutObjectCtx 上传对象至华为云 OBS，opts：对象可选配置
+func (o *OBS) PutObjectCtx(ctx context.Context, key string, reader io.Reader, opts ...types.ObjectOption) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", errors.WithMessage(err, "huawei: obs put object err")
+	}
+
+	oo := types.NewObjectOptions(key, opts...)
 	input := &obs.PutObjectInput{}
 	input.Bucket = o.bucketName
 	input.Key = key
-	input.Body = reader
-	input.ContentType = xhttp.TypeByExtension(key)
 	input.ContentLength, _ = xhttp.GetReaderLen(reader)
-
-	_, err := o.client.PutObject(input)
+	input.Body = xio.NewCtxReader(ctx, reader)
+	input.HttpHeader = toHTTPHeader(oo)
+	input.Metadata = oo.Metadata
+
+	var err error
+	if t := progress.New(ctx, input.ContentLength, oo); t != nil {
+		_, err = o.client.PutObject(input, obs.WithProgress(&progressListener{t: t}))
+	} else {
+		_, err = o.client.PutObject(input)
+	}
 	if err != nil {
 		return "", errors.WithMessage(err, "huawei: obs put object err")
 	}
@@ -106,6 +144,15 @@ func (o *OBS) PutObject(key string, reader io.Reader) (string, error) {
 
 // DeleteObjects 批量删除华为云 OBS 上的对象
 func (o *OBS) DeleteObjects(keys ...string) error {
+	return o.DeleteObjectsCtx(context.Background(), keys...)
+}
+
+// DeleteObjectsCtx 批量删除华为云 OBS 上的对象
+func (o *OBS) DeleteObjectsCtx(ctx context.Context, keys ...string) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithMessage(err, "huawei: obs delete objects err")
+	}
+
 	deletes := make([]obs.ObjectToDelete, 0, len(keys))
 	for _, object := range keys {
 		deletes = append(deletes, obs.ObjectToDelete{Key: object})
@@ -125,6 +172,21 @@ func (o *OBS) DeleteObjects(keys ...string) error {
 
 // UploadFile 上传文件至华为云 OBS，filePath：文件路径，partSize：分块大小（字节），routines：并发数
 func (o *OBS) UploadFile(key, filePath string, partSize int64, routines int) (string, error) {
+	return o.UploadFileCtx(context.Background(), key, filePath, partSize, routines)
+}
+
+// UploadFileCtx 上传文件至华为云 OBS，filePath：文件路径，partSize：分块大小（字节），routines：并发数，opts：对象可选配置
+func (o *OBS) UploadFileCtx(ctx context.Context, key, filePath string, partSize int64, routines int, opts ...types.ObjectOption) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", errors.WithMessage(err, "huawei: obs upload file err")
+	}
+
+	oo := types.NewObjectOptions(key, opts...)
+	t := progress.New(ctx, progress.FileSize(filePath), oo)
+	if t.Limited() {
+		return o.uploadFileLimited(ctx, key, filePath, partSize, t, oo)
+	}
+
 	input := &obs.UploadFileInput{}
 	input.Bucket = o.bucketName
 	input.Key = key
@@ -132,9 +194,15 @@ func (o *OBS) UploadFile(key, filePath string, partSize int64, routines int) (st
 	input.EnableCheckpoint = true // 开启断点续传模式
 	input.PartSize = partSize     // 指定分段大小
 	input.TaskNum = routines      // 指定分段上传时的最大并发数
-	input.ContentType = xhttp.TypeByExtension(key)
-
-	_, err := o.client.UploadFile(input)
+	input.HttpHeader = toHTTPHeader(oo)
+	input.Metadata = oo.Metadata
+
+	var err error
+	if t != nil {
+		_, err = o.client.UploadFile(input, obs.WithProgress(&progressListener{t: t}))
+	} else {
+		_, err = o.client.UploadFile(input)
+	}
 	if err != nil {
 		return "", errors.WithMessage(err, "huawei: obs upload file err")
 	}
@@ -142,6 +210,123 @@ func (o *OBS) UploadFile(key, filePath string, partSize int64, routines int) (st
 	return o.GetURL(key), nil
 }
 
+// CopyObject 在华为云 OBS 服务端复制对象，源对象不存在时返回 types.ErrObjectNotFound
+func (o *OBS) CopyObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", errors.WithMessage(err, "huawei: obs copy object err")
+	}
+
+	input := &obs.CopyObjectInput{}
+	input.Bucket = o.bucketName
+	input.Key = dstKey
+	input.CopySourceBucket = o.bucketName
+	input.CopySourceKey = srcKey
+
+	_, err := o.client.CopyObject(input)
+	if err != nil {
+		if isNotFound(err) {
+			err = types.ErrObjectNotFound
+		}
+		return "", errors.WithMessage(err, "huawei: obs copy object err")
+	}
+
+	return o.GetURL(dstKey), nil
+}
+
+// MoveObject 在华为云 OBS 服务端移动对象，源对象不存在时返回 types.ErrObjectNotFound
+func (o *OBS) MoveObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	u, err := o.CopyObject(ctx, srcKey, dstKey)
+	if err != nil {
+		return "", err
+	}
+	if srcKey != dstKey {
+		if err := o.DeleteObjectsCtx(ctx, srcKey); err != nil {
+			return "", err
+		}
+	}
+
+	return u, nil
+}
+
+// ListObjects 列举华为云 OBS 上指定前缀的对象，opts：列举可选配置
+func (o *OBS) ListObjects(ctx context.Context, prefix string, opts ...types.ListOption) (*types.ListObjectsResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithMessage(err, "huawei: obs list objects err")
+	}
+
+	lo := types.NewListOptions(opts...)
+	input := &obs.ListObjectsInput{}
+	input.Bucket = o.bucketName
+	input.Prefix = prefix
+	input.Marker = lo.Marker
+	input.Delimiter = lo.Delimiter
+	input.MaxKeys = lo.MaxKeys
+
+	output, err := o.client.ListObjects(input)
+	if err != nil {
+		return nil, errors.WithMessage(err, "huawei: obs list objects err")
+	}
+
+	result := &types.ListObjectsResult{
+		Objects:        make([]types.ObjectInfo, 0, len(output.Contents)),
+		CommonPrefixes: output.CommonPrefixes,
+		IsTruncated:    output.IsTruncated,
+		NextMarker:     output.NextMarker,
+	}
+	for _, content := range output.Contents {
+		result.Objects = append(result.Objects, types.ObjectInfo{
+			Key:          content.Key,
+			Size:         content.Size,
+			ETag:         util.TrimETag(content.ETag),
+			LastModified: content.LastModified,
+		})
+	}
+	util.FillNextMarker(result)
+
+	return result, nil
+}
+
+// StatObject 获取华为云 OBS 上对象的元信息，对象不存在时返回 types.ErrObjectNotFound
+func (o *OBS) StatObject(ctx context.Context, key string) (*types.ObjectInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithMessage(err, "huawei: obs stat object err")
+	}
+
+	input := &obs.GetObjectMetadataInput{}
+	input.Bucket = o.bucketName
+	input.Key = key
+
+	output, err := o.client.GetObjectMetadata(input)
+	if err != nil {
+		if isNotFound(err) {
+			err = types.ErrObjectNotFound
+		}
+		return nil, errors.WithMessage(err, "huawei: obs stat object err")
+	}
+
+	return &types.ObjectInfo{
+		Key:          key,
+		Size:         output.ContentLength,
+		ETag:         util.TrimETag(output.ETag),
+		LastModified: output.LastModified,
+		ContentType:  output.ContentType,
+		Metadata:     util.LowerKeys(output.Metadata),
+	}, nil
+}
+
+// Exists 判断对象是否存在于华为云 OBS
+func (o *OBS) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := o.StatObject(ctx, key)
+	if err != nil {
+		if errors.Is(err, types.ErrObjectNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
 // AuthorizedUpload 授权上传至华为云 OBS，expires：过期时间（秒）
 func (o *OBS) AuthorizedUpload(key string, expires int) (string, error) {
 	input := &obs.CreateSignedUrlInput{}
@@ -158,6 +343,175 @@ func (o *OBS) AuthorizedUpload(key string, expires int) (string, error) {
 	return output.SignedUrl, nil
 }
 
+// PresignedGetURL 获取华为云 OBS 上对象的预签名下载 URL，expires：过期时间（秒），opts：预签名可选配置
+func (o *OBS) PresignedGetURL(key string, expires int, opts ...types.PresignOption) (string, error) {
+	input := &obs.CreateSignedUrlInput{}
+	input.Bucket = o.bucketName
+	input.Key = key
+	input.Expires = expires
+	input.Method = obs.HttpMethodGet
+	input.QueryParams = make(map[string]string)
+	for k, v := range types.NewPresignOptions(opts...).Query() {
+		input.QueryParams[k] = v[0]
+	}
+
+	output, err := o.client.CreateSignedUrl(input)
+	if err != nil {
+		return "", errors.WithMessage(err, "huawei: obs presigned get url err")
+	}
+
+	return output.SignedUrl, nil
+}
+
+// PostPolicy 获取华为云 OBS 表单上传策略，expires：过期时间（秒），opts：表单上传策略可选配置
+func (o *OBS) PostPolicy(key string, expires int, opts ...types.PostPolicyOption) (*types.PostPolicy, error) {
+	// 参考文档 https://support.huaweicloud.com/api-obs/obs_04_0012.html
+	// SDK 提供的 CreateBrowserBasedSignature 不支持 starts-with 条件，此处自行生成上传策略
+	p, err := policy.New(key, expires, types.NewPostPolicyOptions(opts...), false)
+	if err != nil {
+		return nil, errors.WithMessage(err, "huawei: obs post policy err")
+	}
+	p.Add(map[string]string{"bucket": o.bucketName})
+
+	_, encoded, err := p.Encode()
+	if err != nil {
+		return nil, errors.WithMessage(err, "huawei: obs post policy err")
+	}
+
+	return p.PostPolicy(fmt.Sprintf("https://%s.%s", o.bucketName, o.endpoint), map[string]string{
+		policy.FieldPolicy: encoded,
+		"AccessKeyId":      o.ak,
+		"signature":        base64.StdEncoding.EncodeToString(policy.HmacSHA1(o.sk, encoded)),
+	}), nil
+}
+
+// InitiateMultipartUpload 初始化华为云 OBS 分片上传，返回上传 ID，opts：对象可选配置
+func (o *OBS) InitiateMultipartUpload(ctx context.Context, key string, opts ...types.ObjectOption) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", errors.WithMessage(err, "huawei: obs initiate multipart upload err")
+	}
+
+	oo := types.NewObjectOptions(key, opts...)
+	input := &obs.InitiateMultipartUploadInput{}
+	input.Bucket = o.bucketName
+	input.Key = key
+	input.HttpHeader = toHTTPHeader(oo)
+	input.Metadata = oo.Metadata
+
+	output, err := o.client.InitiateMultipartUpload(input)
+	if err != nil {
+		return "", errors.WithMessage(err, "huawei: obs initiate multipart upload err")
+	}
+
+	return output.UploadId, nil
+}
+
+// PresignedUploadPartURL 获取华为云 OBS 分片上传的预签名 URL，partNumber：分片序号，expires：过期时间（秒）
+func (o *OBS) PresignedUploadPartURL(key, uploadID string, partNumber, expires int) (string, error) {
+	input := &obs.CreateSignedUrlInput{}
+	input.Bucket = o.bucketName
+	input.Key = key
+	input.Expires = expires
+	input.Method = obs.HttpMethodPut
+	input.QueryParams = map[string]string{
+		"partNumber": strconv.Itoa(partNumber),
+		"uploadId":   uploadID,
+	}
+
+	output, err := o.client.CreateSignedUrl(input)
+	if err != nil {
+		return "", errors.WithMessage(err, "huawei: obs presigned upload part url err")
+	}
+
+	return output.SignedUrl, nil
+}
+
+// CompleteMultipartUpload 完成华为云 OBS 分片上传，parts：已上传的分片列表
+func (o *OBS) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []types.Part) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", errors.WithMessage(err, "huawei: obs complete multipart upload err")
+	}
+
+	input := &obs.CompleteMultipartUploadInput{}
+	input.Bucket = o.bucketName
+	input.Key = key
+	input.UploadId = uploadID
+	input.Parts = make([]obs.Part, 0, len(parts))
+	for _, p := range util.SortParts(parts) {
+		input.Parts = append(input.Parts, obs.Part{PartNumber: p.PartNumber, ETag: p.ETag})
+	}
+
+	_, err := o.client.CompleteMultipartUpload(input)
+	if err != nil {
+		if isNotFound(err) {
+			err = types.ErrUploadNotFound
+		}
+		return "", errors.WithMessage(err, "huawei: obs complete multipart upload err")
+	}
+
+	return o.GetURL(key), nil
+}
+
+// AbortMultipartUpload 取消华为云 OBS 分片上传
+func (o *OBS) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithMessage(err, "huawei: obs abort multipart upload err")
+	}
+
+	input := &obs.AbortMultipartUploadInput{}
+	input.Bucket = o.bucketName
+	input.Key = key
+	input.UploadId = uploadID
+
+	_, err := o.client.AbortMultipartUpload(input)
+	if err != nil {
+		if isNotFound(err) {
+			err = types.ErrUploadNotFound
+		}
+		return errors.WithMessage(err, "huawei: obs abort multipart upload err")
+	}
+
+	return nil
+}
+
+// ListParts 列举华为云 OBS 分片上传中已上传的分片
+func (o *OBS) ListParts(ctx context.Context, key, uploadID string) ([]types.Part, error) {
+	input := &obs.ListPartsInput{}
+	input.Bucket = o.bucketName
+	input.Key = key
+	input.UploadId = uploadID
+
+	var parts []types.Part
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.WithMessage(err, "huawei: obs list parts err")
+		}
+
+		output, err := o.client.ListParts(input)
+		if err != nil {
+			if isNotFound(err) {
+				err = types.ErrUploadNotFound
+			}
+			return nil, errors.WithMessage(err, "huawei: obs list parts err")
+		}
+
+		for _, p := range output.Parts {
+			parts = append(parts, types.Part{
+				PartNumber:   p.PartNumber,
+				ETag:         util.TrimETag(p.ETag),
+				Size:         p.Size,
+				LastModified: p.LastModified,
+			})
+		}
+		if !output.IsTruncated {
+			break
+		}
+		input.PartNumberMarker = output.NextPartNumberMarker
+	}
+
+	return parts, nil
+}
+
 // GetThumbnailSuffix 获取缩略图后缀
 func (o *OBS) GetThumbnailSuffix(width, height int, size int64) string {
 	// 参考文档 https://support.huaweicloud.com/fg-obs/obs_01_0430.html
@@ -176,3 +530,137 @@ func (o *OBS) GetThumbnailSuffix(width, height int, size int64) string {
 
 	return suffix
 }
+
+// ProcessImage 获取华为云 OBS 上图片处理后的访问 URL，opts：图片处理可选配置
+func (o *OBS) ProcessImage(ctx context.Context, key string, opts ...types.ImageOption) (string, error) {
+	// 参考文档 https://support.huaweicloud.com/fg-obs/obs_01_0001.html
+	ip := types.NewImageOptions(opts...)
+	if err := ip.Check(); err != nil {
+		return "", err
+	}
+
+	return o.GetURL(key) + "?x-image-process=" + imaging.Params(ip), nil
+}
+
+// SetLifecycleRules 设置华为云 OBS 存储桶的生命周期规则，覆盖已有规则，rules 为空时删除全部规则
+func (o *OBS) SetLifecycleRules(ctx context.Context, rules []types.LifecycleRule) error {
+	rules, err := types.NormalizeLifecycleRules(rules)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		if _, err := o.client.DeleteBucketLifecycleConfiguration(o.bucketName); err != nil {
+			return errors.WithMessage(err, "huawei: obs delete lifecycle err")
+		}
+		return nil
+	}
+
+	input := &obs.SetBucketLifecycleConfigurationInput{Bucket: o.bucketName}
+	for _, r := range rules {
+		lr := obs.LifecycleRule{ID: r.ID, Prefix: r.Prefix, Status: obs.RuleStatusEnabled}
+		if r.Disabled {
+			lr.Status = obs.RuleStatusDisabled
+		}
+		lr.Expiration.Days = r.ExpirationDays
+		lr.AbortIncompleteMultipartUpload.DaysAfterInitiation = r.AbortMultipartUploadDays
+		input.LifecycleRules = append(input.LifecycleRules, lr)
+	}
+	if _, err := o.client.SetBucketLifecycleConfiguration(input); err != nil {
+		return errors.WithMessage(err, "huawei: obs set lifecycle err")
+	}
+
+	return nil
+}
+
+// GetLifecycleRules 获取华为云 OBS 存储桶的生命周期规则，仅返回按天数过期删除和取消分片上传的规则
+func (o *OBS) GetLifecycleRules(ctx context.Context) ([]types.LifecycleRule, error) {
+	out, err := o.client.GetBucketLifecycleConfiguration(o.bucketName)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.WithMessage(err, "huawei: obs get lifecycle err")
+	}
+
+	var rules []types.LifecycleRule
+	for _, lr := range out.LifecycleRules {
+		r := types.LifecycleRule{
+			ID:                       lr.ID,
+			Prefix:                   lr.Prefix,
+			ExpirationDays:           lr.Expiration.Days,
+			AbortMultipartUploadDays: lr.AbortIncompleteMultipartUpload.DaysAfterInitiation,
+			Disabled:                 lr.Status != obs.RuleStatusEnabled,
+		}
+		if r.ExpirationDays > 0 || r.AbortMultipartUploadDays > 0 {
+			rules = append(rules, r)
+		}
+	}
+
+	return rules, nil
+}
+
+// uploadFileLimited 限速时按分片顺序上传文件，SDK 断点续传上传仅在分片完成后回调进度，无法在传输过程中限速
+func (o *OBS) uploadFileLimited(ctx context.Context, key, filePath string, partSize int64, t *progress.Tracker, oo *types.ObjectOptions) (string, error) {
+	input := &obs.InitiateMultipartUploadInput{}
+	input.Bucket = o.bucketName
+	input.Key = key
+	input.HttpHeader = toHTTPHeader(oo)
+	input.Metadata = oo.Metadata
+
+	output, err := o.client.InitiateMultipartUpload(input)
+	if err != nil {
+		return "", errors.WithMessage(err, "huawei: obs upload file err")
+	}
+
+	parts, err := progress.UploadParts(ctx, t, filePath, partSize, func(partNumber int, r io.Reader, size int64) (string, error) {
+		partInput := &obs.UploadPartInput{}
+		partInput.Bucket = o.bucketName
+		partInput.Key = key
+		partInput.UploadId = output.UploadId
+		partInput.PartNumber = partNumber
+		partInput.PartSize = size
+		partInput.Body = xio.NewCtxReader(ctx, r)
+
+		partOutput, err := o.client.UploadPart(partInput)
+		if err != nil {
+			return "", err
+		}
+		return partOutput.ETag, nil
+	})
+	if err != nil {
+		_ = o.AbortMultipartUpload(context.Background(), key, output.UploadId)
+		return "", errors.WithMessage(err, "huawei: obs upload file err")
+	}
+
+	return o.CompleteMultipartUpload(ctx, key, output.UploadId, parts)
+}
+
+// toHTTPHeader 将对象可选配置转换为华为云 OBS 标准元数据
+func toHTTPHeader(oo *types.ObjectOptions) obs.HttpHeader {
+	return obs.HttpHeader{
+		CacheControl:       oo.CacheControl,
+		ContentDisposition: oo.ContentDisposition,
+		ContentEncoding:    oo.ContentEncoding,
+		ContentType:        oo.ContentType,
+	}
+}
+
+// isNotFound 判断错误是否为对象或分片上传不存在错误
+func isNotFound(err error) bool {
+	var oe obs.ObsError
+	return errors.As(err, &oe) && oe.StatusCode == http.StatusNotFound
+}
+
+// progressListener 华为云 OBS 传输进度监听器
+type progressListener struct {
+	t        *progress.Tracker
+	consumed int64 // SDK 回调的累计已传输字节数
+}
+
+// ProgressChanged 实现 obs.ProgressListener 接口，在监听器中限速等待会阻塞 SDK 读取数据
+func (l *progressListener) ProgressChanged(event *obs.ProgressEvent) {
+	if event.EventType == obs.TransferDataEvent && event.ConsumedBytes > l.consumed {
+		l.t.Add(event.ConsumedBytes - l.consumed)
+		l.consumed = event.ConsumedBytes
+	}
+}
@@ -1,10 +1,16 @@
 package huawei
 
 import (
+	"context"
 	"testing"
 
+	sdk "github.com/huaweicloud/huaweicloud-sdk-go-obs/obs"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/internal/progress"
+	"github.com/sliveryou/micro-pkg/oss/types"
 )
 
 var (
@@ -37,3 +43,58 @@ func TestOBS_AuthorizedUpload(t *testing.T) {
 	require.NoError(t, err)
 	t.Log(obs.AuthorizedUpload("test/test.txt", 120))
 }
+
+func TestIsNotFound(t *testing.T) {
+	notFound := sdk.ObsError{}
+	notFound.StatusCode = 404
+	assert.True(t, isNotFound(notFound))
+	assert.True(t, isNotFound(errors.WithMessage(notFound, "test")))
+	assert.False(t, isNotFound(errors.New("test")))
+}
+
+func TestOBS_PresignedGetURL(t *testing.T) {
+	obs, err := NewOBS(endpoint, accessKeyID, accessKeySecret, bucketName, WithNotSetACL())
+	require.NoError(t, err)
+	signedURL, err := obs.PresignedGetURL("test/test.pdf", 120,
+		types.WithResponseContentDisposition(`attachment; filename="test.pdf"`))
+	require.NoError(t, err)
+	assert.Contains(t, signedURL, "response-content-disposition=")
+}
+
+func TestOBS_PostPolicy(t *testing.T) {
+	obs, err := NewOBS(endpoint, accessKeyID, accessKeySecret, bucketName, WithNotSetACL())
+	require.NoError(t, err)
+	pp, err := obs.PostPolicy("test/test.png", 120, types.WithContentTypes("image/*"))
+	require.NoError(t, err)
+	assert.Equal(t, "test/test.png", pp.Fields["key"])
+	assert.Equal(t, accessKeyID, pp.Fields["AccessKeyId"])
+	assert.NotEmpty(t, pp.Fields["signature"])
+
+	_, err = obs.PostPolicy("test/test.png", 120, types.WithContentTypes("image/png", "image/jpeg"))
+	assert.True(t, errors.Is(err, types.ErrUnsupportedCondition))
+}
+
+func TestOBS_ProcessImage(t *testing.T) {
+	obs, err := NewOBS(endpoint, accessKeyID, accessKeySecret, bucketName, WithNotSetACL())
+	require.NoError(t, err)
+
+	u, err := obs.ProcessImage(context.Background(), "test/test.png",
+		types.WithCrop(0, 0, 200, 200), types.WithWatermark(types.ImageWatermark{Text: "test"}))
+	require.NoError(t, err)
+	assert.Equal(t, obs.GetURL("test/test.png")+
+		"?x-image-process=image/crop,x_0,y_0,w_200,h_200/watermark,text_dGVzdA,size_40,color_000000,g_se,x_0,y_0", u)
+}
+
+func TestProgressListener(t *testing.T) {
+	var consumed int64
+	tr := progress.New(context.Background(), 100, types.NewObjectOptions("test.txt",
+		types.WithProgress(func(e types.ProgressEvent) { consumed = e.ConsumedBytes })))
+	l := &progressListener{t: tr}
+
+	// SDK 回调累计已传输字节数，监听器换算为增量
+	l.ProgressChanged(&sdk.ProgressEvent{EventType: sdk.TransferStartedEvent, TotalBytes: 100})
+	l.ProgressChanged(&sdk.ProgressEvent{EventType: sdk.TransferDataEvent, ConsumedBytes: 40, TotalBytes: 100})
+	l.ProgressChanged(&sdk.ProgressEvent{EventType: sdk.TransferDataEvent, ConsumedBytes: 100, TotalBytes: 100})
+	l.ProgressChanged(&sdk.ProgressEvent{EventType: sdk.TransferCompletedEvent, ConsumedBytes: 100, TotalBytes: 100})
+	assert.Equal(t, int64(100), consumed)
+}
@@ -0,0 +1,404 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+
+	"github.com/sliveryou/micro-pkg/oss/internal/sm4"
+)
+
+// 信封格式：
+//
+//	magic(4) | algorithm(1) | chunkSize(4) | keyIDLen(1) | keyID | wrappedKeyLen(1) | wrappedKey | noncePrefix(7) | chunks...
+//
+// 每个对象使用随机生成的数据密钥加密，数据密钥由密钥 ID 对应的主密钥以相同算法的 GCM 模式加密后保存在头部，
+// 数据按 chunkSize 分块加密，分块随机数为 noncePrefix(7) | 分块序号(4) | 末块标记(1)，头部作为每个分块的附加认证数据，
+// 可检测分块的篡改、重排和截断
+const (
+	// AlgorithmAESGCM AES-GCM 算法，主密钥长度为 16、24 或 32 字节，数据密钥长度为 32 字节
+	AlgorithmAESGCM = "aes-gcm"
+	// AlgorithmSM4GCM SM4-GCM 算法，主密钥和数据密钥长度均为 16 字节
+	AlgorithmSM4GCM = "sm4-gcm"
+
+	// DefaultChunkSize 默认分块大小
+	DefaultChunkSize = 64 << 10
+	// MaxChunkSize 最大分块大小
+	MaxChunkSize = 16 << 20
+
+	// magic 信封格式魔数及版本
+	magic = "OSE\x01"
+	// noncePrefixSize 分块随机数前缀长度
+	noncePrefixSize = 7
+	// maxKeyIDSize 密钥 ID 最大长度
+	maxKeyIDSize = math.MaxUint8
+)
+
+// 算法标识
+const (
+	algAESGCM byte = iota + 1
+	algSM4GCM
+)
+
+var (
+	// ErrInvalidFormat 信封格式无效错误
+	ErrInvalidFormat = errors.New("envelope: invalid format")
+	// ErrUnsupportedAlgorithm 不支持的加密算法错误
+	ErrUnsupportedAlgorithm = errors.New("envelope: unsupported algorithm")
+	// ErrInvalidKey 密钥无效错误
+	ErrInvalidKey = errors.New("envelope: invalid key")
+	// ErrAuthFailed 数据认证失败错误，数据被篡改或密钥错误
+	ErrAuthFailed = errors.New("envelope: message authentication failed")
+)
+
+// KeyFunc 根据密钥 ID 获取主密钥的函数
+type KeyFunc func(keyID string) ([]byte, error)
+
+// CheckKey 检查主密钥是否适用于指定算法
+func CheckKey(algorithm string, key []byte) error {
+	_, err := newAEAD(algorithm, key)
+	return err
+}
+
+// encryptReader 加密读取器
+type encryptReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	header  []byte
+	nonce   []byte
+	counter uint32
+	chunk   []byte // 明文分块缓冲区，多读取 1 字节用于判断是否为末块
+	carry   int    // 缓冲区中已读取的下一分块字节数
+	buf     []byte // 密文缓冲区
+	out     []byte // 待输出的密文
+	done    bool
+}
+
+// NewEncryptReader 新建加密读取器，读取 r 中的明文并输出信封格式的密文，
+// algorithm：加密算法，keyID：密钥 ID，key：主密钥，chunkSize：分块大小，小于等于 0 时使用 DefaultChunkSize
+func NewEncryptReader(r io.Reader, algorithm, keyID string, key []byte, chunkSize int) (io.Reader, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize > MaxChunkSize || len(keyID) > maxKeyIDSize {
+		return nil, ErrInvalidFormat
+	}
+
+	kek, err := newAEAD(algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey := make([]byte, dataKeySize(algorithm))
+	prefix := make([]byte, noncePrefixSize)
+	wrapNonce := make([]byte, kek.NonceSize())
+	for _, b := range [][]byte{dataKey, prefix, wrapNonce} {
+		if _, err := io.ReadFull(rand.Reader, b); err != nil {
+			return nil, errors.WithMessage(err, "envelope: generate random err")
+		}
+	}
+	wrappedKey := kek.Seal(wrapNonce, wrapNonce, dataKey, []byte(keyID))
+
+	aead, err := newAEAD(algorithm, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := bytes.NewBufferString(magic)
+	header.WriteByte(algorithmID(algorithm))
+	_ = binary.Write(header, binary.BigEndian, uint32(chunkSize))
+	header.WriteByte(byte(len(keyID)))
+	header.WriteString(keyID)
+	header.WriteByte(byte(len(wrappedKey)))
+	header.Write(wrappedKey)
+	header.Write(prefix)
+
+	return &encryptReader{
+		r:      r,
+		aead:   aead,
+		header: header.Bytes(),
+		nonce:  chunkNonce(prefix),
+		chunk:  make([]byte, chunkSize+1),
+		buf:    make([]byte, 0, chunkSize+aead.Overhead()),
+		out:    header.Bytes(),
+	}, nil
+}
+
+// Read 实现 io.Reader 接口
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.seal(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+
+	return n, nil
+}
+
+// seal 读取并加密下一个分块
+func (r *encryptReader) seal() error {
+	n, last, err := readChunk(r.r, r.chunk, r.carry)
+	if err != nil {
+		return err
+	}
+	if err := setChunkNonce(r.nonce, r.counter, last); err != nil {
+		return err
+	}
+
+	r.buf = r.aead.Seal(r.buf[:0], r.nonce, r.chunk[:n], r.header)
+	r.out = r.buf
+	r.counter++
+	r.done = last
+	if !last {
+		r.chunk[0], r.carry = r.chunk[n], 1
+	}
+
+	return nil
+}
+
+// DecryptReader 解密读取器
+type DecryptReader interface {
+	io.Reader
+	// KeyID 获取加密使用的密钥 ID
+	KeyID() string
+}
+
+// decryptReader 解密读取器
+type decryptReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	header  []byte
+	keyID   string
+	nonce   []byte
+	counter uint32
+	chunk   []byte // 密文分块缓冲区，多读取 1 字节用于判断是否为末块
+	carry   int    // 缓冲区中已读取的下一分块字节数
+	buf     []byte // 明文缓冲区
+	out     []byte // 待输出的明文
+	done    bool
+}
+
+// NewDecryptReader 新建解密读取器，读取 r 中信封格式的密文并输出明文，keyFunc：根据头部中的密钥 ID 获取主密钥的函数
+func NewDecryptReader(r io.Reader, keyFunc KeyFunc) (DecryptReader, error) {
+	header := &bytes.Buffer{}
+	tr := io.TeeReader(r, header)
+
+	fixed := make([]byte, len(magic)+1+4+1)
+	if _, err := io.ReadFull(tr, fixed); err != nil {
+		return nil, formatErr(err)
+	}
+	if string(fixed[:len(magic)]) != magic {
+		return nil, ErrInvalidFormat
+	}
+	algorithm := algorithmName(fixed[len(magic)])
+	if algorithm == "" {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	chunkSize := int(binary.BigEndian.Uint32(fixed[len(magic)+1:]))
+	if chunkSize <= 0 || chunkSize > MaxChunkSize {
+		return nil, ErrInvalidFormat
+	}
+
+	keyID := make([]byte, fixed[len(fixed)-1])
+	wrappedKeySize := make([]byte, 1)
+	if _, err := io.ReadFull(tr, keyID); err != nil {
+		return nil, formatErr(err)
+	}
+	if _, err := io.ReadFull(tr, wrappedKeySize); err != nil {
+		return nil, formatErr(err)
+	}
+	wrappedKey := make([]byte, wrappedKeySize[0])
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := io.ReadFull(tr, wrappedKey); err != nil {
+		return nil, formatErr(err)
+	}
+	if _, err := io.ReadFull(tr, prefix); err != nil {
+		return nil, formatErr(err)
+	}
+
+	key, err := keyFunc(string(keyID))
+	if err != nil {
+		return nil, err
+	}
+	kek, err := newAEAD(algorithm, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrappedKey) < kek.NonceSize() {
+		return nil, ErrInvalidFormat
+	}
+	dataKey, err := kek.Open(nil, wrappedKey[:kek.NonceSize()], wrappedKey[kek.NonceSize():], keyID)
+	if err != nil {
+		return nil, ErrAuthFailed
+	}
+	aead, err := newAEAD(algorithm, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		r:      r,
+		aead:   aead,
+		header: header.Bytes(),
+		keyID:  string(keyID),
+		nonce:  chunkNonce(prefix),
+		chunk:  make([]byte, chunkSize+aead.Overhead()+1),
+		buf:    make([]byte, 0, chunkSize),
+	}, nil
+}
+
+// KeyID 获取加密使用的密钥 ID
+func (r *decryptReader) KeyID() string {
+	return r.keyID
+}
+
+// Read 实现 io.Reader 接口
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+
+	return n, nil
+}
+
+// open 读取并解密下一个分块
+func (r *decryptReader) open() error {
+	n, last, err := readChunk(r.r, r.chunk, r.carry)
+	if err != nil {
+		return err
+	}
+	if err := setChunkNonce(r.nonce, r.counter, last); err != nil {
+		return err
+	}
+
+	r.buf, err = r.aead.Open(r.buf[:0], r.nonce, r.chunk[:n], r.header)
+	if err != nil {
+		return ErrAuthFailed
+	}
+
+	r.out = r.buf
+	r.counter++
+	r.done = last
+	if !last {
+		r.chunk[0], r.carry = r.chunk[n], 1
+	}
+
+	return nil
+}
+
+// readChunk 读取一个分块至 buf，buf 长度为分块长度加 1，多读取的 1 字节用于判断是否为末块，
+// carry：buf 中已读取的字节数，返回分块长度及是否为末块
+func readChunk(r io.Reader, buf []byte, carry int) (n int, last bool, err error) {
+	m, err := io.ReadFull(r, buf[carry:])
+	n = carry + m
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		return n, true, nil
+	case err != nil:
+		return 0, false, err
+	default:
+		return n - 1, false, nil
+	}
+}
+
+// chunkNonce 新建分块随机数
+func chunkNonce(prefix []byte) []byte {
+	nonce := make([]byte, noncePrefixSize+4+1)
+	copy(nonce, prefix)
+
+	return nonce
+}
+
+// setChunkNonce 设置分块随机数的分块序号和末块标记
+func setChunkNonce(nonce []byte, counter uint32, last bool) error {
+	if counter == math.MaxUint32 {
+		return errors.New("envelope: too many chunks")
+	}
+
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], counter)
+	nonce[len(nonce)-1] = 0
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+
+	return nil
+}
+
+// newAEAD 新建指定算法的 GCM 模式加密器
+func newAEAD(algorithm string, key []byte) (cipher.AEAD, error) {
+	var block cipher.Block
+	var err error
+
+	switch algorithm {
+	case AlgorithmAESGCM:
+		block, err = aes.NewCipher(key)
+	case AlgorithmSM4GCM:
+		block, err = sm4.NewCipher(key)
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	if err != nil {
+		return nil, errors.WithMessage(ErrInvalidKey, err.Error())
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// dataKeySize 获取数据密钥长度
+func dataKeySize(algorithm string) int {
+	if algorithm == AlgorithmSM4GCM {
+		return sm4.KeySize
+	}
+
+	return 32
+}
+
+// algorithmID 获取算法标识
+func algorithmID(algorithm string) byte {
+	if algorithm == AlgorithmSM4GCM {
+		return algSM4GCM
+	}
+
+	return algAESGCM
+}
+
+// algorithmName 获取算法名称
+func algorithmName(id byte) string {
+	switch id {
+	case algAESGCM:
+		return AlgorithmAESGCM
+	case algSM4GCM:
+		return AlgorithmSM4GCM
+	default:
+		return ""
+	}
+}
+
+// formatErr 将读取头部时数据不足的错误转换为 ErrInvalidFormat
+func formatErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrInvalidFormat
+	}
+
+	return err
+}
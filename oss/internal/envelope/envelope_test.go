@@ -0,0 +1,125 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	aesKey = []byte("0123456789abcdef0123456789abcdef")
+	sm4Key = []byte("0123456789abcdef")
+)
+
+func keyFunc(keyID string) ([]byte, error) {
+	switch keyID {
+	case "aes":
+		return aesKey, nil
+	case "sm4":
+		return sm4Key, nil
+	default:
+		return nil, errors.New("key not found")
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	big := make([]byte, 3*DefaultChunkSize+7)
+	_, err := rand.Read(big)
+	require.NoError(t, err)
+
+	cases := []struct {
+		algorithm string
+		keyID     string
+		chunkSize int
+		data      []byte
+	}{
+		{algorithm: AlgorithmAESGCM, keyID: "aes", chunkSize: 0, data: nil},
+		{algorithm: AlgorithmAESGCM, keyID: "aes", chunkSize: 4, data: []byte("hello world")},
+		{algorithm: AlgorithmAESGCM, keyID: "aes", chunkSize: 4, data: []byte("12345678")},
+		{algorithm: AlgorithmAESGCM, keyID: "aes", chunkSize: 0, data: big},
+		{algorithm: AlgorithmSM4GCM, keyID: "sm4", chunkSize: 3, data: []byte("hello world")},
+		{algorithm: AlgorithmSM4GCM, keyID: "sm4", chunkSize: 0, data: big},
+	}
+
+	for _, c := range cases {
+		key, _ := keyFunc(c.keyID)
+		er, err := NewEncryptReader(bytes.NewReader(c.data), c.algorithm, c.keyID, key, c.chunkSize)
+		require.NoError(t, err)
+		encrypted, err := io.ReadAll(er)
+		require.NoError(t, err)
+		if len(c.data) > 0 {
+			assert.False(t, bytes.Contains(encrypted, c.data))
+		}
+
+		dr, err := NewDecryptReader(bytes.NewReader(encrypted), keyFunc)
+		require.NoError(t, err)
+		assert.Equal(t, c.keyID, dr.KeyID())
+		decrypted, err := io.ReadAll(dr)
+		require.NoError(t, err)
+		assert.Equal(t, len(c.data), len(decrypted))
+		assert.True(t, bytes.Equal(c.data, decrypted))
+	}
+}
+
+func TestEnvelope_Tampered(t *testing.T) {
+	er, err := NewEncryptReader(strings.NewReader("hello world"), AlgorithmAESGCM, "aes", aesKey, 4)
+	require.NoError(t, err)
+	encrypted, err := io.ReadAll(er)
+	require.NoError(t, err)
+
+	decrypt := func(data []byte) error {
+		dr, err := NewDecryptReader(bytes.NewReader(data), keyFunc)
+		if err != nil {
+			return err
+		}
+		_, err = io.ReadAll(dr)
+		return err
+	}
+	require.NoError(t, decrypt(encrypted))
+
+	// 篡改末尾数据
+	tampered := bytes.Clone(encrypted)
+	tampered[len(tampered)-1] ^= 1
+	assert.ErrorIs(t, decrypt(tampered), ErrAuthFailed)
+
+	// 截断末块
+	chunk := 4 + 16
+	assert.ErrorIs(t, decrypt(encrypted[:len(encrypted)-(len("hello world")%4+16)]), ErrAuthFailed)
+	assert.ErrorIs(t, decrypt(encrypted[:len(encrypted)-1]), ErrAuthFailed)
+
+	// 追加数据
+	assert.ErrorIs(t, decrypt(append(bytes.Clone(encrypted), make([]byte, chunk)...)), ErrAuthFailed)
+
+	// 头部无效
+	assert.ErrorIs(t, decrypt([]byte("hello world")), ErrInvalidFormat)
+	assert.ErrorIs(t, decrypt(encrypted[:10]), ErrInvalidFormat)
+
+	// 篡改密钥 ID
+	tampered = bytes.Clone(encrypted)
+	copy(tampered[len(magic)+1+4+1:], "sm4")
+	assert.Error(t, decrypt(tampered))
+}
+
+func TestEnvelope_Error(t *testing.T) {
+	_, err := NewEncryptReader(strings.NewReader("test"), AlgorithmSM4GCM, "sm4", aesKey, 0)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = NewEncryptReader(strings.NewReader("test"), "des", "des", aesKey, 0)
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+	_, err = NewEncryptReader(strings.NewReader("test"), AlgorithmAESGCM, "aes", aesKey, MaxChunkSize+1)
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+
+	assert.NoError(t, CheckKey(AlgorithmAESGCM, aesKey[:16]))
+	assert.NoError(t, CheckKey(AlgorithmSM4GCM, sm4Key))
+	assert.ErrorIs(t, CheckKey(AlgorithmSM4GCM, aesKey), ErrInvalidKey)
+
+	er, err := NewEncryptReader(strings.NewReader("test"), AlgorithmAESGCM, "unknown", aesKey, 0)
+	require.NoError(t, err)
+	_, err = NewDecryptReader(er, keyFunc)
+	assert.EqualError(t, err, "key not found")
+}
@@ -0,0 +1,226 @@
+package exif
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MIMEJPEG JPEG 图片内容类型
+	MIMEJPEG = "image/jpeg"
+	// MIMEPNG PNG 图片内容类型
+	MIMEPNG = "image/png"
+
+	// orientationTag EXIF 方向标签
+	orientationTag = 0x0112
+)
+
+var (
+	// ErrInvalidImage 图片格式错误
+	ErrInvalidImage = errors.New("oss: invalid image")
+
+	// exifHeader JPEG APP1 段 EXIF 标识
+	exifHeader = []byte("Exif\x00\x00")
+	// xmpHeader JPEG APP1 段 XMP 标识
+	xmpHeader = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	// xmpKeyword PNG iTXt 块 XMP 关键字
+	xmpKeyword = []byte("XML:com.adobe.xmp\x00")
+	// pngSignature PNG 文件签名
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// Supported 判断是否支持移除该内容类型图片的元数据
+func Supported(mime string) bool {
+	return mime == MIMEJPEG || mime == MIMEPNG
+}
+
+// Strip 移除图片中的 EXIF 和 XMP 元数据，支持 jpeg 和 png，其他内容类型原样复制
+func Strip(w io.Writer, r io.Reader, mime string) error {
+	switch mime {
+	case MIMEJPEG:
+		return StripJPEG(w, r)
+	case MIMEPNG:
+		return StripPNG(w, r)
+	default:
+		_, err := io.Copy(w, r)
+		return err
+	}
+}
+
+// StripJPEG 移除 JPEG 图片 APP1 段中的 EXIF 和 XMP 元数据，仅保留方向标签以免图片显示方向错误
+func StripJPEG(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+
+	var soi [2]byte
+	if _, err := io.ReadFull(br, soi[:]); err != nil || soi[0] != 0xff || soi[1] != 0xd8 {
+		return errors.WithMessage(ErrInvalidImage, "missing jpeg soi marker")
+	}
+	bw.Write(soi[:])
+
+	for {
+		marker, err := readMarker(br)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case marker == 0xda, marker == 0xd9:
+			// 扫描数据开始或图片结束，之后的数据原样复制
+			bw.Write([]byte{0xff, marker})
+			if _, err := io.Copy(bw, br); err != nil {
+				return errors.WithMessage(err, "oss: copy jpeg scan data err")
+			}
+			return bw.Flush()
+		case marker >= 0xd0 && marker <= 0xd7, marker == 0x01:
+			// 无数据段的独立标记
+			bw.Write([]byte{0xff, marker})
+			continue
+		}
+
+		var length [2]byte
+		if _, err := io.ReadFull(br, length[:]); err != nil {
+			return errors.WithMessage(ErrInvalidImage, "truncated jpeg segment")
+		}
+		n := int(binary.BigEndian.Uint16(length[:]))
+		if n < 2 {
+			return errors.WithMessage(ErrInvalidImage, "illegal jpeg segment length")
+		}
+		seg := make([]byte, n-2)
+		if _, err := io.ReadFull(br, seg); err != nil {
+			return errors.WithMessage(ErrInvalidImage, "truncated jpeg segment")
+		}
+
+		if marker == 0xe1 {
+			if bytes.HasPrefix(seg, exifHeader) {
+				if o := orientation(seg[len(exifHeader):]); o > 1 {
+					writeSegment(bw, marker, orientationExif(o))
+				}
+				continue
+			}
+			if bytes.HasPrefix(seg, xmpHeader) {
+				continue
+			}
+		}
+		writeSegment(bw, marker, seg)
+	}
+}
+
+// StripPNG 移除 PNG 图片中的 eXIf 块和 XMP iTXt 块
+func StripPNG(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+
+	sig := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(br, sig); err != nil || !bytes.Equal(sig, pngSignature) {
+		return errors.WithMessage(ErrInvalidImage, "missing png signature")
+	}
+	bw.Write(sig)
+
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			return errors.WithMessage(ErrInvalidImage, "truncated png chunk")
+		}
+		// 块数据和 CRC 校验码的长度
+		n := int64(binary.BigEndian.Uint32(header[:4])) + 4
+		typ := string(header[4:])
+
+		switch typ {
+		case "eXIf":
+			if _, err := br.Discard(int(n)); err != nil {
+				return errors.WithMessage(ErrInvalidImage, "truncated png chunk")
+			}
+			continue
+		case "iTXt":
+			if prefix, _ := br.Peek(len(xmpKeyword)); n-4 >= int64(len(xmpKeyword)) && bytes.Equal(prefix, xmpKeyword) {
+				if _, err := br.Discard(int(n)); err != nil {
+					return errors.WithMessage(ErrInvalidImage, "truncated png chunk")
+				}
+				continue
+			}
+		}
+
+		bw.Write(header[:])
+		if _, err := io.CopyN(bw, br, n); err != nil {
+			return errors.WithMessage(ErrInvalidImage, "truncated png chunk")
+		}
+		if typ == "IEND" {
+			return bw.Flush()
+		}
+	}
+}
+
+// readMarker 读取 JPEG 标记，跳过标记前的填充字节
+func readMarker(br *bufio.Reader) (byte, error) {
+	b, err := br.ReadByte()
+	if err != nil || b != 0xff {
+		return 0, errors.WithMessage(ErrInvalidImage, "illegal jpeg marker")
+	}
+	for b == 0xff {
+		if b, err = br.ReadByte(); err != nil {
+			return 0, errors.WithMessage(ErrInvalidImage, "illegal jpeg marker")
+		}
+	}
+
+	return b, nil
+}
+
+// writeSegment 写入 JPEG 数据段
+func writeSegment(bw *bufio.Writer, marker byte, seg []byte) {
+	bw.Write([]byte{0xff, marker})
+	binary.Write(bw, binary.BigEndian, uint16(len(seg)+2))
+	bw.Write(seg)
+}
+
+// orientation 获取 TIFF 格式 EXIF 数据 IFD0 中的方向标签值，不存在时返回 0
+func orientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var bo binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := uint64(bo.Uint32(tiff[4:8]))
+	if offset+2 > uint64(len(tiff)) {
+		return 0
+	}
+	count := uint64(bo.Uint16(tiff[offset:]))
+	for i := uint64(0); i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > uint64(len(tiff)) {
+			return 0
+		}
+		if bo.Uint16(tiff[entry:]) == orientationTag {
+			return int(bo.Uint16(tiff[entry+8:]))
+		}
+	}
+
+	return 0
+}
+
+// orientationExif 新建仅包含方向标签的 EXIF 数据
+func orientationExif(o int) []byte {
+	seg := append([]byte(nil), exifHeader...)
+	// 大端字节序 TIFF 头，IFD0 偏移为 8
+	seg = append(seg, 'M', 'M', 0x00, 0x2a, 0x00, 0x00, 0x00, 0x08)
+	// 1 个条目：方向标签，SHORT 类型，数量为 1
+	seg = append(seg, 0x00, 0x01, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01)
+	seg = append(seg, byte(o>>8), byte(o), 0x00, 0x00)
+	// 无下一个 IFD
+	seg = append(seg, 0x00, 0x00, 0x00, 0x00)
+
+	return seg
+}
@@ -0,0 +1,103 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripJPEG(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, jpeg.Encode(buf, image.NewGray(image.Rect(0, 0, 16, 8)), nil))
+	src := buf.Bytes()
+
+	// 插入包含方向和 GPS 信息的 EXIF 段以及 XMP 段
+	tiff := []byte{'I', 'I', 0x2a, 0x00, 0x08, 0x00, 0x00, 0x00, 0x02, 0x00,
+		0x12, 0x01, 0x03, 0x00, 0x01, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00,
+		0x25, 0x88, 0x04, 0x00, 0x01, 0x00, 0x00, 0x00, 0x26, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00}
+	exifSeg := append(append([]byte("Exif\x00\x00"), tiff...), []byte("GPS-SECRET")...)
+	xmpSeg := append([]byte("http://ns.adobe.com/xap/1.0/\x00"), []byte("<x:xmpmeta>XMP-SECRET</x:xmpmeta>")...)
+	withMeta := append([]byte{}, src[:2]...)
+	withMeta = append(withMeta, segment(0xe1, exifSeg)...)
+	withMeta = append(withMeta, segment(0xe1, xmpSeg)...)
+	withMeta = append(withMeta, src[2:]...)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, Strip(out, bytes.NewReader(withMeta), MIMEJPEG))
+	assert.NotContains(t, out.String(), "GPS-SECRET")
+	assert.NotContains(t, out.String(), "XMP-SECRET")
+	assert.Contains(t, out.String(), "Exif\x00\x00")
+	assert.Equal(t, 6, orientation(out.Bytes()[bytes.Index(out.Bytes(), []byte("Exif\x00\x00"))+6:]))
+
+	img, err := jpeg.Decode(bytes.NewReader(out.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 16, 8), img.Bounds())
+
+	// 无 EXIF 元数据时内容不变
+	out.Reset()
+	require.NoError(t, StripJPEG(out, bytes.NewReader(src)))
+	assert.Equal(t, src, out.Bytes())
+
+	require.ErrorIs(t, StripJPEG(out, bytes.NewReader([]byte("not jpeg"))), ErrInvalidImage)
+	require.ErrorIs(t, StripJPEG(out, bytes.NewReader(withMeta[:20])), ErrInvalidImage)
+}
+
+func TestStripPNG(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, png.Encode(buf, image.NewGray(image.Rect(0, 0, 16, 8))))
+	src := buf.Bytes()
+
+	// 在 IHDR 块之后插入 eXIf 块、XMP iTXt 块和普通 iTXt 块
+	ihdrEnd := 8 + 8 + 13 + 4
+	withMeta := append([]byte{}, src[:ihdrEnd]...)
+	withMeta = append(withMeta, chunk("eXIf", []byte("MM\x00\x2aGPS-SECRET"))...)
+	withMeta = append(withMeta, chunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00XMP-SECRET"))...)
+	withMeta = append(withMeta, chunk("iTXt", []byte("Comment\x00\x00\x00\x00\x00hello"))...)
+	withMeta = append(withMeta, src[ihdrEnd:]...)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, Strip(out, bytes.NewReader(withMeta), MIMEPNG))
+	assert.NotContains(t, out.String(), "GPS-SECRET")
+	assert.NotContains(t, out.String(), "XMP-SECRET")
+	assert.Contains(t, out.String(), "hello")
+
+	img, err := png.Decode(bytes.NewReader(out.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 16, 8), img.Bounds())
+
+	require.ErrorIs(t, StripPNG(out, bytes.NewReader([]byte("not png"))), ErrInvalidImage)
+	require.ErrorIs(t, StripPNG(out, bytes.NewReader(withMeta[:40])), ErrInvalidImage)
+}
+
+func TestStrip(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, Strip(out, bytes.NewReader([]byte("text")), "text/plain"))
+	assert.Equal(t, "text", out.String())
+	assert.True(t, Supported(MIMEJPEG))
+	assert.False(t, Supported("image/gif"))
+}
+
+// segment 新建 JPEG 数据段
+func segment(marker byte, data []byte) []byte {
+	seg := []byte{0xff, marker, 0x00, 0x00}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(data)+2))
+	return append(seg, data...)
+}
+
+// chunk 新建 PNG 数据块
+func chunk(typ string, data []byte) []byte {
+	c := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(c, uint32(len(data)))
+	copy(c[4:], typ)
+	c = append(c, data...)
+	crc := crc32.ChecksumIEEE(c[4:])
+	return binary.BigEndian.AppendUint32(c, crc)
+}
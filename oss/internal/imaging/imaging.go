@@ -0,0 +1,341 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	_ "golang.org/x/image/webp" // 注册 webp 解码器
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+// Store 缓存处理后图片的对象存储
+type Store interface {
+	// GetURL 获取对象的完整访问 URL
+	GetURL(key string) string
+	// GetObjectCtx 获取对象的存储数据
+	GetObjectCtx(ctx context.Context, key string) (io.ReadCloser, error)
+	// PutObjectCtx 上传对象
+	PutObjectCtx(ctx context.Context, key string, reader io.Reader, opts ...types.ObjectOption) (string, error)
+	// StatObject 获取对象的元信息
+	StatObject(ctx context.Context, key string) (*types.ObjectInfo, error)
+	// Exists 判断对象是否存在
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// Params 获取阿里云 OSS 和华为云 OBS 格式的图片处理参数，如 image/resize,m_lfit,w_100,h_100,limit_0/format,webp
+func Params(o *types.ImageOptions) string {
+	var b strings.Builder
+	b.WriteString("image")
+	if c := o.Crop; c != nil {
+		fmt.Fprintf(&b, "/crop,x_%d,y_%d", c.X, c.Y)
+		if c.Width > 0 {
+			fmt.Fprintf(&b, ",w_%d", c.Width)
+		}
+		if c.Height > 0 {
+			fmt.Fprintf(&b, ",h_%d", c.Height)
+		}
+	}
+	if o.Width > 0 || o.Height > 0 {
+		fmt.Fprintf(&b, "/resize,m_%s", o.Mode)
+		if o.Width > 0 {
+			fmt.Fprintf(&b, ",w_%d", o.Width)
+		}
+		if o.Height > 0 {
+			fmt.Fprintf(&b, ",h_%d", o.Height)
+		}
+		b.WriteString(",limit_0")
+	}
+	if w := o.Watermark; w != nil {
+		b.WriteString("/watermark")
+		if w.Text != "" {
+			fmt.Fprintf(&b, ",text_%s,size_%d,color_%s", Base64(w.Text), w.FontSize, w.Color)
+		} else {
+			fmt.Fprintf(&b, ",image_%s", Base64(w.Image))
+		}
+		fmt.Fprintf(&b, ",g_%s,x_%d,y_%d", w.Gravity, w.X, w.Y)
+	}
+	if o.Format != "" {
+		fmt.Fprintf(&b, "/format,%s", o.Format)
+	}
+	if o.Quality > 0 {
+		fmt.Fprintf(&b, "/quality,Q_%d", o.Quality)
+	}
+
+	return b.String()
+}
+
+// Base64 URL 安全的 Base64 编码，不含填充
+func Base64(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// OutputFormat 获取纯 Go 渲染的实际输出格式，未指定输出格式时保持原图格式，
+// 不支持 webp 编码，输出 webp 或无法识别原图格式时使用 png
+func OutputFormat(o *types.ImageOptions, key string) string {
+	format := o.Format
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(path.Ext(key)), ".")
+	}
+
+	switch format {
+	case types.ImageFormatJPEG, "jpeg":
+		return types.ImageFormatJPEG
+	case types.ImageFormatGIF:
+		return types.ImageFormatGIF
+	default:
+		return types.ImageFormatPNG
+	}
+}
+
+// ProcessCached 使用纯 Go 渲染处理图片，并将结果缓存至 types.ImageCachePrefix 保留前缀下，
+// 缓存存在时直接返回缓存的访问 URL，缓存对象键包含原图 ETag，原图被覆盖后重新处理
+func ProcessCached(ctx context.Context, s Store, key string, opts ...types.ImageOption) (string, error) {
+	o := types.NewImageOptions(opts...)
+	if err := o.Check(); err != nil {
+		return "", err
+	}
+
+	info, err := s.StatObject(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	format := OutputFormat(o, key)
+	cacheKey := o.CacheKey(key, info.ETag, format)
+	if ok, err := s.Exists(ctx, cacheKey); err == nil && ok {
+		return s.GetURL(cacheKey), nil
+	}
+
+	src, err := decodeObject(ctx, s, key)
+	if err != nil {
+		return "", err
+	}
+	var mark image.Image
+	if w := o.Watermark; w != nil && w.Image != "" {
+		if mark, err = decodeObject(ctx, s, w.Image); err != nil {
+			return "", err
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, Process(src, o, mark), format, o.Quality); err != nil {
+		return "", err
+	}
+
+	return s.PutObjectCtx(ctx, cacheKey, buf, types.WithContentType("image/"+contentSubtype(format)))
+}
+
+// Process 处理图片，按裁剪、缩放、水印的顺序处理，mark：图片水印，文字水印时为 nil
+func Process(img image.Image, o *types.ImageOptions, mark image.Image) image.Image {
+	if c := o.Crop; c != nil {
+		img = crop(img, c)
+	}
+	if o.Width > 0 || o.Height > 0 {
+		img = resize(img, o.Width, o.Height, o.Mode)
+	}
+	if w := o.Watermark; w != nil {
+		img = watermark(img, w, mark)
+	}
+
+	return img
+}
+
+// Encode 将图片编码为指定格式，quality：jpg 输出质量，为 0 时使用默认质量
+func Encode(w io.Writer, img image.Image, format string, quality int) error {
+	var err error
+	switch format {
+	case types.ImageFormatJPEG:
+		if quality <= 0 {
+			quality = jpeg.DefaultQuality
+		}
+		err = jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case types.ImageFormatGIF:
+		err = gif.Encode(w, img, nil)
+	case types.ImageFormatPNG:
+		err = png.Encode(w, img)
+	default:
+		return errors.WithMessagef(types.ErrUnsupportedImage, "format: %s", format)
+	}
+	if err != nil {
+		return errors.WithMessage(err, "oss: encode image err")
+	}
+
+	return nil
+}
+
+// decodeObject 获取并解码对象中的图片，像素数超过 types.MaxImagePixels 的图片不解码
+func decodeObject(ctx context.Context, s Store, key string) (image.Image, error) {
+	rc, err := s.GetObjectCtx(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// 先解码图片头部检查尺寸，防止声明超大尺寸的图片在解码时占用过多内存
+	header := &bytes.Buffer{}
+	c, _, err := image.DecodeConfig(io.TeeReader(rc, header))
+	if err != nil {
+		return nil, errors.WithMessagef(types.ErrUnsupportedImage, "key: %s, err: %v", key, err)
+	}
+	if c.Width <= 0 || c.Height <= 0 || int64(c.Width)*int64(c.Height) > types.MaxImagePixels {
+		return nil, errors.WithMessagef(types.ErrUnsupportedImage, "key: %s, size: %dx%d out of range", key, c.Width, c.Height)
+	}
+
+	img, _, err := image.Decode(io.MultiReader(header, rc))
+	if err != nil {
+		return nil, errors.WithMessagef(types.ErrUnsupportedImage, "key: %s, err: %v", key, err)
+	}
+
+	return img, nil
+}
+
+// crop 裁剪图片，裁剪区域超出图片时裁剪至图片边缘
+func crop(img image.Image, c *types.ImageCrop) image.Image {
+	b := img.Bounds()
+	r := image.Rect(b.Min.X+c.X, b.Min.Y+c.Y, b.Max.X, b.Max.Y)
+	if c.Width > 0 {
+		r.Max.X = r.Min.X + c.Width
+	}
+	if c.Height > 0 {
+		r.Max.Y = r.Min.Y + c.Height
+	}
+	r = r.Intersect(b)
+	if r.Empty() {
+		return img
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+
+	return dst
+}
+
+// resize 缩放图片，只设置宽高之一时在 types.MaxImageSize 范围内等比缩放，输出宽高均不超过 types.MaxImageSize
+func resize(img image.Image, width, height int, mode string) image.Image {
+	b := img.Bounds()
+	sw, sh := float64(b.Dx()), float64(b.Dy())
+	if sw == 0 || sh == 0 {
+		return img
+	}
+	if width <= 0 {
+		width, mode = types.MaxImageSize, types.ResizeModeFit
+	}
+	if height <= 0 {
+		height, mode = types.MaxImageSize, types.ResizeModeFit
+	}
+
+	scaleW, scaleH := float64(width)/sw, float64(height)/sh
+	src := b
+	var w, h int
+	switch mode {
+	case types.ResizeModeFixed:
+		w, h = width, height
+	case types.ResizeModeFill:
+		// 按覆盖目标宽高的比例居中截取原图区域，直接缩放至目标宽高，避免生成超大的中间图片
+		scale := math.Max(scaleW, scaleH)
+		cw, ch := int(math.Min(sw, math.Max(1, math.Round(float64(width)/scale)))),
+			int(math.Min(sh, math.Max(1, math.Round(float64(height)/scale))))
+		x, y := b.Min.X+(b.Dx()-cw)/2, b.Min.Y+(b.Dy()-ch)/2
+		src = image.Rect(x, y, x+cw, y+ch)
+		w, h = width, height
+	default:
+		scale := math.Min(scaleW, scaleH)
+		w, h = scaled(sw, scale), scaled(sh, scale)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+
+	return dst
+}
+
+// scaled 获取缩放后的边长，最小为 1
+func scaled(size, scale float64) int {
+	return int(math.Max(1, math.Round(size*scale)))
+}
+
+// watermark 为图片添加水印
+func watermark(img image.Image, w *types.ImageWatermark, mark image.Image) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+
+	if mark != nil {
+		mb := mark.Bounds()
+		pt := position(dst.Bounds(), mb.Dx(), mb.Dy(), w)
+		draw.Draw(dst, image.Rectangle{Min: pt, Max: pt.Add(mb.Size())}, mark, mb.Min, draw.Over)
+		return dst
+	}
+
+	face, err := opentype.NewFace(regularFont, &opentype.FaceOptions{Size: float64(w.FontSize), DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return dst
+	}
+	defer face.Close()
+
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(parseColor(w.Color)), Face: face}
+	metrics := face.Metrics()
+	tw := d.MeasureString(w.Text).Ceil()
+	th := (metrics.Ascent + metrics.Descent).Ceil()
+	pt := position(dst.Bounds(), tw, th, w)
+	d.Dot = fixed.P(pt.X, pt.Y+metrics.Ascent.Ceil())
+	d.DrawString(w.Text)
+
+	return dst
+}
+
+// position 根据水印位置和边距获取水印左上角坐标
+func position(r image.Rectangle, w, h int, wm *types.ImageWatermark) image.Point {
+	x, y := wm.X, wm.Y
+	switch wm.Gravity {
+	case types.GravityNorth, types.GravityCenter, types.GravitySouth:
+		x = (r.Dx() - w) / 2
+	case types.GravityNorthEast, types.GravityEast, types.GravitySouthEast:
+		x = r.Dx() - w - wm.X
+	}
+	switch wm.Gravity {
+	case types.GravityWest, types.GravityCenter, types.GravityEast:
+		y = (r.Dy() - h) / 2
+	case types.GravitySouthWest, types.GravitySouth, types.GravitySouthEast:
+		y = r.Dy() - h - wm.Y
+	}
+
+	return image.Pt(x, y)
+}
+
+// parseColor 解析 RRGGBB 格式的颜色
+func parseColor(s string) color.Color {
+	v, _ := strconv.ParseUint(s, 16, 32)
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}
+}
+
+// contentSubtype 获取图片格式对应的内容子类型
+func contentSubtype(format string) string {
+	if format == types.ImageFormatJPEG {
+		return "jpeg"
+	}
+
+	return format
+}
+
+// regularFont 文字水印字体，仅支持拉丁字符
+var regularFont, _ = opentype.Parse(goregular.TTF)
@@ -0,0 +1,226 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+func TestParams(t *testing.T) {
+	o := types.NewImageOptions(types.WithCrop(10, 20, 300, 0), types.WithResize(100, 100, types.ResizeModeFill),
+		types.WithFormat(types.ImageFormatWebP), types.WithQuality(80))
+	assert.Equal(t, "image/crop,x_10,y_20,w_300/resize,m_fill,w_100,h_100,limit_0/format,webp/quality,Q_80", Params(o))
+
+	o = types.NewImageOptions(types.WithWatermark(types.ImageWatermark{Text: "test", X: 10, Y: 10}))
+	assert.Equal(t, "image/watermark,text_dGVzdA,size_40,color_000000,g_se,x_10,y_10", Params(o))
+
+	o = types.NewImageOptions(types.WithWatermark(types.ImageWatermark{Image: "mark.png", Gravity: types.GravityCenter}))
+	assert.Equal(t, "image/watermark,image_bWFyay5wbmc,g_center,x_0,y_0", Params(o))
+}
+
+func TestOutputFormat(t *testing.T) {
+	assert.Equal(t, types.ImageFormatJPEG, OutputFormat(types.NewImageOptions(), "test.JPEG"))
+	assert.Equal(t, types.ImageFormatGIF, OutputFormat(types.NewImageOptions(), "test.gif"))
+	assert.Equal(t, types.ImageFormatPNG, OutputFormat(types.NewImageOptions(), "test"))
+	assert.Equal(t, types.ImageFormatPNG, OutputFormat(types.NewImageOptions(types.WithFormat("webp")), "test.jpg"))
+	assert.Equal(t, types.ImageFormatJPEG, OutputFormat(types.NewImageOptions(types.WithFormat("jpg")), "test.png"))
+}
+
+func TestProcess(t *testing.T) {
+	src := newImage(400, 200)
+	cases := []struct {
+		opts   []types.ImageOption
+		width  int
+		height int
+	}{
+		{opts: []types.ImageOption{types.WithResize(100, 100, "")}, width: 100, height: 50},
+		{opts: []types.ImageOption{types.WithResize(100, 100, types.ResizeModeFill)}, width: 100, height: 100},
+		{opts: []types.ImageOption{types.WithResize(100, 100, types.ResizeModeFixed)}, width: 100, height: 100},
+		{opts: []types.ImageOption{types.WithResize(0, 100, "")}, width: 200, height: 100},
+		{opts: []types.ImageOption{types.WithCrop(100, 50, 0, 0)}, width: 300, height: 150},
+		{opts: []types.ImageOption{types.WithCrop(300, 0, 200, 100)}, width: 100, height: 100},
+		{opts: []types.ImageOption{types.WithCrop(0, 0, 200, 200), types.WithResize(50, 0, "")}, width: 50, height: 50},
+	}
+
+	for _, c := range cases {
+		img := Process(src, types.NewImageOptions(c.opts...), nil)
+		assert.Equal(t, c.width, img.Bounds().Dx())
+		assert.Equal(t, c.height, img.Bounds().Dy())
+	}
+}
+
+func TestProcess_Watermark(t *testing.T) {
+	src := newImage(200, 100)
+
+	o := types.NewImageOptions(types.WithWatermark(types.ImageWatermark{Text: "test", Color: "FF0000", Gravity: types.GravityCenter}))
+	img := Process(src, o, nil)
+	assert.True(t, hasColor(img, image.Rect(50, 25, 150, 75), color.RGBA{R: 0xff, A: 0xff}))
+	assert.False(t, hasColor(img, image.Rect(0, 0, 40, 100), color.RGBA{R: 0xff, A: 0xff}))
+
+	mark := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for i := range mark.Pix {
+		mark.Pix[i] = 0xff
+	}
+	o = types.NewImageOptions(types.WithWatermark(types.ImageWatermark{Image: "mark.png", X: 5, Y: 5}))
+	img = Process(src, o, mark)
+	assert.Equal(t, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, img.At(190, 90))
+	assert.Equal(t, src.At(196, 96), img.At(196, 96))
+}
+
+func TestProcessCached(t *testing.T) {
+	s := &memStore{objects: map[string][]byte{}}
+	buf := &bytes.Buffer{}
+	require.NoError(t, png.Encode(buf, newImage(400, 200)))
+	s.objects["test/test.png"] = buf.Bytes()
+
+	ctx := context.Background()
+	_, err := ProcessCached(ctx, s, "test/test.png")
+	require.ErrorIs(t, err, types.ErrInvalidImageOptions)
+	_, err = ProcessCached(ctx, s, "test/none.png", types.WithResize(100, 0, ""))
+	require.ErrorIs(t, err, types.ErrObjectNotFound)
+
+	s.objects["test/bad.png"] = []byte("not image")
+	_, err = ProcessCached(ctx, s, "test/bad.png", types.WithResize(100, 0, ""))
+	require.ErrorIs(t, err, types.ErrUnsupportedImage)
+
+	url, err := ProcessCached(ctx, s, "test/test.png", types.WithResize(100, 0, ""), types.WithFormat("jpg"))
+	require.NoError(t, err)
+	assert.Regexp(t, `^\.image-cache/test/test\.png@[0-9a-f]{16}\.jpg$`, url)
+	assert.Equal(t, 1, s.puts)
+
+	img, format, err := image.Decode(bytes.NewReader(s.objects[url]))
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, 100, img.Bounds().Dx())
+	assert.Equal(t, 50, img.Bounds().Dy())
+
+	cached, err := ProcessCached(ctx, s, "test/test.png", types.WithFormat("jpg"), types.WithResize(100, 0, ""))
+	require.NoError(t, err)
+	assert.Equal(t, url, cached)
+	assert.Equal(t, 1, s.puts)
+
+	// 原图被覆盖后重新处理
+	buf.Reset()
+	require.NoError(t, png.Encode(buf, newImage(200, 200)))
+	s.objects["test/test.png"] = buf.Bytes()
+	updated, err := ProcessCached(ctx, s, "test/test.png", types.WithResize(100, 0, ""), types.WithFormat("jpg"))
+	require.NoError(t, err)
+	assert.NotEqual(t, url, updated)
+	assert.Equal(t, 2, s.puts)
+	img, _, err = image.Decode(bytes.NewReader(s.objects[updated]))
+	require.NoError(t, err)
+	assert.Equal(t, 100, img.Bounds().Dy())
+}
+
+func TestProcessCached_TooLarge(t *testing.T) {
+	// 图片头部声明 65535x65535 的 gif 图片，解码前拒绝
+	buf := &bytes.Buffer{}
+	require.NoError(t, gif.Encode(buf, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black}), nil))
+	b := buf.Bytes()
+	binary.LittleEndian.PutUint16(b[6:8], 0xffff)
+	binary.LittleEndian.PutUint16(b[8:10], 0xffff)
+
+	s := &memStore{objects: map[string][]byte{"test/bomb.gif": b}}
+	_, err := ProcessCached(context.Background(), s, "test/bomb.gif", types.WithResize(100, 0, ""))
+	require.ErrorIs(t, err, types.ErrUnsupportedImage)
+	assert.Contains(t, err.Error(), "65535x65535")
+	assert.Equal(t, 0, s.puts)
+}
+
+func TestProcess_Bounded(t *testing.T) {
+	// 只设置宽高之一时，输出宽高均不超过 MaxImageSize
+	src := newImage(1, 2000)
+	img := Process(src, types.NewImageOptions(types.WithResize(types.MaxImageSize, 0, "")), nil)
+	assert.Equal(t, image.Rect(0, 0, 2, types.MaxImageSize), img.Bounds())
+
+	// 填充模式直接缩放至目标宽高
+	img = Process(src, types.NewImageOptions(types.WithResize(types.MaxImageSize, 100, types.ResizeModeFill)), nil)
+	assert.Equal(t, image.Rect(0, 0, types.MaxImageSize, 100), img.Bounds())
+}
+
+// newImage 新建左右两种颜色的测试图片
+func newImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			c := color.RGBA{R: 0x20, G: 0x40, B: 0x80, A: 0xff}
+			if x >= width/2 {
+				c = color.RGBA{R: 0x80, G: 0x40, B: 0x20, A: 0xff}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	return img
+}
+
+// hasColor 判断图片指定区域内是否存在指定颜色
+func hasColor(img image.Image, r image.Rectangle, c color.Color) bool {
+	for x := r.Min.X; x < r.Max.X; x++ {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			if color.RGBAModel.Convert(img.At(x, y)) == c {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// memStore 内存对象存储
+type memStore struct {
+	objects map[string][]byte
+	puts    int
+}
+
+func (s *memStore) GetURL(key string) string {
+	return key
+}
+
+func (s *memStore) GetObjectCtx(ctx context.Context, key string) (io.ReadCloser, error) {
+	b, ok := s.objects[key]
+	if !ok {
+		return nil, types.ErrObjectNotFound
+	}
+
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (s *memStore) PutObjectCtx(ctx context.Context, key string, reader io.Reader, opts ...types.ObjectOption) (string, error) {
+	b, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	s.objects[key] = b
+	s.puts++
+
+	return key, nil
+}
+
+func (s *memStore) StatObject(ctx context.Context, key string) (*types.ObjectInfo, error) {
+	b, ok := s.objects[key]
+	if !ok {
+		return nil, types.ErrObjectNotFound
+	}
+	sum := md5.Sum(b)
+
+	return &types.ObjectInfo{Key: key, Size: int64(len(b)), ETag: hex.EncodeToString(sum[:])}, nil
+}
+
+func (s *memStore) Exists(ctx context.Context, key string) (bool, error) {
+	_, ok := s.objects[key]
+	return ok, nil
+}
@@ -0,0 +1,116 @@
+package policy
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+const (
+	// ExpirationFormat 表单上传策略过期时间格式
+	ExpirationFormat = "2006-01-02T15:04:05.000Z"
+
+	// FieldKey 对象键表单字段
+	FieldKey = "key"
+	// FieldPolicy 上传策略表单字段
+	FieldPolicy = "policy"
+	// FieldContentType 内容类型表单字段
+	FieldContentType = "Content-Type"
+)
+
+// Policy 表单上传策略
+type Policy struct {
+	Expiration string `json:"expiration"` // 过期时间
+	Conditions []any  `json:"conditions"` // 条件列表
+
+	expiration time.Time         // 过期时间
+	fields     map[string]string // 表单字段
+}
+
+// New 新建表单上传策略，根据可选配置生成对象键、内容长度和内容类型条件，
+// supportIn 表示服务商是否支持 in 条件，不支持时设置多个内容类型返回 types.ErrUnsupportedCondition
+func New(key string, expires int, po *types.PostPolicyOptions, supportIn bool) (*Policy, error) {
+	formKey := po.FormKey(key)
+	if err := po.Check(formKey); err != nil {
+		return nil, err
+	}
+
+	expiration := time.Now().Add(time.Duration(expires) * time.Second).UTC()
+	p := &Policy{
+		Expiration: expiration.Format(ExpirationFormat),
+		expiration: expiration,
+		fields:     map[string]string{FieldKey: formKey},
+	}
+	if po.KeyPrefix != "" {
+		p.Add([]any{"starts-with", "$" + FieldKey, po.KeyPrefix})
+	} else {
+		p.Add(map[string]string{FieldKey: formKey})
+	}
+	if po.MaxContentLength > 0 {
+		p.Add([]any{"content-length-range", po.MinContentLength, po.MaxContentLength})
+	}
+
+	switch cts := po.ContentTypes; {
+	case len(cts) == 1 && strings.HasSuffix(cts[0], "/*"):
+		p.Add([]any{"starts-with", "$" + FieldContentType, strings.TrimSuffix(cts[0], "*")})
+	case len(cts) == 1:
+		p.Add([]any{"eq", "$" + FieldContentType, cts[0]})
+		p.fields[FieldContentType] = cts[0]
+	case len(cts) > 1:
+		for _, ct := range cts {
+			if !supportIn || strings.HasSuffix(ct, "/*") {
+				return nil, errors.WithMessagef(types.ErrUnsupportedCondition, "content types: %v", cts)
+			}
+		}
+		p.Add([]any{"in", "$" + FieldContentType, cts})
+	}
+
+	return p, nil
+}
+
+// Add 添加条件
+func (p *Policy) Add(condition any) {
+	p.Conditions = append(p.Conditions, condition)
+}
+
+// Encode 获取 JSON 格式的上传策略及其 Base64 编码
+func (p *Policy) Encode() ([]byte, string, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, "", errors.WithMessage(err, "oss: marshal post policy err")
+	}
+
+	return raw, base64.StdEncoding.EncodeToString(raw), nil
+}
+
+// PostPolicy 获取表单上传策略结果，url：表单提交地址，fields：附加的表单字段
+func (p *Policy) PostPolicy(url string, fields map[string]string) *types.PostPolicy {
+	pp := &types.PostPolicy{
+		URL:        url,
+		Fields:     make(map[string]string, len(p.fields)+len(fields)),
+		Expiration: p.expiration,
+	}
+	for k, v := range p.fields {
+		pp.Fields[k] = v
+	}
+	for k, v := range fields {
+		pp.Fields[k] = v
+	}
+
+	return pp
+}
+
+// HmacSHA1 计算 HMAC-SHA1 摘要
+func HmacSHA1(key, data string) []byte {
+	h := hmac.New(sha1.New, []byte(key))
+	h.Write([]byte(data))
+
+	return h.Sum(nil)
+}
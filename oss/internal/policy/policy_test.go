@@ -0,0 +1,49 @@
+package policy
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+func TestNew(t *testing.T) {
+	p, err := New("test/test.png", 120, types.NewPostPolicyOptions(
+		types.WithContentLengthRange(1, 1024), types.WithContentTypes("image/png")), false)
+	require.NoError(t, err)
+	raw, encoded, err := p.Encode()
+	require.NoError(t, err)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(raw), encoded)
+	assert.Contains(t, string(raw), `{"key":"test/test.png"}`)
+	assert.Contains(t, string(raw), `["content-length-range",1,1024]`)
+	assert.Contains(t, string(raw), `["eq","$Content-Type","image/png"]`)
+
+	pp := p.PostPolicy("https://example.com", map[string]string{FieldPolicy: encoded})
+	assert.Equal(t, map[string]string{
+		FieldKey:         "test/test.png",
+		FieldContentType: "image/png",
+		FieldPolicy:      encoded,
+	}, pp.Fields)
+
+	p, err = New("", 120, types.NewPostPolicyOptions(
+		types.WithKeyPrefix("upload/"), types.WithContentTypes("image/*")), false)
+	require.NoError(t, err)
+	raw, _, err = p.Encode()
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `["starts-with","$key","upload/"]`)
+	assert.Contains(t, string(raw), `["starts-with","$Content-Type","image/"]`)
+
+	_, err = New("test.png", 120, types.NewPostPolicyOptions(types.WithContentTypes("image/png", "image/jpeg")), false)
+	assert.True(t, errors.Is(err, types.ErrUnsupportedCondition))
+	_, err = New("test.png", 120, types.NewPostPolicyOptions(types.WithContentTypes("image/png", "image/jpeg")), true)
+	require.NoError(t, err)
+
+	_, err = New("test.png", 120, types.NewPostPolicyOptions(types.WithKeyPrefix("upload/")), true)
+	assert.Error(t, err)
+	_, err = New("test.png", 120, types.NewPostPolicyOptions(types.WithContentLengthRange(10, 1)), true)
+	assert.Error(t, err)
+}
@@ -0,0 +1,195 @@
+package progress
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+// DefaultPartSize 限速分片上传的默认分片大小
+const DefaultPartSize = 8 << 20
+
+// global 进程内全部传输共享的限速器
+var global atomic.Pointer[rate.Limiter]
+
+// SetGlobalLimit 设置进程内全部传输共享的带宽限制（字节/秒），为 0 时不限制
+func SetGlobalLimit(bytesPerSecond int64) {
+	if bytesPerSecond <= 0 {
+		global.Store(nil)
+		return
+	}
+
+	global.Store(newLimiter(bytesPerSecond))
+}
+
+// Tracker 传输进度跟踪器，累计已传输字节数，按令牌桶限速并回调进度
+type Tracker struct {
+	ctx      context.Context    // 上下文，上下文结束后不再限速等待
+	total    int64              // 总字节数
+	fn       types.ProgressFunc // 传输进度回调函数
+	limiters []*rate.Limiter    // 单次传输和进程内共享的限速器
+	start    time.Time          // 传输开始时间
+
+	mu       sync.Mutex // 保护已传输字节数并串行执行回调
+	consumed int64      // 已传输字节数
+}
+
+// New 新建传输进度跟踪器，total：总字节数，无法获取时为 0，未设置进度回调和带宽限制时返回 nil
+func New(ctx context.Context, total int64, oo *types.ObjectOptions) *Tracker {
+	var limiters []*rate.Limiter
+	if oo.RateLimit > 0 {
+		limiters = append(limiters, newLimiter(oo.RateLimit))
+	}
+	if l := global.Load(); l != nil {
+		limiters = append(limiters, l)
+	}
+	if oo.Progress == nil && len(limiters) == 0 {
+		return nil
+	}
+
+	return &Tracker{ctx: ctx, total: total, fn: oo.Progress, limiters: limiters, start: time.Now()}
+}
+
+// Limited 判断是否限速
+func (t *Tracker) Limited() bool {
+	return t != nil && len(t.limiters) > 0
+}
+
+// Add 记录新传输的字节数，按带宽限制等待后回调进度
+func (t *Tracker) Add(n int64) {
+	if t == nil || n <= 0 {
+		return
+	}
+
+	for _, l := range t.limiters {
+		if err := wait(t.ctx, l, n); err != nil {
+			break
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.consumed += n
+	if t.fn != nil {
+		e := types.ProgressEvent{ConsumedBytes: t.consumed, TotalBytes: t.total}
+		if elapsed := time.Since(t.start).Seconds(); elapsed > 0 {
+			e.Rate = float64(t.consumed) / elapsed
+		}
+		t.fn(e)
+	}
+}
+
+// Reader 包装读取器，读取时记录传输进度并限速
+func (t *Tracker) Reader(r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+
+	return &reader{t: t, r: r}
+}
+
+// UploadPartFunc 上传分片函数，r：已包装进度跟踪的分片数据，size：分片大小（字节），返回分片 ETag
+type UploadPartFunc func(partNumber int, r io.Reader, size int64) (string, error)
+
+// UploadParts 按分片顺序上传文件，用于 SDK 断点续传上传仅在分片完成后回调进度而无法在传输过程中限速的情况，
+// partSize 不大于 0 时使用 DefaultPartSize，分片数量超过上限时自动增大分片大小
+func UploadParts(ctx context.Context, t *Tracker, filePath string, partSize int64, fn UploadPartFunc) ([]types.Part, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "oss: open file path: %s err", filePath)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "oss: stat file path: %s err", filePath)
+	}
+
+	size := fi.Size()
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	if size > partSize*types.MaxPartNumber {
+		partSize = (size + types.MaxPartNumber - 1) / types.MaxPartNumber
+	}
+
+	var parts []types.Part
+	for number, offset := 1, int64(0); number == 1 || offset < size; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.WithMessage(err, "oss: upload parts err")
+		}
+
+		n := partSize
+		if size-offset < n {
+			n = size - offset
+		}
+		etag, err := fn(number, t.Reader(io.NewSectionReader(f, offset, n)), n)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, types.Part{PartNumber: number, ETag: etag})
+		offset += n
+	}
+
+	return parts, nil
+}
+
+// FileSize 获取文件大小，获取失败时返回 0
+func FileSize(filePath string) int64 {
+	fi, err := os.Stat(filePath)
+	if err != nil {
+		return 0
+	}
+
+	return fi.Size()
+}
+
+// reader 记录传输进度并限速的读取器
+type reader struct {
+	t *Tracker
+	r io.Reader
+}
+
+// Read 实现 io.Reader 接口
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.t.Add(int64(n))
+
+	return n, err
+}
+
+// newLimiter 新建令牌桶限速器，桶容量为 1 秒的令牌数
+func newLimiter(bytesPerSecond int64) *rate.Limiter {
+	burst := bytesPerSecond
+	if burst > 1<<30 {
+		burst = 1 << 30
+	}
+
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(burst))
+}
+
+// wait 等待 n 个令牌，n 超过桶容量时分多次等待
+func wait(ctx context.Context, l *rate.Limiter, n int64) error {
+	burst := int64(l.Burst())
+	for n > 0 {
+		k := n
+		if k > burst {
+			k = burst
+		}
+		if err := l.WaitN(ctx, int(k)); err != nil {
+			return err
+		}
+		n -= k
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package progress
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+func TestNew(t *testing.T) {
+	ctx := context.Background()
+	tr := New(ctx, 100, types.NewObjectOptions("test.txt"))
+	assert.Nil(t, tr)
+	assert.False(t, tr.Limited())
+	tr.Add(10)
+	r := strings.NewReader("test")
+	assert.Equal(t, r, tr.Reader(r))
+
+	tr = New(ctx, 100, types.NewObjectOptions("test.txt", types.WithProgress(func(e types.ProgressEvent) {})))
+	require.NotNil(t, tr)
+	assert.False(t, tr.Limited())
+
+	tr = New(ctx, 100, types.NewObjectOptions("test.txt", types.WithRateLimit(1024)))
+	require.NotNil(t, tr)
+	assert.True(t, tr.Limited())
+
+	SetGlobalLimit(1024)
+	defer SetGlobalLimit(0)
+	tr = New(ctx, 100, types.NewObjectOptions("test.txt"))
+	require.NotNil(t, tr)
+	assert.True(t, tr.Limited())
+}
+
+func TestTracker_Reader(t *testing.T) {
+	var events []types.ProgressEvent
+	oo := types.NewObjectOptions("test.txt", types.WithProgress(func(e types.ProgressEvent) {
+		events = append(events, e)
+	}), types.WithRateLimit(1000))
+	tr := New(context.Background(), 1500, oo)
+
+	// 令牌桶初始容量为 1 秒的令牌数，超出部分需要等待
+	start := time.Now()
+	data, err := io.ReadAll(tr.Reader(io.LimitReader(strings.NewReader(strings.Repeat("a", 2000)), 1500)))
+	require.NoError(t, err)
+	assert.Len(t, data, 1500)
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+
+	require.NotEmpty(t, events)
+	last := events[len(events)-1]
+	assert.Equal(t, int64(1500), last.ConsumedBytes)
+	assert.Equal(t, int64(1500), last.TotalBytes)
+	assert.InDelta(t, 100, last.Percent(), 0.001)
+	assert.Greater(t, last.Rate, float64(0))
+}
+
+func TestTracker_Add_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var consumed int64
+	tr := New(ctx, 0, types.NewObjectOptions("test.txt", types.WithProgress(func(e types.ProgressEvent) {
+		consumed = e.ConsumedBytes
+	}), types.WithRateLimit(1)))
+
+	// 上下文结束后不再等待
+	start := time.Now()
+	tr.Add(100)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int64(100), consumed)
+}
+
+func TestUploadParts(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "test.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("hello world"), 0o644))
+	assert.Equal(t, int64(11), FileSize(filePath))
+	assert.Zero(t, FileSize(filepath.Join(t.TempDir(), "none.txt")))
+
+	var consumed int64
+	oo := types.NewObjectOptions(filePath, types.WithProgress(func(e types.ProgressEvent) {
+		consumed = e.ConsumedBytes
+	}))
+	tr := New(context.Background(), FileSize(filePath), oo)
+
+	var uploaded []string
+	parts, err := UploadParts(context.Background(), tr, filePath, 4, func(partNumber int, r io.Reader, size int64) (string, error) {
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, size, int64(len(data)))
+		uploaded = append(uploaded, string(data))
+		return "etag-" + string(data), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hell", "o wo", "rld"}, uploaded)
+	assert.Equal(t, []types.Part{
+		{PartNumber: 1, ETag: "etag-hell"},
+		{PartNumber: 2, ETag: "etag-o wo"},
+		{PartNumber: 3, ETag: "etag-rld"},
+	}, parts)
+	assert.Equal(t, int64(11), consumed)
+
+	// 空文件上传一个空分片
+	emptyPath := filepath.Join(t.TempDir(), "empty.txt")
+	require.NoError(t, os.WriteFile(emptyPath, nil, 0o644))
+	parts, err = UploadParts(context.Background(), nil, emptyPath, 0, func(partNumber int, r io.Reader, size int64) (string, error) {
+		assert.Zero(t, size)
+		return "etag", nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, parts, 1)
+
+	_, err = UploadParts(context.Background(), nil, filepath.Join(t.TempDir(), "none.txt"), 0, nil)
+	require.Error(t, err)
+}
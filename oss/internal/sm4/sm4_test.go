@@ -0,0 +1,66 @@
+package sm4
+
+import (
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSM4(t *testing.T) {
+	cases := []struct {
+		key    string
+		data   string
+		expect string
+	}{
+		{key: "0123456789abcdeffedcba9876543210", data: "0123456789abcdeffedcba9876543210", expect: "681edf34d206965e86b3e94f536e4246"},
+		{key: "000102030405060708090a0b0c0d0e0f", data: hex.EncodeToString([]byte("hello sm4 block!")), expect: "365e494e81da3ea4e0cf6ccd360c6efb"},
+	}
+
+	for _, c := range cases {
+		key, _ := hex.DecodeString(c.key)
+		data, _ := hex.DecodeString(c.data)
+		block, err := NewCipher(key)
+		require.NoError(t, err)
+		assert.Equal(t, BlockSize, block.BlockSize())
+
+		dst := make([]byte, BlockSize)
+		block.Encrypt(dst, data)
+		assert.Equal(t, c.expect, hex.EncodeToString(dst))
+		block.Decrypt(dst, dst)
+		assert.Equal(t, data, dst)
+	}
+}
+
+func TestSM4_Iterations(t *testing.T) {
+	key, _ := hex.DecodeString("0123456789abcdeffedcba9876543210")
+	block, err := NewCipher(key)
+	require.NoError(t, err)
+
+	dst := make([]byte, BlockSize)
+	copy(dst, key)
+	for i := 0; i < 1000000; i++ {
+		block.Encrypt(dst, dst)
+	}
+	assert.Equal(t, "595298c7c6fd271f0402f804c33d3f66", hex.EncodeToString(dst))
+}
+
+func TestSM4_GCM(t *testing.T) {
+	block, err := NewCipher(make([]byte, KeySize))
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	nonce := make([]byte, aead.NonceSize())
+	sealed := aead.Seal(nil, nonce, []byte("test sm4 gcm"), nil)
+	opened, err := aead.Open(nil, nonce, sealed, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "test sm4 gcm", string(opened))
+}
+
+func TestNewCipher(t *testing.T) {
+	_, err := NewCipher(make([]byte, 32))
+	assert.EqualError(t, err, "sm4: invalid key size 32")
+}
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+// TrimETag 去除 ETag 两侧的双引号
+func TrimETag(etag string) string {
+	return strings.Trim(etag, `"`)
+}
+
+// LowerKeys 将用户自定义元数据的键统一转换为小写
+func LowerKeys(metadata map[string]string) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	m := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		m[strings.ToLower(k)] = v
+	}
+
+	return m
+}
+
+// FillNextMarker 在列举结果被截断且未返回下一页分页标记时，使用最后一个对象键或公共前缀填充
+func FillNextMarker(r *types.ListObjectsResult) {
+	if !r.IsTruncated || r.NextMarker != "" {
+		return
+	}
+
+	if n := len(r.Objects); n > 0 {
+		r.NextMarker = r.Objects[n-1].Key
+	}
+	if n := len(r.CommonPrefixes); n > 0 && r.CommonPrefixes[n-1] > r.NextMarker {
+		r.NextMarker = r.CommonPrefixes[n-1]
+	}
+}
+
+// SkipImageCache 从列举结果中去除处理后图片缓存对象及其公共前缀，需在 FillNextMarker 之后调用，
+// prefix：列举前缀，位于 types.ImageCachePrefix 保留前缀下时不去除
+func SkipImageCache(r *types.ListObjectsResult, prefix string) {
+	if types.IsImageCacheKey(prefix) {
+		return
+	}
+
+	objects := r.Objects[:0]
+	for _, o := range r.Objects {
+		if !types.IsImageCacheKey(o.Key) {
+			objects = append(objects, o)
+		}
+	}
+	r.Objects = objects
+
+	prefixes := r.CommonPrefixes[:0]
+	for _, p := range r.CommonPrefixes {
+		if !types.IsImageCacheKey(p) {
+			prefixes = append(prefixes, p)
+		}
+	}
+	r.CommonPrefixes = prefixes
+}
+
+// SortParts 获取按分片序号升序排列的分片列表副本
+func SortParts(parts []types.Part) []types.Part {
+	sorted := make([]types.Part, len(parts))
+	copy(sorted, parts)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].PartNumber < sorted[j].PartNumber
+	})
+
+	return sorted
+}
@@ -0,0 +1,66 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+func TestTrimETag(t *testing.T) {
+	assert.Equal(t, "abc", TrimETag(`"abc"`))
+	assert.Equal(t, "abc", TrimETag("abc"))
+}
+
+func TestLowerKeys(t *testing.T) {
+	assert.Nil(t, LowerKeys(nil))
+	assert.Equal(t, map[string]string{"owner": "test"}, LowerKeys(map[string]string{"Owner": "test"}))
+}
+
+func TestFillNextMarker(t *testing.T) {
+	r := &types.ListObjectsResult{
+		Objects:        []types.ObjectInfo{{Key: "a.txt"}, {Key: "c.txt"}},
+		CommonPrefixes: []string{"b/"},
+		IsTruncated:    true,
+	}
+	FillNextMarker(r)
+	assert.Equal(t, "c.txt", r.NextMarker)
+
+	r = &types.ListObjectsResult{
+		Objects:        []types.ObjectInfo{{Key: "a.txt"}},
+		CommonPrefixes: []string{"b/"},
+		IsTruncated:    true,
+	}
+	FillNextMarker(r)
+	assert.Equal(t, "b/", r.NextMarker)
+
+	r = &types.ListObjectsResult{NextMarker: "x", IsTruncated: true}
+	FillNextMarker(r)
+	assert.Equal(t, "x", r.NextMarker)
+}
+
+func TestSkipImageCache(t *testing.T) {
+	r := &types.ListObjectsResult{
+		Objects:        []types.ObjectInfo{{Key: ".image-cache/a.png@0123456789abcdef.png"}, {Key: "a.png"}},
+		CommonPrefixes: []string{".image-cache/", "b/"},
+		IsTruncated:    true,
+		NextMarker:     "b/",
+	}
+	SkipImageCache(r, "")
+	assert.Equal(t, []types.ObjectInfo{{Key: "a.png"}}, r.Objects)
+	assert.Equal(t, []string{"b/"}, r.CommonPrefixes)
+	assert.Equal(t, "b/", r.NextMarker)
+
+	// 列举保留前缀时不去除
+	r = &types.ListObjectsResult{Objects: []types.ObjectInfo{{Key: ".image-cache/a.png@0123456789abcdef.png"}}}
+	SkipImageCache(r, ".image-cache/")
+	assert.Len(t, r.Objects, 1)
+}
+
+func TestSortParts(t *testing.T) {
+	parts := []types.Part{{PartNumber: 3}, {PartNumber: 1}, {PartNumber: 2}}
+	sorted := SortParts(parts)
+	assert.Equal(t, []types.Part{{PartNumber: 1}, {PartNumber: 2}, {PartNumber: 3}}, sorted)
+	assert.Equal(t, 3, parts[0].PartNumber)
+}
@@ -0,0 +1,50 @@
+package xio
+
+import (
+	"context"
+	"io"
+)
+
+// ctxReader 感知上下文的读取器
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// NewCtxReader 新建感知上下文的读取器，上下文结束后读取将返回上下文错误
+func NewCtxReader(ctx context.Context, r io.Reader) io.Reader {
+	if ctx == nil || ctx.Done() == nil {
+		return r
+	}
+
+	return &ctxReader{ctx: ctx, r: r}
+}
+
+// Read 实现 io.Reader 接口
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.r.Read(p)
+}
+
+// ctxReadCloser 感知上下文的读取关闭器
+type ctxReadCloser struct {
+	io.Reader
+	c io.Closer
+}
+
+// NewCtxReadCloser 新建感知上下文的读取关闭器，上下文结束后读取将返回上下文错误
+func NewCtxReadCloser(ctx context.Context, rc io.ReadCloser) io.ReadCloser {
+	if ctx == nil || ctx.Done() == nil {
+		return rc
+	}
+
+	return &ctxReadCloser{Reader: &ctxReader{ctx: ctx, r: rc}, c: rc}
+}
+
+// Close 实现 io.Closer 接口
+func (rc *ctxReadCloser) Close() error {
+	return rc.c.Close()
+}
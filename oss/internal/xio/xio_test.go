@@ -0,0 +1,38 @@
+package xio
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCtxReader(t *testing.T) {
+	r := strings.NewReader("test")
+	assert.Equal(t, r, NewCtxReader(context.Background(), r))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cr := NewCtxReader(ctx, r)
+	b := make([]byte, 2)
+	n, err := cr.Read(b)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	cancel()
+	_, err = cr.Read(b)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestNewCtxReadCloser(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rc := NewCtxReadCloser(ctx, io.NopCloser(strings.NewReader("test")))
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "test", string(b))
+	require.NoError(t, rc.Close())
+}
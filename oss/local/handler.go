@@ -0,0 +1,149 @@
+package local
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+// metaHeaderPrefix 用户自定义元数据响应头前缀
+const metaHeaderPrefix = "X-Lss-Meta-"
+
+// Handler 获取本地 LSS 的 http.Handler，请求路径即为对象键，挂载在子路径下时需配合 http.StripPrefix 使用：
+// GET/HEAD 读取对象，支持 Range、If-None-Match 等条件请求，携带签名或开启私有模式时校验签名 URL；
+// PUT 通过 AuthorizedUpload 或 PresignedUploadPartURL 生成的签名 URL 上传对象或分片；
+// POST 处理 PostPolicy 生成的表单上传，同 PostPolicyHandler
+func (l *LSS) Handler() http.Handler {
+	return http.HandlerFunc(l.serveHTTP)
+}
+
+// serveHTTP 处理对象请求
+func (l *LSS) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		l.servePostPolicy(w, r)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	if !validKey(key) {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		l.serveGet(w, r, key)
+	case http.MethodPut:
+		l.servePut(w, r, key)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// serveGet 处理读取对象请求
+func (l *LSS) serveGet(w http.ResponseWriter, r *http.Request, key string) {
+	query := r.URL.Query()
+	signed := query.Has(QuerySignature)
+	if signed || l.private {
+		if err := l.VerifySignature(http.MethodGet, key, query); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	info, err := l.statObject(key, false)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	meta, err := l.readMeta(key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f, err := os.Open(l.objectPath(key))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	h := w.Header()
+	setHeader(h, "Content-Type", meta.ContentType)
+	setHeader(h, "Content-Disposition", meta.ContentDisposition)
+	setHeader(h, "Content-Encoding", meta.ContentEncoding)
+	setHeader(h, "Cache-Control", meta.CacheControl)
+	for k, v := range meta.Metadata {
+		h.Set(metaHeaderPrefix+k, v)
+	}
+	if signed {
+		// 签名 URL 可覆盖响应头，参数已包含在签名中
+		setHeader(h, "Content-Type", query.Get(types.QueryResponseContentType))
+		setHeader(h, "Content-Disposition", query.Get(types.QueryResponseContentDisposition))
+		setHeader(h, "Cache-Control", query.Get(types.QueryResponseCacheControl))
+	}
+	h.Set("ETag", `"`+info.ETag+`"`)
+
+	http.ServeContent(w, r, "", info.LastModified, f)
+}
+
+// servePut 处理签名 URL 上传对象或分片请求
+func (l *LSS) servePut(w http.ResponseWriter, r *http.Request, key string) {
+	query := r.URL.Query()
+	if err := l.VerifySignature(http.MethodPut, key, query); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	if uploadID := query.Get(QueryUploadID); uploadID != "" {
+		partNumber, _ := strconv.Atoi(query.Get(QueryPartNumber))
+		etag, err := l.UploadPart(r.Context(), key, uploadID, partNumber, r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		w.Header().Set("ETag", `"`+etag+`"`)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if _, err := l.PutObjectCtx(r.Context(), key, r.Body,
+		types.WithContentType(r.Header.Get("Content-Type")),
+		types.WithContentDisposition(r.Header.Get("Content-Disposition")),
+		types.WithContentEncoding(r.Header.Get("Content-Encoding")),
+		types.WithCacheControl(r.Header.Get("Cache-Control")),
+	); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+	if info, err := l.statObject(key, false); err == nil {
+		w.Header().Set("ETag", `"`+info.ETag+`"`)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// setHeader 设置非空响应头
+func setHeader(h http.Header, key, value string) {
+	if value != "" {
+		h.Set(key, value)
+	}
+}
+
+// errorStatus 获取错误对应的 HTTP 状态码
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, types.ErrUploadNotFound), errors.Is(err, types.ErrObjectNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, types.ErrInvalidPartNumber), errors.Is(err, types.ErrInvalidPart),
+		errors.Is(err, ErrContentLengthOutOfRange):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
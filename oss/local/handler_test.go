@@ -0,0 +1,193 @@
+package local
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+func TestLSS_Handler(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName, WithSecretKey("test-secret"))
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	ts := httptest.NewServer(lss.Handler())
+	defer ts.Close()
+
+	// 通过 AuthorizedUpload 生成的签名 URL 上传
+	signedURL, err := lss.AuthorizedUpload("test/test.txt", 120)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPut, serverURL(t, ts, signedURL), strings.NewReader("hello world"))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// 未签名的上传被拒绝
+	req, err = http.NewRequest(http.MethodPut, ts.URL+"/test/test.txt", strings.NewReader("test"))
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// 读取对象
+	resp, err = http.Get(ts.URL + "/test/test.txt")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "hello world", string(data))
+	assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+	assert.Equal(t, etag, resp.Header.Get("ETag"))
+
+	// Range 请求
+	req, err = http.NewRequest(http.MethodGet, ts.URL+"/test/test.txt", nil)
+	require.NoError(t, err)
+	req.Header.Set("Range", "bytes=6-")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	data, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	assert.Equal(t, "world", string(data))
+
+	// If-None-Match 条件请求
+	req, err = http.NewRequest(http.MethodGet, ts.URL+"/test/test.txt", nil)
+	require.NoError(t, err)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	// 签名 URL 覆盖响应头，篡改后签名无效
+	signedURL, err = lss.PresignedGetURL("test/test.txt", 120,
+		types.WithResponseContentDisposition(`attachment; filename="test.txt"`))
+	require.NoError(t, err)
+	resp, err = http.Get(serverURL(t, ts, signedURL))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `attachment; filename="test.txt"`, resp.Header.Get("Content-Disposition"))
+	resp, err = http.Get(strings.Replace(serverURL(t, ts, signedURL), "attachment", "inline", 1))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// 对象不存在、路径穿越和保留目录
+	for _, p := range []string{"/test/none.txt", "/../local.go", "/test/../../local.go", "/.lss/meta/test/test.txt.json"} {
+		req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+		require.NoError(t, err)
+		req.URL.Path = p
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode, p)
+	}
+
+	// 不支持的请求方法
+	req, err = http.NewRequest(http.MethodDelete, ts.URL+"/test/test.txt", nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestLSS_HandlerPrivate(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName, WithSecretKey("test-secret"), WithPrivate())
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	_, err = lss.PutObject("test/test.txt", strings.NewReader("test"))
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(lss.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/test/test.txt")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	signedURL, err := lss.PresignedGetURL("test/test.txt", 120)
+	require.NoError(t, err)
+	resp, err = http.Get(serverURL(t, ts, signedURL))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestLSS_HandlerUploadPart(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName, WithSecretKey("test-secret"))
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	ts := httptest.NewServer(lss.Handler())
+	defer ts.Close()
+
+	ctx := context.Background()
+	key := "test/multipart.txt"
+	uploadID, err := lss.InitiateMultipartUpload(ctx, key)
+	require.NoError(t, err)
+
+	var parts []types.Part
+	for i, content := range []string{"hello ", "world"} {
+		signedURL, err := lss.PresignedUploadPartURL(key, uploadID, i+1, 120)
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPut, serverURL(t, ts, signedURL), strings.NewReader(content))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		parts = append(parts, types.Part{PartNumber: i + 1, ETag: resp.Header.Get("ETag")})
+	}
+
+	_, err = lss.CompleteMultipartUpload(ctx, key, uploadID, parts)
+	require.NoError(t, err)
+
+	resp, err := http.Get(ts.URL + "/" + key)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "hello world", string(data))
+
+	// 分片上传已完成
+	signedURL, err := lss.PresignedUploadPartURL(key, uploadID, 3, 120)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPut, serverURL(t, ts, signedURL), strings.NewReader("test"))
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// serverURL 将签名 URL 的访问域名替换为测试服务地址
+func serverURL(t *testing.T, ts *httptest.Server, signedURL string) string {
+	t.Helper()
+
+	u, err := url.Parse(signedURL)
+	require.NoError(t, err)
+	su, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	u.Scheme, u.Host = su.Scheme, su.Host
+
+	return u.String()
+}
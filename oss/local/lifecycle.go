@@ -0,0 +1,215 @@
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+// lifecycleFile 生命周期规则文件
+const lifecycleFile = reservedDir + "/lifecycle.json"
+
+// lifecycleRule 生命周期规则
+type lifecycleRule struct {
+	ID                       string `json:"id"`                                    // 规则 ID
+	Prefix                   string `json:"prefix,omitempty"`                      // 对象键前缀
+	ExpirationDays           int    `json:"expiration_days,omitempty"`             // 对象过期删除天数
+	AbortMultipartUploadDays int    `json:"abort_multipart_upload_days,omitempty"` // 分片上传取消天数
+	Disabled                 bool   `json:"disabled,omitempty"`                    // 是否停用
+}
+
+// SetLifecycleRules 设置本地 LSS 存储桶的生命周期规则，覆盖已有规则，rules 为空时删除全部规则，
+// 规则由 Sweep 或 WithSweepInterval 开启的后台清理执行
+func (l *LSS) SetLifecycleRules(ctx context.Context, rules []types.LifecycleRule) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithMessage(err, "local: lss set lifecycle err")
+	}
+	rules, err := types.NormalizeLifecycleRules(rules)
+	if err != nil {
+		return err
+	}
+
+	destPath := filepath.Join(l.bucketName, lifecycleFile)
+	if len(rules) == 0 {
+		if err := os.Remove(destPath); err != nil && !os.IsNotExist(err) {
+			return errors.WithMessagef(err, "local: lss remove lifecycle path: %s err", destPath)
+		}
+		return nil
+	}
+
+	lrs := make([]lifecycleRule, 0, len(rules))
+	for _, r := range rules {
+		lrs = append(lrs, lifecycleRule(r))
+	}
+	data, err := json.Marshal(lrs)
+	if err != nil {
+		return errors.WithMessage(err, "local: lss marshal lifecycle err")
+	}
+	if err := l.mkdir(destPath); err != nil {
+		return err
+	}
+
+	return errors.WithMessagef(os.WriteFile(destPath, data, 0o644),
+		"local: lss write lifecycle path: %s err", destPath)
+}
+
+// GetLifecycleRules 获取本地 LSS 存储桶的生命周期规则
+func (l *LSS) GetLifecycleRules(ctx context.Context) ([]types.LifecycleRule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithMessage(err, "local: lss get lifecycle err")
+	}
+
+	data, err := os.ReadFile(filepath.Join(l.bucketName, lifecycleFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WithMessage(err, "local: lss read lifecycle err")
+	}
+
+	var lrs []lifecycleRule
+	if err := json.Unmarshal(data, &lrs); err != nil {
+		return nil, errors.WithMessage(err, "local: lss unmarshal lifecycle err")
+	}
+	rules := make([]types.LifecycleRule, 0, len(lrs))
+	for _, r := range lrs {
+		rules = append(rules, types.LifecycleRule(r))
+	}
+
+	return rules, nil
+}
+
+// Sweep 执行一次生命周期清理，删除最后修改时间超过过期天数的对象，并取消初始化时间超过取消天数的分片上传，
+// 返回删除的对象数量和取消的分片上传数量
+func (l *LSS) Sweep(ctx context.Context) (expired, aborted int, err error) {
+	rules, err := l.GetLifecycleRules(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	now := time.Now()
+	for _, r := range rules {
+		if r.Disabled {
+			continue
+		}
+		if r.ExpirationDays > 0 {
+			n, err := l.expireObjects(ctx, &r, now.AddDate(0, 0, -r.ExpirationDays))
+			expired += n
+			if err != nil {
+				return expired, aborted, err
+			}
+		}
+		if r.AbortMultipartUploadDays > 0 {
+			n, err := l.abortUploads(ctx, &r, now.AddDate(0, 0, -r.AbortMultipartUploadDays))
+			aborted += n
+			if err != nil {
+				return expired, aborted, err
+			}
+		}
+	}
+
+	return expired, aborted, nil
+}
+
+// Close 停止后台生命周期清理
+func (l *LSS) Close() error {
+	if l.cancel != nil {
+		l.cancel()
+		l.wg.Wait()
+	}
+
+	return nil
+}
+
+// expireObjects 删除匹配规则且最后修改时间不晚于截止时间的对象
+func (l *LSS) expireObjects(ctx context.Context, r *types.LifecycleRule, deadline time.Time) (int, error) {
+	keys, err := l.walkKeys(ctx, r.Prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, key := range keys {
+		fi, err := os.Stat(l.objectPath(key))
+		if err != nil || fi.ModTime().After(deadline) {
+			continue
+		}
+		if err := l.DeleteObjectsCtx(ctx, key); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// abortUploads 取消匹配规则且初始化时间不晚于截止时间的分片上传
+func (l *LSS) abortUploads(ctx context.Context, r *types.LifecycleRule, deadline time.Time) (int, error) {
+	entries, err := os.ReadDir(filepath.Join(l.bucketName, multipartDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, errors.WithMessage(err, "local: lss read multipart dir err")
+	}
+
+	n := 0
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return n, errors.WithMessage(err, "local: lss abort uploads err")
+		}
+
+		uploadPath := l.uploadPath(entry.Name())
+		data, err := os.ReadFile(filepath.Join(uploadPath, uploadFile))
+		if err != nil {
+			continue
+		}
+		var info uploadInfo
+		if err := json.Unmarshal(data, &info); err != nil ||
+			!r.Match(info.Key) || info.Initiated.After(deadline) {
+			continue
+		}
+		if err := os.RemoveAll(uploadPath); err != nil {
+			return n, errors.WithMessagef(err, "local: lss remove upload path: %s err", uploadPath)
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// startSweeper 启动后台生命周期清理
+func (l *LSS) startSweeper(interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	l.wg.Add(1)
+
+	threading.GoSafe(func() {
+		defer l.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				expired, aborted, err := l.Sweep(ctx)
+				if err != nil && ctx.Err() == nil {
+					logx.Errorf("local: lss sweep err: %v", err)
+				}
+				if expired > 0 || aborted > 0 {
+					logx.Infof("local: lss sweep expired %d objects, aborted %d uploads", expired, aborted)
+				}
+			}
+		}
+	})
+}
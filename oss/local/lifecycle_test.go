@@ -0,0 +1,139 @@
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+func TestLSS_LifecycleRules(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName)
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	ctx := context.Background()
+	rules, err := lss.GetLifecycleRules(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	err = lss.SetLifecycleRules(ctx, []types.LifecycleRule{{Prefix: "export/"}})
+	require.ErrorIs(t, err, types.ErrInvalidLifecycleRule)
+
+	err = lss.SetLifecycleRules(ctx, []types.LifecycleRule{
+		types.NewExpirationRule("export/", 7),
+		{ID: "upload", AbortMultipartUploadDays: 1, Disabled: true},
+	})
+	require.NoError(t, err)
+	rules, err = lss.GetLifecycleRules(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []types.LifecycleRule{
+		{ID: "rule-1", Prefix: "export/", ExpirationDays: 7},
+		{ID: "upload", AbortMultipartUploadDays: 1, Disabled: true},
+	}, rules)
+
+	require.NoError(t, lss.SetLifecycleRules(ctx, nil))
+	rules, err = lss.GetLifecycleRules(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+}
+
+func TestLSS_Sweep(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName)
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	ctx := context.Background()
+	old := time.Now().AddDate(0, 0, -8)
+	for _, key := range []string{"export/old.csv", "export/new.csv", "upload/old.csv"} {
+		_, err := lss.PutObjectCtx(ctx, key, strings.NewReader("test-oss"), types.WithMetadata(map[string]string{"k": "v"}))
+		require.NoError(t, err)
+	}
+	require.NoError(t, os.Chtimes(lss.objectPath("export/old.csv"), old, old))
+	require.NoError(t, os.Chtimes(lss.objectPath("upload/old.csv"), old, old))
+
+	oldUpload, err := lss.InitiateMultipartUpload(ctx, "export/big.zip")
+	require.NoError(t, err)
+	setInitiated(t, lss, oldUpload, time.Now().AddDate(0, 0, -2))
+	newUpload, err := lss.InitiateMultipartUpload(ctx, "export/big.zip")
+	require.NoError(t, err)
+	otherUpload, err := lss.InitiateMultipartUpload(ctx, "upload/big.zip")
+	require.NoError(t, err)
+	setInitiated(t, lss, otherUpload, time.Now().AddDate(0, 0, -2))
+
+	expired, aborted, err := lss.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, expired)
+	assert.Zero(t, aborted)
+
+	require.NoError(t, lss.SetLifecycleRules(ctx, []types.LifecycleRule{
+		types.NewExpirationRule("export/", 7),
+		types.NewAbortMultipartUploadRule("export/", 1),
+		{Prefix: "upload/", ExpirationDays: 1, AbortMultipartUploadDays: 1, Disabled: true},
+	}))
+	expired, aborted, err = lss.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	assert.Equal(t, 1, aborted)
+
+	_, err = lss.StatObject(ctx, "export/old.csv")
+	require.ErrorIs(t, err, types.ErrObjectNotFound)
+	assert.NoFileExists(t, lss.metaPath("export/old.csv"))
+	for _, key := range []string{"export/new.csv", "upload/old.csv"} {
+		ok, err := lss.Exists(ctx, key)
+		require.NoError(t, err)
+		assert.True(t, ok, key)
+	}
+
+	_, err = lss.ListParts(ctx, "export/big.zip", oldUpload)
+	require.ErrorIs(t, err, types.ErrUploadNotFound)
+	_, err = lss.ListParts(ctx, "export/big.zip", newUpload)
+	require.NoError(t, err)
+	_, err = lss.ListParts(ctx, "upload/big.zip", otherUpload)
+	require.NoError(t, err)
+}
+
+func TestLSS_Sweeper(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName, WithSweepInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+	defer lss.Close()
+
+	ctx := context.Background()
+	_, err = lss.PutObjectCtx(ctx, "export/old.csv", strings.NewReader("test-oss"))
+	require.NoError(t, err)
+	old := time.Now().AddDate(0, 0, -2)
+	require.NoError(t, os.Chtimes(lss.objectPath("export/old.csv"), old, old))
+	require.NoError(t, lss.SetLifecycleRules(ctx, []types.LifecycleRule{types.NewExpirationRule("export/", 1)}))
+
+	assert.Eventually(t, func() bool {
+		ok, err := lss.Exists(ctx, "export/old.csv")
+		return err == nil && !ok
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, lss.Close())
+	require.NoError(t, lss.Close())
+}
+
+// setInitiated 修改分片上传的初始化时间
+func setInitiated(t *testing.T, lss *LSS, uploadID string, initiated time.Time) {
+	t.Helper()
+
+	infoPath := filepath.Join(lss.uploadPath(uploadID), uploadFile)
+	data, err := os.ReadFile(infoPath)
+	require.NoError(t, err)
+	var info uploadInfo
+	require.NoError(t, json.Unmarshal(data, &info))
+	info.Initiated = initiated
+	data, err = json.Marshal(&info)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(infoPath, data, 0o644))
+}
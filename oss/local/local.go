@@ -1,22 +1,55 @@
 package local
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/sliveryou/go-tool/v2/filex"
+
+	"github.com/sliveryou/micro-pkg/oss/internal/imaging"
+	"github.com/sliveryou/micro-pkg/oss/internal/progress"
+	"github.com/sliveryou/micro-pkg/oss/internal/util"
+	"github.com/sliveryou/micro-pkg/oss/internal/xio"
+	"github.com/sliveryou/micro-pkg/oss/types"
+	"github.com/sliveryou/micro-pkg/xhttp"
 )
 
 const (
 	// CloudLocal 云服务商：本地
 	CloudLocal = "local"
+
+	// reservedDir 存储桶内保留目录，用于存放对象元数据等内部数据
+	reservedDir = ".lss"
+	// metaDir 对象元数据目录
+	metaDir = reservedDir + "/meta"
+	// tmpDir 临时文件目录
+	tmpDir = reservedDir + "/tmp"
+	// metaExt 对象元数据文件扩展名
+	metaExt = ".json"
 )
 
+// objectMeta 对象元数据
+type objectMeta struct {
+	ContentType        string            `json:"content_type,omitempty"`        // 内容类型
+	ContentDisposition string            `json:"content_disposition,omitempty"` // 内容展示方式
+	ContentEncoding    string            `json:"content_encoding,omitempty"`    // 内容编码
+	CacheControl       string            `json:"cache_control,omitempty"`       // 缓存控制
+	Metadata           map[string]string `json:"metadata,omitempty"`            // 用户自定义元数据
+}
+
 // Option 可选配置
 type Option func(l *LSS)
 
@@ -30,11 +63,40 @@ func WithSecure(secure ...bool) Option {
 	}
 }
 
+// WithPrivate 使用私有读配置，开启后通过 Handler 读取对象需使用签名 URL
+func WithPrivate(private ...bool) Option {
+	return func(l *LSS) {
+		l.private = true
+		if len(private) > 0 {
+			l.private = private[0]
+		}
+	}
+}
+
+// WithSecretKey 使用签名密钥，用于生成和校验签名 URL
+func WithSecretKey(secretKey string) Option {
+	return func(l *LSS) {
+		l.secretKey = secretKey
+	}
+}
+
+// WithSweepInterval 使用后台生命周期清理，按 interval 间隔执行 Sweep，为 0 时不开启，不再使用时需调用 Close 停止
+func WithSweepInterval(interval time.Duration) Option {
+	return func(l *LSS) {
+		l.sweepInterval = interval
+	}
+}
+
 // LSS 本地 LSS 客户端
 type LSS struct {
-	secure     bool   // 是否使用安全配置
-	endpoint   string // 端节点
-	bucketName string // 存储桶名称
+	secure        bool               // 是否使用安全配置
+	private       bool               // 是否使用私有读配置
+	endpoint      string             // 端节点
+	bucketName    string             // 存储桶名称
+	secretKey     string             // 签名密钥
+	sweepInterval time.Duration      // 后台生命周期清理间隔
+	cancel        context.CancelFunc // 停止后台生命周期清理
+	wg            sync.WaitGroup     // 后台生命周期清理等待组
 }
 
 // NewLSS 创建一个本地 LSS 客户端
@@ -47,6 +109,9 @@ func NewLSS(endpoint, bucketName string, opts ...Option) (*LSS, error) {
 	for _, opt := range opts {
 		opt(l)
 	}
+	if l.sweepInterval > 0 {
+		l.startSweeper(l.sweepInterval)
+	}
 
 	return l, nil
 }
@@ -75,31 +140,38 @@ func (l *LSS) GetURL(key string) string {
 
 // GetObject 获取对象在本地 LSS 的存储数据
 func (l *LSS) GetObject(key string) (io.ReadCloser, error) {
-	destPath := filepath.Join(l.bucketName, key)
+	return l.GetObjectCtx(context.Background(), key)
+}
+
+// GetObjectCtx 获取对象在本地 LSS 的存储数据
+func (l *LSS) GetObjectCtx(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithMessage(err, "local: lss get object err")
+	}
+
+	destPath := l.objectPath(key)
 	obj, err := os.Open(destPath)
 	if err != nil {
 		return nil, errors.WithMessage(err, "local: lss get object err")
 	}
 
-	return obj, nil
+	return xio.NewCtxReadCloser(ctx, obj), nil
 }
 
 // PutObject 上传对象至本地 LSS
 func (l *LSS) PutObject(key string, reader io.Reader) (string, error) {
-	destPath := filepath.Join(l.bucketName, key)
-	if err := l.mkdir(destPath); err != nil {
-		return "", err
-	}
+	return l.PutObjectCtx(context.Background(), key, reader)
+}
 
-	dest, err := os.Create(destPath)
-	if err != nil {
-		return "", errors.WithMessagef(err, "local: lss create dest path: %s err", destPath)
+// PutObjectCtx 上传对象至本地 LSS，opts：对象可选配置
+func (l *LSS) PutObjectCtx(ctx context.Context, key string, reader io.Reader, opts ...types.ObjectOption) (string, error) {
+	oo := types.NewObjectOptions(key, opts...)
+	total, _ := xhttp.GetReaderLen(reader)
+	if err := l.writeObject(ctx, key, progress.New(ctx, total, oo).Reader(reader)); err != nil {
+		return "", err
 	}
-	defer dest.Close()
-
-	_, err = io.Copy(dest, reader)
-	if err != nil {
-		return "", errors.WithMessage(err, "local: lss copy reader to dest err")
+	if err := l.writeMeta(key, toObjectMeta(oo)); err != nil {
+		return "", err
 	}
 
 	return l.GetURL(key), nil
@@ -107,9 +179,17 @@ func (l *LSS) PutObject(key string, reader io.Reader) (string, error) {
 
 // DeleteObjects 批量删除本地 LSS 上的对象
 func (l *LSS) DeleteObjects(keys ...string) error {
+	return l.DeleteObjectsCtx(context.Background(), keys...)
+}
+
+// DeleteObjectsCtx 批量删除本地 LSS 上的对象
+func (l *LSS) DeleteObjectsCtx(ctx context.Context, keys ...string) error {
 	for _, key := range keys {
-		destPath := filepath.Join(l.bucketName, key)
-		_ = os.Remove(destPath)
+		if err := ctx.Err(); err != nil {
+			return errors.WithMessage(err, "local: lss delete objects err")
+		}
+		_ = os.Remove(l.objectPath(key))
+		_ = os.Remove(l.metaPath(key))
 	}
 
 	return nil
@@ -117,36 +197,169 @@ func (l *LSS) DeleteObjects(keys ...string) error {
 
 // UploadFile 上传文件至本地 LSS，filePath：文件路径，partSize：分块大小（字节），routines：并发数
 func (l *LSS) UploadFile(key, filePath string, partSize int64, routines int) (string, error) {
-	destPath := filepath.Join(l.bucketName, key)
-	if err := l.mkdir(destPath); err != nil {
-		return "", err
-	}
+	return l.UploadFileCtx(context.Background(), key, filePath, partSize, routines)
+}
 
-	if filePath != destPath {
+// UploadFileCtx 上传文件至本地 LSS，filePath：文件路径，partSize：分块大小（字节），routines：并发数，opts：对象可选配置
+func (l *LSS) UploadFileCtx(ctx context.Context, key, filePath string, partSize int64, routines int, opts ...types.ObjectOption) (string, error) {
+	oo := types.NewObjectOptions(key, opts...)
+	if destPath := l.objectPath(key); filePath != destPath {
 		src, err := os.Open(filePath)
 		if err != nil {
 			return "", errors.WithMessagef(err, "local: lss open file path: %s err", filePath)
 		}
 		defer src.Close()
 
-		dest, err := os.Create(destPath)
-		if err != nil {
-			return "", errors.WithMessagef(err, "local: lss create dest path: %s err", destPath)
+		if err := l.writeObject(ctx, key, progress.New(ctx, progress.FileSize(filePath), oo).Reader(src)); err != nil {
+			return "", err
 		}
-		defer dest.Close()
+	}
+	if err := l.writeMeta(key, toObjectMeta(oo)); err != nil {
+		return "", err
+	}
 
-		_, err = io.Copy(dest, src)
+	return l.GetURL(key), nil
+}
+
+// CopyObject 在本地 LSS 复制对象，源对象不存在时返回 types.ErrObjectNotFound
+func (l *LSS) CopyObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	if _, err := l.StatObject(ctx, srcKey); err != nil {
+		return "", errors.WithMessage(err, "local: lss copy object err")
+	}
+	if srcKey == dstKey {
+		return l.GetURL(dstKey), nil
+	}
+
+	src, err := os.Open(l.objectPath(srcKey))
+	if err != nil {
+		return "", errors.WithMessage(err, "local: lss copy object err")
+	}
+	defer src.Close()
+
+	if err := l.writeObject(ctx, dstKey, src); err != nil {
+		return "", err
+	}
+	if err := l.copyMeta(srcKey, dstKey); err != nil {
+		return "", err
+	}
+
+	return l.GetURL(dstKey), nil
+}
+
+// MoveObject 在本地 LSS 移动对象，直接重命名对象文件，源对象不存在时返回 types.ErrObjectNotFound
+func (l *LSS) MoveObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	if _, err := l.StatObject(ctx, srcKey); err != nil {
+		return "", errors.WithMessage(err, "local: lss move object err")
+	}
+	if srcKey == dstKey {
+		return l.GetURL(dstKey), nil
+	}
+
+	destPath := l.objectPath(dstKey)
+	if err := l.mkdir(destPath); err != nil {
+		return "", err
+	}
+	if err := os.Rename(l.objectPath(srcKey), destPath); err != nil {
+		return "", errors.WithMessagef(err, "local: lss rename dest path: %s err", destPath)
+	}
+	if err := l.copyMeta(srcKey, dstKey); err != nil {
+		return "", err
+	}
+	_ = os.Remove(l.metaPath(srcKey))
+
+	return l.GetURL(dstKey), nil
+}
+
+// ListObjects 列举本地 LSS 上指定前缀的对象，opts：列举可选配置
+func (l *LSS) ListObjects(ctx context.Context, prefix string, opts ...types.ListOption) (*types.ListObjectsResult, error) {
+	lo := types.NewListOptions(opts...)
+	keys, err := l.walkKeys(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.ListObjectsResult{}
+	count := 0
+	for _, key := range keys {
+		entry, isPrefix := key, false
+		if lo.Delimiter != "" {
+			if i := strings.Index(key[len(prefix):], lo.Delimiter); i >= 0 {
+				entry, isPrefix = key[:len(prefix)+i+len(lo.Delimiter)], true
+			}
+		}
+		if entry <= lo.Marker {
+			continue
+		}
+		if isPrefix {
+			if n := len(result.CommonPrefixes); n > 0 && result.CommonPrefixes[n-1] == entry {
+				continue
+			}
+		}
+		if count == lo.MaxKeys {
+			result.IsTruncated = true
+			break
+		}
+
+		count++
+		if isPrefix {
+			result.CommonPrefixes = append(result.CommonPrefixes, entry)
+			continue
+		}
+		info, err := l.statObject(key, false)
 		if err != nil {
-			return "", errors.WithMessage(err, "local: lss copy src to dest err")
+			return nil, err
 		}
+		result.Objects = append(result.Objects, *info)
 	}
+	util.FillNextMarker(result)
 
-	return l.GetURL(key), nil
+	return result, nil
 }
 
-// AuthorizedUpload 授权上传至本地 LSS，expires：过期时间（秒）
+// StatObject 获取本地 LSS 上对象的元信息，对象不存在时返回 types.ErrObjectNotFound
+func (l *LSS) StatObject(ctx context.Context, key string) (*types.ObjectInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithMessage(err, "local: lss stat object err")
+	}
+
+	return l.statObject(key, true)
+}
+
+// Exists 判断对象是否存在于本地 LSS
+func (l *LSS) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := l.StatObject(ctx, key)
+	if err != nil {
+		if errors.Is(err, types.ErrObjectNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// AuthorizedUpload 授权上传至本地 LSS，设置签名密钥时返回可 PUT 至 Handler 的签名 URL，expires：过期时间（秒）
 func (l *LSS) AuthorizedUpload(key string, expires int) (string, error) {
-	return l.GetURL(key), nil
+	if l.secretKey == "" {
+		return l.GetURL(key), nil
+	}
+
+	signedURL, err := l.SignURL(http.MethodPut, key, expires, nil)
+	if err != nil {
+		return "", errors.WithMessage(err, "local: lss authorized upload err")
+	}
+
+	return signedURL, nil
+}
+
+// PresignedGetURL 获取本地 LSS 上对象的预签名下载 URL，expires：过期时间（秒），opts：预签名可选配置
+func (l *LSS) PresignedGetURL(key string, expires int, opts ...types.PresignOption) (string, error) {
+	signedURL, err := l.SignURL(http.MethodGet, key, expires, types.NewPresignOptions(opts...).Query())
+	if err != nil {
+		return "", errors.WithMessage(err, "local: lss presigned get url err")
+	}
+
+	return signedURL, nil
 }
 
 // GetThumbnailSuffix 获取缩略图后缀
@@ -154,6 +367,21 @@ func (l *LSS) GetThumbnailSuffix(width, height int, size int64) string {
 	return ""
 }
 
+// ProcessImage 获取本地 LSS 上图片处理后的访问 URL，使用纯 Go 渲染并将结果缓存至 types.ImageCachePrefix 保留前缀下，opts：图片处理可选配置
+func (l *LSS) ProcessImage(ctx context.Context, key string, opts ...types.ImageOption) (string, error) {
+	return imaging.ProcessCached(ctx, l, key, opts...)
+}
+
+// validKey 判断对象键是否合法，对象键不能为空，不能包含 .. 等路径穿越片段，也不能指向保留目录
+func validKey(key string) bool {
+	if key == "" || strings.ContainsRune(key, '\\') {
+		return false
+	}
+	clean := path.Clean("/" + key)[1:]
+
+	return clean == key && clean != reservedDir && !strings.HasPrefix(clean, reservedDir+"/")
+}
+
 // mkdir 创建目标目录
 func (l *LSS) mkdir(destPath string) error {
 	destDir := filepath.Dir(destPath)
@@ -164,3 +392,194 @@ func (l *LSS) mkdir(destPath string) error {
 
 	return nil
 }
+
+// objectPath 获取对象存储路径
+func (l *LSS) objectPath(key string) string {
+	return filepath.Join(l.bucketName, key)
+}
+
+// metaPath 获取对象元数据存储路径
+func (l *LSS) metaPath(key string) string {
+	return filepath.Join(l.bucketName, metaDir, key+metaExt)
+}
+
+// writeObject 写入对象数据，先写入临时文件再重命名，避免上下文结束时残留不完整的对象
+func (l *LSS) writeObject(ctx context.Context, key string, reader io.Reader) error {
+	destPath := l.objectPath(key)
+	if err := l.mkdir(destPath); err != nil {
+		return err
+	}
+
+	tmpPath := filepath.Join(l.bucketName, tmpDir)
+	if err := filex.Mkdir(tmpPath); err != nil {
+		return errors.WithMessagef(err, "local: lss mkdir tmp dir: %s err", tmpPath)
+	}
+
+	tmp, err := os.CreateTemp(tmpPath, "object-*")
+	if err != nil {
+		return errors.WithMessagef(err, "local: lss create dest path: %s err", destPath)
+	}
+	defer os.Remove(tmp.Name())
+
+	// 临时文件默认权限为 0600，调整为常规文件权限以便静态文件服务读取
+	_ = tmp.Chmod(0o644)
+	_, err = io.Copy(tmp, xio.NewCtxReader(ctx, reader))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.WithMessage(err, "local: lss copy reader to dest err")
+	}
+
+	if err := os.Rename(tmp.Name(), destPath); err != nil {
+		return errors.WithMessagef(err, "local: lss rename dest path: %s err", destPath)
+	}
+
+	return nil
+}
+
+// toObjectMeta 将对象可选配置转换为对象元数据
+func toObjectMeta(oo *types.ObjectOptions) *objectMeta {
+	return &objectMeta{
+		ContentType:        oo.ContentType,
+		ContentDisposition: oo.ContentDisposition,
+		ContentEncoding:    oo.ContentEncoding,
+		CacheControl:       oo.CacheControl,
+		Metadata:           oo.Metadata,
+	}
+}
+
+// writeMeta 写入对象元数据
+func (l *LSS) writeMeta(key string, meta *objectMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return errors.WithMessage(err, "local: lss marshal object meta err")
+	}
+
+	destPath := l.metaPath(key)
+	if err := l.mkdir(destPath); err != nil {
+		return err
+	}
+
+	return errors.WithMessagef(os.WriteFile(destPath, data, 0o644),
+		"local: lss write meta path: %s err", destPath)
+}
+
+// copyMeta 复制对象元数据，源对象元数据不存在时删除目标对象元数据
+func (l *LSS) copyMeta(srcKey, dstKey string) error {
+	data, err := os.ReadFile(l.metaPath(srcKey))
+	if err != nil {
+		if os.IsNotExist(err) {
+			_ = os.Remove(l.metaPath(dstKey))
+			return nil
+		}
+		return errors.WithMessage(err, "local: lss read object meta err")
+	}
+
+	destPath := l.metaPath(dstKey)
+	if err := l.mkdir(destPath); err != nil {
+		return err
+	}
+
+	return errors.WithMessagef(os.WriteFile(destPath, data, 0o644),
+		"local: lss write meta path: %s err", destPath)
+}
+
+// readMeta 读取对象元数据，元数据不存在时根据 key 推断内容类型
+func (l *LSS) readMeta(key string) (*objectMeta, error) {
+	data, err := os.ReadFile(l.metaPath(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &objectMeta{ContentType: types.NewObjectOptions(key).ContentType}, nil
+		}
+		return nil, errors.WithMessage(err, "local: lss read object meta err")
+	}
+
+	var meta objectMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, errors.WithMessage(err, "local: lss unmarshal object meta err")
+	}
+
+	return &meta, nil
+}
+
+// statObject 获取对象元信息，withMeta 为 true 时同时读取对象元数据
+func (l *LSS) statObject(key string, withMeta bool) (*types.ObjectInfo, error) {
+	fi, err := os.Stat(l.objectPath(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = types.ErrObjectNotFound
+		}
+		return nil, errors.WithMessage(err, "local: lss stat object err")
+	}
+	if fi.IsDir() {
+		return nil, errors.WithMessage(types.ErrObjectNotFound, "local: lss stat object err")
+	}
+
+	info := &types.ObjectInfo{
+		Key:          key,
+		Size:         fi.Size(),
+		ETag:         fmt.Sprintf("%x-%x", fi.ModTime().UnixNano(), fi.Size()),
+		LastModified: fi.ModTime(),
+	}
+	if withMeta {
+		meta, err := l.readMeta(key)
+		if err != nil {
+			return nil, err
+		}
+		info.ContentType = meta.ContentType
+		info.Metadata = util.LowerKeys(meta.Metadata)
+	}
+
+	return info, nil
+}
+
+// walkKeys 遍历存储桶获取指定前缀的全部对象键，结果按字典序排列
+func (l *LSS) walkKeys(ctx context.Context, prefix string) ([]string, error) {
+	root := filepath.Clean(l.bucketName)
+	// 仅遍历前缀所在的目录，减少遍历范围
+	walkRoot := root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		walkRoot = filepath.Join(root, filepath.FromSlash(prefix[:i]))
+	}
+
+	var keys []string
+	err := filepath.WalkDir(walkRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if d.IsDir() {
+			if key == reservedDir {
+				return filepath.SkipDir
+			}
+			// 前缀不在处理后图片缓存保留前缀下时跳过缓存对象，列举对象和生命周期清理均不包含缓存对象
+			if key+"/" == types.ImageCachePrefix && !types.IsImageCacheKey(prefix) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "local: lss list objects err")
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
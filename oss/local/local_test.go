@@ -1,12 +1,21 @@
 package local
 
 import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
 )
 
 var (
@@ -44,3 +53,260 @@ func TestLSS_AuthorizedUpload(t *testing.T) {
 	require.NoError(t, err)
 	t.Log(lss.AuthorizedUpload("test/test.txt", 120))
 }
+
+func TestLSS_PutObjectCtx(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName)
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	ctx := context.Background()
+	key := "test/test.pdf"
+	_, err = lss.PutObjectCtx(ctx, key, strings.NewReader("test-oss"),
+		types.WithContentDisposition(`attachment; filename="test.pdf"`),
+		types.WithMetadata(map[string]string{"owner": "test"}))
+	require.NoError(t, err)
+
+	rc, err := lss.GetObjectCtx(ctx, key)
+	require.NoError(t, err)
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	rc.Close()
+	assert.Equal(t, "test-oss", string(b))
+
+	meta, err := os.ReadFile(lss.metaPath(key))
+	require.NoError(t, err)
+	assert.Contains(t, string(meta), `"content_type":"application/pdf"`)
+	assert.Contains(t, string(meta), `"owner":"test"`)
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = lss.PutObjectCtx(cctx, "test/cancel.txt", strings.NewReader("test-oss"))
+	require.ErrorIs(t, err, context.Canceled)
+	assert.NoFileExists(t, lss.objectPath("test/cancel.txt"))
+
+	err = lss.DeleteObjectsCtx(ctx, key)
+	require.NoError(t, err)
+	assert.NoFileExists(t, lss.objectPath(key))
+	assert.NoFileExists(t, lss.metaPath(key))
+}
+
+func TestLSS_PutObjectCtx_Progress(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName)
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	var events []types.ProgressEvent
+	progress := types.WithProgress(func(e types.ProgressEvent) {
+		events = append(events, e)
+	})
+
+	ctx := context.Background()
+	data := strings.Repeat("a", 3000)
+	start := time.Now()
+	_, err = lss.PutObjectCtx(ctx, "test/test.txt", strings.NewReader(data), progress, types.WithRateLimit(2000))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+	require.NotEmpty(t, events)
+	assert.Equal(t, types.ProgressEvent{ConsumedBytes: 3000, TotalBytes: 3000, Rate: events[len(events)-1].Rate},
+		events[len(events)-1])
+
+	filePath := filepath.Join(t.TempDir(), "test.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte(data), 0o644))
+	events = nil
+	_, err = lss.UploadFileCtx(ctx, "test/file.txt", filePath, 0, 1, progress)
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	assert.Equal(t, int64(3000), events[len(events)-1].ConsumedBytes)
+	assert.Equal(t, int64(3000), events[len(events)-1].TotalBytes)
+
+	// 内容类型由对象键而不是本地文件路径推断
+	tmpPath := filepath.Join(t.TempDir(), "upload-123")
+	require.NoError(t, os.WriteFile(tmpPath, []byte(data), 0o644))
+	_, err = lss.UploadFileCtx(ctx, "test/a.png", tmpPath, 0, 1)
+	require.NoError(t, err)
+	info, err := lss.StatObject(ctx, "test/a.png")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", info.ContentType)
+}
+
+func TestLSS_ListObjects(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName)
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	ctx := context.Background()
+	for _, key := range []string{"list/a.txt", "list/b/1.txt", "list/b/2.txt", "list/c.txt", "other.txt"} {
+		_, err := lss.PutObjectCtx(ctx, key, strings.NewReader(key))
+		require.NoError(t, err)
+	}
+
+	result, err := lss.ListObjects(ctx, "list/")
+	require.NoError(t, err)
+	assert.False(t, result.IsTruncated)
+	require.Len(t, result.Objects, 4)
+	assert.Equal(t, "list/a.txt", result.Objects[0].Key)
+	assert.Equal(t, int64(len("list/a.txt")), result.Objects[0].Size)
+
+	result, err = lss.ListObjects(ctx, "list/", types.WithDelimiter("/"), types.WithMaxKeys(2))
+	require.NoError(t, err)
+	assert.True(t, result.IsTruncated)
+	assert.Equal(t, "list/b/", result.NextMarker)
+	require.Len(t, result.Objects, 1)
+	assert.Equal(t, "list/a.txt", result.Objects[0].Key)
+	assert.Equal(t, []string{"list/b/"}, result.CommonPrefixes)
+
+	result, err = lss.ListObjects(ctx, "list/", types.WithDelimiter("/"),
+		types.WithMaxKeys(2), types.WithMarker(result.NextMarker))
+	require.NoError(t, err)
+	assert.False(t, result.IsTruncated)
+	require.Len(t, result.Objects, 1)
+	assert.Equal(t, "list/c.txt", result.Objects[0].Key)
+	assert.Empty(t, result.CommonPrefixes)
+
+	result, err = lss.ListObjects(ctx, "not-exist/")
+	require.NoError(t, err)
+	assert.Empty(t, result.Objects)
+}
+
+func TestLSS_StatObject(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName)
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	ctx := context.Background()
+	key := "test/test.txt"
+	_, err = lss.PutObjectCtx(ctx, key, strings.NewReader("test-oss"),
+		types.WithMetadata(map[string]string{"Owner": "test"}))
+	require.NoError(t, err)
+
+	info, err := lss.StatObject(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, key, info.Key)
+	assert.Equal(t, int64(8), info.Size)
+	assert.NotEmpty(t, info.ETag)
+	assert.Equal(t, "text/plain", info.ContentType)
+	assert.Equal(t, map[string]string{"owner": "test"}, info.Metadata)
+
+	ok, err := lss.Exists(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = lss.StatObject(ctx, "test/not-exist.txt")
+	require.ErrorIs(t, err, types.ErrObjectNotFound)
+
+	ok, err = lss.Exists(ctx, "test/not-exist.txt")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestLSS_CopyObject(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName)
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	ctx := context.Background()
+	_, err = lss.PutObjectCtx(ctx, "tmp/test.txt", strings.NewReader("test-oss"),
+		types.WithMetadata(map[string]string{"owner": "test"}))
+	require.NoError(t, err)
+
+	out, err := lss.CopyObject(ctx, "tmp/test.txt", "copy/test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "http://endpoint/copy/test.txt", out)
+
+	info, err := lss.StatObject(ctx, "copy/test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), info.Size)
+	assert.Equal(t, map[string]string{"owner": "test"}, info.Metadata)
+
+	ok, err := lss.Exists(ctx, "tmp/test.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = lss.CopyObject(ctx, "tmp/not-exist.txt", "copy/not-exist.txt")
+	require.ErrorIs(t, err, types.ErrObjectNotFound)
+}
+
+func TestLSS_MoveObject(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName)
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	ctx := context.Background()
+	_, err = lss.PutObjectCtx(ctx, "tmp/test.txt", strings.NewReader("test-oss"),
+		types.WithMetadata(map[string]string{"owner": "test"}))
+	require.NoError(t, err)
+
+	out, err := lss.MoveObject(ctx, "tmp/test.txt", "move/test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "http://endpoint/move/test.txt", out)
+
+	info, err := lss.StatObject(ctx, "move/test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), info.Size)
+	assert.Equal(t, map[string]string{"owner": "test"}, info.Metadata)
+
+	ok, err := lss.Exists(ctx, "tmp/test.txt")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoFileExists(t, lss.metaPath("tmp/test.txt"))
+
+	_, err = lss.MoveObject(ctx, "tmp/test.txt", "move/test.txt")
+	require.ErrorIs(t, err, types.ErrObjectNotFound)
+}
+
+func TestLSS_ProcessImage(t *testing.T) {
+	lss, err := NewLSS("", bucketName)
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	buf := &bytes.Buffer{}
+	require.NoError(t, png.Encode(buf, img))
+
+	ctx := context.Background()
+	key := "test/test.png"
+	_, err = lss.PutObjectCtx(ctx, key, buf)
+	require.NoError(t, err)
+
+	u, err := lss.ProcessImage(ctx, key, types.WithResize(100, 0, ""), types.WithFormat("jpg"))
+	require.NoError(t, err)
+	assert.Regexp(t, `^\.image-cache/test/test\.png@[0-9a-f]{16}\.jpg$`, u)
+
+	src, err := lss.StatObject(ctx, key)
+	require.NoError(t, err)
+	cacheKey := types.NewImageOptions(types.WithResize(100, 0, ""), types.WithFormat("jpg")).CacheKey(key, src.ETag, "jpg")
+	info, err := lss.StatObject(ctx, cacheKey)
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", info.ContentType)
+
+	rc, err := lss.GetObjectCtx(ctx, cacheKey)
+	require.NoError(t, err)
+	defer rc.Close()
+	thumb, _, err := image.Decode(rc)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), thumb.Bounds())
+
+	cached, err := lss.ProcessImage(ctx, key, types.WithFormat("jpeg"), types.WithResize(100, 0, ""))
+	require.NoError(t, err)
+	assert.Equal(t, u, cached)
+
+	// 列举对象和生命周期清理均跳过缓存对象，显式列举保留前缀时包含
+	result, err := lss.ListObjects(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, result.Objects, 1)
+	assert.Equal(t, key, result.Objects[0].Key)
+	assert.Empty(t, result.CommonPrefixes)
+	result, err = lss.ListObjects(ctx, types.ImageCachePrefix)
+	require.NoError(t, err)
+	assert.Len(t, result.Objects, 1)
+	require.NoError(t, lss.SetLifecycleRules(ctx, []types.LifecycleRule{types.NewExpirationRule("", 1)}))
+	old := time.Now().AddDate(0, 0, -2)
+	require.NoError(t, os.Chtimes(lss.objectPath(key), old, old))
+	require.NoError(t, os.Chtimes(lss.objectPath(cacheKey), old, old))
+	expired, _, err := lss.Sweep(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	ok, err := lss.Exists(ctx, cacheKey)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,281 @@
+package local
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sliveryou/micro-pkg/oss/internal/util"
+	"github.com/sliveryou/micro-pkg/oss/internal/xio"
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+const (
+	// QueryPartNumber 分片上传 URL 分片序号查询参数
+	QueryPartNumber = "partNumber"
+	// QueryUploadID 分片上传 URL 上传 ID 查询参数
+	QueryUploadID = "uploadId"
+
+	// multipartDir 分片上传暂存目录
+	multipartDir = reservedDir + "/multipart"
+	// uploadFile 分片上传信息文件名称
+	uploadFile = "upload.json"
+	// uploadIDLen 上传 ID 字节长度
+	uploadIDLen = 16
+)
+
+// uploadInfo 分片上传信息
+type uploadInfo struct {
+	Key       string     `json:"key"`       // 对象键
+	Meta      objectMeta `json:"meta"`      // 对象元数据
+	Initiated time.Time  `json:"initiated"` // 初始化时间
+}
+
+// InitiateMultipartUpload 初始化本地 LSS 分片上传，返回上传 ID，opts：对象可选配置
+func (l *LSS) InitiateMultipartUpload(ctx context.Context, key string, opts ...types.ObjectOption) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", errors.WithMessage(err, "local: lss initiate multipart upload err")
+	}
+
+	b := make([]byte, uploadIDLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.WithMessage(err, "local: lss generate upload id err")
+	}
+	uploadID := hex.EncodeToString(b)
+
+	data, err := json.Marshal(&uploadInfo{
+		Key:       key,
+		Meta:      *toObjectMeta(types.NewObjectOptions(key, opts...)),
+		Initiated: time.Now(),
+	})
+	if err != nil {
+		return "", errors.WithMessage(err, "local: lss marshal upload info err")
+	}
+
+	destPath := filepath.Join(l.uploadPath(uploadID), uploadFile)
+	if err := l.mkdir(destPath); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(destPath, data, 0o644); err != nil {
+		return "", errors.WithMessagef(err, "local: lss write upload path: %s err", destPath)
+	}
+
+	return uploadID, nil
+}
+
+// PresignedUploadPartURL 获取本地 LSS 分片上传的预签名 URL，partNumber：分片序号，expires：过期时间（秒）
+func (l *LSS) PresignedUploadPartURL(key, uploadID string, partNumber, expires int) (string, error) {
+	query := url.Values{}
+	query.Set(QueryPartNumber, strconv.Itoa(partNumber))
+	query.Set(QueryUploadID, uploadID)
+	signedURL, err := l.SignURL(http.MethodPut, key, expires, query)
+	if err != nil {
+		return "", errors.WithMessage(err, "local: lss presigned upload part url err")
+	}
+
+	return signedURL, nil
+}
+
+// UploadPart 上传分片至本地 LSS 暂存目录，返回分片 ETag（分片数据的 MD5 值），重复上传同一分片序号时覆盖原分片
+func (l *LSS) UploadPart(ctx context.Context, key, uploadID string, partNumber int, reader io.Reader) (string, error) {
+	if err := types.CheckPartNumber(partNumber); err != nil {
+		return "", errors.WithMessage(err, "local: lss upload part err")
+	}
+	if _, err := l.readUpload(key, uploadID); err != nil {
+		return "", errors.WithMessage(err, "local: lss upload part err")
+	}
+
+	uploadPath := l.uploadPath(uploadID)
+	tmp, err := os.CreateTemp(uploadPath, "tmp-*")
+	if err != nil {
+		return "", errors.WithMessage(err, "local: lss create part err")
+	}
+	defer os.Remove(tmp.Name())
+
+	h := md5.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), xio.NewCtxReader(ctx, reader))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", errors.WithMessage(err, "local: lss copy reader to part err")
+	}
+
+	// 先删除同一分片序号的旧分片，分片 ETag 记录在分片文件名中
+	if old, ok := l.readParts(uploadID)[partNumber]; ok {
+		_ = os.Remove(filepath.Join(uploadPath, partName(old.PartNumber, old.ETag)))
+	}
+	etag := hex.EncodeToString(h.Sum(nil))
+	if err := os.Rename(tmp.Name(), filepath.Join(uploadPath, partName(partNumber, etag))); err != nil {
+		return "", errors.WithMessage(err, "local: lss rename part err")
+	}
+
+	return etag, nil
+}
+
+// CompleteMultipartUpload 完成本地 LSS 分片上传，按分片序号顺序合并分片，parts：已上传的分片列表
+func (l *LSS) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []types.Part) (string, error) {
+	info, err := l.readUpload(key, uploadID)
+	if err != nil {
+		return "", errors.WithMessage(err, "local: lss complete multipart upload err")
+	}
+	if len(parts) == 0 {
+		return "", errors.WithMessage(types.ErrInvalidPart, "local: lss complete multipart upload err: empty parts")
+	}
+
+	uploaded := l.readParts(uploadID)
+	uploadPath := l.uploadPath(uploadID)
+	readers := make([]io.Reader, 0, len(parts))
+	prev := 0
+	for _, p := range util.SortParts(parts) {
+		up, ok := uploaded[p.PartNumber]
+		if !ok || p.PartNumber == prev || up.ETag != util.TrimETag(p.ETag) {
+			return "", errors.WithMessagef(types.ErrInvalidPart,
+				"local: lss complete multipart upload err: part number: %d", p.PartNumber)
+		}
+		prev = p.PartNumber
+
+		f, err := os.Open(filepath.Join(uploadPath, partName(up.PartNumber, up.ETag)))
+		if err != nil {
+			return "", errors.WithMessage(err, "local: lss open part err")
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+
+	if err := l.writeObject(ctx, key, io.MultiReader(readers...)); err != nil {
+		return "", err
+	}
+	if err := l.writeMeta(key, &info.Meta); err != nil {
+		return "", err
+	}
+	_ = os.RemoveAll(uploadPath)
+
+	return l.GetURL(key), nil
+}
+
+// AbortMultipartUpload 取消本地 LSS 分片上传，删除已上传的分片
+func (l *LSS) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithMessage(err, "local: lss abort multipart upload err")
+	}
+	if _, err := l.readUpload(key, uploadID); err != nil {
+		return errors.WithMessage(err, "local: lss abort multipart upload err")
+	}
+
+	uploadPath := l.uploadPath(uploadID)
+	if err := os.RemoveAll(uploadPath); err != nil {
+		return errors.WithMessagef(err, "local: lss remove upload path: %s err", uploadPath)
+	}
+
+	return nil
+}
+
+// ListParts 列举本地 LSS 分片上传中已上传的分片
+func (l *LSS) ListParts(ctx context.Context, key, uploadID string) ([]types.Part, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithMessage(err, "local: lss list parts err")
+	}
+	if _, err := l.readUpload(key, uploadID); err != nil {
+		return nil, errors.WithMessage(err, "local: lss list parts err")
+	}
+
+	uploaded := l.readParts(uploadID)
+	parts := make([]types.Part, 0, len(uploaded))
+	for _, p := range uploaded {
+		parts = append(parts, p)
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+
+	return parts, nil
+}
+
+// uploadPath 获取分片上传暂存目录路径
+func (l *LSS) uploadPath(uploadID string) string {
+	return filepath.Join(l.bucketName, multipartDir, uploadID)
+}
+
+// readUpload 读取分片上传信息，上传 ID 非法、不存在或与对象键不匹配时返回 types.ErrUploadNotFound
+func (l *LSS) readUpload(key, uploadID string) (*uploadInfo, error) {
+	// 上传 ID 来自请求参数，校验格式以避免路径穿越
+	if b, err := hex.DecodeString(uploadID); err != nil || len(b) != uploadIDLen {
+		return nil, types.ErrUploadNotFound
+	}
+
+	data, err := os.ReadFile(filepath.Join(l.uploadPath(uploadID), uploadFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, types.ErrUploadNotFound
+		}
+		return nil, errors.WithMessage(err, "local: lss read upload info err")
+	}
+
+	var info uploadInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, errors.WithMessage(err, "local: lss unmarshal upload info err")
+	}
+	if info.Key != key {
+		return nil, types.ErrUploadNotFound
+	}
+
+	return &info, nil
+}
+
+// readParts 读取暂存目录中已上传的分片，返回分片序号到分片信息的映射
+func (l *LSS) readParts(uploadID string) map[int]types.Part {
+	entries, _ := os.ReadDir(l.uploadPath(uploadID))
+	parts := make(map[int]types.Part, len(entries))
+	for _, entry := range entries {
+		number, etag, ok := parsePartName(entry.Name())
+		if !ok {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		parts[number] = types.Part{
+			PartNumber:   number,
+			ETag:         etag,
+			Size:         fi.Size(),
+			LastModified: fi.ModTime(),
+		}
+	}
+
+	return parts
+}
+
+// partName 获取分片文件名称，格式为 分片序号.分片ETag
+func partName(partNumber int, etag string) string {
+	return fmt.Sprintf("%05d.%s", partNumber, etag)
+}
+
+// parsePartName 解析分片文件名称
+func parsePartName(name string) (int, string, bool) {
+	number, etag, ok := strings.Cut(name, ".")
+	if !ok || etag == "" {
+		return 0, "", false
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil || types.CheckPartNumber(n) != nil {
+		return 0, "", false
+	}
+
+	return n, etag, true
+}
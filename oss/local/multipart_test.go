@@ -0,0 +1,132 @@
+package local
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+func TestLSS_MultipartUpload(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName, WithSecretKey("test-secret"))
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	ctx := context.Background()
+	key := "test/multipart.txt"
+	uploadID, err := lss.InitiateMultipartUpload(ctx, key, types.WithMetadata(map[string]string{"Author": "test"}))
+	require.NoError(t, err)
+	assert.Len(t, uploadID, 32)
+
+	// 乱序上传分片，并重复上传分片 2
+	etag2, err := lss.UploadPart(ctx, key, uploadID, 2, strings.NewReader("old"))
+	require.NoError(t, err)
+	etag1, err := lss.UploadPart(ctx, key, uploadID, 1, strings.NewReader("hello "))
+	require.NoError(t, err)
+	newEtag2, err := lss.UploadPart(ctx, key, uploadID, 2, strings.NewReader("world"))
+	require.NoError(t, err)
+	assert.NotEqual(t, etag2, newEtag2)
+
+	parts, err := lss.ListParts(ctx, key, uploadID)
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+	assert.Equal(t, 1, parts[0].PartNumber)
+	assert.Equal(t, etag1, parts[0].ETag)
+	assert.Equal(t, int64(6), parts[0].Size)
+	assert.Equal(t, newEtag2, parts[1].ETag)
+
+	_, err = lss.CompleteMultipartUpload(ctx, key, uploadID, []types.Part{{PartNumber: 2, ETag: etag2}, {PartNumber: 1, ETag: etag1}})
+	assert.True(t, errors.Is(err, types.ErrInvalidPart))
+
+	out, err := lss.CompleteMultipartUpload(ctx, key, uploadID, []types.Part{{PartNumber: 2, ETag: `"` + newEtag2 + `"`}, {PartNumber: 1, ETag: etag1}})
+	require.NoError(t, err)
+	assert.Equal(t, lss.GetURL(key), out)
+
+	rc, err := lss.GetObject(key)
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+
+	info, err := lss.StatObject(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, "test", info.Metadata["author"])
+
+	// 完成后暂存目录被删除
+	_, err = lss.ListParts(ctx, key, uploadID)
+	assert.True(t, errors.Is(err, types.ErrUploadNotFound))
+	_, err = os.Stat(lss.uploadPath(uploadID))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLSS_AbortMultipartUpload(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName)
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	ctx := context.Background()
+	key := "test/abort.txt"
+	uploadID, err := lss.InitiateMultipartUpload(ctx, key)
+	require.NoError(t, err)
+	_, err = lss.UploadPart(ctx, key, uploadID, 1, strings.NewReader("test"))
+	require.NoError(t, err)
+
+	err = lss.AbortMultipartUpload(ctx, "test/other.txt", uploadID)
+	assert.True(t, errors.Is(err, types.ErrUploadNotFound))
+
+	err = lss.AbortMultipartUpload(ctx, key, uploadID)
+	require.NoError(t, err)
+	err = lss.AbortMultipartUpload(ctx, key, uploadID)
+	assert.True(t, errors.Is(err, types.ErrUploadNotFound))
+
+	ok, err := lss.Exists(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestLSS_UploadPart(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName)
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	ctx := context.Background()
+	key := "test/part.txt"
+	uploadID, err := lss.InitiateMultipartUpload(ctx, key)
+	require.NoError(t, err)
+
+	_, err = lss.UploadPart(ctx, key, uploadID, 0, strings.NewReader("test"))
+	assert.True(t, errors.Is(err, types.ErrInvalidPartNumber))
+	_, err = lss.UploadPart(ctx, key, uploadID, types.MaxPartNumber+1, strings.NewReader("test"))
+	assert.True(t, errors.Is(err, types.ErrInvalidPartNumber))
+	_, err = lss.UploadPart(ctx, key, "../../meta", 1, strings.NewReader("test"))
+	assert.True(t, errors.Is(err, types.ErrUploadNotFound))
+
+	etag, err := lss.UploadPart(ctx, key, uploadID, 1, strings.NewReader("test"))
+	require.NoError(t, err)
+	assert.Equal(t, "098f6bcd4621d373cade4e832627b4f6", etag)
+}
+
+func TestLSS_PresignedUploadPartURL(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName, WithSecretKey("test-secret"))
+	require.NoError(t, err)
+
+	signedURL, err := lss.PresignedUploadPartURL("test/test.txt", "upload-id", 3, 120)
+	require.NoError(t, err)
+
+	u, err := url.Parse(signedURL)
+	require.NoError(t, err)
+	assert.Equal(t, "3", u.Query().Get(QueryPartNumber))
+	assert.Equal(t, "upload-id", u.Query().Get(QueryUploadID))
+	require.NoError(t, lss.VerifySignature(http.MethodPut, "test/test.txt", u.Query()))
+	assert.ErrorIs(t, lss.VerifySignature(http.MethodGet, "test/test.txt", u.Query()), ErrInvalidSignature)
+}
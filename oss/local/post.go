@@ -0,0 +1,264 @@
+package local
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sliveryou/micro-pkg/oss/internal/policy"
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+const (
+	// FieldFile 表单上传文件字段
+	FieldFile = "file"
+	// FieldSuccessActionStatus 表单上传成功状态码字段，支持 200、201 和 204，默认为 204
+	FieldSuccessActionStatus = "success_action_status"
+
+	// maxFieldSize 表单字段最大长度
+	maxFieldSize = 64 << 10
+)
+
+var (
+	// ErrInvalidPolicy 表单上传策略无效错误
+	ErrInvalidPolicy = errors.New("local: lss invalid post policy")
+	// ErrPolicyExpired 表单上传策略过期错误
+	ErrPolicyExpired = errors.New("local: lss post policy expired")
+	// ErrPolicyConditionFailed 表单上传策略条件不满足错误
+	ErrPolicyConditionFailed = errors.New("local: lss post policy condition failed")
+	// ErrContentLengthOutOfRange 上传内容长度超出范围错误
+	ErrContentLengthOutOfRange = errors.New("local: lss content length out of range")
+)
+
+// PostPolicy 获取本地 LSS 表单上传策略，表单需提交至 PostPolicyHandler，expires：过期时间（秒），opts：表单上传策略可选配置
+func (l *LSS) PostPolicy(key string, expires int, opts ...types.PostPolicyOption) (*types.PostPolicy, error) {
+	if l.secretKey == "" {
+		return nil, ErrSecretKeyNotSet
+	}
+
+	p, err := policy.New(key, expires, types.NewPostPolicyOptions(opts...), true)
+	if err != nil {
+		return nil, errors.WithMessage(err, "local: lss post policy err")
+	}
+
+	_, encoded, err := p.Encode()
+	if err != nil {
+		return nil, errors.WithMessage(err, "local: lss post policy err")
+	}
+
+	return p.PostPolicy(l.GetURL(""), map[string]string{
+		policy.FieldPolicy: encoded,
+		QuerySignature:     l.signPolicy(encoded),
+	}), nil
+}
+
+// PostPolicyHandler 获取处理表单上传的 http.Handler，校验表单上传策略的签名、过期时间和条件后保存文件字段 file 的内容，
+// 文件字段需位于其他表单字段之后，对象键中的 ${filename} 会被替换为上传的文件名
+func (l *LSS) PostPolicyHandler() http.Handler {
+	return http.HandlerFunc(l.servePostPolicy)
+}
+
+// servePostPolicy 处理表单上传请求
+func (l *LSS) servePostPolicy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 表单字段名称不区分大小写
+	fields := make(map[string]string)
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			if err == io.EOF {
+				err = errors.Errorf("local: lss form field %s not found", FieldFile)
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if part.FormName() != FieldFile {
+			value, err := io.ReadAll(io.LimitReader(part, maxFieldSize))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			fields[strings.ToLower(part.FormName())] = string(value)
+			continue
+		}
+
+		key := strings.ReplaceAll(fields[policy.FieldKey], types.FilenameVar, path.Base(part.FileName()))
+		contentType := fields[strings.ToLower(policy.FieldContentType)]
+		if contentType == "" {
+			contentType = part.Header.Get("Content-Type")
+		}
+		fields[policy.FieldKey] = key
+		fields[strings.ToLower(policy.FieldContentType)] = contentType
+
+		min, max, err := l.verifyPostPolicy(fields)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if !validKey(key) {
+			http.Error(w, "local: lss invalid object key", http.StatusBadRequest)
+			return
+		}
+
+		reader := &rangeReader{r: part, min: min, max: max}
+		if _, err := l.PutObjectCtx(r.Context(), key, reader, types.WithContentType(contentType)); err != nil {
+			if errors.Is(err, ErrContentLengthOutOfRange) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			} else {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if info, err := l.statObject(key, false); err == nil {
+			w.Header().Set("ETag", `"`+info.ETag+`"`)
+		}
+		w.Header().Set("Location", l.GetURL(key))
+		switch status := fields[FieldSuccessActionStatus]; status {
+		case "200", "201":
+			code, _ := strconv.Atoi(status)
+			w.WriteHeader(code)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+}
+
+// verifyPostPolicy 校验表单上传策略，fields：字段名称为小写的表单字段，返回内容长度范围，max 为 0 时不限制
+func (l *LSS) verifyPostPolicy(fields map[string]string) (min, max int64, err error) {
+	if l.secretKey == "" {
+		return 0, 0, ErrSecretKeyNotSet
+	}
+
+	encoded := fields[policy.FieldPolicy]
+	signature := fields[strings.ToLower(QuerySignature)]
+	if encoded == "" || signature == "" ||
+		!hmac.Equal([]byte(signature), []byte(l.signPolicy(encoded))) {
+		return 0, 0, ErrInvalidSignature
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, 0, ErrInvalidPolicy
+	}
+	var p struct {
+		Expiration string `json:"expiration"`
+		Conditions []any  `json:"conditions"`
+	}
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return 0, 0, ErrInvalidPolicy
+	}
+	expiration, err := time.Parse(policy.ExpirationFormat, p.Expiration)
+	if err != nil {
+		return 0, 0, ErrInvalidPolicy
+	}
+	if time.Now().After(expiration) {
+		return 0, 0, ErrPolicyExpired
+	}
+
+	for _, cond := range p.Conditions {
+		switch c := cond.(type) {
+		case map[string]any:
+			for name, value := range c {
+				if fields[strings.ToLower(name)] != fmt.Sprint(value) {
+					return 0, 0, errors.WithMessagef(ErrPolicyConditionFailed, "field: %s", name)
+				}
+			}
+		case []any:
+			if len(c) != 3 {
+				return 0, 0, ErrInvalidPolicy
+			}
+			op, _ := c[0].(string)
+			if op == "content-length-range" {
+				minValue, ok1 := c[1].(float64)
+				maxValue, ok2 := c[2].(float64)
+				if !ok1 || !ok2 {
+					return 0, 0, ErrInvalidPolicy
+				}
+				min, max = int64(minValue), int64(maxValue)
+				continue
+			}
+
+			name, _ := c[1].(string)
+			value := fields[strings.ToLower(strings.TrimPrefix(name, "$"))]
+			if !matchCondition(op, value, c[2]) {
+				return 0, 0, errors.WithMessagef(ErrPolicyConditionFailed, "field: %s", name)
+			}
+		default:
+			return 0, 0, ErrInvalidPolicy
+		}
+	}
+
+	return min, max, nil
+}
+
+// signPolicy 计算表单上传策略签名
+func (l *LSS) signPolicy(encoded string) string {
+	h := hmac.New(sha256.New, []byte(l.secretKey))
+	h.Write([]byte(encoded))
+
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// matchCondition 判断表单字段值是否满足条件
+func matchCondition(op, value string, expected any) bool {
+	switch op {
+	case "eq":
+		return value == fmt.Sprint(expected)
+	case "starts-with":
+		return strings.HasPrefix(value, fmt.Sprint(expected))
+	case "in":
+		values, _ := expected.([]any)
+		for _, v := range values {
+			if value == fmt.Sprint(v) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// rangeReader 校验内容长度的读取器，读取长度超出范围时返回 ErrContentLengthOutOfRange
+type rangeReader struct {
+	r        io.Reader
+	n        int64
+	min, max int64
+}
+
+// Read 实现 io.Reader 接口
+func (r *rangeReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	if r.max > 0 && r.n > r.max {
+		return n, ErrContentLengthOutOfRange
+	}
+	if err == io.EOF && r.n < r.min {
+		return n, ErrContentLengthOutOfRange
+	}
+
+	return n, err
+}
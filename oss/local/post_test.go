@@ -0,0 +1,127 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+func TestLSS_PostPolicy(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName, WithSecretKey("test-secret"))
+	require.NoError(t, err)
+	defer os.RemoveAll(bucketName)
+
+	ctx := context.Background()
+	handler := lss.PostPolicyHandler()
+	pp, err := lss.PostPolicy("", 120, types.WithKeyPrefix("upload/"),
+		types.WithContentLengthRange(1, 10), types.WithContentTypes("image/png", "image/jpeg"))
+	require.NoError(t, err)
+	assert.Equal(t, "http://endpoint/", pp.URL)
+	assert.Equal(t, "upload/${filename}", pp.Fields["key"])
+
+	// 上传成功，文件名替换至对象键中
+	rec := postForm(t, handler, pp.Fields, nil, "test.png", "image/png", "test-png")
+	require.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+	assert.Equal(t, "http://endpoint/upload/test.png", rec.Header().Get("Location"))
+	info, err := lss.StatObject(ctx, "upload/test.png")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", info.ContentType)
+	assert.Equal(t, int64(8), info.Size)
+
+	// 客户端在前缀内修改对象键，并指定成功状态码
+	rec = postForm(t, handler, pp.Fields, map[string]string{"key": "upload/a/b.jpg", "success_action_status": "201"},
+		"b.jpg", "image/jpeg", "test-jpg")
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	ok, err := lss.Exists(ctx, "upload/a/b.jpg")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 对象键前缀不匹配
+	rec = postForm(t, handler, pp.Fields, map[string]string{"key": "other/test.png"}, "test.png", "image/png", "test")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// 内容类型不允许
+	rec = postForm(t, handler, pp.Fields, nil, "test.gif", "image/gif", "test")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// 内容长度超出范围，且不覆盖已存在的对象
+	rec = postForm(t, handler, pp.Fields, nil, "test.png", "image/png", "test-png-too-large")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	info, err = lss.StatObject(ctx, "upload/test.png")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), info.Size)
+
+	// 路径穿越
+	rec = postForm(t, handler, pp.Fields, map[string]string{"key": "upload/../../test.png"}, "test.png", "image/png", "test")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	// 签名错误
+	rec = postForm(t, handler, pp.Fields, map[string]string{"Signature": "invalid"}, "test.png", "image/png", "test")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), ErrInvalidSignature.Error())
+
+	// 上传策略过期
+	pp, err = lss.PostPolicy("upload/expired.png", -1)
+	require.NoError(t, err)
+	rec = postForm(t, handler, pp.Fields, nil, "expired.png", "image/png", "test")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), ErrPolicyExpired.Error())
+}
+
+func TestLSS_PostPolicyError(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName)
+	require.NoError(t, err)
+	_, err = lss.PostPolicy("test.png", 120)
+	assert.ErrorIs(t, err, ErrSecretKeyNotSet)
+
+	lss, err = NewLSS(endpoint, bucketName, WithSecretKey("test-secret"))
+	require.NoError(t, err)
+	_, err = lss.PostPolicy("test.png", 120, types.WithKeyPrefix("upload/"))
+	assert.Error(t, err)
+
+	rec := httptest.NewRecorder()
+	lss.PostPolicyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func postForm(t *testing.T, handler http.Handler, fields, overrides map[string]string, filename, contentType, content string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for k, v := range fields {
+		if _, ok := overrides[k]; !ok {
+			require.NoError(t, mw.WriteField(k, v))
+		}
+	}
+	for k, v := range overrides {
+		require.NoError(t, mw.WriteField(k, v))
+	}
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+	h.Set("Content-Type", contentType)
+	fw, err := mw.CreatePart(h)
+	require.NoError(t, err)
+	_, err = fw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body.String()))
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec
+}
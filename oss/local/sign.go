@@ -0,0 +1,87 @@
+package local
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// QueryExpires 签名 URL 过期时间查询参数
+	QueryExpires = "Expires"
+	// QuerySignature 签名 URL 签名查询参数
+	QuerySignature = "Signature"
+)
+
+var (
+	// ErrSecretKeyNotSet 签名密钥未设置错误
+	ErrSecretKeyNotSet = errors.New("local: lss secret key not set")
+	// ErrInvalidSignature 签名无效错误
+	ErrInvalidSignature = errors.New("local: lss invalid signature")
+	// ErrSignatureExpired 签名过期错误
+	ErrSignatureExpired = errors.New("local: lss signature expired")
+)
+
+// SignURL 生成对象的签名 URL，method：请求方法，expires：过期时间（秒），query：需要一并签名的查询参数
+func (l *LSS) SignURL(method, key string, expires int, query url.Values) (string, error) {
+	if l.secretKey == "" {
+		return "", ErrSecretKeyNotSet
+	}
+
+	signed := make(url.Values, len(query)+2)
+	for k, v := range query {
+		signed[k] = v
+	}
+	signed.Set(QueryExpires, strconv.FormatInt(time.Now().Add(time.Duration(expires)*time.Second).Unix(), 10))
+	signed.Set(QuerySignature, l.sign(method, key, signed))
+
+	return l.GetURL(key) + "?" + signed.Encode(), nil
+}
+
+// VerifySignature 校验签名 URL 的查询参数，method：请求方法
+func (l *LSS) VerifySignature(method, key string, query url.Values) error {
+	if l.secretKey == "" {
+		return ErrSecretKeyNotSet
+	}
+
+	signature := query.Get(QuerySignature)
+	expires, err := strconv.ParseInt(query.Get(QueryExpires), 10, 64)
+	if signature == "" || err != nil {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(l.sign(method, key, query))) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > expires {
+		return ErrSignatureExpired
+	}
+
+	return nil
+}
+
+// sign 计算签名，待签名字符串由请求方法、对象键和除签名外的全部查询参数组成
+func (l *LSS) sign(method, key string, query url.Values) string {
+	params := make(url.Values, len(query))
+	for k, v := range query {
+		if k != QuerySignature {
+			params[k] = v
+		}
+	}
+
+	stringToSign := strings.Join([]string{
+		strings.ToUpper(method),
+		strings.TrimPrefix(key, "/"),
+		params.Encode(),
+	}, "\n")
+
+	h := hmac.New(sha256.New, []byte(l.secretKey))
+	h.Write([]byte(stringToSign))
+
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,48 @@
+package local
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+func TestLSS_PresignedGetURL(t *testing.T) {
+	lss, err := NewLSS(endpoint, bucketName)
+	require.NoError(t, err)
+	_, err = lss.PresignedGetURL("test/test.txt", 120)
+	require.ErrorIs(t, err, ErrSecretKeyNotSet)
+
+	lss, err = NewLSS(endpoint, bucketName, WithSecretKey("secret"))
+	require.NoError(t, err)
+
+	key := "test/test.pdf"
+	signedURL, err := lss.PresignedGetURL(key, 120,
+		types.WithResponseContentDisposition(`attachment; filename="test.pdf"`))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(signedURL, "http://endpoint/test/test.pdf?"))
+	t.Log(signedURL)
+
+	u, err := url.Parse(signedURL)
+	require.NoError(t, err)
+	query := u.Query()
+	assert.Equal(t, `attachment; filename="test.pdf"`, query.Get("response-content-disposition"))
+	require.NoError(t, lss.VerifySignature(http.MethodGet, key, query))
+	require.ErrorIs(t, lss.VerifySignature(http.MethodPut, key, query), ErrInvalidSignature)
+	require.ErrorIs(t, lss.VerifySignature(http.MethodGet, "test/other.pdf", query), ErrInvalidSignature)
+
+	query.Set("response-content-disposition", "inline")
+	require.ErrorIs(t, lss.VerifySignature(http.MethodGet, key, query), ErrInvalidSignature)
+	require.ErrorIs(t, lss.VerifySignature(http.MethodGet, key, url.Values{}), ErrInvalidSignature)
+
+	signedURL, err = lss.SignURL(http.MethodGet, key, -10, nil)
+	require.NoError(t, err)
+	u, err = url.Parse(signedURL)
+	require.NoError(t, err)
+	require.ErrorIs(t, lss.VerifySignature(http.MethodGet, key, u.Query()), ErrSignatureExpired)
+}
@@ -1,10 +1,16 @@
 package minio
 
 import (
+	"context"
 	"testing"
 
+	minio "github.com/minio/minio-go/v7"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/internal/progress"
+	"github.com/sliveryou/micro-pkg/oss/types"
 )
 
 var (
@@ -42,3 +48,24 @@ func TestMinIO_AuthorizedUpload(t *testing.T) {
 	require.NoError(t, err)
 	t.Log(mio.AuthorizedUpload("test/test.txt", 120))
 }
+
+func TestIsNotFound(t *testing.T) {
+	assert.True(t, isNotFound(minio.ErrorResponse{Code: "NoSuchKey"}))
+	assert.True(t, isNotFound(minio.ErrorResponse{Code: "NoSuchUpload"}))
+	assert.False(t, isNotFound(minio.ErrorResponse{Code: "AccessDenied"}))
+	assert.False(t, isNotFound(errors.New("test")))
+}
+
+func TestProgressReader(t *testing.T) {
+	var consumed int64
+	tr := progress.New(context.Background(), 100, types.NewObjectOptions("test.txt",
+		types.WithProgress(func(e types.ProgressEvent) { consumed = e.ConsumedBytes })))
+	r := &progressReader{t: tr}
+
+	n, err := r.Read(make([]byte, 40))
+	require.NoError(t, err)
+	assert.Equal(t, 40, n)
+	_, err = r.Read(make([]byte, 60))
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), consumed)
+}
@@ -1,6 +1,14 @@
 package mock
 
-import "io"
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
 
 const (
 	// CloudMock 云服务商：模拟
@@ -30,31 +38,136 @@ func (m *MSS) GetObject(key string) (io.ReadCloser, error) {
 	return &mockReadCloser{}, nil
 }
 
+// GetObjectCtx 获取对象在模拟 MSS 的存储数据
+func (m *MSS) GetObjectCtx(ctx context.Context, key string) (io.ReadCloser, error) {
+	return &mockReadCloser{}, nil
+}
+
 // PutObject 上传对象至模拟 MSS
 func (m *MSS) PutObject(key string, reader io.Reader) (string, error) {
 	return m.GetURL(key), nil
 }
 
+// PutObjectCtx 上传对象至模拟 MSS，opts：对象可选配置
+func (m *MSS) PutObjectCtx(ctx context.Context, key string, reader io.Reader, opts ...types.ObjectOption) (string, error) {
+	return m.GetURL(key), nil
+}
+
 // DeleteObjects 批量删除模拟 MSS 上的对象
 func (m *MSS) DeleteObjects(keys ...string) error {
 	return nil
 }
 
+// DeleteObjectsCtx 批量删除模拟 MSS 上的对象
+func (m *MSS) DeleteObjectsCtx(ctx context.Context, keys ...string) error {
+	return nil
+}
+
 // UploadFile 上传文件至模拟 MSS，filePath：文件路径，partSize：分块大小（字节），routines：并发数
 func (m *MSS) UploadFile(key, filePath string, partSize int64, routines int) (string, error) {
 	return m.GetURL(key), nil
 }
 
+// UploadFileCtx 上传文件至模拟 MSS，filePath：文件路径，partSize：分块大小（字节），routines：并发数，opts：对象可选配置
+func (m *MSS) UploadFileCtx(ctx context.Context, key, filePath string, partSize int64, routines int, opts ...types.ObjectOption) (string, error) {
+	return m.GetURL(key), nil
+}
+
+// CopyObject 在模拟 MSS 服务端复制对象
+func (m *MSS) CopyObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	return m.GetURL(dstKey), nil
+}
+
+// MoveObject 在模拟 MSS 服务端移动对象
+func (m *MSS) MoveObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	return m.GetURL(dstKey), nil
+}
+
+// ListObjects 列举模拟 MSS 上指定前缀的对象，opts：列举可选配置
+func (m *MSS) ListObjects(ctx context.Context, prefix string, opts ...types.ListOption) (*types.ListObjectsResult, error) {
+	return &types.ListObjectsResult{}, nil
+}
+
+// StatObject 获取模拟 MSS 上对象的元信息，模拟 MSS 不存储对象，始终返回 types.ErrObjectNotFound
+func (m *MSS) StatObject(ctx context.Context, key string) (*types.ObjectInfo, error) {
+	return nil, errors.WithMessage(types.ErrObjectNotFound, "mock: mss stat object err")
+}
+
+// Exists 判断对象是否存在于模拟 MSS
+func (m *MSS) Exists(ctx context.Context, key string) (bool, error) {
+	return false, nil
+}
+
 // AuthorizedUpload 授权上传至模拟 MSS，expires：过期时间（秒）
 func (m *MSS) AuthorizedUpload(key string, expires int) (string, error) {
 	return m.GetURL(key), nil
 }
 
+// PresignedGetURL 获取模拟 MSS 上对象的预签名下载 URL，expires：过期时间（秒），opts：预签名可选配置
+func (m *MSS) PresignedGetURL(key string, expires int, opts ...types.PresignOption) (string, error) {
+	return m.GetURL(key), nil
+}
+
+// PostPolicy 获取模拟 MSS 表单上传策略，expires：过期时间（秒），opts：表单上传策略可选配置
+func (m *MSS) PostPolicy(key string, expires int, opts ...types.PostPolicyOption) (*types.PostPolicy, error) {
+	return &types.PostPolicy{
+		URL:        m.GetURL(""),
+		Fields:     map[string]string{"key": types.NewPostPolicyOptions(opts...).FormKey(key)},
+		Expiration: time.Now().Add(time.Duration(expires) * time.Second),
+	}, nil
+}
+
+// InitiateMultipartUpload 初始化模拟 MSS 分片上传，返回上传 ID，opts：对象可选配置
+func (m *MSS) InitiateMultipartUpload(ctx context.Context, key string, opts ...types.ObjectOption) (string, error) {
+	return "mock", nil
+}
+
+// PresignedUploadPartURL 获取模拟 MSS 分片上传的预签名 URL，partNumber：分片序号，expires：过期时间（秒）
+func (m *MSS) PresignedUploadPartURL(key, uploadID string, partNumber, expires int) (string, error) {
+	return m.GetURL(key), nil
+}
+
+// CompleteMultipartUpload 完成模拟 MSS 分片上传，parts：已上传的分片列表
+func (m *MSS) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []types.Part) (string, error) {
+	return m.GetURL(key), nil
+}
+
+// AbortMultipartUpload 取消模拟 MSS 分片上传
+func (m *MSS) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	return nil
+}
+
+// ListParts 列举模拟 MSS 分片上传中已上传的分片
+func (m *MSS) ListParts(ctx context.Context, key, uploadID string) ([]types.Part, error) {
+	return nil, nil
+}
+
 // GetThumbnailSuffix 获取缩略图后缀
 func (m *MSS) GetThumbnailSuffix(width, height int, size int64) string {
 	return ""
 }
 
+// ProcessImage 获取模拟 MSS 上图片处理后的访问 URL，opts：图片处理可选配置
+func (m *MSS) ProcessImage(ctx context.Context, key string, opts ...types.ImageOption) (string, error) {
+	ip := types.NewImageOptions(opts...)
+	if err := ip.Check(); err != nil {
+		return "", err
+	}
+
+	return m.GetURL(ip.CacheKey(key, "", ip.Format)), nil
+}
+
+// SetLifecycleRules 设置模拟 MSS 存储桶的生命周期规则，仅校验规则
+func (m *MSS) SetLifecycleRules(ctx context.Context, rules []types.LifecycleRule) error {
+	_, err := types.NormalizeLifecycleRules(rules)
+	return err
+}
+
+// GetLifecycleRules 获取模拟 MSS 存储桶的生命周期规则
+func (m *MSS) GetLifecycleRules(ctx context.Context) ([]types.LifecycleRule, error) {
+	return nil, nil
+}
+
 // mockReadCloser 模拟 io.ReadCloser
 type mockReadCloser struct{}
 
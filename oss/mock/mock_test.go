@@ -1,11 +1,14 @@
 package mock
 
 import (
+	"context"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
 )
 
 func TestNewMSS(t *testing.T) {
@@ -33,3 +36,42 @@ func TestLSS_AuthorizedUpload(t *testing.T) {
 	mss := NewMSS()
 	t.Log(mss.AuthorizedUpload("test/test.txt", 120))
 }
+
+func TestMSS_StatObject(t *testing.T) {
+	mss := NewMSS()
+	ctx := context.Background()
+
+	result, err := mss.ListObjects(ctx, "test/")
+	require.NoError(t, err)
+	assert.Empty(t, result.Objects)
+
+	_, err = mss.StatObject(ctx, "test/test.txt")
+	require.ErrorIs(t, err, types.ErrObjectNotFound)
+
+	ok, err := mss.Exists(ctx, "test/test.txt")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestMSS_ProcessImage(t *testing.T) {
+	mss := NewMSS()
+
+	u, err := mss.ProcessImage(context.Background(), "test/test.png", types.WithResize(100, 0, ""))
+	require.NoError(t, err)
+	assert.Regexp(t, `^\.image-cache/test/test\.png@[0-9a-f]{16}\.png$`, u)
+
+	_, err = mss.ProcessImage(context.Background(), "test/test.png")
+	require.ErrorIs(t, err, types.ErrInvalidImageOptions)
+}
+
+func TestMSS_LifecycleRules(t *testing.T) {
+	mss := NewMSS()
+	ctx := context.Background()
+
+	require.NoError(t, mss.SetLifecycleRules(ctx, []types.LifecycleRule{types.NewExpirationRule("export/", 7)}))
+	require.ErrorIs(t, mss.SetLifecycleRules(ctx, []types.LifecycleRule{{Prefix: "export/"}}), types.ErrInvalidLifecycleRule)
+
+	rules, err := mss.GetLifecycleRules(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+}
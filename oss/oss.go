@@ -1,16 +1,22 @@
 package oss
 
 import (
+	"context"
+	stderrors "errors"
 	"io"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/sliveryou/micro-pkg/oss/aliyun"
 	"github.com/sliveryou/micro-pkg/oss/huawei"
+	"github.com/sliveryou/micro-pkg/oss/internal/progress"
 	"github.com/sliveryou/micro-pkg/oss/local"
 	"github.com/sliveryou/micro-pkg/oss/minio"
 	"github.com/sliveryou/micro-pkg/oss/mock"
+	"github.com/sliveryou/micro-pkg/oss/s3"
 	"github.com/sliveryou/micro-pkg/oss/tencent"
+	"github.com/sliveryou/micro-pkg/oss/types"
 	"github.com/sliveryou/micro-pkg/xhttp"
 )
 
@@ -22,28 +28,73 @@ type OSS interface {
 	GetURL(key string) string
 	// GetObject 获取对象在 OSS 的存储数据
 	GetObject(key string) (io.ReadCloser, error)
+	// GetObjectCtx 获取对象在 OSS 的存储数据
+	GetObjectCtx(ctx context.Context, key string) (io.ReadCloser, error)
 	// PutObject 上传对象至 OSS
 	PutObject(key string, reader io.Reader) (string, error)
+	// PutObjectCtx 上传对象至 OSS，opts：对象可选配置
+	PutObjectCtx(ctx context.Context, key string, reader io.Reader, opts ...types.ObjectOption) (string, error)
 	// DeleteObjects 批量删除 OSS 上的对象
 	DeleteObjects(keys ...string) error
+	// DeleteObjectsCtx 批量删除 OSS 上的对象
+	DeleteObjectsCtx(ctx context.Context, keys ...string) error
 	// UploadFile 上传文件至 OSS，filePath：文件路径，partSize：分块大小（字节），routines：并发数
 	UploadFile(key, filePath string, partSize int64, routines int) (string, error)
+	// UploadFileCtx 上传文件至 OSS，filePath：文件路径，partSize：分块大小（字节），routines：并发数，opts：对象可选配置
+	UploadFileCtx(ctx context.Context, key, filePath string, partSize int64, routines int, opts ...types.ObjectOption) (string, error)
+	// CopyObject 在 OSS 服务端复制对象，源对象不存在时返回 types.ErrObjectNotFound
+	CopyObject(ctx context.Context, srcKey, dstKey string) (string, error)
+	// MoveObject 在 OSS 服务端移动对象，源对象不存在时返回 types.ErrObjectNotFound
+	MoveObject(ctx context.Context, srcKey, dstKey string) (string, error)
+	// ListObjects 列举 OSS 上指定前缀的对象，opts：列举可选配置
+	ListObjects(ctx context.Context, prefix string, opts ...types.ListOption) (*types.ListObjectsResult, error)
+	// StatObject 获取 OSS 上对象的元信息，对象不存在时返回 types.ErrObjectNotFound
+	StatObject(ctx context.Context, key string) (*types.ObjectInfo, error)
+	// Exists 判断对象是否存在于 OSS
+	Exists(ctx context.Context, key string) (bool, error)
 	// AuthorizedUpload 授权上传至 OSS，expires：过期时间（秒）
 	AuthorizedUpload(key string, expires int) (string, error)
+	// PresignedGetURL 获取 OSS 上对象的预签名下载 URL，expires：过期时间（秒），opts：预签名可选配置
+	PresignedGetURL(key string, expires int, opts ...types.PresignOption) (string, error)
+	// PostPolicy 获取浏览器表单直传的上传策略，可限制对象键前缀、内容长度和内容类型，expires：过期时间（秒），opts：表单上传策略可选配置
+	PostPolicy(key string, expires int, opts ...types.PostPolicyOption) (*types.PostPolicy, error)
+	// InitiateMultipartUpload 初始化分片上传，返回上传 ID，opts：对象可选配置
+	InitiateMultipartUpload(ctx context.Context, key string, opts ...types.ObjectOption) (string, error)
+	// PresignedUploadPartURL 获取分片上传的预签名 URL，partNumber：分片序号（1~10000），expires：过期时间（秒）
+	PresignedUploadPartURL(key, uploadID string, partNumber, expires int) (string, error)
+	// CompleteMultipartUpload 完成分片上传，parts：已上传的分片列表，分片上传不存在时返回 types.ErrUploadNotFound
+	CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []types.Part) (string, error)
+	// AbortMultipartUpload 取消分片上传，分片上传不存在时返回 types.ErrUploadNotFound
+	AbortMultipartUpload(ctx context.Context, key, uploadID string) error
+	// ListParts 列举分片上传中已上传的分片，分片上传不存在时返回 types.ErrUploadNotFound
+	ListParts(ctx context.Context, key, uploadID string) ([]types.Part, error)
 	// GetThumbnailSuffix 获取缩略图后缀，如果只传一个值则进行等比缩放，两个值都传时会强制缩放，可能会导致图片变形
 	GetThumbnailSuffix(width, height int, size int64) string
+	// ProcessImage 获取图片处理（裁剪、缩放、水印、格式转换和质量）后的访问 URL，opts：图片处理可选配置，
+	// aliyun、huawei 和 tencent 模式下使用云服务商图片处理参数，minio、s3 和 local 模式下使用纯 Go 渲染并将结果缓存至 types.ImageCachePrefix 保留前缀下
+	ProcessImage(ctx context.Context, key string, opts ...types.ImageOption) (string, error)
+	// SetLifecycleRules 设置存储桶的生命周期规则（按前缀过期删除对象、取消未完成的分片上传），覆盖已有规则，rules 为空时删除全部规则
+	SetLifecycleRules(ctx context.Context, rules []types.LifecycleRule) error
+	// GetLifecycleRules 获取存储桶的生命周期规则
+	GetLifecycleRules(ctx context.Context) ([]types.LifecycleRule, error)
 }
 
 // Config OSS 配置
 type Config struct {
-	UseSSL          bool   `json:",optional"`                                         // 是否使用安全配置（用于 minio 和 local 云服务商模式）
-	UploadInternal  bool   `json:",optional"`                                         // 是否使用内网上传（用于 aliyun 云服务商模式）
-	NotSetACL       bool   `json:",optional"`                                         // 不设置权限规则
-	Cloud           string `json:",options=[aliyun,huawei,tencent,minio,local,mock]"` // 云服务商（当前支持 aliyun、huawei、tencent、minio、local 和 mock）
-	EndPoint        string `json:",optional"`                                         // 端节点
-	AccessKeyID     string `json:",optional"`                                         // 访问鉴权ID
-	AccessKeySecret string `json:",optional"`                                         // 访问鉴权私钥
-	BucketName      string `json:",optional"`                                         // 存储桶名称
+	UseSSL          bool   `json:",optional"`                                            // 是否使用安全配置（用于 minio、s3 和 local 云服务商模式）
+	UploadInternal  bool   `json:",optional"`                                            // 是否使用内网上传（用于 aliyun 云服务商模式）
+	NotSetACL       bool   `json:",optional"`                                            // 不设置权限规则（local 云服务商模式下读取对象需使用签名 URL）
+	Cloud           string `json:",options=[aliyun,huawei,tencent,minio,s3,local,mock]"` // 云服务商（当前支持 aliyun、huawei、tencent、minio、s3、local 和 mock）
+	EndPoint        string `json:",optional"`                                            // 端节点
+	AccessKeyID     string `json:",optional"`                                            // 访问鉴权ID
+	AccessKeySecret string `json:",optional"`                                            // 访问鉴权私钥（local 云服务商模式下用作签名 URL 的密钥）
+	BucketName      string `json:",optional"`                                            // 存储桶名称
+	Region          string `json:",optional"`                                            // 存储桶所在区域（用于 s3 云服务商模式，为空时自动探测）
+	PathStyle       bool   `json:",optional"`                                            // 是否使用路径寻址（用于 s3 云服务商模式，默认使用虚拟主机寻址）
+
+	SweepInterval time.Duration `json:",optional"` // 生命周期后台清理间隔（用于 local 云服务商模式，为 0 时不开启，不再使用时需调用 Close 停止）
+
+	Replication ReplicationConfig `json:",optional"` // 多后端复制配置（配置副本后端后，NewOSS 返回 *ReplicatedOSS）
 }
 
 // checkConfig 检查配置
@@ -60,16 +111,70 @@ func checkConfig(c Config) (err error) {
 			err = errors.Errorf("oss: illegal oss cloud %s config", cloud)
 		}
 	}
+	if err == nil && len(c.Replication.Replicas) > 0 {
+		rc := c.Replication
+		err = checkReplication(len(rc.Replicas)+1, rc.WritePolicy, rc.ReadOrder)
+	}
 
 	return
 }
 
-// NewOSS 新建 OSS 客户端
+// NewOSS 新建 OSS 客户端，配置副本后端时新建多后端复制 OSS 客户端
 func NewOSS(c Config) (OSS, error) {
 	if err := checkConfig(c); err != nil {
 		return nil, err
 	}
 
+	o, err := newOSS(c)
+	if err != nil || len(c.Replication.Replicas) == 0 {
+		return o, err
+	}
+
+	backends := []OSS{o}
+	for _, replica := range c.Replication.Replicas {
+		rc := replica.config()
+		if err := checkConfig(rc); err != nil {
+			return nil, stderrors.Join(err, closeBackends(backends))
+		}
+		b, err := newOSS(rc)
+		if err != nil {
+			return nil, stderrors.Join(err, closeBackends(backends))
+		}
+		backends = append(backends, b)
+	}
+
+	opts := []ReplicationOption{
+		WithWritePolicy(c.Replication.WritePolicy),
+		WithReadOrder(c.Replication.ReadOrder...),
+		WithReconcile(c.Replication.ReconcileInterval, c.Replication.ReconcilePrefix),
+	}
+	if c.Replication.ReconcileDelete {
+		opts = append(opts, WithReconcileDelete())
+	}
+	r, err := NewReplicatedOSS(backends, opts...)
+	if err != nil {
+		return nil, stderrors.Join(err, closeBackends(backends))
+	}
+
+	return r, nil
+}
+
+// closeBackends 关闭全部后端，如停止本地 LSS 的后台生命周期清理，返回全部关闭错误
+func closeBackends(backends []OSS) error {
+	var errs []error
+	for i, b := range backends {
+		if closer, ok := b.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, errors.WithMessagef(err, "oss: close backend %d err", i))
+			}
+		}
+	}
+
+	return stderrors.Join(errs...)
+}
+
+// newOSS 新建单个后端的 OSS 客户端
+func newOSS(c Config) (OSS, error) {
 	var client OSS
 	var err error
 	parsedEndpoint, useSSL := xhttp.ParseEndpoint(c.EndPoint)
@@ -89,9 +194,18 @@ func NewOSS(c Config) (OSS, error) {
 		client, err = minio.NewMinIO(parsedEndpoint, c.AccessKeyID, c.AccessKeySecret, c.BucketName,
 			minio.WithSecure(c.UseSSL || useSSL),
 			minio.WithNotSetACL(c.NotSetACL))
+	case s3.CloudS3:
+		client, err = s3.NewS3(parsedEndpoint, c.AccessKeyID, c.AccessKeySecret, c.BucketName,
+			s3.WithSecure(c.UseSSL || useSSL),
+			s3.WithNotSetACL(c.NotSetACL),
+			s3.WithRegion(c.Region),
+			s3.WithPathStyle(c.PathStyle))
 	case local.CloudLocal:
 		client, err = local.NewLSS(parsedEndpoint, c.BucketName,
-			local.WithSecure(c.UseSSL || useSSL))
+			local.WithSecure(c.UseSSL || useSSL),
+			local.WithPrivate(c.NotSetACL),
+			local.WithSecretKey(c.AccessKeySecret),
+			local.WithSweepInterval(c.SweepInterval))
 	default:
 		client = mock.NewMSS()
 	}
@@ -112,12 +226,27 @@ func MustNewOSS(c Config) OSS {
 	return o
 }
 
+// SetGlobalRateLimit 设置进程内全部 OSS 客户端上传共享的带宽限制（字节/秒），为 0 时不限制，
+// 与 types.WithRateLimit 设置的单次传输带宽限制同时生效
+func SetGlobalRateLimit(bytesPerSecond int64) {
+	progress.SetGlobalLimit(bytesPerSecond)
+}
+
 // defaultOSS 默认 OSS 客户端
 type defaultOSS struct {
 	c      Config
 	client OSS
 }
 
+// Close 关闭 OSS 客户端，如停止本地 LSS 的后台生命周期清理，客户端无需关闭时直接返回
+func (o *defaultOSS) Close() error {
+	if closer, ok := o.client.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
 // Cloud 获取云服务商名称
 func (o *defaultOSS) Cloud() string {
 	return o.client.Cloud()
@@ -133,18 +262,38 @@ func (o *defaultOSS) GetObject(key string) (io.ReadCloser, error) {
 	return o.client.GetObject(key)
 }
 
+// GetObjectCtx 获取对象在 OSS 的存储数据
+func (o *defaultOSS) GetObjectCtx(ctx context.Context, key string) (io.ReadCloser, error) {
+	return o.client.GetObjectCtx(ctx, key)
+}
+
 // PutObject 上传对象至 OSS
 func (o *defaultOSS) PutObject(key string, reader io.Reader) (string, error) {
 	return o.client.PutObject(key, reader)
 }
 
+// PutObjectCtx 上传对象至 OSS，opts：对象可选配置
+func (o *defaultOSS) PutObjectCtx(ctx context.Context, key string, reader io.Reader, opts ...types.ObjectOption) (string, error) {
+	return o.client.PutObjectCtx(ctx, key, reader, opts...)
+}
+
 // DeleteObjects 批量删除 OSS 上的对象
 func (o *defaultOSS) DeleteObjects(keys ...string) error {
 	return o.client.DeleteObjects(keys...)
 }
 
+// DeleteObjectsCtx 批量删除 OSS 上的对象
+func (o *defaultOSS) DeleteObjectsCtx(ctx context.Context, keys ...string) error {
+	return o.client.DeleteObjectsCtx(ctx, keys...)
+}
+
 // UploadFile 上传文件至 OSS，filePath：文件路径，partSize：分块大小（字节），routines：并发数
 func (o *defaultOSS) UploadFile(key, filePath string, partSize int64, routines int) (string, error) {
+	return o.UploadFileCtx(context.Background(), key, filePath, partSize, routines)
+}
+
+// UploadFileCtx 上传文件至 OSS，filePath：文件路径，partSize：分块大小（字节），routines：并发数，opts：对象可选配置
+func (o *defaultOSS) UploadFileCtx(ctx context.Context, key, filePath string, partSize int64, routines int, opts ...types.ObjectOption) (string, error) {
 	// 默认分片大小为 5MB
 	if partSize <= 0 {
 		partSize = 5 * 1024 * 1024
@@ -154,7 +303,32 @@ func (o *defaultOSS) UploadFile(key, filePath string, partSize int64, routines i
 		routines = 5
 	}
 
-	return o.client.UploadFile(key, filePath, partSize, routines)
+	return o.client.UploadFileCtx(ctx, key, filePath, partSize, routines, opts...)
+}
+
+// CopyObject 在 OSS 服务端复制对象，源对象不存在时返回 types.ErrObjectNotFound
+func (o *defaultOSS) CopyObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	return o.client.CopyObject(ctx, srcKey, dstKey)
+}
+
+// MoveObject 在 OSS 服务端移动对象，源对象不存在时返回 types.ErrObjectNotFound
+func (o *defaultOSS) MoveObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	return o.client.MoveObject(ctx, srcKey, dstKey)
+}
+
+// ListObjects 列举 OSS 上指定前缀的对象，opts：列举可选配置
+func (o *defaultOSS) ListObjects(ctx context.Context, prefix string, opts ...types.ListOption) (*types.ListObjectsResult, error) {
+	return o.client.ListObjects(ctx, prefix, opts...)
+}
+
+// StatObject 获取 OSS 上对象的元信息，对象不存在时返回 types.ErrObjectNotFound
+func (o *defaultOSS) StatObject(ctx context.Context, key string) (*types.ObjectInfo, error) {
+	return o.client.StatObject(ctx, key)
+}
+
+// Exists 判断对象是否存在于 OSS
+func (o *defaultOSS) Exists(ctx context.Context, key string) (bool, error) {
+	return o.client.Exists(ctx, key)
 }
 
 // AuthorizedUpload 授权上传至 OSS，expires：过期时间（秒）
@@ -167,7 +341,83 @@ func (o *defaultOSS) AuthorizedUpload(key string, expires int) (string, error) {
 	return o.client.AuthorizedUpload(key, expires)
 }
 
+// PresignedGetURL 获取 OSS 上对象的预签名下载 URL，expires：过期时间（秒），opts：预签名可选配置
+func (o *defaultOSS) PresignedGetURL(key string, expires int, opts ...types.PresignOption) (string, error) {
+	// 默认过期时间为3600s
+	if expires <= 0 {
+		expires = 3600
+	}
+
+	return o.client.PresignedGetURL(key, expires, opts...)
+}
+
+// PostPolicy 获取浏览器表单直传的上传策略，可限制对象键前缀、内容长度和内容类型，expires：过期时间（秒），opts：表单上传策略可选配置
+func (o *defaultOSS) PostPolicy(key string, expires int, opts ...types.PostPolicyOption) (*types.PostPolicy, error) {
+	// 默认过期时间为3600s
+	if expires <= 0 {
+		expires = 3600
+	}
+
+	return o.client.PostPolicy(key, expires, opts...)
+}
+
+// InitiateMultipartUpload 初始化分片上传，返回上传 ID，opts：对象可选配置
+func (o *defaultOSS) InitiateMultipartUpload(ctx context.Context, key string, opts ...types.ObjectOption) (string, error) {
+	return o.client.InitiateMultipartUpload(ctx, key, opts...)
+}
+
+// PresignedUploadPartURL 获取分片上传的预签名 URL，partNumber：分片序号（1~10000），expires：过期时间（秒）
+func (o *defaultOSS) PresignedUploadPartURL(key, uploadID string, partNumber, expires int) (string, error) {
+	if err := types.CheckPartNumber(partNumber); err != nil {
+		return "", err
+	}
+	// 默认过期时间为3600s
+	if expires <= 0 {
+		expires = 3600
+	}
+
+	return o.client.PresignedUploadPartURL(key, uploadID, partNumber, expires)
+}
+
+// CompleteMultipartUpload 完成分片上传，parts：已上传的分片列表，为空时使用全部已上传的分片
+func (o *defaultOSS) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []types.Part) (string, error) {
+	if len(parts) == 0 {
+		listed, err := o.client.ListParts(ctx, key, uploadID)
+		if err != nil {
+			return "", err
+		}
+		parts = listed
+	}
+
+	return o.client.CompleteMultipartUpload(ctx, key, uploadID, parts)
+}
+
+// AbortMultipartUpload 取消分片上传，分片上传不存在时返回 types.ErrUploadNotFound
+func (o *defaultOSS) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	return o.client.AbortMultipartUpload(ctx, key, uploadID)
+}
+
+// ListParts 列举分片上传中已上传的分片，分片上传不存在时返回 types.ErrUploadNotFound
+func (o *defaultOSS) ListParts(ctx context.Context, key, uploadID string) ([]types.Part, error) {
+	return o.client.ListParts(ctx, key, uploadID)
+}
+
 // GetThumbnailSuffix 获取缩略图后缀
 func (o *defaultOSS) GetThumbnailSuffix(width, height int, size int64) string {
 	return o.client.GetThumbnailSuffix(width, height, size)
 }
+
+// ProcessImage 获取图片处理后的访问 URL，opts：图片处理可选配置
+func (o *defaultOSS) ProcessImage(ctx context.Context, key string, opts ...types.ImageOption) (string, error) {
+	return o.client.ProcessImage(ctx, key, opts...)
+}
+
+// SetLifecycleRules 设置存储桶的生命周期规则，覆盖已有规则，rules 为空时删除全部规则
+func (o *defaultOSS) SetLifecycleRules(ctx context.Context, rules []types.LifecycleRule) error {
+	return o.client.SetLifecycleRules(ctx, rules)
+}
+
+// GetLifecycleRules 获取存储桶的生命周期规则
+func (o *defaultOSS) GetLifecycleRules(ctx context.Context) ([]types.LifecycleRule, error) {
+	return o.client.GetLifecycleRules(ctx)
+}
@@ -2,10 +2,15 @@ package oss
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/mock"
 )
 
 func TestMustNewOSS(t *testing.T) {
@@ -78,6 +83,29 @@ func TestMinIOGetURL(t *testing.T) {
 	fmt.Println(o.GetURL("test/test.txt"))
 }
 
+func TestS3GetURL(t *testing.T) {
+	c := Config{
+		NotSetACL:       true,
+		Cloud:           "s3",
+		EndPoint:        "https://s3.ap-east-1.amazonaws.com",
+		AccessKeyID:     "accessKeyID",
+		AccessKeySecret: "accessKeySecret",
+		BucketName:      "my-test",
+		Region:          "ap-east-1",
+	}
+
+	o, err := NewOSS(c)
+	require.NoError(t, err)
+	assert.Equal(t, "s3", o.Cloud())
+	assert.Equal(t, "https://my-test.s3.ap-east-1.amazonaws.com/test/test.txt", o.GetURL("test/test.txt"))
+
+	c.EndPoint = "ceph.local:7480"
+	c.PathStyle = true
+	o, err = NewOSS(c)
+	require.NoError(t, err)
+	assert.Equal(t, "http://ceph.local:7480/my-test/test/test.txt", o.GetURL("test/test.txt"))
+}
+
 func TestLocalGetURL(t *testing.T) {
 	c := Config{
 		Cloud:      "local",
@@ -99,3 +127,20 @@ func TestMockGetURL(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "test/test.txt", o.GetURL("test/test.txt"))
 }
+
+func TestOSS_Close(t *testing.T) {
+	defer os.RemoveAll("testdata")
+
+	// 关闭时停止本地 LSS 的后台生命周期清理
+	o, err := NewOSS(Config{Cloud: "local", BucketName: "testdata", SweepInterval: time.Hour})
+	require.NoError(t, err)
+	closer, ok := o.(io.Closer)
+	require.True(t, ok)
+	require.NoError(t, closer.Close())
+
+	// 转发至被包装的客户端，客户端无需关闭时直接返回
+	client := &closerOSS{OSS: mock.NewMSS(), err: errBackendDown}
+	assert.ErrorIs(t, (&defaultOSS{client: client}).Close(), errBackendDown)
+	assert.True(t, client.closed)
+	assert.NoError(t, MustNewOSS(Config{Cloud: "mock"}).(io.Closer).Close())
+}
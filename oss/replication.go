@@ -0,0 +1,637 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+const (
+	// WritePolicyAll 同步写入全部后端，任一后端写入失败时返回错误
+	WritePolicyAll = "all"
+	// WritePolicyAsync 同步写入主后端，副本后端在后台异步写入，副本后端写入失败时仅记录日志
+	WritePolicyAsync = "async"
+)
+
+// ReplicationConfig 多后端复制配置
+type ReplicationConfig struct {
+	Replicas          []BackendConfig `json:",optional"`                     // 副本后端配置，配置后启用多后端复制，当前配置作为主后端
+	WritePolicy       string          `json:",optional,options=[all,async]"` // 写入策略（all：同步写入全部后端，async：同步写入主后端后异步写入副本后端），默认为 all
+	ReadOrder         []int           `json:",optional"`                     // 读取回退顺序（后端序号，0 为主后端，副本后端从 1 开始），默认依次读取主后端和副本后端
+	ReconcileInterval time.Duration   `json:",optional"`                     // 后台对账间隔，大于 0 时定期以主后端为准对账副本后端的对象
+	ReconcilePrefix   string          `json:",optional"`                     // 后台对账的对象键前缀，默认对账全部对象
+	ReconcileDelete   bool            `json:",optional"`                     // 后台对账时是否删除仅存在于副本后端的对象，默认不删除
+}
+
+// BackendConfig 副本后端 OSS 配置，字段含义同 Config
+type BackendConfig struct {
+	UseSSL          bool   `json:",optional"`                                            // 是否使用安全配置（用于 minio、s3 和 local 云服务商模式）
+	UploadInternal  bool   `json:",optional"`                                            // 是否使用内网上传（用于 aliyun 云服务商模式）
+	NotSetACL       bool   `json:",optional"`                                            // 不设置权限规则（local 云服务商模式下读取对象需使用签名 URL）
+	Cloud           string `json:",options=[aliyun,huawei,tencent,minio,s3,local,mock]"` // 云服务商（当前支持 aliyun、huawei、tencent、minio、s3、local 和 mock）
+	EndPoint        string `json:",optional"`                                            // 端节点
+	AccessKeyID     string `json:",optional"`                                            // 访问鉴权ID
+	AccessKeySecret string `json:",optional"`                                            // 访问鉴权私钥（local 云服务商模式下用作签名 URL 的密钥）
+	BucketName      string `json:",optional"`                                            // 存储桶名称
+	Region          string `json:",optional"`                                            // 存储桶所在区域（用于 s3 云服务商模式，为空时自动探测）
+	PathStyle       bool   `json:",optional"`                                            // 是否使用路径寻址（用于 s3 云服务商模式，默认使用虚拟主机寻址）
+
+	SweepInterval time.Duration `json:",optional"` // 生命周期后台清理间隔（用于 local 云服务商模式，为 0 时不开启）
+}
+
+// config 获取副本后端对应的 OSS 配置
+func (c BackendConfig) config() Config {
+	return Config{
+		UseSSL:          c.UseSSL,
+		UploadInternal:  c.UploadInternal,
+		NotSetACL:       c.NotSetACL,
+		Cloud:           c.Cloud,
+		EndPoint:        c.EndPoint,
+		AccessKeyID:     c.AccessKeyID,
+		AccessKeySecret: c.AccessKeySecret,
+		BucketName:      c.BucketName,
+		Region:          c.Region,
+		PathStyle:       c.PathStyle,
+		SweepInterval:   c.SweepInterval,
+	}
+}
+
+// ReplicationOption 多后端复制 OSS 客户端可选配置
+type ReplicationOption func(r *ReplicatedOSS)
+
+// WithWritePolicy 使用写入策略，支持 WritePolicyAll 和 WritePolicyAsync，默认为 WritePolicyAll
+func WithWritePolicy(writePolicy string) ReplicationOption {
+	return func(r *ReplicatedOSS) {
+		if writePolicy != "" {
+			r.writePolicy = writePolicy
+		}
+	}
+}
+
+// WithReadOrder 使用读取回退顺序，order：后端序号，0 为主后端，默认依次读取主后端和副本后端
+func WithReadOrder(order ...int) ReplicationOption {
+	return func(r *ReplicatedOSS) {
+		if len(order) > 0 {
+			r.readOrder = order
+		}
+	}
+}
+
+// WithReconcile 使用后台对账，interval：对账间隔，大于 0 时启用，prefix：对账的对象键前缀
+func WithReconcile(interval time.Duration, prefix string) ReplicationOption {
+	return func(r *ReplicatedOSS) {
+		r.reconcileInterval = interval
+		r.reconcilePrefix = prefix
+	}
+}
+
+// WithReconcileDelete 使用对账删除，对账时删除仅存在于副本后端的对象，默认不删除
+//
+// 副本后端已有数据（如迁移场景）时启用会删除这些数据，请谨慎使用
+func WithReconcileDelete() ReplicationOption {
+	return func(r *ReplicatedOSS) {
+		r.reconcileDelete = true
+	}
+}
+
+// ReplicatedOSS 多后端复制 OSS 客户端，写入操作按写入策略复制至全部后端，
+// 读取操作按读取回退顺序依次尝试各后端，签名 URL、表单上传策略和分片上传会话均由主后端提供
+type ReplicatedOSS struct {
+	backends          []OSS              // 后端列表，第一个为主后端
+	writePolicy       string             // 写入策略
+	readOrder         []int              // 读取回退顺序
+	reconcileInterval time.Duration      // 后台对账间隔
+	reconcilePrefix   string             // 后台对账的对象键前缀
+	reconcileDelete   bool               // 对账时是否删除仅存在于副本后端的对象
+	ctx               context.Context    // 后台对账上下文
+	cancel            context.CancelFunc // 后台对账取消函数
+	wg                sync.WaitGroup     // 后台任务等待组
+}
+
+// NewReplicatedOSS 新建多后端复制 OSS 客户端，backends：后端列表，第一个为主后端，其余为副本后端
+func NewReplicatedOSS(backends []OSS, opts ...ReplicationOption) (*ReplicatedOSS, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("oss: illegal replicated oss config")
+	}
+	for _, b := range backends {
+		if b == nil {
+			return nil, errors.New("oss: illegal replicated oss config")
+		}
+	}
+
+	r := &ReplicatedOSS{backends: backends, writePolicy: WritePolicyAll}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if err := checkReplication(len(backends), r.writePolicy, r.readOrder); err != nil {
+		return nil, err
+	}
+	if len(r.readOrder) == 0 {
+		for i := range backends {
+			r.readOrder = append(r.readOrder, i)
+		}
+	}
+
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	if r.reconcileInterval > 0 && len(backends) > 1 {
+		r.startReconcile()
+	}
+
+	return r, nil
+}
+
+// MustNewReplicatedOSS 新建多后端复制 OSS 客户端
+func MustNewReplicatedOSS(backends []OSS, opts ...ReplicationOption) *ReplicatedOSS {
+	r, err := NewReplicatedOSS(backends, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+// Close 停止后台对账，等待异步写入完成后关闭全部后端
+func (r *ReplicatedOSS) Close() error {
+	r.cancel()
+	r.wg.Wait()
+
+	return closeBackends(r.backends)
+}
+
+// Backends 获取后端列表，第一个为主后端
+func (r *ReplicatedOSS) Backends() []OSS {
+	return r.backends
+}
+
+// Cloud 获取主后端云服务商名称
+func (r *ReplicatedOSS) Cloud() string {
+	return r.primary().Cloud()
+}
+
+// GetURL 获取对象在主后端上的完整访问 URL
+func (r *ReplicatedOSS) GetURL(key string) string {
+	return r.primary().GetURL(key)
+}
+
+// GetObject 按读取回退顺序获取对象的存储数据
+func (r *ReplicatedOSS) GetObject(key string) (io.ReadCloser, error) {
+	return r.GetObjectCtx(context.Background(), key)
+}
+
+// GetObjectCtx 按读取回退顺序获取对象的存储数据
+func (r *ReplicatedOSS) GetObjectCtx(ctx context.Context, key string) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	err := r.read(func(b OSS) (err error) {
+		rc, err = b.GetObjectCtx(ctx, key)
+		return err
+	})
+
+	return rc, err
+}
+
+// PutObject 上传对象至全部后端
+func (r *ReplicatedOSS) PutObject(key string, reader io.Reader) (string, error) {
+	return r.PutObjectCtx(context.Background(), key, reader)
+}
+
+// PutObjectCtx 上传对象至全部后端，数据暂存至本地临时文件以便写入多个后端，opts：对象可选配置
+func (r *ReplicatedOSS) PutObjectCtx(ctx context.Context, key string, reader io.Reader, opts ...types.ObjectOption) (string, error) {
+	if len(r.backends) == 1 {
+		return r.primary().PutObjectCtx(ctx, key, reader, opts...)
+	}
+
+	tmp, size, err := spool(reader)
+	if err != nil {
+		return "", err
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+
+	u, err := r.primary().PutObjectCtx(ctx, key, io.NewSectionReader(tmp, 0, size), opts...)
+	if err != nil {
+		cleanup()
+		return "", err
+	}
+
+	return u, r.replicate(ctx, "put object "+key, func(ctx context.Context, b OSS) error {
+		_, err := b.PutObjectCtx(ctx, key, io.NewSectionReader(tmp, 0, size), opts...)
+		return err
+	}, cleanup)
+}
+
+// DeleteObjects 批量删除全部后端上的对象
+func (r *ReplicatedOSS) DeleteObjects(keys ...string) error {
+	return r.DeleteObjectsCtx(context.Background(), keys...)
+}
+
+// DeleteObjectsCtx 批量删除全部后端上的对象
+func (r *ReplicatedOSS) DeleteObjectsCtx(ctx context.Context, keys ...string) error {
+	if err := r.primary().DeleteObjectsCtx(ctx, keys...); err != nil {
+		return err
+	}
+
+	return r.replicate(ctx, "delete objects", func(ctx context.Context, b OSS) error {
+		return b.DeleteObjectsCtx(ctx, keys...)
+	}, nil)
+}
+
+// UploadFile 上传文件至全部后端，filePath：文件路径，partSize：分块大小（字节），routines：并发数
+func (r *ReplicatedOSS) UploadFile(key, filePath string, partSize int64, routines int) (string, error) {
+	return r.UploadFileCtx(context.Background(), key, filePath, partSize, routines)
+}
+
+// UploadFileCtx 上传文件至全部后端，filePath：文件路径，partSize：分块大小（字节），routines：并发数，opts：对象可选配置，
+// 使用 WritePolicyAsync 写入策略时，需保证异步写入完成前文件仍然存在
+func (r *ReplicatedOSS) UploadFileCtx(ctx context.Context, key, filePath string, partSize int64, routines int, opts ...types.ObjectOption) (string, error) {
+	u, err := r.primary().UploadFileCtx(ctx, key, filePath, partSize, routines, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return u, r.replicate(ctx, "upload file "+key, func(ctx context.Context, b OSS) error {
+		_, err := b.UploadFileCtx(ctx, key, filePath, partSize, routines, opts...)
+		return err
+	}, nil)
+}
+
+// CopyObject 在全部后端的服务端复制对象，副本后端缺少源对象时从主后端复制目标对象
+func (r *ReplicatedOSS) CopyObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	u, err := r.primary().CopyObject(ctx, srcKey, dstKey)
+	if err != nil {
+		return "", err
+	}
+
+	return u, r.replicate(ctx, "copy object "+dstKey, func(ctx context.Context, b OSS) error {
+		if _, err := b.CopyObject(ctx, srcKey, dstKey); !errors.Is(err, types.ErrObjectNotFound) {
+			return err
+		}
+		return copyObject(ctx, r.primary(), b, dstKey)
+	}, nil)
+}
+
+// MoveObject 在全部后端的服务端移动对象，副本后端缺少源对象时从主后端复制目标对象
+func (r *ReplicatedOSS) MoveObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	u, err := r.primary().MoveObject(ctx, srcKey, dstKey)
+	if err != nil {
+		return "", err
+	}
+
+	return u, r.replicate(ctx, "move object "+dstKey, func(ctx context.Context, b OSS) error {
+		if _, err := b.MoveObject(ctx, srcKey, dstKey); !errors.Is(err, types.ErrObjectNotFound) {
+			return err
+		}
+		return copyObject(ctx, r.primary(), b, dstKey)
+	}, nil)
+}
+
+// ListObjects 按读取回退顺序列举指定前缀的对象，opts：列举可选配置
+func (r *ReplicatedOSS) ListObjects(ctx context.Context, prefix string, opts ...types.ListOption) (*types.ListObjectsResult, error) {
+	var result *types.ListObjectsResult
+	err := r.read(func(b OSS) (err error) {
+		result, err = b.ListObjects(ctx, prefix, opts...)
+		return err
+	})
+
+	return result, err
+}
+
+// StatObject 按读取回退顺序获取对象的元信息，对象不存在时返回 types.ErrObjectNotFound
+func (r *ReplicatedOSS) StatObject(ctx context.Context, key string) (*types.ObjectInfo, error) {
+	var info *types.ObjectInfo
+	err := r.read(func(b OSS) (err error) {
+		info, err = b.StatObject(ctx, key)
+		return err
+	})
+
+	return info, err
+}
+
+// Exists 判断对象是否存在于任一后端
+func (r *ReplicatedOSS) Exists(ctx context.Context, key string) (bool, error) {
+	var exists bool
+	err := r.read(func(b OSS) (err error) {
+		exists, err = b.Exists(ctx, key)
+		if err == nil && !exists {
+			err = types.ErrObjectNotFound
+		}
+		return err
+	})
+	if errors.Is(err, types.ErrObjectNotFound) {
+		return false, nil
+	}
+
+	return exists, err
+}
+
+// AuthorizedUpload 授权上传至主后端，expires：过期时间（秒），上传的对象由后台对账复制至副本后端
+func (r *ReplicatedOSS) AuthorizedUpload(key string, expires int) (string, error) {
+	return r.primary().AuthorizedUpload(key, expires)
+}
+
+// PresignedGetURL 获取主后端上对象的预签名下载 URL，expires：过期时间（秒），opts：预签名可选配置
+func (r *ReplicatedOSS) PresignedGetURL(key string, expires int, opts ...types.PresignOption) (string, error) {
+	return r.primary().PresignedGetURL(key, expires, opts...)
+}
+
+// PostPolicy 获取主后端的浏览器表单直传上传策略，expires：过期时间（秒），opts：表单上传策略可选配置，上传的对象由后台对账复制至副本后端
+func (r *ReplicatedOSS) PostPolicy(key string, expires int, opts ...types.PostPolicyOption) (*types.PostPolicy, error) {
+	return r.primary().PostPolicy(key, expires, opts...)
+}
+
+// InitiateMultipartUpload 在主后端初始化分片上传，返回上传 ID，opts：对象可选配置
+func (r *ReplicatedOSS) InitiateMultipartUpload(ctx context.Context, key string, opts ...types.ObjectOption) (string, error) {
+	return r.primary().InitiateMultipartUpload(ctx, key, opts...)
+}
+
+// PresignedUploadPartURL 获取主后端分片上传的预签名 URL，partNumber：分片序号（1~10000），expires：过期时间（秒）
+func (r *ReplicatedOSS) PresignedUploadPartURL(key, uploadID string, partNumber, expires int) (string, error) {
+	return r.primary().PresignedUploadPartURL(key, uploadID, partNumber, expires)
+}
+
+// CompleteMultipartUpload 在主后端完成分片上传后将对象复制至副本后端，parts：已上传的分片列表
+func (r *ReplicatedOSS) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []types.Part) (string, error) {
+	u, err := r.primary().CompleteMultipartUpload(ctx, key, uploadID, parts)
+	if err != nil {
+		return "", err
+	}
+
+	return u, r.replicate(ctx, "complete multipart upload "+key, func(ctx context.Context, b OSS) error {
+		return copyObject(ctx, r.primary(), b, key)
+	}, nil)
+}
+
+// AbortMultipartUpload 取消主后端的分片上传，分片上传不存在时返回 types.ErrUploadNotFound
+func (r *ReplicatedOSS) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	return r.primary().AbortMultipartUpload(ctx, key, uploadID)
+}
+
+// ListParts 列举主后端分片上传中已上传的分片，分片上传不存在时返回 types.ErrUploadNotFound
+func (r *ReplicatedOSS) ListParts(ctx context.Context, key, uploadID string) ([]types.Part, error) {
+	return r.primary().ListParts(ctx, key, uploadID)
+}
+
+// GetThumbnailSuffix 获取主后端的缩略图后缀
+func (r *ReplicatedOSS) GetThumbnailSuffix(width, height int, size int64) string {
+	return r.primary().GetThumbnailSuffix(width, height, size)
+}
+
+// ProcessImage 获取主后端上图片处理后的访问 URL，opts：图片处理可选配置
+func (r *ReplicatedOSS) ProcessImage(ctx context.Context, key string, opts ...types.ImageOption) (string, error) {
+	return r.primary().ProcessImage(ctx, key, opts...)
+}
+
+// SetLifecycleRules 按写入策略设置全部后端存储桶的生命周期规则，覆盖已有规则，rules 为空时删除全部规则
+func (r *ReplicatedOSS) SetLifecycleRules(ctx context.Context, rules []types.LifecycleRule) error {
+	if err := r.primary().SetLifecycleRules(ctx, rules); err != nil {
+		return err
+	}
+
+	return r.replicate(ctx, "set lifecycle rules", func(ctx context.Context, b OSS) error {
+		return b.SetLifecycleRules(ctx, rules)
+	}, nil)
+}
+
+// GetLifecycleRules 获取主后端存储桶的生命周期规则
+func (r *ReplicatedOSS) GetLifecycleRules(ctx context.Context) ([]types.LifecycleRule, error) {
+	return r.primary().GetLifecycleRules(ctx)
+}
+
+// Reconcile 以主后端为准对账指定前缀的对象，将主后端存在而副本后端缺失的对象复制至副本后端，
+// 启用对账删除时还会删除仅存在于副本后端的对象（如副本后端删除失败或异步删除尚未执行），
+// 返回复制和删除的对象数量
+//
+// 任一后端列举失败时不做任何修改；先列举副本后端再列举主后端，写入总是先于副本后端写入主后端，
+// 因此列举期间新写入的对象不会被误删
+func (r *ReplicatedOSS) Reconcile(ctx context.Context, prefix string) (int, error) {
+	if len(r.backends) < 2 {
+		return 0, nil
+	}
+
+	replicaKeys := make([]map[string]struct{}, len(r.backends))
+	for i := 1; i < len(r.backends); i++ {
+		bk, err := listKeys(ctx, r.backends[i], prefix)
+		if err != nil {
+			return 0, errors.WithMessagef(err, "oss: reconcile list backend %d objects err", i)
+		}
+		replicaKeys[i] = bk
+	}
+	primaryKeys, err := listKeys(ctx, r.primary(), prefix)
+	if err != nil {
+		return 0, errors.WithMessage(err, "oss: reconcile list backend 0 objects err")
+	}
+
+	var firstErr error
+	setErr := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	fixed := 0
+	for i := 1; i < len(r.backends); i++ {
+		dst := r.backends[i]
+		for key := range primaryKeys {
+			if _, ok := replicaKeys[i][key]; ok {
+				continue
+			}
+			if err := copyObject(ctx, r.primary(), dst, key); err != nil {
+				// 列举后被删除的对象无需复制
+				if !errors.Is(err, types.ErrObjectNotFound) {
+					setErr(errors.WithMessagef(err, "oss: reconcile copy object %s to backend %d err", key, i))
+				}
+				continue
+			}
+			fixed++
+		}
+		if !r.reconcileDelete {
+			continue
+		}
+
+		deletes := make([]string, 0)
+		for key := range replicaKeys[i] {
+			if _, ok := primaryKeys[key]; !ok {
+				deletes = append(deletes, key)
+			}
+		}
+		if len(deletes) == 0 {
+			continue
+		}
+		if err := dst.DeleteObjectsCtx(ctx, deletes...); err != nil {
+			setErr(errors.WithMessagef(err, "oss: reconcile delete objects from backend %d err", i))
+			continue
+		}
+		fixed += len(deletes)
+	}
+
+	return fixed, firstErr
+}
+
+// startReconcile 启动后台对账
+func (r *ReplicatedOSS) startReconcile() {
+	r.wg.Add(1)
+	threading.GoSafe(func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.reconcileInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-ticker.C:
+				fixed, err := r.Reconcile(r.ctx, r.reconcilePrefix)
+				if err != nil && r.ctx.Err() == nil {
+					logx.Errorf("oss: replicated oss reconcile err: %v", err)
+				}
+				if fixed > 0 {
+					logx.Infof("oss: replicated oss reconcile successfully, fixed: %d", fixed)
+				}
+			}
+		}
+	})
+}
+
+// primary 获取主后端
+func (r *ReplicatedOSS) primary() OSS {
+	return r.backends[0]
+}
+
+// read 按读取回退顺序依次在各后端执行读取操作，直到成功，全部失败时返回最后一个错误
+func (r *ReplicatedOSS) read(fn func(b OSS) error) (err error) {
+	for _, i := range r.readOrder {
+		if err = fn(r.backends[i]); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// replicate 按写入策略在副本后端执行写入操作，cleanup 在全部副本后端写入完成后调用
+func (r *ReplicatedOSS) replicate(ctx context.Context, op string, fn func(ctx context.Context, b OSS) error, cleanup func()) error {
+	if r.writePolicy != WritePolicyAsync {
+		if cleanup != nil {
+			defer cleanup()
+		}
+		return r.replicateSync(ctx, op, fn)
+	}
+
+	r.wg.Add(1)
+	threading.GoSafe(func() {
+		defer r.wg.Done()
+		if cleanup != nil {
+			defer cleanup()
+		}
+
+		// 异步写入不受请求上下文取消的影响
+		if err := r.replicateSync(context.Background(), op, fn); err != nil {
+			logx.Errorf("oss: replicated oss async replicate err: %v", err)
+		}
+	})
+
+	return nil
+}
+
+// replicateSync 依次在副本后端执行写入操作，返回第一个错误
+func (r *ReplicatedOSS) replicateSync(ctx context.Context, op string, fn func(ctx context.Context, b OSS) error) error {
+	var firstErr error
+	for i, b := range r.backends[1:] {
+		if err := fn(ctx, b); err != nil && firstErr == nil {
+			firstErr = errors.WithMessagef(err, "oss: replica %d %s err", i+1, op)
+		}
+	}
+
+	return firstErr
+}
+
+// checkReplication 检查多后端复制配置
+func checkReplication(n int, writePolicy string, readOrder []int) error {
+	if writePolicy != "" && writePolicy != WritePolicyAll && writePolicy != WritePolicyAsync {
+		return errors.Errorf("oss: illegal replication write policy %s", writePolicy)
+	}
+
+	seen := make(map[int]struct{}, len(readOrder))
+	for _, i := range readOrder {
+		if _, ok := seen[i]; ok || i < 0 || i >= n {
+			return errors.Errorf("oss: illegal replication read order %v", readOrder)
+		}
+		seen[i] = struct{}{}
+	}
+
+	return nil
+}
+
+// spool 将数据暂存至本地临时文件，返回临时文件及数据大小
+func spool(reader io.Reader) (*os.File, int64, error) {
+	tmp, err := os.CreateTemp("", "oss-spool-*")
+	if err != nil {
+		return nil, 0, errors.WithMessage(err, "oss: create temp file err")
+	}
+
+	size, err := io.Copy(tmp, reader)
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, 0, errors.WithMessage(err, "oss: copy reader err")
+	}
+
+	return tmp, size, nil
+}
+
+// copyObject 将对象从源后端复制至目标后端，保留内容类型和用户自定义元数据
+func copyObject(ctx context.Context, src, dst OSS, key string) error {
+	info, err := src.StatObject(ctx, key)
+	if err != nil {
+		return err
+	}
+	rc, err := src.GetObjectCtx(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	_, err = dst.PutObjectCtx(ctx, key, rc,
+		types.WithContentType(info.ContentType),
+		types.WithMetadata(info.Metadata))
+
+	return err
+}
+
+// listKeys 列举后端上指定前缀的全部对象键
+func listKeys(ctx context.Context, b OSS, prefix string) (map[string]struct{}, error) {
+	keys := make(map[string]struct{})
+	marker := ""
+	for {
+		result, err := b.ListObjects(ctx, prefix, types.WithMarker(marker))
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range result.Objects {
+			// 处理后图片缓存对象可由各后端重新生成，无需对账
+			if types.IsImageCacheKey(o.Key) && !types.IsImageCacheKey(prefix) {
+				continue
+			}
+			keys[o.Key] = struct{}{}
+		}
+		if !result.IsTruncated {
+			return keys, nil
+		}
+		// 列举结果被截断却没有下一页标记时无法继续列举，不能将其当作完整的列举结果
+		if result.NextMarker == "" {
+			return nil, errors.New("oss: list objects truncated without next marker")
+		}
+		marker = result.NextMarker
+	}
+}
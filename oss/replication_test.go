@@ -0,0 +1,354 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/mock"
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+// failingOSS 读写均失败的 OSS 客户端
+type failingOSS struct {
+	OSS
+}
+
+var errBackendDown = errors.New("backend down")
+
+func (f *failingOSS) GetObjectCtx(ctx context.Context, key string) (io.ReadCloser, error) {
+	return nil, errBackendDown
+}
+
+func (f *failingOSS) StatObject(ctx context.Context, key string) (*types.ObjectInfo, error) {
+	return nil, errBackendDown
+}
+
+func (f *failingOSS) PutObjectCtx(ctx context.Context, key string, reader io.Reader, opts ...types.ObjectOption) (string, error) {
+	return "", errBackendDown
+}
+
+func TestNewOSS_Replication(t *testing.T) {
+	defer os.RemoveAll("testdata")
+
+	c := Config{
+		Cloud:      "local",
+		EndPoint:   "primary",
+		BucketName: "testdata/primary",
+		Replication: ReplicationConfig{
+			Replicas: []BackendConfig{
+				{Cloud: "local", EndPoint: "replica", BucketName: "testdata/replica"},
+			},
+			ReadOrder: []int{1, 0},
+		},
+	}
+	o, err := NewOSS(c)
+	require.NoError(t, err)
+	r, ok := o.(*ReplicatedOSS)
+	require.True(t, ok)
+	defer r.Close()
+	assert.Len(t, r.Backends(), 2)
+	assert.Equal(t, "http://primary/test.txt", r.GetURL("test.txt"))
+
+	c.Replication.WritePolicy = "unknown"
+	_, err = NewOSS(c)
+	require.Error(t, err)
+
+	c.Replication.WritePolicy = WritePolicyAsync
+	c.Replication.ReadOrder = []int{0, 2}
+	_, err = NewOSS(c)
+	require.Error(t, err)
+
+	c.Replication.ReadOrder = nil
+	c.Replication.Replicas[0].BucketName = ""
+	_, err = NewOSS(c)
+	require.Error(t, err)
+}
+
+func TestReplicatedOSS(t *testing.T) {
+	defer os.RemoveAll("testdata")
+	primary, replica := newTestBackends(t)
+
+	r, err := NewReplicatedOSS([]OSS{primary, replica})
+	require.NoError(t, err)
+	defer r.Close()
+
+	ctx := context.Background()
+	u, err := r.PutObjectCtx(ctx, "test/test.txt", strings.NewReader("hello world"),
+		types.WithMetadata(map[string]string{"foo": "bar"}))
+	require.NoError(t, err)
+	assert.Equal(t, "http://primary/test/test.txt", u)
+	for _, b := range r.Backends() {
+		info, err := b.StatObject(ctx, "test/test.txt")
+		require.NoError(t, err)
+		assert.Equal(t, int64(11), info.Size)
+		assert.Equal(t, "bar", info.Metadata["foo"])
+	}
+
+	// 复制和移动对象，副本后端缺少源对象时从主后端复制
+	_, err = primary.PutObject("test/only-primary.txt", strings.NewReader("primary"))
+	require.NoError(t, err)
+	_, err = r.CopyObject(ctx, "test/only-primary.txt", "test/copied.txt")
+	require.NoError(t, err)
+	_, err = r.MoveObject(ctx, "test/test.txt", "test/moved.txt")
+	require.NoError(t, err)
+	for _, b := range r.Backends() {
+		ok, err := b.Exists(ctx, "test/copied.txt")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = b.Exists(ctx, "test/moved.txt")
+		require.NoError(t, err)
+		assert.True(t, ok)
+		ok, err = b.Exists(ctx, "test/test.txt")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+
+	// 删除对象
+	require.NoError(t, r.DeleteObjects("test/moved.txt"))
+	for _, b := range r.Backends() {
+		ok, err := b.Exists(ctx, "test/moved.txt")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+
+	// 对象仅存在于副本后端时读取回退
+	_, err = replica.PutObject("test/only-replica.txt", strings.NewReader("replica"))
+	require.NoError(t, err)
+	rc, err := r.GetObject("test/only-replica.txt")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	assert.Equal(t, "replica", string(data))
+	ok, err := r.Exists(ctx, "test/only-replica.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = r.Exists(ctx, "test/none.txt")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, err = r.StatObject(ctx, "test/none.txt")
+	assert.ErrorIs(t, err, types.ErrObjectNotFound)
+
+	// 以主后端为准对账，补齐副本后端缺失的对象，默认保留仅存在于副本后端的对象
+	fixed, err := r.Reconcile(ctx, "test/")
+	require.NoError(t, err)
+	assert.Equal(t, 1, fixed)
+	result, err := primary.ListObjects(ctx, "test/")
+	require.NoError(t, err)
+	assert.Len(t, result.Objects, 2)
+	result, err = replica.ListObjects(ctx, "test/")
+	require.NoError(t, err)
+	assert.Len(t, result.Objects, 3)
+	fixed, err = r.Reconcile(ctx, "test/")
+	require.NoError(t, err)
+	assert.Equal(t, 0, fixed)
+
+	// 启用对账删除时删除仅存在于副本后端的对象，如副本后端删除失败的对象
+	require.NoError(t, primary.DeleteObjects("test/copied.txt"))
+	rd, err := NewReplicatedOSS([]OSS{primary, replica}, WithReconcileDelete())
+	require.NoError(t, err)
+	fixed, err = rd.Reconcile(ctx, "test/")
+	require.NoError(t, err)
+	assert.Equal(t, 2, fixed)
+	for _, b := range r.Backends() {
+		result, err := b.ListObjects(ctx, "test/")
+		require.NoError(t, err)
+		assert.Len(t, result.Objects, 1)
+		ok, err := b.Exists(ctx, "test/only-primary.txt")
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+}
+
+func TestReplicatedOSS_Failover(t *testing.T) {
+	defer os.RemoveAll("testdata")
+	primary, replica := newTestBackends(t)
+
+	ctx := context.Background()
+	_, err := replica.PutObject("test/test.txt", strings.NewReader("hello world"))
+	require.NoError(t, err)
+
+	// 主后端故障时从副本后端读取
+	r, err := NewReplicatedOSS([]OSS{&failingOSS{OSS: primary}, replica})
+	require.NoError(t, err)
+	defer r.Close()
+	rc, err := r.GetObjectCtx(ctx, "test/test.txt")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	assert.Equal(t, "hello world", string(data))
+	info, err := r.StatObject(ctx, "test/test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), info.Size)
+
+	// 主后端写入失败时返回错误
+	_, err = r.PutObject("test/new.txt", strings.NewReader("test"))
+	assert.ErrorIs(t, err, errBackendDown)
+
+	// 同步写入策略下副本后端写入失败时返回错误，主后端已写入
+	r2, err := NewReplicatedOSS([]OSS{primary, &failingOSS{OSS: replica}})
+	require.NoError(t, err)
+	defer r2.Close()
+	_, err = r2.PutObject("test/new.txt", strings.NewReader("test"))
+	assert.ErrorIs(t, err, errBackendDown)
+	ok, err := primary.Exists(ctx, "test/new.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestReplicatedOSS_Async(t *testing.T) {
+	defer os.RemoveAll("testdata")
+	primary, replica := newTestBackends(t)
+
+	r, err := NewReplicatedOSS([]OSS{primary, replica}, WithWritePolicy(WritePolicyAsync))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = r.PutObjectCtx(ctx, "test/test.txt", strings.NewReader("hello world"))
+	require.NoError(t, err)
+	ok, err := primary.Exists(ctx, "test/test.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 副本后端写入失败仅记录日志
+	r2, err := NewReplicatedOSS([]OSS{primary, &failingOSS{OSS: replica}}, WithWritePolicy(WritePolicyAsync))
+	require.NoError(t, err)
+	_, err = r2.PutObject("test/other.txt", strings.NewReader("test"))
+	require.NoError(t, err)
+	r2.Close()
+
+	// 等待异步写入完成
+	r.Close()
+	ok, err = replica.Exists(ctx, "test/test.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestReplicatedOSS_BackgroundReconcile(t *testing.T) {
+	defer os.RemoveAll("testdata")
+	primary, replica := newTestBackends(t)
+
+	ctx := context.Background()
+	_, err := primary.PutObject("test/test.txt", strings.NewReader("hello world"))
+	require.NoError(t, err)
+
+	r, err := NewReplicatedOSS([]OSS{primary, replica}, WithReconcile(10*time.Millisecond, "test/"))
+	require.NoError(t, err)
+	defer r.Close()
+
+	assert.Eventually(t, func() bool {
+		ok, err := replica.Exists(ctx, "test/test.txt")
+		return err == nil && ok
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestReplicatedOSS_ReconcileTruncated(t *testing.T) {
+	defer os.RemoveAll("testdata")
+	primary, replica := newTestBackends(t)
+
+	ctx := context.Background()
+	_, err := replica.PutObject("test/test.txt", strings.NewReader("hello world"))
+	require.NoError(t, err)
+
+	// 主后端列举结果被截断且没有下一页标记时，对账返回错误且不删除副本后端的对象
+	r, err := NewReplicatedOSS([]OSS{&truncatedOSS{OSS: primary}, replica}, WithReconcileDelete())
+	require.NoError(t, err)
+	defer r.Close()
+	_, err = r.Reconcile(ctx, "test/")
+	require.Error(t, err)
+	ok, err := replica.Exists(ctx, "test/test.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+// truncatedOSS 列举结果被截断且没有下一页标记的 OSS 客户端
+type truncatedOSS struct {
+	OSS
+}
+
+func (o *truncatedOSS) ListObjects(ctx context.Context, prefix string, opts ...types.ListOption) (*types.ListObjectsResult, error) {
+	return &types.ListObjectsResult{IsTruncated: true}, nil
+}
+
+func TestReplicatedOSS_Close(t *testing.T) {
+	primary := &closerOSS{OSS: mock.NewMSS()}
+	replica := &closerOSS{OSS: mock.NewMSS(), err: errBackendDown}
+
+	// 关闭全部后端并返回关闭错误
+	r := MustNewReplicatedOSS([]OSS{&defaultOSS{client: primary}, replica})
+	err := r.Close()
+	assert.ErrorIs(t, err, errBackendDown)
+	assert.True(t, primary.closed)
+	assert.True(t, replica.closed)
+}
+
+func TestCloseBackends(t *testing.T) {
+	primary := &closerOSS{OSS: mock.NewMSS()}
+	replica := &closerOSS{OSS: mock.NewMSS()}
+
+	// 副本后端新建失败时关闭已新建的后端
+	require.NoError(t, closeBackends([]OSS{&defaultOSS{client: primary}, replica, MustNewOSS(Config{Cloud: "mock"})}))
+	assert.True(t, primary.closed)
+	assert.True(t, replica.closed)
+}
+
+type closerOSS struct {
+	OSS
+	closed bool
+	err    error
+}
+
+func (o *closerOSS) Close() error {
+	o.closed = true
+	return o.err
+}
+
+func TestNewReplicatedOSS(t *testing.T) {
+	_, err := NewReplicatedOSS(nil)
+	assert.Error(t, err)
+	_, err = NewReplicatedOSS([]OSS{nil})
+	assert.Error(t, err)
+	assert.Panics(t, func() {
+		MustNewReplicatedOSS([]OSS{MustNewOSS(Config{Cloud: "mock"})}, WithReadOrder(1))
+	})
+}
+
+func TestReplicatedOSS_LifecycleRules(t *testing.T) {
+	defer os.RemoveAll("testdata")
+
+	primary, replica := newTestBackends(t)
+	r := MustNewReplicatedOSS([]OSS{primary, replica})
+	defer r.Close()
+
+	ctx := context.Background()
+	rules := []types.LifecycleRule{types.NewExpirationRule("export/", 7)}
+	require.NoError(t, r.SetLifecycleRules(ctx, rules))
+	for _, b := range r.Backends() {
+		got, err := b.GetLifecycleRules(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []types.LifecycleRule{{ID: "rule-1", Prefix: "export/", ExpirationDays: 7}}, got)
+	}
+
+	require.NoError(t, r.SetLifecycleRules(ctx, nil))
+	got, err := replica.GetLifecycleRules(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
+
+func newTestBackends(t *testing.T) (OSS, OSS) {
+	t.Helper()
+
+	primary, err := NewOSS(Config{Cloud: "local", EndPoint: "primary", BucketName: "testdata/primary"})
+	require.NoError(t, err)
+	replica, err := NewOSS(Config{Cloud: "local", EndPoint: "replica", BucketName: "testdata/replica"})
+	require.NoError(t, err)
+
+	return primary, replica
+}
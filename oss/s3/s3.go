@@ -0,0 +1,87 @@
+package s3
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/sliveryou/micro-pkg/oss/minio"
+)
+
+const (
+	// CloudS3 云服务商：S3 兼容存储（AWS S3、Ceph RGW、Cloudflare R2 和七牛云等）
+	CloudS3 = "s3"
+)
+
+// Option 可选配置
+type Option func(s *S3)
+
+// WithSecure 使用安全配置
+func WithSecure(secure ...bool) Option {
+	return func(s *S3) {
+		s.secure = true
+		if len(secure) > 0 {
+			s.secure = secure[0]
+		}
+	}
+}
+
+// WithNotSetACL 不设置权限规则（AWS S3 默认阻止公共访问，需开启该配置）
+func WithNotSetACL(notSetACL ...bool) Option {
+	return func(s *S3) {
+		s.notSetACL = true
+		if len(notSetACL) > 0 {
+			s.notSetACL = notSetACL[0]
+		}
+	}
+}
+
+// WithRegion 使用存储桶所在区域，为空时通过 GetBucketLocation 接口自动探测
+func WithRegion(region string) Option {
+	return func(s *S3) {
+		s.region = region
+	}
+}
+
+// WithPathStyle 使用路径寻址（endpoint/bucket/key），默认使用虚拟主机寻址（bucket.endpoint/key），
+// Ceph RGW 和 MinIO 等未配置泛域名解析的服务需开启该配置
+func WithPathStyle(pathStyle ...bool) Option {
+	return func(s *S3) {
+		s.pathStyle = true
+		if len(pathStyle) > 0 {
+			s.pathStyle = pathStyle[0]
+		}
+	}
+}
+
+// S3 S3 兼容存储客户端，基于 minio-go 使用 AWS Signature V4 签名请求
+type S3 struct {
+	*minio.MinIO
+	secure    bool
+	notSetACL bool
+	pathStyle bool
+	region    string
+}
+
+// NewS3 新建一个 S3 兼容存储客户端
+func NewS3(endpoint, accessKeyID, accessKeySecret, bucketName string, opts ...Option) (*S3, error) {
+	s := &S3{}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	m, err := minio.NewMinIO(endpoint, accessKeyID, accessKeySecret, bucketName,
+		minio.WithSecure(s.secure),
+		minio.WithNotSetACL(s.notSetACL),
+		minio.WithRegion(s.region),
+		minio.WithVirtualHost(!s.pathStyle))
+	if err != nil {
+		return nil, errors.WithMessage(err, "s3: new s3 err")
+	}
+	s.MinIO = m
+
+	return s, nil
+}
+
+// Cloud 获取云服务商名称
+func (s *S3) Cloud() string {
+	return CloudS3
+}
@@ -0,0 +1,701 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+var (
+	accessKeyID     = "s3"
+	accessKeySecret = "s3123456"
+	bucketName      = "my-test"
+	region          = "cn-test-1"
+)
+
+func TestNewS3(t *testing.T) {
+	s, err := NewS3("s3.cn-test-1.example.com", accessKeyID, accessKeySecret, bucketName,
+		WithSecure(), WithNotSetACL(), WithRegion(region))
+	require.NoError(t, err)
+	assert.Equal(t, "s3", s.Cloud())
+	assert.Equal(t, "https://my-test.s3.cn-test-1.example.com/test/test.txt", s.GetURL("test/test.txt"))
+
+	// 虚拟主机寻址的签名 URL
+	signedURL, err := s.AuthorizedUpload("test/test.txt", 120)
+	require.NoError(t, err)
+	u, err := url.Parse(signedURL)
+	require.NoError(t, err)
+	assert.Equal(t, "my-test.s3.cn-test-1.example.com", u.Host)
+	assert.Equal(t, "/test/test.txt", u.Path)
+	assert.Equal(t, "AWS4-HMAC-SHA256", u.Query().Get("X-Amz-Algorithm"))
+	assert.Contains(t, u.Query().Get("X-Amz-Credential"), "/cn-test-1/s3/aws4_request")
+
+	s, err = NewS3("ceph.local:7480", accessKeyID, accessKeySecret, bucketName,
+		WithNotSetACL(), WithRegion(region), WithPathStyle())
+	require.NoError(t, err)
+	assert.Equal(t, "http://ceph.local:7480/my-test/test/test.txt", s.GetURL("test/test.txt"))
+}
+
+func TestS3(t *testing.T) {
+	srv := newFakeS3(t)
+	s, err := NewS3(srv.host, accessKeyID, accessKeySecret, bucketName,
+		WithRegion(region), WithPathStyle())
+	require.NoError(t, err)
+	assert.True(t, srv.policy, "bucket policy should be set")
+
+	ctx := context.Background()
+	u, err := s.PutObjectCtx(ctx, "test/test.txt", strings.NewReader("hello s3"),
+		types.WithMetadata(map[string]string{"Biz": "test"}))
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("http://%s/my-test/test/test.txt", srv.host), u)
+
+	rc, err := s.GetObjectCtx(ctx, "test/test.txt")
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	rc.Close()
+	assert.Equal(t, "hello s3", string(data))
+
+	info, err := s.StatObject(ctx, "test/test.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), info.Size)
+	assert.Equal(t, "text/plain", info.ContentType)
+	assert.Equal(t, "test", info.Metadata["biz"])
+	assert.Equal(t, md5Hex("hello s3"), info.ETag)
+
+	_, err = s.StatObject(ctx, "test/none.txt")
+	require.ErrorIs(t, err, types.ErrObjectNotFound)
+	exist, err := s.Exists(ctx, "test/none.txt")
+	require.NoError(t, err)
+	assert.False(t, exist)
+
+	filePath := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte("hello file"), 0o644))
+	_, err = s.UploadFileCtx(ctx, "test/file.txt", filePath, 0, 1)
+	require.NoError(t, err)
+
+	_, err = s.CopyObject(ctx, "test/test.txt", "test/copy.txt")
+	require.NoError(t, err)
+	_, err = s.MoveObject(ctx, "test/copy.txt", "dir/move.txt")
+	require.NoError(t, err)
+	_, err = s.CopyObject(ctx, "test/none.txt", "test/copy.txt")
+	require.ErrorIs(t, err, types.ErrObjectNotFound)
+
+	result, err := s.ListObjects(ctx, "", types.WithDelimiter("/"))
+	require.NoError(t, err)
+	assert.Empty(t, result.Objects)
+	assert.Equal(t, []string{"dir/", "test/"}, result.CommonPrefixes)
+	result, err = s.ListObjects(ctx, "test/")
+	require.NoError(t, err)
+	require.Len(t, result.Objects, 2)
+	assert.Equal(t, "test/file.txt", result.Objects[0].Key)
+	assert.Equal(t, int64(10), result.Objects[0].Size)
+	assert.Equal(t, "test/test.txt", result.Objects[1].Key)
+
+	require.NoError(t, s.DeleteObjectsCtx(ctx, "test/file.txt", "dir/move.txt"))
+	result, err = s.ListObjects(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, result.Objects, 1)
+	assert.Equal(t, "test/test.txt", result.Objects[0].Key)
+
+	// 签名 URL 直传
+	signedURL, err := s.AuthorizedUpload("test/direct.txt", 120)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPut, signedURL, strings.NewReader("direct"))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "direct", srv.object("test/direct.txt"))
+
+	// 签名被篡改时拒绝上传
+	req, err = http.NewRequest(http.MethodPut, strings.Replace(signedURL, "direct.txt", "other.txt", 1), strings.NewReader("other"))
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	signedURL, err = s.PresignedGetURL("test/test.txt", 120)
+	require.NoError(t, err)
+	resp, err = http.Get(signedURL)
+	require.NoError(t, err)
+	data, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "hello s3", string(data))
+
+	require.Empty(t, srv.errs())
+}
+
+func TestS3_Multipart(t *testing.T) {
+	srv := newFakeS3(t)
+	s, err := NewS3(srv.host, accessKeyID, accessKeySecret, bucketName,
+		WithNotSetACL(), WithRegion(region), WithPathStyle())
+	require.NoError(t, err)
+	assert.False(t, srv.policy)
+
+	ctx := context.Background()
+	uploadID, err := s.InitiateMultipartUpload(ctx, "test/multi.txt")
+	require.NoError(t, err)
+
+	var parts []types.Part
+	for i, content := range []string{"part-1,", "part-2"} {
+		signedURL, err := s.PresignedUploadPartURL("test/multi.txt", uploadID, i+1, 120)
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPut, signedURL, strings.NewReader(content))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		parts = append(parts, types.Part{PartNumber: i + 1, ETag: strings.Trim(resp.Header.Get("ETag"), `"`)})
+	}
+
+	listed, err := s.ListParts(ctx, "test/multi.txt", uploadID)
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, parts[1].ETag, listed[1].ETag)
+	assert.Equal(t, int64(6), listed[1].Size)
+
+	// 分片乱序传入
+	_, err = s.CompleteMultipartUpload(ctx, "test/multi.txt", uploadID, []types.Part{parts[1], parts[0]})
+	require.NoError(t, err)
+	assert.Equal(t, "part-1,part-2", srv.object("test/multi.txt"))
+
+	_, err = s.ListParts(ctx, "test/multi.txt", uploadID)
+	require.ErrorIs(t, err, types.ErrUploadNotFound)
+	err = s.AbortMultipartUpload(ctx, "test/multi.txt", uploadID)
+	require.ErrorIs(t, err, types.ErrUploadNotFound)
+
+	uploadID, err = s.InitiateMultipartUpload(ctx, "test/abort.txt")
+	require.NoError(t, err)
+	require.NoError(t, s.AbortMultipartUpload(ctx, "test/abort.txt", uploadID))
+
+	require.Empty(t, srv.errs())
+}
+
+func TestS3_Lifecycle(t *testing.T) {
+	srv := newFakeS3(t)
+	s, err := NewS3(srv.host, accessKeyID, accessKeySecret, bucketName,
+		WithNotSetACL(), WithRegion(region), WithPathStyle())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	rules, err := s.GetLifecycleRules(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	err = s.SetLifecycleRules(ctx, []types.LifecycleRule{types.NewExpirationRule("export/", 7)})
+	require.NoError(t, err)
+	rules, err = s.GetLifecycleRules(ctx)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "export/", rules[0].Prefix)
+	assert.Equal(t, 7, rules[0].ExpirationDays)
+
+	require.NoError(t, s.SetLifecycleRules(ctx, nil))
+	rules, err = s.GetLifecycleRules(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	require.Empty(t, srv.errs())
+}
+
+func TestS3_BadCredentials(t *testing.T) {
+	srv := newFakeS3(t)
+	s, err := NewS3(srv.host, accessKeyID, "wrong-secret", bucketName,
+		WithNotSetACL(), WithRegion(region), WithPathStyle())
+	require.NoError(t, err)
+
+	_, err = s.PutObject("test/test.txt", strings.NewReader("hello s3"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "signature mismatch")
+	assert.Empty(t, srv.object("test/test.txt"))
+}
+
+// fakeObject 模拟 S3 服务的对象
+type fakeObject struct {
+	data        []byte
+	contentType string
+	metadata    http.Header
+	modified    time.Time
+}
+
+// fakeS3 路径寻址的内存 S3 兼容服务，校验 AWS Signature V4 签名，仅支持单个存储桶
+type fakeS3 struct {
+	t    *testing.T
+	host string
+
+	mu        sync.Mutex
+	policy    bool
+	lifecycle []byte
+	objects   map[string]*fakeObject
+	uploads   map[string]map[int][]byte
+	failures  []string
+}
+
+// newFakeS3 启动模拟 S3 服务
+func newFakeS3(t *testing.T) *fakeS3 {
+	t.Helper()
+
+	f := &fakeS3{t: t, objects: map[string]*fakeObject{}, uploads: map[string]map[int][]byte{}}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	f.host = strings.TrimPrefix(srv.URL, "http://")
+
+	return f
+}
+
+// object 获取对象内容
+func (f *fakeS3) object(key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if o, ok := f.objects[key]; ok {
+		return string(o.data)
+	}
+
+	return ""
+}
+
+// errs 获取服务端处理失败的请求
+func (f *fakeS3) errs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.failures
+}
+
+// ServeHTTP 实现 http.Handler 接口
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := verifySignature(r); err != nil {
+		writeError(w, http.StatusForbidden, "SignatureDoesNotMatch", err.Error())
+		return
+	}
+
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != bucketName {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", bucket)
+		return
+	}
+
+	query := r.URL.Query()
+	if key == "" {
+		f.serveBucket(w, r, query, body)
+	} else {
+		f.serveObject(w, r, query, key, body)
+	}
+}
+
+// serveBucket 处理存储桶请求
+func (f *fakeS3) serveBucket(w http.ResponseWriter, r *http.Request, query url.Values, body []byte) {
+	switch {
+	case query.Has("policy") && r.Method == http.MethodPut:
+		f.policy = true
+		w.WriteHeader(http.StatusNoContent)
+	case query.Has("location"):
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"LocationConstraint"`
+			Value   string   `xml:",chardata"`
+		}{Value: region})
+	case query.Has("lifecycle"):
+		switch r.Method {
+		case http.MethodPut:
+			f.lifecycle = body
+		case http.MethodDelete:
+			f.lifecycle = nil
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			if f.lifecycle == nil {
+				writeError(w, http.StatusNotFound, "NoSuchLifecycleConfiguration", "lifecycle")
+				return
+			}
+			_, _ = w.Write(f.lifecycle)
+		}
+	case query.Has("delete") && r.Method == http.MethodPost:
+		var req struct {
+			Objects []struct {
+				Key string `xml:"Key"`
+			} `xml:"Object"`
+		}
+		if err := xml.Unmarshal(body, &req); err != nil {
+			f.fail(w, "delete objects: %v", err)
+			return
+		}
+		for _, o := range req.Objects {
+			delete(f.objects, o.Key)
+		}
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"DeleteResult"`
+		}{})
+	case query.Get("list-type") == "2" && r.Method == http.MethodGet:
+		f.list(w, query)
+	default:
+		f.fail(w, "unexpected bucket request: %s %s", r.Method, r.URL)
+	}
+}
+
+// serveObject 处理对象请求
+func (f *fakeS3) serveObject(w http.ResponseWriter, r *http.Request, query url.Values, key string, body []byte) {
+	uploadID := query.Get("uploadId")
+	switch {
+	case query.Has("uploads") && r.Method == http.MethodPost:
+		uploadID = strconv.FormatInt(time.Now().UnixNano(), 36)
+		f.uploads[uploadID] = map[int][]byte{}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string
+			Key      string
+			UploadId string
+		}{Bucket: bucketName, Key: key, UploadId: uploadID})
+	case uploadID != "":
+		parts, ok := f.uploads[uploadID]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchUpload", uploadID)
+			return
+		}
+		f.serveUpload(w, r, query, key, uploadID, parts, body)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		src, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		o, ok := f.objects[strings.TrimPrefix(strings.TrimPrefix(src, "/"), bucketName+"/")]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", src)
+			return
+		}
+		cp := *o
+		cp.modified = time.Now()
+		f.objects[key] = &cp
+		writeXML(w, struct {
+			XMLName      xml.Name `xml:"CopyObjectResult"`
+			ETag         string
+			LastModified string
+		}{ETag: etag(cp.data), LastModified: cp.modified.UTC().Format(time.RFC3339)})
+	case r.Method == http.MethodPut:
+		o := &fakeObject{data: body, contentType: r.Header.Get("Content-Type"), metadata: http.Header{}, modified: time.Now()}
+		for k, v := range r.Header {
+			if strings.HasPrefix(strings.ToLower(k), "x-amz-meta-") {
+				o.metadata[k] = v
+			}
+		}
+		f.objects[key] = o
+		w.Header().Set("ETag", etag(body))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		o, ok := f.objects[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", key)
+			return
+		}
+		for k, v := range o.metadata {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Type", o.contentType)
+		w.Header().Set("ETag", etag(o.data))
+		http.ServeContent(w, r, key, o.modified, bytes.NewReader(o.data))
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.fail(w, "unexpected object request: %s %s", r.Method, r.URL)
+	}
+}
+
+// serveUpload 处理分片上传请求
+func (f *fakeS3) serveUpload(w http.ResponseWriter, r *http.Request, query url.Values, key, uploadID string, parts map[int][]byte, body []byte) {
+	switch r.Method {
+	case http.MethodPut:
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		parts[number] = body
+		w.Header().Set("ETag", etag(body))
+	case http.MethodGet:
+		type part struct {
+			PartNumber   int
+			ETag         string
+			Size         int64
+			LastModified string
+		}
+		out := struct {
+			XMLName  xml.Name `xml:"ListPartsResult"`
+			Bucket   string
+			Key      string
+			UploadId string
+			Part     []part
+		}{Bucket: bucketName, Key: key, UploadId: uploadID}
+		for _, n := range sortedParts(parts) {
+			out.Part = append(out.Part, part{PartNumber: n, ETag: etag(parts[n]), Size: int64(len(parts[n])),
+				LastModified: time.Now().UTC().Format(time.RFC3339)})
+		}
+		writeXML(w, out)
+	case http.MethodPost:
+		var req struct {
+			Parts []struct {
+				PartNumber int
+				ETag       string
+			} `xml:"Part"`
+		}
+		if err := xml.Unmarshal(body, &req); err != nil {
+			f.fail(w, "complete multipart upload: %v", err)
+			return
+		}
+		var data []byte
+		for i, p := range req.Parts {
+			if p.PartNumber != i+1 || md5Hex(string(parts[p.PartNumber])) != strings.Trim(p.ETag, `"`) {
+				writeError(w, http.StatusBadRequest, "InvalidPart", strconv.Itoa(p.PartNumber))
+				return
+			}
+			data = append(data, parts[p.PartNumber]...)
+		}
+		delete(f.uploads, uploadID)
+		f.objects[key] = &fakeObject{data: data, contentType: "application/octet-stream", modified: time.Now()}
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Bucket  string
+			Key     string
+			ETag    string
+		}{Bucket: bucketName, Key: key, ETag: etag(data)})
+	case http.MethodDelete:
+		delete(f.uploads, uploadID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.fail(w, "unexpected upload request: %s %s", r.Method, r.URL)
+	}
+}
+
+// list 处理 ListObjectsV2 请求
+func (f *fakeS3) list(w http.ResponseWriter, query url.Values) {
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int64
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	out := struct {
+		XMLName        xml.Name `xml:"ListBucketResult"`
+		Name           string
+		Prefix         string
+		Delimiter      string
+		KeyCount       int
+		MaxKeys        int
+		IsTruncated    bool
+		Contents       []content
+		CommonPrefixes []commonPrefix
+	}{Name: bucketName, Prefix: query.Get("prefix"), Delimiter: query.Get("delimiter"), MaxKeys: 1000}
+
+	keys := make([]string, 0, len(f.objects))
+	for k := range f.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	seen := map[string]bool{}
+	for _, k := range keys {
+		if !strings.HasPrefix(k, out.Prefix) {
+			continue
+		}
+		if i := strings.Index(strings.TrimPrefix(k, out.Prefix), out.Delimiter); out.Delimiter != "" && i >= 0 {
+			p := k[:len(out.Prefix)+i+len(out.Delimiter)]
+			if !seen[p] {
+				seen[p] = true
+				out.CommonPrefixes = append(out.CommonPrefixes, commonPrefix{Prefix: p})
+			}
+			continue
+		}
+		o := f.objects[k]
+		out.Contents = append(out.Contents, content{Key: k, LastModified: o.modified.UTC().Format(time.RFC3339),
+			ETag: etag(o.data), Size: int64(len(o.data))})
+	}
+	out.KeyCount = len(out.Contents) + len(out.CommonPrefixes)
+
+	writeXML(w, out)
+}
+
+// fail 记录并返回未预期的请求
+func (f *fakeS3) fail(w http.ResponseWriter, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	f.failures = append(f.failures, msg)
+	writeError(w, http.StatusNotImplemented, "NotImplemented", msg)
+}
+
+// verifySignature 校验请求头或查询参数中的 AWS Signature V4 签名
+func verifySignature(r *http.Request) error {
+	query := r.URL.Query()
+	var credential, signedHeaders, signature, date, payloadHash string
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		rest, ok := strings.CutPrefix(auth, "AWS4-HMAC-SHA256 ")
+		if !ok {
+			return fmt.Errorf("unsupported authorization: %s", auth)
+		}
+		for _, field := range strings.Split(rest, ",") {
+			k, v, _ := strings.Cut(strings.TrimSpace(field), "=")
+			switch k {
+			case "Credential":
+				credential = v
+			case "SignedHeaders":
+				signedHeaders = v
+			case "Signature":
+				signature = v
+			}
+		}
+		date = r.Header.Get("X-Amz-Date")
+		payloadHash = r.Header.Get("X-Amz-Content-Sha256")
+	} else {
+		if query.Get("X-Amz-Algorithm") != "AWS4-HMAC-SHA256" {
+			return fmt.Errorf("missing signature")
+		}
+		credential = query.Get("X-Amz-Credential")
+		signedHeaders = query.Get("X-Amz-SignedHeaders")
+		signature = query.Get("X-Amz-Signature")
+		date = query.Get("X-Amz-Date")
+		payloadHash = "UNSIGNED-PAYLOAD"
+		query.Del("X-Amz-Signature")
+	}
+
+	scope := strings.Split(credential, "/")
+	if len(scope) != 5 || scope[0] != accessKeyID || scope[2] != region || scope[3] != "s3" || scope[4] != "aws4_request" {
+		return fmt.Errorf("invalid credential: %s", credential)
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, h := range strings.Split(signedHeaders, ";") {
+		v := strings.Join(r.Header.Values(h), ",")
+		if h == "host" {
+			v = r.Host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(v) + "\n")
+	}
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		s3utils.EncodePath(r.URL.Path),
+		strings.ReplaceAll(query.Encode(), "+", "%20"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", date, strings.Join(scope[1:], "/"), sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := []byte("AWS4" + accessKeySecret)
+	for _, s := range scope[1:] {
+		key = hmacSHA256(key, s)
+	}
+	if expected := hex.EncodeToString(hmacSHA256(key, stringToSign)); expected != signature {
+		return fmt.Errorf("signature mismatch")
+	}
+
+	return nil
+}
+
+// readBody 读取请求体，解码 aws-chunked 流式签名请求体
+func readBody(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var body []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return body, nil
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return nil, err
+		}
+		body = append(body, chunk[:size]...)
+	}
+}
+
+// writeXML 写入 XML 响应
+func writeXML(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+// writeError 写入 S3 错误响应
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: code, Message: message})
+}
+
+// sortedParts 获取升序的分片序号
+func sortedParts(parts map[int][]byte) []int {
+	numbers := make([]int, 0, len(parts))
+	for n := range parts {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	return numbers
+}
+
+func etag(data []byte) string {
+	return `"` + md5Hex(string(data)) + `"`
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,182 @@
+package oss
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+// Scanner 上传内容安全扫描器
+type Scanner interface {
+	// Scan 扫描内容，内容存在威胁时返回 types.ErrFileInfected，扫描失败时返回其他错误
+	Scan(ctx context.Context, r io.Reader) error
+}
+
+// ScannerFunc 上传内容安全扫描函数，可用于接入其他扫描服务或在测试中替代 ClamAVScanner
+type ScannerFunc func(ctx context.Context, r io.Reader) error
+
+// Scan 扫描内容
+func (f ScannerFunc) Scan(ctx context.Context, r io.Reader) error {
+	return f(ctx, r)
+}
+
+// ClamAVOption ClamAV 扫描器可选配置
+type ClamAVOption func(c *ClamAVScanner)
+
+// WithClamAVTimeout 使用单次扫描超时时间，默认为 30s
+func WithClamAVTimeout(timeout time.Duration) ClamAVOption {
+	return func(c *ClamAVScanner) {
+		c.timeout = timeout
+	}
+}
+
+// WithClamAVChunkSize 使用发送数据的分块大小（字节），默认为 64KB
+func WithClamAVChunkSize(chunkSize int) ClamAVOption {
+	return func(c *ClamAVScanner) {
+		c.chunkSize = chunkSize
+	}
+}
+
+// ClamAVScanner ClamAV 扫描器，通过 TCP 使用 clamd INSTREAM 命令扫描内容，
+// 内容大小不能超过 clamd 配置的 StreamMaxLength
+type ClamAVScanner struct {
+	addr      string        // clamd 地址
+	timeout   time.Duration // 单次扫描超时时间
+	chunkSize int           // 发送数据的分块大小
+}
+
+// NewClamAVScanner 新建 ClamAV 扫描器，addr：clamd 地址，如 127.0.0.1:3310
+func NewClamAVScanner(addr string, opts ...ClamAVOption) (*ClamAVScanner, error) {
+	c := &ClamAVScanner{addr: addr, timeout: 30 * time.Second, chunkSize: 64 * 1024}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.addr == "" || c.timeout <= 0 || c.chunkSize <= 0 {
+		return nil, errors.New("oss: illegal clamav scanner config")
+	}
+
+	return c, nil
+}
+
+// Scan 扫描内容，发现病毒时返回包含病毒名称的 types.ErrFileInfected
+func (c *ClamAVScanner) Scan(ctx context.Context, r io.Reader) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := c.stream(conn, r); err != nil {
+		// clamd 可能因内容超出大小限制提前返回错误并关闭连接，优先返回 clamd 的响应
+		if resp, rerr := readResponse(conn); rerr == nil && resp != "" {
+			return errors.Errorf("oss: clamav scan err: %s", resp)
+		}
+		return err
+	}
+
+	resp, err := readResponse(conn)
+	if err != nil {
+		return err
+	}
+
+	// 响应格式为 stream: OK 或 stream: <病毒名称> FOUND
+	result := strings.TrimPrefix(resp, "stream: ")
+	switch {
+	case result == "OK":
+		return nil
+	case strings.HasSuffix(result, " FOUND"):
+		return errors.WithMessagef(types.ErrFileInfected, "signature: %s", strings.TrimSuffix(result, " FOUND"))
+	default:
+		return errors.Errorf("oss: clamav scan err: %s", resp)
+	}
+}
+
+// Ping 检查 clamd 是否可用
+func (c *ClamAVScanner) Ping(ctx context.Context) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return errors.WithMessage(err, "oss: clamav write command err")
+	}
+	resp, err := readResponse(conn)
+	if err != nil {
+		return err
+	}
+	if resp != "PONG" {
+		return errors.Errorf("oss: clamav ping err: %s", resp)
+	}
+
+	return nil
+}
+
+// dial 连接 clamd，连接的读写截止时间为超时时间和上下文截止时间中较早的一个
+func (c *ClamAVScanner) dial(ctx context.Context) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, errors.WithMessage(err, "oss: clamav dial err")
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return nil, errors.WithMessage(err, "oss: clamav set deadline err")
+	}
+
+	return conn, nil
+}
+
+// stream 使用 INSTREAM 命令发送内容，每个分块前为 4 字节大端序的分块长度，以长度为 0 的分块结束
+func (c *ClamAVScanner) stream(conn net.Conn, r io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return errors.WithMessage(err, "oss: clamav write command err")
+	}
+
+	buf := make([]byte, 4+c.chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				return errors.WithMessage(werr, "oss: clamav write chunk err")
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return errors.WithMessage(err, "oss: clamav read content err")
+		}
+	}
+
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return errors.WithMessage(err, "oss: clamav write chunk err")
+	}
+
+	return nil
+}
+
+// readResponse 读取以 \x00 结尾的 clamd 响应
+func readResponse(conn net.Conn) (string, error) {
+	resp, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(errors.Is(err, io.EOF) && resp != "") {
+		return "", errors.WithMessage(err, "oss: clamav read response err")
+	}
+
+	return strings.TrimSpace(strings.TrimSuffix(resp, "\x00")), nil
+}
@@ -0,0 +1,111 @@
+package oss
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/errcode"
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+func TestClamAVScanner(t *testing.T) {
+	addr := startClamd(t)
+
+	_, err := NewClamAVScanner("")
+	require.Error(t, err)
+
+	s, err := NewClamAVScanner(addr, WithClamAVChunkSize(4))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, s.Ping(ctx))
+	require.NoError(t, s.Scan(ctx, strings.NewReader("hello world")))
+	require.NoError(t, s.Scan(ctx, strings.NewReader("")))
+
+	err = s.Scan(ctx, strings.NewReader("hello EICAR world"))
+	require.ErrorIs(t, err, types.ErrFileInfected)
+	assert.Contains(t, err.Error(), "Eicar-Signature")
+	ec, ok := errcode.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, uint32(162), ec.Code)
+
+	err = s.Scan(ctx, strings.NewReader("hello ERROR world"))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, types.ErrFileInfected)
+
+	s, err = NewClamAVScanner("127.0.0.1:1")
+	require.NoError(t, err)
+	require.Error(t, s.Scan(ctx, strings.NewReader("hello world")))
+}
+
+// startClamd 启动模拟 clamd 服务，内容包含 EICAR 时报告病毒，包含 ERROR 时返回错误
+func startClamd(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveClamd(conn)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+// serveClamd 处理模拟 clamd 连接
+func serveClamd(conn net.Conn) {
+	defer conn.Close()
+
+	br := bufio.NewReader(conn)
+	cmd, err := br.ReadString(0)
+	if err != nil {
+		return
+	}
+
+	switch cmd {
+	case "zPING\x00":
+		conn.Write([]byte("PONG\x00"))
+	case "zINSTREAM\x00":
+		data := &bytes.Buffer{}
+		for {
+			var size [4]byte
+			if _, err := io.ReadFull(br, size[:]); err != nil {
+				return
+			}
+			n := binary.BigEndian.Uint32(size[:])
+			if n == 0 {
+				break
+			}
+			if _, err := io.CopyN(data, br, int64(n)); err != nil {
+				return
+			}
+		}
+
+		switch {
+		case bytes.Contains(data.Bytes(), []byte("EICAR")):
+			conn.Write([]byte("stream: Eicar-Signature FOUND\x00"))
+		case bytes.Contains(data.Bytes(), []byte("ERROR")):
+			conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+		default:
+			conn.Write([]byte("stream: OK\x00"))
+		}
+	default:
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+	}
+}
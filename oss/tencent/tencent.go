@@ -2,16 +2,24 @@ package tencent
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	cos "github.com/tencentyun/cos-go-sdk-v5"
 
+	"github.com/sliveryou/micro-pkg/oss/internal/imaging"
+	"github.com/sliveryou/micro-pkg/oss/internal/policy"
+	"github.com/sliveryou/micro-pkg/oss/internal/progress"
+	"github.com/sliveryou/micro-pkg/oss/internal/util"
+	"github.com/sliveryou/micro-pkg/oss/types"
 	"github.com/sliveryou/micro-pkg/xhttp"
 )
 
@@ -89,7 +97,12 @@ func (c *COS) GetURL(key string) string {
 
 // GetObject 获取对象在腾讯云 COS 的存储数据
 func (c *COS) GetObject(key string) (io.ReadCloser, error) {
-	obj, err := c.client.Object.Get(context.Background(), key, &cos.ObjectGetOptions{})
+	return c.GetObjectCtx(context.Background(), key)
+}
+
+// GetObjectCtx 获取对象在腾讯云 COS 的存储数据
+func (c *COS) GetObjectCtx(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := c.client.Object.Get(ctx, key, &cos.ObjectGetOptions{})
 	if err != nil {
 		return nil, errors.WithMessage(err, "tencent: cos get object err")
 	}
@@ -99,13 +112,20 @@ func (c *COS) GetObject(key string) (io.ReadCloser, error) {
 
 // PutObject 上传对象至腾讯云 COS
 func (c *COS) PutObject(key string, reader io.Reader) (string, error) {
-	contentLength, _ := xhttp.GetReaderLen(reader)
+	return c.PutObjectCtx(context.Background(), key, reader)
+}
 
-	_, err := c.client.Object.Put(context.Background(), key, reader, &cos.ObjectPutOptions{
-		ObjectPutHeaderOptions: &cos.ObjectPutHeaderOptions{
-			ContentType:   xhttp.TypeByExtension(key),
-			ContentLength: contentLength,
-		},
+// PutObjectCtx 上传对象至腾讯云 COS，opts：对象可选配置
+func (c *COS) PutObjectCtx(ctx context.Context, key string, reader io.Reader, opts ...types.ObjectOption) (string, error) {
+	oo := types.NewObjectOptions(key, opts...)
+	header := toPutHeaderOptions(oo)
+	header.ContentLength, _ = xhttp.GetReaderLen(reader)
+	if t := progress.New(ctx, header.ContentLength, oo); t != nil {
+		header.Listener = &progressListener{t: t}
+	}
+
+	_, err := c.client.Object.Put(ctx, key, reader, &cos.ObjectPutOptions{
+		ObjectPutHeaderOptions: header,
 	})
 	if err != nil {
 		return "", errors.WithMessage(err, "tencent: cos put object err")
@@ -116,27 +136,45 @@ func (c *COS) PutObject(key string, reader io.Reader) (string, error) {
 
 // DeleteObjects 批量删除腾讯云 COS 上的对象
 func (c *COS) DeleteObjects(keys ...string) error {
+	return c.DeleteObjectsCtx(context.Background(), keys...)
+}
+
+// DeleteObjectsCtx 批量删除腾讯云 COS 上的对象
+func (c *COS) DeleteObjectsCtx(ctx context.Context, keys ...string) error {
 	objects := make([]cos.Object, 0, len(keys))
 	for _, key := range keys {
 		objects = append(objects, cos.Object{Key: key})
 	}
 
 	option := &cos.ObjectDeleteMultiOptions{Objects: objects, Quiet: true}
-	_, _, err := c.client.Object.DeleteMulti(context.Background(), option)
+	_, _, err := c.client.Object.DeleteMulti(ctx, option)
 
 	return errors.WithMessage(err, "tencent: cos delete objects err")
 }
 
 // UploadFile 上传文件至腾讯云 COS，filePath：文件路径，partSize：分块大小（字节），routines：并发数
 func (c *COS) UploadFile(key, filePath string, partSize int64, routines int) (string, error) {
-	_, _, err := c.client.Object.Upload(context.Background(), key, filePath, &cos.MultiUploadOptions{
+	return c.UploadFileCtx(context.Background(), key, filePath, partSize, routines)
+}
+
+// UploadFileCtx 上传文件至腾讯云 COS，filePath：文件路径，partSize：分块大小（字节），routines：并发数，opts：对象可选配置
+func (c *COS) UploadFileCtx(ctx context.Context, key, filePath string, partSize int64, routines int, opts ...types.ObjectOption) (string, error) {
+	oo := types.NewObjectOptions(key, opts...)
+	t := progress.New(ctx, progress.FileSize(filePath), oo)
+	if t.Limited() {
+		return c.uploadFileLimited(ctx, key, filePath, partSize, t, oo)
+	}
+
+	header := toPutHeaderOptions(oo)
+	if t != nil {
+		header.Listener = &progressListener{t: t}
+	}
+	_, _, err := c.client.Object.Upload(ctx, key, filePath, &cos.MultiUploadOptions{
 		PartSize:       partSize / 1024 / 1024,
 		ThreadPoolSize: routines,
 		CheckPoint:     true,
 		OptIni: &cos.InitiateMultipartUploadOptions{
-			ObjectPutHeaderOptions: &cos.ObjectPutHeaderOptions{
-				ContentType: xhttp.TypeByExtension(filePath),
-			},
+			ObjectPutHeaderOptions: header,
 		},
 	})
 	if err != nil {
@@ -146,6 +184,107 @@ func (c *COS) UploadFile(key, filePath string, partSize int64, routines int) (st
 	return c.GetURL(key), nil
 }
 
+// CopyObject 在腾讯云 COS 服务端复制对象，源对象不存在时返回 types.ErrObjectNotFound
+func (c *COS) CopyObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	sourceURL := fmt.Sprintf("%s/%s", c.client.BaseURL.BucketURL.Host, srcKey)
+	_, _, err := c.client.Object.Copy(ctx, dstKey, sourceURL, nil)
+	if err != nil {
+		if cos.IsNotFoundError(err) {
+			err = types.ErrObjectNotFound
+		}
+		return "", errors.WithMessage(err, "tencent: cos copy object err")
+	}
+
+	return c.GetURL(dstKey), nil
+}
+
+// MoveObject 在腾讯云 COS 服务端移动对象，源对象不存在时返回 types.ErrObjectNotFound
+func (c *COS) MoveObject(ctx context.Context, srcKey, dstKey string) (string, error) {
+	u, err := c.CopyObject(ctx, srcKey, dstKey)
+	if err != nil {
+		return "", err
+	}
+	if srcKey != dstKey {
+		if err := c.DeleteObjectsCtx(ctx, srcKey); err != nil {
+			return "", err
+		}
+	}
+
+	return u, nil
+}
+
+// ListObjects 列举腾讯云 COS 上指定前缀的对象，opts：列举可选配置
+func (c *COS) ListObjects(ctx context.Context, prefix string, opts ...types.ListOption) (*types.ListObjectsResult, error) {
+	lo := types.NewListOptions(opts...)
+	out, _, err := c.client.Bucket.Get(ctx, &cos.BucketGetOptions{
+		Prefix:    prefix,
+		Delimiter: lo.Delimiter,
+		Marker:    lo.Marker,
+		MaxKeys:   lo.MaxKeys,
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "tencent: cos list objects err")
+	}
+
+	result := &types.ListObjectsResult{
+		Objects:        make([]types.ObjectInfo, 0, len(out.Contents)),
+		CommonPrefixes: out.CommonPrefixes,
+		IsTruncated:    out.IsTruncated,
+		NextMarker:     out.NextMarker,
+	}
+	for _, obj := range out.Contents {
+		lastModified, _ := time.Parse(time.RFC3339, obj.LastModified)
+		result.Objects = append(result.Objects, types.ObjectInfo{
+			Key:          obj.Key,
+			Size:         obj.Size,
+			ETag:         util.TrimETag(obj.ETag),
+			LastModified: lastModified,
+		})
+	}
+	util.FillNextMarker(result)
+
+	return result, nil
+}
+
+// StatObject 获取腾讯云 COS 上对象的元信息，对象不存在时返回 types.ErrObjectNotFound
+func (c *COS) StatObject(ctx context.Context, key string) (*types.ObjectInfo, error) {
+	resp, err := c.client.Object.Head(ctx, key, nil)
+	if err != nil {
+		if cos.IsNotFoundError(err) {
+			err = types.ErrObjectNotFound
+		}
+		return nil, errors.WithMessage(err, "tencent: cos stat object err")
+	}
+
+	size, _ := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	lastModified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	metadata := make(map[string]string)
+	for k := range resp.Header {
+		if prefix := "X-Cos-Meta-"; strings.HasPrefix(k, prefix) {
+			metadata[k[len(prefix):]] = resp.Header.Get(k)
+		}
+	}
+
+	return &types.ObjectInfo{
+		Key:          key,
+		Size:         size,
+		ETag:         util.TrimETag(resp.Header.Get("ETag")),
+		LastModified: lastModified,
+		ContentType:  resp.Header.Get("Content-Type"),
+		Metadata:     util.LowerKeys(metadata),
+	}, nil
+}
+
+// Exists 判断对象是否存在于腾讯云 COS
+func (c *COS) Exists(ctx context.Context, key string) (bool, error) {
+	ok, err := c.client.Object.IsExist(ctx, key)
+	if err != nil {
+		return false, errors.WithMessage(err, "tencent: cos check object exists err")
+	}
+
+	return ok, nil
+}
+
 // AuthorizedUpload 授权上传至腾讯云 COS，expires：过期时间（秒）
 func (c *COS) AuthorizedUpload(key string, expires int) (string, error) {
 	signedURL, err := c.client.Object.GetPresignedURL(context.Background(), http.MethodPut, key,
@@ -157,6 +296,138 @@ func (c *COS) AuthorizedUpload(key string, expires int) (string, error) {
 	return signedURL.String(), nil
 }
 
+// PresignedGetURL 获取腾讯云 COS 上对象的预签名下载 URL，expires：过期时间（秒），opts：预签名可选配置
+func (c *COS) PresignedGetURL(key string, expires int, opts ...types.PresignOption) (string, error) {
+	query := types.NewPresignOptions(opts...).Query()
+	signedURL, err := c.client.Object.GetPresignedURL(context.Background(), http.MethodGet, key,
+		c.ak, c.sk, time.Duration(expires)*time.Second, &cos.PresignedURLOptions{Query: &query})
+	if err != nil {
+		return "", errors.WithMessage(err, "tencent: cos presigned get url err")
+	}
+
+	return signedURL.String(), nil
+}
+
+// PostPolicy 获取腾讯云 COS 表单上传策略，expires：过期时间（秒），opts：表单上传策略可选配置
+func (c *COS) PostPolicy(key string, expires int, opts ...types.PostPolicyOption) (*types.PostPolicy, error) {
+	// 参考文档 https://cloud.tencent.com/document/product/436/14690
+	p, err := policy.New(key, expires, types.NewPostPolicyOptions(opts...), false)
+	if err != nil {
+		return nil, errors.WithMessage(err, "tencent: cos post policy err")
+	}
+
+	now := time.Now()
+	keyTime := fmt.Sprintf("%d;%d", now.Unix(), now.Add(time.Duration(expires)*time.Second).Unix())
+	p.Add(map[string]string{"q-sign-algorithm": "sha1"})
+	p.Add(map[string]string{"q-ak": c.ak})
+	p.Add(map[string]string{"q-sign-time": keyTime})
+
+	raw, encoded, err := p.Encode()
+	if err != nil {
+		return nil, errors.WithMessage(err, "tencent: cos post policy err")
+	}
+
+	// SignKey = HMAC-SHA1(SecretKey, KeyTime)，StringToSign = SHA1(Policy)，Signature = HMAC-SHA1(SignKey, StringToSign)
+	signKey := hex.EncodeToString(policy.HmacSHA1(c.sk, keyTime))
+	stringToSign := fmt.Sprintf("%x", sha1.Sum(raw))
+
+	return p.PostPolicy(c.client.BaseURL.BucketURL.String(), map[string]string{
+		policy.FieldPolicy: encoded,
+		"q-sign-algorithm": "sha1",
+		"q-ak":             c.ak,
+		"q-key-time":       keyTime,
+		"q-signature":      hex.EncodeToString(policy.HmacSHA1(signKey, stringToSign)),
+	}), nil
+}
+
+// InitiateMultipartUpload 初始化腾讯云 COS 分片上传，返回上传 ID，opts：对象可选配置
+func (c *COS) InitiateMultipartUpload(ctx context.Context, key string, opts ...types.ObjectOption) (string, error) {
+	out, _, err := c.client.Object.InitiateMultipartUpload(ctx, key, &cos.InitiateMultipartUploadOptions{
+		ObjectPutHeaderOptions: toPutHeaderOptions(types.NewObjectOptions(key, opts...)),
+	})
+	if err != nil {
+		return "", errors.WithMessage(err, "tencent: cos initiate multipart upload err")
+	}
+
+	return out.UploadID, nil
+}
+
+// PresignedUploadPartURL 获取腾讯云 COS 分片上传的预签名 URL，partNumber：分片序号，expires：过期时间（秒）
+func (c *COS) PresignedUploadPartURL(key, uploadID string, partNumber, expires int) (string, error) {
+	query := url.Values{}
+	query.Set("partNumber", strconv.Itoa(partNumber))
+	query.Set("uploadId", uploadID)
+	signedURL, err := c.client.Object.GetPresignedURL(context.Background(), http.MethodPut, key,
+		c.ak, c.sk, time.Duration(expires)*time.Second, &cos.PresignedURLOptions{Query: &query})
+	if err != nil {
+		return "", errors.WithMessage(err, "tencent: cos presigned upload part url err")
+	}
+
+	return signedURL.String(), nil
+}
+
+// CompleteMultipartUpload 完成腾讯云 COS 分片上传，parts：已上传的分片列表
+func (c *COS) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []types.Part) (string, error) {
+	opt := &cos.CompleteMultipartUploadOptions{Parts: make([]cos.Object, 0, len(parts))}
+	for _, p := range util.SortParts(parts) {
+		opt.Parts = append(opt.Parts, cos.Object{PartNumber: p.PartNumber, ETag: p.ETag})
+	}
+
+	_, _, err := c.client.Object.CompleteMultipartUpload(ctx, key, uploadID, opt)
+	if err != nil {
+		if cos.IsNotFoundError(err) {
+			err = types.ErrUploadNotFound
+		}
+		return "", errors.WithMessage(err, "tencent: cos complete multipart upload err")
+	}
+
+	return c.GetURL(key), nil
+}
+
+// AbortMultipartUpload 取消腾讯云 COS 分片上传
+func (c *COS) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	_, err := c.client.Object.AbortMultipartUpload(ctx, key, uploadID)
+	if err != nil {
+		if cos.IsNotFoundError(err) {
+			err = types.ErrUploadNotFound
+		}
+		return errors.WithMessage(err, "tencent: cos abort multipart upload err")
+	}
+
+	return nil
+}
+
+// ListParts 列举腾讯云 COS 分片上传中已上传的分片
+func (c *COS) ListParts(ctx context.Context, key, uploadID string) ([]types.Part, error) {
+	var parts []types.Part
+	opt := &cos.ObjectListPartsOptions{}
+	for {
+		out, _, err := c.client.Object.ListParts(ctx, key, uploadID, opt)
+		if err != nil {
+			if cos.IsNotFoundError(err) {
+				err = types.ErrUploadNotFound
+			}
+			return nil, errors.WithMessage(err, "tencent: cos list parts err")
+		}
+
+		for _, p := range out.Parts {
+			lastModified, _ := time.Parse(time.RFC3339, p.LastModified)
+			parts = append(parts, types.Part{
+				PartNumber:   p.PartNumber,
+				ETag:         util.TrimETag(p.ETag),
+				Size:         p.Size,
+				LastModified: lastModified,
+			})
+		}
+		if !out.IsTruncated {
+			break
+		}
+		opt.PartNumberMarker = out.NextPartNumberMarker
+	}
+
+	return parts, nil
+}
+
 // GetThumbnailSuffix 获取缩略图后缀
 func (c *COS) GetThumbnailSuffix(width, height int, size int64) string {
 	// 参考文档 https://cloud.tencent.com/document/product/436/44880
@@ -178,3 +449,203 @@ func (c *COS) GetThumbnailSuffix(width, height int, size int64) string {
 
 	return suffix
 }
+
+// ProcessImage 获取腾讯云 COS 上图片处理后的访问 URL，opts：图片处理可选配置
+func (c *COS) ProcessImage(ctx context.Context, key string, opts ...types.ImageOption) (string, error) {
+	// 参考文档 https://cloud.tencent.com/document/product/436/44879
+	ip := types.NewImageOptions(opts...)
+	if err := ip.Check(); err != nil {
+		return "", err
+	}
+
+	var processes []string
+	var b strings.Builder
+	if cr := ip.Crop; cr != nil {
+		fmt.Fprintf(&b, "/cut/%dx%dx%dx%d", orMax(cr.Width), orMax(cr.Height), cr.X, cr.Y)
+	}
+	if ip.Width > 0 || ip.Height > 0 {
+		switch {
+		case ip.Width > 0 && ip.Height > 0 && ip.Mode == types.ResizeModeFill:
+			fmt.Fprintf(&b, "/thumbnail/!%dx%dr/gravity/center/crop/%dx%d", ip.Width, ip.Height, ip.Width, ip.Height)
+		case ip.Width > 0 && ip.Height > 0 && ip.Mode == types.ResizeModeFixed:
+			fmt.Fprintf(&b, "/thumbnail/%dx%d!", ip.Width, ip.Height)
+		default:
+			b.WriteString("/thumbnail/")
+			if ip.Width > 0 {
+				b.WriteString(strconv.Itoa(ip.Width))
+			}
+			b.WriteString("x")
+			if ip.Height > 0 {
+				b.WriteString(strconv.Itoa(ip.Height))
+			}
+		}
+	}
+	if ip.Format != "" {
+		fmt.Fprintf(&b, "/format/%s", ip.Format)
+	}
+	if ip.Quality > 0 {
+		fmt.Fprintf(&b, "/quality/%d", ip.Quality)
+	}
+	if b.Len() > 0 {
+		processes = append(processes, "imageMogr2"+b.String())
+	}
+	if w := ip.Watermark; w != nil {
+		var wm string
+		if w.Text != "" {
+			wm = fmt.Sprintf("watermark/2/text/%s/fontsize/%d/fill/%s",
+				imaging.Base64(w.Text), w.FontSize, imaging.Base64("#"+w.Color))
+		} else {
+			wm = fmt.Sprintf("watermark/1/image/%s", imaging.Base64(c.GetURL(w.Image)))
+		}
+		processes = append(processes, fmt.Sprintf("%s/gravity/%s/dx/%d/dy/%d", wm, gravities[w.Gravity], w.X, w.Y))
+	}
+
+	return c.GetURL(key) + "?" + strings.Join(processes, "|"), nil
+}
+
+// SetLifecycleRules 设置腾讯云 COS 存储桶的生命周期规则，覆盖已有规则，rules 为空时删除全部规则
+func (c *COS) SetLifecycleRules(ctx context.Context, rules []types.LifecycleRule) error {
+	rules, err := types.NormalizeLifecycleRules(rules)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		if _, err := c.client.Bucket.DeleteLifecycle(ctx); err != nil {
+			return errors.WithMessage(err, "tencent: cos delete lifecycle err")
+		}
+		return nil
+	}
+
+	opt := &cos.BucketPutLifecycleOptions{Rules: make([]cos.BucketLifecycleRule, 0, len(rules))}
+	for _, r := range rules {
+		lr := cos.BucketLifecycleRule{ID: r.ID, Status: "Enabled", Filter: &cos.BucketLifecycleFilter{Prefix: r.Prefix}}
+		if r.Disabled {
+			lr.Status = "Disabled"
+		}
+		if r.ExpirationDays > 0 {
+			lr.Expiration = &cos.BucketLifecycleExpiration{Days: r.ExpirationDays}
+		}
+		if r.AbortMultipartUploadDays > 0 {
+			lr.AbortIncompleteMultipartUpload = &cos.BucketLifecycleAbortIncompleteMultipartUpload{
+				DaysAfterInitiation: r.AbortMultipartUploadDays,
+			}
+		}
+		opt.Rules = append(opt.Rules, lr)
+	}
+	if _, err := c.client.Bucket.PutLifecycle(ctx, opt); err != nil {
+		return errors.WithMessage(err, "tencent: cos set lifecycle err")
+	}
+
+	return nil
+}
+
+// GetLifecycleRules 获取腾讯云 COS 存储桶的生命周期规则，仅返回按天数过期删除和取消分片上传的规则
+func (c *COS) GetLifecycleRules(ctx context.Context) ([]types.LifecycleRule, error) {
+	out, _, err := c.client.Bucket.GetLifecycle(ctx)
+	if err != nil {
+		if cos.IsNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, errors.WithMessage(err, "tencent: cos get lifecycle err")
+	}
+
+	var rules []types.LifecycleRule
+	for _, lr := range out.Rules {
+		r := types.LifecycleRule{ID: lr.ID, Disabled: lr.Status != "Enabled"}
+		if lr.Filter != nil {
+			r.Prefix = lr.Filter.Prefix
+			if lr.Filter.And != nil && r.Prefix == "" {
+				r.Prefix = lr.Filter.And.Prefix
+			}
+		}
+		if lr.Expiration != nil {
+			r.ExpirationDays = lr.Expiration.Days
+		}
+		if lr.AbortIncompleteMultipartUpload != nil {
+			r.AbortMultipartUploadDays = lr.AbortIncompleteMultipartUpload.DaysAfterInitiation
+		}
+		if r.ExpirationDays > 0 || r.AbortMultipartUploadDays > 0 {
+			rules = append(rules, r)
+		}
+	}
+
+	return rules, nil
+}
+
+// gravities 水印位置与腾讯云 COS 九宫格方位的映射
+var gravities = map[string]string{
+	types.GravityNorthWest: "northwest",
+	types.GravityNorth:     "north",
+	types.GravityNorthEast: "northeast",
+	types.GravityWest:      "west",
+	types.GravityCenter:    "center",
+	types.GravityEast:      "east",
+	types.GravitySouthWest: "southwest",
+	types.GravitySouth:     "south",
+	types.GravitySouthEast: "southeast",
+}
+
+// orMax 裁剪宽高为 0 时使用最大边长以裁剪至图片边缘
+func orMax(size int) int {
+	if size <= 0 {
+		return 9999
+	}
+
+	return size
+}
+
+// uploadFileLimited 限速时按分片顺序上传文件，SDK 断点续传上传仅在分片完成后回调进度，无法在传输过程中限速
+func (c *COS) uploadFileLimited(ctx context.Context, key, filePath string, partSize int64, t *progress.Tracker, oo *types.ObjectOptions) (string, error) {
+	out, _, err := c.client.Object.InitiateMultipartUpload(ctx, key, &cos.InitiateMultipartUploadOptions{
+		ObjectPutHeaderOptions: toPutHeaderOptions(oo),
+	})
+	if err != nil {
+		return "", errors.WithMessage(err, "tencent: cos upload file err")
+	}
+
+	parts, err := progress.UploadParts(ctx, t, filePath, partSize, func(partNumber int, r io.Reader, size int64) (string, error) {
+		resp, err := c.client.Object.UploadPart(ctx, key, out.UploadID, partNumber, r,
+			&cos.ObjectUploadPartOptions{ContentLength: size})
+		if err != nil {
+			return "", err
+		}
+		return resp.Header.Get("ETag"), nil
+	})
+	if err != nil {
+		_, _ = c.client.Object.AbortMultipartUpload(context.Background(), key, out.UploadID)
+		return "", errors.WithMessage(err, "tencent: cos upload file err")
+	}
+
+	return c.CompleteMultipartUpload(ctx, key, out.UploadID, parts)
+}
+
+// toPutHeaderOptions 将对象可选配置转换为腾讯云 COS 上传请求头选项
+func toPutHeaderOptions(oo *types.ObjectOptions) *cos.ObjectPutHeaderOptions {
+	header := &cos.ObjectPutHeaderOptions{
+		CacheControl:       oo.CacheControl,
+		ContentDisposition: oo.ContentDisposition,
+		ContentEncoding:    oo.ContentEncoding,
+		ContentType:        oo.ContentType,
+	}
+	if len(oo.Metadata) > 0 {
+		meta := make(http.Header, len(oo.Metadata))
+		for k, v := range oo.Metadata {
+			meta.Set("x-cos-meta-"+k, v)
+		}
+		header.XCosMetaXXX = &meta
+	}
+
+	return header
+}
+
+// progressListener 腾讯云 COS 传输进度监听器
+type progressListener struct {
+	t *progress.Tracker
+}
+
+// ProgressChangedCallback 实现 cos.ProgressListener 接口，在监听器中限速等待会阻塞 SDK 读取数据
+func (l *progressListener) ProgressChangedCallback(event *cos.ProgressEvent) {
+	if event.EventType == cos.ProgressDataEvent {
+		l.t.Add(event.RWBytes)
+	}
+}
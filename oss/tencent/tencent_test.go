@@ -1,6 +1,7 @@
 package tencent
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -8,6 +9,8 @@ import (
 	"github.com/stretchr/testify/require"
 	cossdk "github.com/tencentyun/cos-go-sdk-v5"
 
+	"github.com/sliveryou/micro-pkg/oss/internal/progress"
+	"github.com/sliveryou/micro-pkg/oss/types"
 	"github.com/sliveryou/micro-pkg/xhttp"
 )
 
@@ -54,3 +57,58 @@ func TestCloneObjectPutOptions(t *testing.T) {
 	cloneOpt := cossdk.CloneObjectPutOptions(opt)
 	fmt.Printf("%+v\n", cloneOpt.ObjectPutHeaderOptions)
 }
+
+func TestCOS_PresignedGetURL(t *testing.T) {
+	cos, err := NewCOS(endpoint, accessKeyID, accessKeySecret, bucketName, WithNotSetACL())
+	require.NoError(t, err)
+	signedURL, err := cos.PresignedGetURL("test/test.pdf", 120,
+		types.WithResponseContentDisposition(`attachment; filename="test.pdf"`))
+	require.NoError(t, err)
+	assert.Contains(t, signedURL, "response-content-disposition=")
+}
+
+func TestCOS_PostPolicy(t *testing.T) {
+	cos, err := NewCOS(endpoint, accessKeyID, accessKeySecret, bucketName, WithNotSetACL())
+	require.NoError(t, err)
+	pp, err := cos.PostPolicy("test/test.png", 120, types.WithContentLengthRange(1, 1024))
+	require.NoError(t, err)
+	assert.Equal(t, cos.GetURL(""), pp.URL+"/")
+	assert.Equal(t, "test/test.png", pp.Fields["key"])
+	assert.Equal(t, "sha1", pp.Fields["q-sign-algorithm"])
+	assert.Equal(t, accessKeyID, pp.Fields["q-ak"])
+	assert.Len(t, pp.Fields["q-signature"], 40)
+}
+
+func TestCOS_ProcessImage(t *testing.T) {
+	cos, err := NewCOS(endpoint, accessKeyID, accessKeySecret, bucketName, WithNotSetACL())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	u, err := cos.ProcessImage(ctx, "test/test.png",
+		types.WithResize(100, 100, types.ResizeModeFill), types.WithFormat("jpg"), types.WithQuality(80))
+	require.NoError(t, err)
+	assert.Equal(t, cos.GetURL("test/test.png")+
+		"?imageMogr2/thumbnail/!100x100r/gravity/center/crop/100x100/format/jpg/quality/80", u)
+
+	u, err = cos.ProcessImage(ctx, "test/test.png", types.WithCrop(10, 10, 0, 100), types.WithResize(100, 0, ""),
+		types.WithWatermark(types.ImageWatermark{Text: "test", Color: "FF0000", Gravity: types.GravityNorthWest, X: 5, Y: 5}))
+	require.NoError(t, err)
+	assert.Equal(t, cos.GetURL("test/test.png")+"?imageMogr2/cut/9999x100x10x10/thumbnail/100x"+
+		"|watermark/2/text/dGVzdA/fontsize/40/fill/I0ZGMDAwMA/gravity/northwest/dx/5/dy/5", u)
+
+	_, err = cos.ProcessImage(ctx, "test/test.png", types.WithResize(100, 100, "unknown"))
+	require.ErrorIs(t, err, types.ErrInvalidImageOptions)
+}
+
+func TestProgressListener(t *testing.T) {
+	var consumed int64
+	tr := progress.New(context.Background(), 100, types.NewObjectOptions("test.txt",
+		types.WithProgress(func(e types.ProgressEvent) { consumed = e.ConsumedBytes })))
+	l := &progressListener{t: tr}
+
+	l.ProgressChangedCallback(&cossdk.ProgressEvent{EventType: cossdk.ProgressStartedEvent, TotalBytes: 100})
+	l.ProgressChangedCallback(&cossdk.ProgressEvent{EventType: cossdk.ProgressDataEvent, RWBytes: 40, ConsumedBytes: 40, TotalBytes: 100})
+	l.ProgressChangedCallback(&cossdk.ProgressEvent{EventType: cossdk.ProgressDataEvent, RWBytes: 60, ConsumedBytes: 100, TotalBytes: 100})
+	l.ProgressChangedCallback(&cossdk.ProgressEvent{EventType: cossdk.ProgressCompletedEvent, ConsumedBytes: 100, TotalBytes: 100})
+	assert.Equal(t, int64(100), consumed)
+}
@@ -0,0 +1,19 @@
+package types
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/sliveryou/micro-pkg/internal/bizerr"
+)
+
+// ErrObjectNotFound 对象不存在错误
+var ErrObjectNotFound = errors.New("oss: object not found")
+
+var (
+	// ErrFileTypeNotAllowed 文件类型不允许上传错误
+	ErrFileTypeNotAllowed = bizerr.ErrFileTypeNotAllowed
+	// ErrFileTooLarge 文件过大错误
+	ErrFileTooLarge = bizerr.ErrFileTooLarge
+	// ErrFileInfected 文件未通过安全扫描错误
+	ErrFileInfected = bizerr.ErrFileInfected
+)
@@ -0,0 +1,251 @@
+package types
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ResizeModeFit 等比缩放至指定宽高范围内（默认）
+	ResizeModeFit = "lfit"
+	// ResizeModeFill 等比缩放至覆盖指定宽高后居中裁剪
+	ResizeModeFill = "fill"
+	// ResizeModeFixed 强制缩放至指定宽高，可能会导致图片变形
+	ResizeModeFixed = "fixed"
+
+	// ImageFormatJPEG JPEG 图片格式
+	ImageFormatJPEG = "jpg"
+	// ImageFormatPNG PNG 图片格式
+	ImageFormatPNG = "png"
+	// ImageFormatGIF GIF 图片格式
+	ImageFormatGIF = "gif"
+	// ImageFormatWebP WebP 图片格式
+	ImageFormatWebP = "webp"
+
+	// GravityNorthWest 左上
+	GravityNorthWest = "nw"
+	// GravityNorth 中上
+	GravityNorth = "north"
+	// GravityNorthEast 右上
+	GravityNorthEast = "ne"
+	// GravityWest 左中
+	GravityWest = "west"
+	// GravityCenter 中部
+	GravityCenter = "center"
+	// GravityEast 右中
+	GravityEast = "east"
+	// GravitySouthWest 左下
+	GravitySouthWest = "sw"
+	// GravitySouth 中下
+	GravitySouth = "south"
+	// GravitySouthEast 右下（默认）
+	GravitySouthEast = "se"
+
+	// MaxImageSize 图片处理的最大宽高（像素）
+	MaxImageSize = 4096
+	// MaxImagePixels 图片处理的原图最大像素数，防止解码声明超大尺寸的图片时占用过多内存
+	MaxImagePixels = 50_000_000
+
+	// ImageCachePrefix 处理后图片缓存对象的保留键前缀，列举对象、本地生命周期清理和多后端对账均跳过该前缀下的对象
+	ImageCachePrefix = ".image-cache/"
+)
+
+var (
+	// ErrInvalidImageOptions 图片处理可选配置无效错误
+	ErrInvalidImageOptions = errors.New("oss: invalid image options")
+	// ErrUnsupportedImage 不支持的图片格式错误
+	ErrUnsupportedImage = errors.New("oss: unsupported image")
+)
+
+// ImageCrop 图片裁剪区域，宽高为 0 时裁剪至图片边缘
+type ImageCrop struct {
+	X      int // 左上角横坐标
+	Y      int // 左上角纵坐标
+	Width  int // 裁剪宽度
+	Height int // 裁剪高度
+}
+
+// ImageWatermark 图片水印，文字水印和图片水印二选一
+type ImageWatermark struct {
+	Text     string // 文字水印内容
+	FontSize int    // 文字水印字体大小，默认为 40
+	Color    string // 文字水印颜色，格式为 RRGGBB，默认为 000000
+	Image    string // 图片水印对象键，需与原图位于同一存储桶
+	Gravity  string // 水印位置，默认为 GravitySouthEast
+	X        int    // 水平边距
+	Y        int    // 垂直边距
+}
+
+// ImageOptions 图片处理可选配置，处理顺序为裁剪、缩放、水印、格式转换和质量
+type ImageOptions struct {
+	Crop      *ImageCrop      // 裁剪区域
+	Width     int             // 缩放宽度，只设置宽高之一时等比缩放
+	Height    int             // 缩放高度，只设置宽高之一时等比缩放
+	Mode      string          // 缩放模式，默认为 ResizeModeFit
+	Format    string          // 输出格式，为空时保持原图格式
+	Quality   int             // 输出质量（1~100），仅对 jpg 和 webp 有效
+	Watermark *ImageWatermark // 水印
+}
+
+// ImageOption 图片处理可选配置函数
+type ImageOption func(o *ImageOptions)
+
+// WithResize 使用缩放，mode：缩放模式，为空时使用 ResizeModeFit
+func WithResize(width, height int, mode string) ImageOption {
+	return func(o *ImageOptions) {
+		o.Width = width
+		o.Height = height
+		o.Mode = mode
+	}
+}
+
+// WithCrop 使用裁剪，宽高为 0 时裁剪至图片边缘
+func WithCrop(x, y, width, height int) ImageOption {
+	return func(o *ImageOptions) {
+		o.Crop = &ImageCrop{X: x, Y: y, Width: width, Height: height}
+	}
+}
+
+// WithFormat 使用输出格式
+func WithFormat(format string) ImageOption {
+	return func(o *ImageOptions) {
+		o.Format = format
+	}
+}
+
+// WithQuality 使用输出质量（1~100）
+func WithQuality(quality int) ImageOption {
+	return func(o *ImageOptions) {
+		o.Quality = quality
+	}
+}
+
+// WithWatermark 使用水印
+func WithWatermark(watermark ImageWatermark) ImageOption {
+	return func(o *ImageOptions) {
+		o.Watermark = &watermark
+	}
+}
+
+// NewImageOptions 新建图片处理可选配置
+func NewImageOptions(opts ...ImageOption) *ImageOptions {
+	o := &ImageOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	o.Format = strings.ToLower(strings.TrimPrefix(o.Format, "."))
+	if o.Format == "jpeg" {
+		o.Format = ImageFormatJPEG
+	}
+	if (o.Width > 0 || o.Height > 0) && o.Mode == "" {
+		o.Mode = ResizeModeFit
+	}
+	if w := o.Watermark; w != nil {
+		if w.Gravity == "" {
+			w.Gravity = GravitySouthEast
+		}
+		if w.Text != "" {
+			if w.FontSize <= 0 {
+				w.FontSize = 40
+			}
+			w.Color = strings.ToUpper(strings.TrimPrefix(w.Color, "#"))
+			if w.Color == "" {
+				w.Color = "000000"
+			}
+		}
+	}
+
+	return o
+}
+
+// Check 检查图片处理可选配置
+func (o *ImageOptions) Check() error {
+	if o.Crop == nil && o.Width <= 0 && o.Height <= 0 &&
+		o.Format == "" && o.Quality == 0 && o.Watermark == nil {
+		return errors.WithMessage(ErrInvalidImageOptions, "no image process")
+	}
+	if c := o.Crop; c != nil && (c.X < 0 || c.Y < 0 || c.Width < 0 || c.Height < 0 ||
+		c.Width > MaxImageSize || c.Height > MaxImageSize) {
+		return errors.WithMessage(ErrInvalidImageOptions, "crop out of range")
+	}
+	if o.Width < 0 || o.Height < 0 || o.Width > MaxImageSize || o.Height > MaxImageSize {
+		return errors.WithMessage(ErrInvalidImageOptions, "size out of range")
+	}
+	switch o.Mode {
+	case "", ResizeModeFit, ResizeModeFill, ResizeModeFixed:
+	default:
+		return errors.WithMessagef(ErrInvalidImageOptions, "mode: %s", o.Mode)
+	}
+	if o.Mode == ResizeModeFill && (o.Width <= 0 || o.Height <= 0) {
+		return errors.WithMessage(ErrInvalidImageOptions, "fill mode requires width and height")
+	}
+	switch o.Format {
+	case "", ImageFormatJPEG, ImageFormatPNG, ImageFormatGIF, ImageFormatWebP:
+	default:
+		return errors.WithMessagef(ErrInvalidImageOptions, "format: %s", o.Format)
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return errors.WithMessage(ErrInvalidImageOptions, "quality out of range")
+	}
+	if w := o.Watermark; w != nil {
+		if (w.Text == "") == (w.Image == "") {
+			return errors.WithMessage(ErrInvalidImageOptions, "watermark requires either text or image")
+		}
+		if w.Text != "" && (len(w.Color) != 6 || strings.Trim(w.Color, "0123456789ABCDEF") != "") {
+			return errors.WithMessagef(ErrInvalidImageOptions, "watermark color: %s", w.Color)
+		}
+		switch w.Gravity {
+		case GravityNorthWest, GravityNorth, GravityNorthEast, GravityWest, GravityCenter,
+			GravityEast, GravitySouthWest, GravitySouth, GravitySouthEast:
+		default:
+			return errors.WithMessagef(ErrInvalidImageOptions, "watermark gravity: %s", w.Gravity)
+		}
+	}
+
+	return nil
+}
+
+// String 获取图片处理可选配置的规范描述，相同处理的描述相同
+func (o *ImageOptions) String() string {
+	var b strings.Builder
+	if c := o.Crop; c != nil {
+		fmt.Fprintf(&b, "crop:%d,%d,%d,%d;", c.X, c.Y, c.Width, c.Height)
+	}
+	if o.Width > 0 || o.Height > 0 {
+		fmt.Fprintf(&b, "resize:%s,%d,%d;", o.Mode, o.Width, o.Height)
+	}
+	if w := o.Watermark; w != nil {
+		fmt.Fprintf(&b, "watermark:%q,%d,%s,%q,%s,%d,%d;", w.Text, w.FontSize, w.Color, w.Image, w.Gravity, w.X, w.Y)
+	}
+	if o.Format != "" {
+		fmt.Fprintf(&b, "format:%s;", o.Format)
+	}
+	if o.Quality > 0 {
+		fmt.Fprintf(&b, "quality:%d;", o.Quality)
+	}
+
+	return b.String()
+}
+
+// CacheKey 获取处理后图片的缓存对象键，位于 ImageCachePrefix 保留前缀下，
+// 格式为 ImageCachePrefix原图对象键@处理摘要.输出格式，etag：原图 ETag，原图被覆盖后缓存对象键随之变化，
+// format：实际输出格式
+func (o *ImageOptions) CacheKey(key, etag, format string) string {
+	sum := md5.Sum([]byte(etag + "\n" + o.String()))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(path.Ext(key)), ".")
+	}
+
+	return ImageCachePrefix + key + "@" + hex.EncodeToString(sum[:8]) + "." + format
+}
+
+// IsImageCacheKey 判断对象键是否位于处理后图片缓存对象的保留前缀下
+func IsImageCacheKey(key string) bool {
+	return strings.HasPrefix(key, ImageCachePrefix)
+}
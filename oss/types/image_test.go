@@ -0,0 +1,66 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewImageOptions(t *testing.T) {
+	o := NewImageOptions(WithResize(100, 0, ""), WithFormat(".JPEG"),
+		WithWatermark(ImageWatermark{Text: "test", Color: "#ff0000"}))
+	assert.Equal(t, ResizeModeFit, o.Mode)
+	assert.Equal(t, ImageFormatJPEG, o.Format)
+	assert.Equal(t, 40, o.Watermark.FontSize)
+	assert.Equal(t, "FF0000", o.Watermark.Color)
+	assert.Equal(t, GravitySouthEast, o.Watermark.Gravity)
+	require.NoError(t, o.Check())
+}
+
+func TestImageOptions_Check(t *testing.T) {
+	cases := []struct {
+		opts []ImageOption
+		ok   bool
+	}{
+		{opts: nil},
+		{opts: []ImageOption{WithResize(100, 100, ResizeModeFill)}, ok: true},
+		{opts: []ImageOption{WithResize(100, 0, ResizeModeFill)}},
+		{opts: []ImageOption{WithResize(MaxImageSize+1, 0, "")}},
+		{opts: []ImageOption{WithResize(100, 100, "unknown")}},
+		{opts: []ImageOption{WithCrop(0, 0, 100, 100)}, ok: true},
+		{opts: []ImageOption{WithCrop(-1, 0, 100, 100)}},
+		{opts: []ImageOption{WithFormat("bmp")}},
+		{opts: []ImageOption{WithQuality(101)}},
+		{opts: []ImageOption{WithWatermark(ImageWatermark{})}},
+		{opts: []ImageOption{WithWatermark(ImageWatermark{Text: "test", Image: "mark.png"})}},
+		{opts: []ImageOption{WithWatermark(ImageWatermark{Text: "test", Color: "red"})}},
+		{opts: []ImageOption{WithWatermark(ImageWatermark{Image: "mark.png", Gravity: "top"})}},
+		{opts: []ImageOption{WithWatermark(ImageWatermark{Image: "mark.png", Gravity: GravityCenter})}, ok: true},
+	}
+
+	for i, c := range cases {
+		err := NewImageOptions(c.opts...).Check()
+		if c.ok {
+			assert.NoError(t, err, i)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidImageOptions, i)
+		}
+	}
+}
+
+func TestImageOptions_CacheKey(t *testing.T) {
+	o1 := NewImageOptions(WithResize(100, 100, ""), WithQuality(80))
+	o2 := NewImageOptions(WithQuality(80), WithResize(100, 100, ResizeModeFit))
+	o3 := NewImageOptions(WithResize(100, 100, ResizeModeFill))
+
+	key := o1.CacheKey("test/test.png", "etag", "")
+	assert.Regexp(t, `^\.image-cache/test/test\.png@[0-9a-f]{16}\.png$`, key)
+	assert.True(t, IsImageCacheKey(key))
+	assert.False(t, IsImageCacheKey("test/test.png"))
+	assert.Equal(t, key, o2.CacheKey("test/test.png", "etag", ""))
+	assert.NotEqual(t, key, o3.CacheKey("test/test.png", "etag", ""))
+	// 原图被覆盖后 ETag 变化，缓存对象键随之变化
+	assert.NotEqual(t, key, o1.CacheKey("test/test.png", "other", ""))
+	assert.Regexp(t, `\.jpg$`, o1.CacheKey("test/test.png", "etag", ImageFormatJPEG))
+}
@@ -0,0 +1,64 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MaxLifecycleRules 存储桶最大生命周期规则数量
+const MaxLifecycleRules = 1000
+
+// ErrInvalidLifecycleRule 生命周期规则无效错误
+var ErrInvalidLifecycleRule = errors.New("oss: invalid lifecycle rule")
+
+// LifecycleRule 存储桶生命周期规则，过期删除和取消分片上传至少设置一项
+type LifecycleRule struct {
+	ID                       string // 规则 ID，为空时使用 rule-序号
+	Prefix                   string // 对象键前缀，为空时作用于整个存储桶
+	ExpirationDays           int    // 对象最后修改后过期删除的天数，为 0 时不删除
+	AbortMultipartUploadDays int    // 未完成的分片上传初始化后取消的天数，为 0 时不取消
+	Disabled                 bool   // 是否停用规则
+}
+
+// NewExpirationRule 新建过期删除规则，prefix：对象键前缀，days：对象最后修改后过期删除的天数
+func NewExpirationRule(prefix string, days int) LifecycleRule {
+	return LifecycleRule{Prefix: prefix, ExpirationDays: days}
+}
+
+// NewAbortMultipartUploadRule 新建取消分片上传规则，prefix：对象键前缀，days：分片上传初始化后取消的天数
+func NewAbortMultipartUploadRule(prefix string, days int) LifecycleRule {
+	return LifecycleRule{Prefix: prefix, AbortMultipartUploadDays: days}
+}
+
+// Match 判断对象键是否匹配规则前缀
+func (r LifecycleRule) Match(key string) bool {
+	return strings.HasPrefix(key, r.Prefix)
+}
+
+// NormalizeLifecycleRules 检查生命周期规则并填充为空的规则 ID，返回新的规则列表
+func NormalizeLifecycleRules(rules []LifecycleRule) ([]LifecycleRule, error) {
+	if len(rules) > MaxLifecycleRules {
+		return nil, errors.WithMessagef(ErrInvalidLifecycleRule, "too many rules: %d", len(rules))
+	}
+
+	normalized := make([]LifecycleRule, len(rules))
+	ids := make(map[string]struct{}, len(rules))
+	for i, r := range rules {
+		if r.ID == "" {
+			r.ID = "rule-" + strconv.Itoa(i+1)
+		}
+		if _, ok := ids[r.ID]; ok {
+			return nil, errors.WithMessagef(ErrInvalidLifecycleRule, "duplicate id: %s", r.ID)
+		}
+		if r.ExpirationDays < 0 || r.AbortMultipartUploadDays < 0 ||
+			(r.ExpirationDays == 0 && r.AbortMultipartUploadDays == 0) {
+			return nil, errors.WithMessagef(ErrInvalidLifecycleRule, "id: %s, days out of range", r.ID)
+		}
+		ids[r.ID] = struct{}{}
+		normalized[i] = r
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,42 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeLifecycleRules(t *testing.T) {
+	rules := []LifecycleRule{
+		NewExpirationRule("export/", 7),
+		{ID: "upload", AbortMultipartUploadDays: 1},
+		NewAbortMultipartUploadRule("tmp/", 3),
+	}
+	normalized, err := NormalizeLifecycleRules(rules)
+	require.NoError(t, err)
+	assert.Equal(t, "rule-1", normalized[0].ID)
+	assert.Equal(t, "upload", normalized[1].ID)
+	assert.Equal(t, "rule-3", normalized[2].ID)
+	assert.Empty(t, rules[0].ID)
+
+	normalized, err = NormalizeLifecycleRules(nil)
+	require.NoError(t, err)
+	assert.Empty(t, normalized)
+
+	_, err = NormalizeLifecycleRules([]LifecycleRule{{Prefix: "export/"}})
+	require.ErrorIs(t, err, ErrInvalidLifecycleRule)
+	_, err = NormalizeLifecycleRules([]LifecycleRule{NewExpirationRule("export/", -1)})
+	require.ErrorIs(t, err, ErrInvalidLifecycleRule)
+	_, err = NormalizeLifecycleRules([]LifecycleRule{{ID: "a", ExpirationDays: 1}, {ID: "a", ExpirationDays: 2}})
+	require.ErrorIs(t, err, ErrInvalidLifecycleRule)
+	_, err = NormalizeLifecycleRules(make([]LifecycleRule, MaxLifecycleRules+1))
+	require.ErrorIs(t, err, ErrInvalidLifecycleRule)
+}
+
+func TestLifecycleRule_Match(t *testing.T) {
+	r := NewExpirationRule("export/", 7)
+	assert.True(t, r.Match("export/test.csv"))
+	assert.False(t, r.Match("upload/test.csv"))
+	assert.True(t, NewExpirationRule("", 7).Match("upload/test.csv"))
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MinPartNumber 最小分片序号
+	MinPartNumber = 1
+	// MaxPartNumber 最大分片序号
+	MaxPartNumber = 10000
+)
+
+var (
+	// ErrUploadNotFound 分片上传不存在错误
+	ErrUploadNotFound = errors.New("oss: multipart upload not found")
+	// ErrInvalidPartNumber 分片序号错误
+	ErrInvalidPartNumber = errors.New("oss: invalid part number")
+	// ErrInvalidPart 分片不存在或分片 ETag 不匹配错误
+	ErrInvalidPart = errors.New("oss: invalid part")
+)
+
+// Part 分片信息
+type Part struct {
+	PartNumber   int       // 分片序号，取值范围为 1~10000
+	ETag         string    // 分片 ETag，上传分片后从响应头 ETag 中获取
+	Size         int64     // 分片大小（字节），仅列举分片时返回
+	LastModified time.Time // 最后修改时间，仅列举分片时返回
+}
+
+// CheckPartNumber 检查分片序号
+func CheckPartNumber(partNumber int) error {
+	if partNumber < MinPartNumber || partNumber > MaxPartNumber {
+		return errors.WithMessagef(ErrInvalidPartNumber, "part number: %d", partNumber)
+	}
+
+	return nil
+}
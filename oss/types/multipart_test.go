@@ -0,0 +1,15 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPartNumber(t *testing.T) {
+	assert.NoError(t, CheckPartNumber(MinPartNumber))
+	assert.NoError(t, CheckPartNumber(MaxPartNumber))
+	assert.True(t, errors.Is(CheckPartNumber(0), ErrInvalidPartNumber))
+	assert.True(t, errors.Is(CheckPartNumber(MaxPartNumber+1), ErrInvalidPartNumber))
+}
@@ -0,0 +1,94 @@
+package types
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// FilenameVar 表单上传对象键中的文件名变量，上传时会被替换为客户端上传的文件名
+	FilenameVar = "${filename}"
+)
+
+// ErrUnsupportedCondition 不支持的表单上传策略条件错误
+var ErrUnsupportedCondition = errors.New("oss: unsupported post policy condition")
+
+// PostPolicyOptions 表单上传策略可选配置
+type PostPolicyOptions struct {
+	KeyPrefix        string   // 对象键前缀，设置后允许客户端修改表单中的 key 字段，上传该前缀下的任意对象
+	MinContentLength int64    // 最小内容长度（字节）
+	MaxContentLength int64    // 最大内容长度（字节），为 0 时不限制
+	ContentTypes     []string // 允许的内容类型，如 image/png，以 /* 结尾时按前缀匹配，如 image/*
+}
+
+// PostPolicyOption 表单上传策略可选配置函数
+type PostPolicyOption func(o *PostPolicyOptions)
+
+// WithKeyPrefix 使用对象键前缀
+func WithKeyPrefix(keyPrefix string) PostPolicyOption {
+	return func(o *PostPolicyOptions) {
+		o.KeyPrefix = keyPrefix
+	}
+}
+
+// WithContentLengthRange 使用内容长度范围（字节）
+func WithContentLengthRange(min, max int64) PostPolicyOption {
+	return func(o *PostPolicyOptions) {
+		o.MinContentLength = min
+		o.MaxContentLength = max
+	}
+}
+
+// WithContentTypes 使用允许的内容类型，多次调用时会进行合并
+func WithContentTypes(contentTypes ...string) PostPolicyOption {
+	return func(o *PostPolicyOptions) {
+		o.ContentTypes = append(o.ContentTypes, contentTypes...)
+	}
+}
+
+// NewPostPolicyOptions 新建表单上传策略可选配置
+func NewPostPolicyOptions(opts ...PostPolicyOption) *PostPolicyOptions {
+	o := &PostPolicyOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.MinContentLength < 0 {
+		o.MinContentLength = 0
+	}
+
+	return o
+}
+
+// FormKey 获取表单中的对象键，key 为空且设置了对象键前缀时使用 前缀+${filename}
+func (o *PostPolicyOptions) FormKey(key string) string {
+	if key == "" && o.KeyPrefix != "" {
+		return o.KeyPrefix + FilenameVar
+	}
+
+	return key
+}
+
+// Check 检查表单上传策略可选配置，key：表单中的对象键
+func (o *PostPolicyOptions) Check(key string) error {
+	if key == "" {
+		return errors.New("oss: post policy key is empty")
+	}
+	if o.KeyPrefix != "" && !strings.HasPrefix(key, o.KeyPrefix) {
+		return errors.Errorf("oss: post policy key: %s does not match key prefix: %s", key, o.KeyPrefix)
+	}
+	if o.MaxContentLength > 0 && o.MinContentLength > o.MaxContentLength {
+		return errors.Errorf("oss: post policy illegal content length range: [%d, %d]",
+			o.MinContentLength, o.MaxContentLength)
+	}
+
+	return nil
+}
+
+// PostPolicy 表单上传策略
+type PostPolicy struct {
+	URL        string            // 表单提交地址
+	Fields     map[string]string // 表单字段，需在文件字段 file 之前提交
+	Expiration time.Time         // 过期时间
+}
@@ -0,0 +1,69 @@
+package types
+
+import "net/url"
+
+const (
+	// QueryResponseContentType 覆盖响应内容类型的查询参数
+	QueryResponseContentType = "response-content-type"
+	// QueryResponseContentDisposition 覆盖响应内容展示方式的查询参数
+	QueryResponseContentDisposition = "response-content-disposition"
+	// QueryResponseCacheControl 覆盖响应缓存控制的查询参数
+	QueryResponseCacheControl = "response-cache-control"
+)
+
+// PresignOptions 预签名可选配置
+type PresignOptions struct {
+	ResponseContentType        string // 响应内容类型
+	ResponseContentDisposition string // 响应内容展示方式，如 attachment; filename="test.pdf"
+	ResponseCacheControl       string // 响应缓存控制
+}
+
+// PresignOption 预签名可选配置函数
+type PresignOption func(o *PresignOptions)
+
+// WithResponseContentType 使用响应内容类型
+func WithResponseContentType(contentType string) PresignOption {
+	return func(o *PresignOptions) {
+		o.ResponseContentType = contentType
+	}
+}
+
+// WithResponseContentDisposition 使用响应内容展示方式
+func WithResponseContentDisposition(contentDisposition string) PresignOption {
+	return func(o *PresignOptions) {
+		o.ResponseContentDisposition = contentDisposition
+	}
+}
+
+// WithResponseCacheControl 使用响应缓存控制
+func WithResponseCacheControl(cacheControl string) PresignOption {
+	return func(o *PresignOptions) {
+		o.ResponseCacheControl = cacheControl
+	}
+}
+
+// NewPresignOptions 新建预签名可选配置
+func NewPresignOptions(opts ...PresignOption) *PresignOptions {
+	o := &PresignOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Query 获取响应头覆盖查询参数
+func (o *PresignOptions) Query() url.Values {
+	query := make(url.Values)
+	if o.ResponseContentType != "" {
+		query.Set(QueryResponseContentType, o.ResponseContentType)
+	}
+	if o.ResponseContentDisposition != "" {
+		query.Set(QueryResponseContentDisposition, o.ResponseContentDisposition)
+	}
+	if o.ResponseCacheControl != "" {
+		query.Set(QueryResponseCacheControl, o.ResponseCacheControl)
+	}
+
+	return query
+}
@@ -0,0 +1,22 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresignOptions_Query(t *testing.T) {
+	po := NewPresignOptions()
+	assert.Empty(t, po.Query())
+
+	po = NewPresignOptions(
+		WithResponseContentType("application/pdf"),
+		WithResponseContentDisposition(`attachment; filename="test.pdf"`),
+		WithResponseCacheControl("no-cache"),
+	)
+	query := po.Query()
+	assert.Equal(t, "application/pdf", query.Get("response-content-type"))
+	assert.Equal(t, `attachment; filename="test.pdf"`, query.Get("response-content-disposition"))
+	assert.Equal(t, "no-cache", query.Get("response-cache-control"))
+}
@@ -0,0 +1,34 @@
+package types
+
+// ProgressEvent 传输进度事件
+type ProgressEvent struct {
+	ConsumedBytes int64   // 已传输字节数
+	TotalBytes    int64   // 总字节数，无法获取时为 0
+	Rate          float64 // 传输开始至今的平均速率（字节/秒）
+}
+
+// Percent 获取传输进度百分比，总字节数未知时返回 0
+func (e ProgressEvent) Percent() float64 {
+	if e.TotalBytes <= 0 {
+		return 0
+	}
+
+	return float64(e.ConsumedBytes) * 100 / float64(e.TotalBytes)
+}
+
+// ProgressFunc 传输进度回调函数，同一次传输的回调不会并发执行
+type ProgressFunc func(e ProgressEvent)
+
+// WithProgress 使用传输进度回调函数
+func WithProgress(fn ProgressFunc) ObjectOption {
+	return func(o *ObjectOptions) {
+		o.Progress = fn
+	}
+}
+
+// WithRateLimit 使用单次传输的带宽限制（字节/秒），为 0 时不限制
+func WithRateLimit(bytesPerSecond int64) ObjectOption {
+	return func(o *ObjectOptions) {
+		o.RateLimit = bytesPerSecond
+	}
+}
@@ -0,0 +1,141 @@
+package types
+
+import (
+	"time"
+
+	"github.com/sliveryou/micro-pkg/xhttp"
+)
+
+const (
+	// MaxListKeys 单次列举对象的最大数量
+	MaxListKeys = 1000
+)
+
+// ObjectOptions 对象可选配置
+type ObjectOptions struct {
+	ContentType        string            // 内容类型，为空时根据 key 的扩展名推断
+	ContentDisposition string            // 内容展示方式，如 attachment; filename="test.pdf"
+	ContentEncoding    string            // 内容编码
+	CacheControl       string            // 缓存控制，如 max-age=3600
+	Metadata           map[string]string // 用户自定义元数据
+	Progress           ProgressFunc      // 传输进度回调函数
+	RateLimit          int64             // 单次传输的带宽限制（字节/秒）
+}
+
+// ObjectOption 对象可选配置函数
+type ObjectOption func(o *ObjectOptions)
+
+// WithContentType 使用内容类型
+func WithContentType(contentType string) ObjectOption {
+	return func(o *ObjectOptions) {
+		o.ContentType = contentType
+	}
+}
+
+// WithContentDisposition 使用内容展示方式
+func WithContentDisposition(contentDisposition string) ObjectOption {
+	return func(o *ObjectOptions) {
+		o.ContentDisposition = contentDisposition
+	}
+}
+
+// WithContentEncoding 使用内容编码
+func WithContentEncoding(contentEncoding string) ObjectOption {
+	return func(o *ObjectOptions) {
+		o.ContentEncoding = contentEncoding
+	}
+}
+
+// WithCacheControl 使用缓存控制
+func WithCacheControl(cacheControl string) ObjectOption {
+	return func(o *ObjectOptions) {
+		o.CacheControl = cacheControl
+	}
+}
+
+// WithMetadata 使用用户自定义元数据，多次调用时会进行合并
+func WithMetadata(metadata map[string]string) ObjectOption {
+	return func(o *ObjectOptions) {
+		if o.Metadata == nil {
+			o.Metadata = make(map[string]string, len(metadata))
+		}
+		for k, v := range metadata {
+			o.Metadata[k] = v
+		}
+	}
+}
+
+// NewObjectOptions 新建对象可选配置，key 用于在未指定内容类型时推断内容类型
+func NewObjectOptions(key string, opts ...ObjectOption) *ObjectOptions {
+	o := &ObjectOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.ContentType == "" {
+		o.ContentType = xhttp.TypeByExtension(key)
+	}
+
+	return o
+}
+
+// ObjectInfo 对象信息
+type ObjectInfo struct {
+	Key          string            // 对象键
+	Size         int64             // 对象大小（字节）
+	ETag         string            // 对象 ETag（不含双引号）
+	LastModified time.Time         // 最后修改时间
+	ContentType  string            // 内容类型，列举对象时可能为空
+	Metadata     map[string]string // 用户自定义元数据（键统一为小写），列举对象时为空
+}
+
+// ListObjectsResult 列举对象结果
+type ListObjectsResult struct {
+	Objects        []ObjectInfo // 对象列表
+	CommonPrefixes []string     // 公共前缀列表，仅在设置分隔符时返回
+	IsTruncated    bool         // 是否被截断，为 true 时表示还有下一页
+	NextMarker     string       // 下一页分页标记
+}
+
+// ListOptions 列举对象可选配置
+type ListOptions struct {
+	Marker    string // 分页标记，列举键名字典序大于该标记的对象，一般传入上一页结果的 NextMarker
+	Delimiter string // 分隔符，如 /，设置后仅列举当前层级的对象，下级层级以公共前缀返回
+	MaxKeys   int    // 最大返回数量，默认且最大为 1000
+}
+
+// ListOption 列举对象可选配置函数
+type ListOption func(o *ListOptions)
+
+// WithMarker 使用分页标记
+func WithMarker(marker string) ListOption {
+	return func(o *ListOptions) {
+		o.Marker = marker
+	}
+}
+
+// WithDelimiter 使用分隔符
+func WithDelimiter(delimiter string) ListOption {
+	return func(o *ListOptions) {
+		o.Delimiter = delimiter
+	}
+}
+
+// WithMaxKeys 使用最大返回数量
+func WithMaxKeys(maxKeys int) ListOption {
+	return func(o *ListOptions) {
+		o.MaxKeys = maxKeys
+	}
+}
+
+// NewListOptions 新建列举对象可选配置
+func NewListOptions(opts ...ListOption) *ListOptions {
+	o := &ListOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.MaxKeys <= 0 || o.MaxKeys > MaxListKeys {
+		o.MaxKeys = MaxListKeys
+	}
+
+	return o
+}
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewObjectOptions(t *testing.T) {
+	oo := NewObjectOptions("test/test.pdf")
+	assert.Equal(t, "application/pdf", oo.ContentType)
+
+	oo = NewObjectOptions("test/test.pdf",
+		WithContentType("application/octet-stream"),
+		WithContentDisposition(`attachment; filename="test.pdf"`),
+		WithContentEncoding("gzip"),
+		WithCacheControl("max-age=3600"),
+		WithMetadata(map[string]string{"a": "1"}),
+		WithMetadata(map[string]string{"b": "2"}),
+	)
+	assert.Equal(t, "application/octet-stream", oo.ContentType)
+	assert.Equal(t, `attachment; filename="test.pdf"`, oo.ContentDisposition)
+	assert.Equal(t, "gzip", oo.ContentEncoding)
+	assert.Equal(t, "max-age=3600", oo.CacheControl)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, oo.Metadata)
+}
+
+func TestNewObjectOptions_Progress(t *testing.T) {
+	var events []ProgressEvent
+	oo := NewObjectOptions("test/test.pdf", WithProgress(func(e ProgressEvent) {
+		events = append(events, e)
+	}), WithRateLimit(1024))
+	require.NotNil(t, oo.Progress)
+	assert.Equal(t, int64(1024), oo.RateLimit)
+
+	oo.Progress(ProgressEvent{ConsumedBytes: 25, TotalBytes: 100})
+	require.Len(t, events, 1)
+	assert.InDelta(t, 25, events[0].Percent(), 0.001)
+	assert.Zero(t, ProgressEvent{ConsumedBytes: 25}.Percent())
+}
+
+func TestNewListOptions(t *testing.T) {
+	lo := NewListOptions()
+	assert.Equal(t, MaxListKeys, lo.MaxKeys)
+
+	lo = NewListOptions(WithMarker("a"), WithDelimiter("/"), WithMaxKeys(10))
+	assert.Equal(t, "a", lo.Marker)
+	assert.Equal(t, "/", lo.Delimiter)
+	assert.Equal(t, 10, lo.MaxKeys)
+
+	lo = NewListOptions(WithMaxKeys(10000))
+	assert.Equal(t, MaxListKeys, lo.MaxKeys)
+}
@@ -0,0 +1,311 @@
+package oss
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/h2non/filetype"
+	"github.com/pkg/errors"
+
+	"github.com/sliveryou/micro-pkg/oss/internal/exif"
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+// sniffLen 探测内容类型读取的头部长度
+// https://github.com/h2non/filetype#file-header
+const sniffLen = 261
+
+// ErrValidationNotSupported 校验 OSS 客户端不支持该操作错误，签名 URL 和表单直传等方式无法在上传前校验数据
+var ErrValidationNotSupported = errors.New("oss: operation not supported by validated oss")
+
+// ValidationOption 校验 OSS 客户端可选配置
+type ValidationOption func(v *ValidatedOSS)
+
+// WithAllowedTypes 使用允许上传的内容类型，支持 image/* 形式的通配，默认允许全部类型
+func WithAllowedTypes(mimes ...string) ValidationOption {
+	return func(v *ValidatedOSS) {
+		v.allowedTypes = append(v.allowedTypes, mimes...)
+	}
+}
+
+// WithMaxSize 使用允许上传的最大文件大小（字节），默认不限制
+func WithMaxSize(maxSize int64) ValidationOption {
+	return func(v *ValidatedOSS) {
+		v.maxSize = maxSize
+	}
+}
+
+// WithKeepEXIF 使用是否保留 jpeg 和 png 图片中的 EXIF 和 XMP 元数据，默认移除
+func WithKeepEXIF(keep ...bool) ValidationOption {
+	return func(v *ValidatedOSS) {
+		v.keepEXIF = len(keep) == 0 || keep[0]
+	}
+}
+
+// WithScanners 使用上传内容安全扫描器，按顺序执行
+func WithScanners(scanners ...Scanner) ValidationOption {
+	return func(v *ValidatedOSS) {
+		v.scanners = append(v.scanners, scanners...)
+	}
+}
+
+// ValidatedOSS 上传校验 OSS 客户端，上传前依次校验文件大小、根据文件头探测的内容类型、移除图片元数据并执行安全扫描，
+// 校验失败时返回 types.ErrFileTooLarge、types.ErrFileTypeNotAllowed 或 types.ErrFileInfected
+type ValidatedOSS struct {
+	OSS                    // 内部 OSS 客户端
+	allowedTypes []string  // 允许上传的内容类型
+	maxSize      int64     // 允许上传的最大文件大小
+	keepEXIF     bool      // 是否保留图片元数据
+	scanners     []Scanner // 上传内容安全扫描器
+}
+
+// NewValidatedOSS 新建上传校验 OSS 客户端
+func NewValidatedOSS(client OSS, opts ...ValidationOption) (*ValidatedOSS, error) {
+	if client == nil {
+		return nil, errors.New("oss: illegal validated oss config")
+	}
+
+	v := &ValidatedOSS{OSS: client}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	if v.maxSize < 0 {
+		return nil, errors.New("oss: illegal validated oss max size")
+	}
+	for _, s := range v.scanners {
+		if s == nil {
+			return nil, errors.New("oss: illegal validated oss scanner")
+		}
+	}
+
+	return v, nil
+}
+
+// MustNewValidatedOSS 新建上传校验 OSS 客户端
+func MustNewValidatedOSS(client OSS, opts ...ValidationOption) *ValidatedOSS {
+	v, err := NewValidatedOSS(client, opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// PutObject 校验并上传对象至 OSS
+func (v *ValidatedOSS) PutObject(key string, reader io.Reader) (string, error) {
+	return v.PutObjectCtx(context.Background(), key, reader)
+}
+
+// PutObjectCtx 校验并上传对象至 OSS，数据先写入本地临时文件完成校验，
+// 识别出内容类型时将其作为对象的默认内容类型，opts：对象可选配置
+func (v *ValidatedOSS) PutObjectCtx(ctx context.Context, key string, reader io.Reader, opts ...types.ObjectOption) (string, error) {
+	f, contentType, err := v.prepare(ctx, reader)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	return v.OSS.PutObjectCtx(ctx, key, f, withDetectedType(contentType, opts)...)
+}
+
+// UploadFile 校验并上传文件至 OSS，filePath：文件路径，partSize：分块大小（字节），routines：并发数
+func (v *ValidatedOSS) UploadFile(key, filePath string, partSize int64, routines int) (string, error) {
+	return v.UploadFileCtx(context.Background(), key, filePath, partSize, routines)
+}
+
+// UploadFileCtx 校验并上传文件至 OSS，文件校验后写入本地临时文件再分块上传，
+// filePath：文件路径，partSize：分块大小（字节），routines：并发数，opts：对象可选配置
+func (v *ValidatedOSS) UploadFileCtx(ctx context.Context, key, filePath string, partSize int64, routines int, opts ...types.ObjectOption) (string, error) {
+	fi, err := os.Stat(filePath)
+	if err != nil {
+		return "", errors.WithMessagef(err, "oss: stat file path: %s err", filePath)
+	}
+	if v.maxSize > 0 && fi.Size() > v.maxSize {
+		return "", errors.WithMessagef(types.ErrFileTooLarge, "size: %d, max size: %d", fi.Size(), v.maxSize)
+	}
+
+	src, err := os.Open(filePath)
+	if err != nil {
+		return "", errors.WithMessagef(err, "oss: open file path: %s err", filePath)
+	}
+	defer src.Close()
+
+	f, contentType, err := v.prepare(ctx, src)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+	if err := f.Close(); err != nil {
+		return "", errors.WithMessage(err, "oss: close temp file err")
+	}
+
+	return v.OSS.UploadFileCtx(ctx, key, f.Name(), partSize, routines, withDetectedType(contentType, opts)...)
+}
+
+// AuthorizedUpload 上传校验模式下不支持授权上传，返回 ErrValidationNotSupported
+func (v *ValidatedOSS) AuthorizedUpload(key string, expires int) (string, error) {
+	return "", ErrValidationNotSupported
+}
+
+// PostPolicy 上传校验模式下不支持表单直传，返回 ErrValidationNotSupported
+func (v *ValidatedOSS) PostPolicy(key string, expires int, opts ...types.PostPolicyOption) (*types.PostPolicy, error) {
+	return nil, ErrValidationNotSupported
+}
+
+// InitiateMultipartUpload 上传校验模式下不支持分片上传会话，返回 ErrValidationNotSupported
+func (v *ValidatedOSS) InitiateMultipartUpload(ctx context.Context, key string, opts ...types.ObjectOption) (string, error) {
+	return "", ErrValidationNotSupported
+}
+
+// PresignedUploadPartURL 上传校验模式下不支持分片上传会话，返回 ErrValidationNotSupported
+func (v *ValidatedOSS) PresignedUploadPartURL(key, uploadID string, partNumber, expires int) (string, error) {
+	return "", ErrValidationNotSupported
+}
+
+// Validate 校验数据但不上传，返回探测到的内容类型，内容类型无法识别时返回空字符串
+func (v *ValidatedOSS) Validate(ctx context.Context, reader io.Reader) (string, error) {
+	f, contentType, err := v.prepare(ctx, reader)
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	return contentType, nil
+}
+
+// prepare 将数据写入本地临时文件并执行校验流水线，返回已定位至开头的校验后文件和探测到的内容类型，
+// 调用方负责关闭并删除文件
+func (v *ValidatedOSS) prepare(ctx context.Context, reader io.Reader) (_ *os.File, contentType string, err error) {
+	if v.maxSize > 0 {
+		reader = io.LimitReader(reader, v.maxSize+1)
+	}
+	f, size, err := spool(reader)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if v.maxSize > 0 && size > v.maxSize {
+		return nil, "", errors.WithMessagef(types.ErrFileTooLarge, "max size: %d", v.maxSize)
+	}
+
+	contentType, mediaType, err := sniff(f)
+	if err != nil {
+		return nil, "", err
+	}
+	if !v.allowed(mediaType) {
+		return nil, "", errors.WithMessagef(types.ErrFileTypeNotAllowed, "content type: %s", mediaType)
+	}
+
+	if !v.keepEXIF && exif.Supported(contentType) {
+		stripped, serr := strip(f, contentType)
+		if serr != nil {
+			return nil, "", serr
+		}
+		f.Close()
+		os.Remove(f.Name())
+		f = stripped
+	}
+
+	for _, s := range v.scanners {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, "", errors.WithMessage(err, "oss: seek temp file err")
+		}
+		if err := s.Scan(ctx, f); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, "", errors.WithMessage(err, "oss: seek temp file err")
+	}
+
+	return f, contentType, nil
+}
+
+// allowed 判断内容类型是否允许上传
+func (v *ValidatedOSS) allowed(mediaType string) bool {
+	if len(v.allowedTypes) == 0 {
+		return true
+	}
+
+	for _, t := range v.allowedTypes {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == mediaType || t == "*/*" ||
+			(strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sniff 根据文件头探测内容类型，contentType 为 filetype 识别出的内容类型，无法识别时为空，
+// mediaType 为用于校验的内容类型，无法识别时使用 http.DetectContentType 的结果
+func sniff(f *os.File) (contentType, mediaType string, err error) {
+	head := make([]byte, sniffLen)
+	n, err := f.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", "", errors.WithMessage(err, "oss: read file head err")
+	}
+	head = head[:n]
+
+	if kind, _ := filetype.Match(head); kind != filetype.Unknown {
+		return kind.MIME.Value, kind.MIME.Value, nil
+	}
+
+	mediaType, _, err = mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "", "", errors.WithMessage(err, "oss: parse media type err")
+	}
+
+	return "", mediaType, nil
+}
+
+// strip 移除图片元数据并写入新的本地临时文件
+func strip(f *os.File, contentType string) (*os.File, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.WithMessage(err, "oss: seek temp file err")
+	}
+
+	tmp, err := os.CreateTemp("", "oss-strip-*")
+	if err != nil {
+		return nil, errors.WithMessage(err, "oss: create temp file err")
+	}
+	if err := exif.Strip(tmp, f, contentType); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		if errors.Is(err, exif.ErrInvalidImage) {
+			return nil, errors.WithMessagef(types.ErrFileTypeNotAllowed, "content type: %s, %v", contentType, err)
+		}
+		return nil, errors.WithMessage(err, "oss: strip image metadata err")
+	}
+
+	return tmp, nil
+}
+
+// withDetectedType 将探测到的内容类型作为首个对象可选配置，调用方传入的内容类型优先
+func withDetectedType(contentType string, opts []types.ObjectOption) []types.ObjectOption {
+	if contentType == "" {
+		return opts
+	}
+
+	return append([]types.ObjectOption{types.WithContentType(contentType)}, opts...)
+}
@@ -0,0 +1,161 @@
+package oss
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/errcode"
+	"github.com/sliveryou/micro-pkg/oss/types"
+)
+
+func TestValidatedOSS(t *testing.T) {
+	defer os.RemoveAll("testdata")
+	inner := MustNewOSS(Config{Cloud: "local", EndPoint: "endpoint", BucketName: "testdata"})
+
+	_, err := NewValidatedOSS(nil)
+	require.Error(t, err)
+	_, err = NewValidatedOSS(inner, WithMaxSize(-1))
+	require.Error(t, err)
+
+	v, err := NewValidatedOSS(inner, WithAllowedTypes("image/*", "text/plain"), WithMaxSize(1024))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	img := newJPEG(t)
+	_, err = v.PutObjectCtx(ctx, "test/test.bin", bytes.NewReader(img))
+	require.NoError(t, err)
+	info, err := inner.StatObject(ctx, "test/test.bin")
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", info.ContentType)
+
+	// 调用方传入的内容类型优先
+	_, err = v.PutObjectCtx(ctx, "test/test2.bin", bytes.NewReader(img), types.WithContentType("image/x-custom"))
+	require.NoError(t, err)
+	info, err = inner.StatObject(ctx, "test/test2.bin")
+	require.NoError(t, err)
+	assert.Equal(t, "image/x-custom", info.ContentType)
+
+	_, err = v.PutObject("test/test.txt", strings.NewReader("hello world"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", readObject(t, inner, "test/test.txt"))
+
+	// 内容类型根据文件头探测，与对象键后缀无关
+	_, err = v.PutObject("test/test.png", bytes.NewReader([]byte("%PDF-1.4\n%test")))
+	require.ErrorIs(t, err, types.ErrFileTypeNotAllowed)
+	assert.Contains(t, err.Error(), "application/pdf")
+	ec, ok := errcode.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, uint32(160), ec.Code)
+	exist, err := inner.Exists(ctx, "test/test.png")
+	require.NoError(t, err)
+	assert.False(t, exist)
+
+	_, err = v.PutObject("test/large.txt", strings.NewReader(strings.Repeat("a", 1025)))
+	require.ErrorIs(t, err, types.ErrFileTooLarge)
+	ec, ok = errcode.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, uint32(161), ec.Code)
+
+	filePath := filepath.Join(t.TempDir(), "large.txt")
+	require.NoError(t, os.WriteFile(filePath, []byte(strings.Repeat("a", 1025)), 0o644))
+	_, err = v.UploadFile("test/large.txt", filePath, 0, 1)
+	require.ErrorIs(t, err, types.ErrFileTooLarge)
+
+	filePath = filepath.Join(t.TempDir(), "test.jpg")
+	require.NoError(t, os.WriteFile(filePath, img, 0o644))
+	_, err = v.UploadFile("test/file.jpg", filePath, 0, 1)
+	require.NoError(t, err)
+
+	contentType, err := v.Validate(ctx, bytes.NewReader(img))
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", contentType)
+
+	// 不支持直传
+	_, err = v.AuthorizedUpload("test/test.txt", 60)
+	require.ErrorIs(t, err, ErrValidationNotSupported)
+	_, err = v.PostPolicy("test/test.txt", 60)
+	require.ErrorIs(t, err, ErrValidationNotSupported)
+	_, err = v.InitiateMultipartUpload(ctx, "test/test.txt")
+	require.ErrorIs(t, err, ErrValidationNotSupported)
+	_, err = v.PresignedUploadPartURL("test/test.txt", "upload", 1, 60)
+	require.ErrorIs(t, err, ErrValidationNotSupported)
+}
+
+func TestValidatedOSS_EXIF(t *testing.T) {
+	defer os.RemoveAll("testdata")
+	inner := MustNewOSS(Config{Cloud: "local", EndPoint: "endpoint", BucketName: "testdata"})
+
+	img := newJPEG(t)
+	withExif := append([]byte{}, img[:2]...)
+	withExif = append(withExif, 0xff, 0xe1)
+	seg := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x00GPS-SECRET")
+	withExif = binary.BigEndian.AppendUint16(withExif, uint16(len(seg)+2))
+	withExif = append(withExif, seg...)
+	withExif = append(withExif, img[2:]...)
+
+	v := MustNewValidatedOSS(inner)
+	_, err := v.PutObject("test/strip.jpg", bytes.NewReader(withExif))
+	require.NoError(t, err)
+	stripped := readObject(t, inner, "test/strip.jpg")
+	assert.NotContains(t, stripped, "GPS-SECRET")
+	_, err = jpeg.Decode(strings.NewReader(stripped))
+	require.NoError(t, err)
+
+	v = MustNewValidatedOSS(inner, WithKeepEXIF())
+	_, err = v.PutObject("test/keep.jpg", bytes.NewReader(withExif))
+	require.NoError(t, err)
+	assert.Contains(t, readObject(t, inner, "test/keep.jpg"), "GPS-SECRET")
+
+	// 文件头为 jpeg 但内容损坏时拒绝上传
+	v = MustNewValidatedOSS(inner)
+	_, err = v.PutObject("test/bad.jpg", bytes.NewReader(withExif[:30]))
+	require.ErrorIs(t, err, types.ErrFileTypeNotAllowed)
+}
+
+func TestValidatedOSS_Scanners(t *testing.T) {
+	defer os.RemoveAll("testdata")
+	inner := MustNewOSS(Config{Cloud: "local", EndPoint: "endpoint", BucketName: "testdata"})
+
+	clamav, err := NewClamAVScanner(startClamd(t))
+	require.NoError(t, err)
+	var scanned []string
+	recorder := ScannerFunc(func(ctx context.Context, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		scanned = append(scanned, string(data))
+		return err
+	})
+
+	ctx := context.Background()
+	v := MustNewValidatedOSS(inner, WithScanners(recorder, clamav, recorder))
+	_, err = v.PutObject("test/test.txt", strings.NewReader("hello world"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hello world", "hello world"}, scanned)
+	assert.Equal(t, "hello world", readObject(t, inner, "test/test.txt"))
+
+	_, err = v.PutObject("test/virus.txt", strings.NewReader("hello EICAR world"))
+	require.ErrorIs(t, err, types.ErrFileInfected)
+	exist, err := inner.Exists(ctx, "test/virus.txt")
+	require.NoError(t, err)
+	assert.False(t, exist)
+}
+
+// newJPEG 新建测试 jpeg 图片
+func newJPEG(t *testing.T) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, jpeg.Encode(buf, image.NewGray(image.Rect(0, 0, 8, 8)), nil))
+
+	return buf.Bytes()
+}
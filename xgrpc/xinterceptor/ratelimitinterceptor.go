@@ -0,0 +1,180 @@
+package xinterceptor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/sliveryou/go-tool/v2/convert"
+
+	"github.com/sliveryou/micro-pkg/appsign"
+	"github.com/sliveryou/micro-pkg/internal/bizerr"
+	"github.com/sliveryou/micro-pkg/jwt"
+	"github.com/sliveryou/micro-pkg/limit"
+	"github.com/sliveryou/micro-pkg/xhttp"
+)
+
+// ErrTooManyRequests 请求过于频繁错误，以 ResourceExhausted 状态码返回
+var ErrTooManyRequests = status.Error(codes.ResourceExhausted, bizerr.ErrTooManyRequests.Error())
+
+// RateLimitOption 限流拦截器可选配置
+type RateLimitOption func(o *rateLimitOptions)
+
+// rateLimitOptions 限流拦截器配置
+type rateLimitOptions struct {
+	trustForwarded bool // 是否信任网关传递的 X-Forwarded-For 和 X-Real-Ip
+}
+
+// WithTrustForwarded 信任网关传递的 X-Forwarded-For 和 X-Real-Ip metadata 作为客户端 IP，默认仅使用对端地址
+//
+// 注意：仅在服务只能经由可信网关访问时使用，否则客户端可以伪造 IP 绕过限流
+func WithTrustForwarded() RateLimitOption {
+	return func(o *rateLimitOptions) {
+		o.trustForwarded = true
+	}
+}
+
+// newRateLimitOptions 新建限流拦截器配置
+func newRateLimitOptions(opts ...RateLimitOption) *rateLimitOptions {
+	o := &rateLimitOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// RateLimitInterceptor 限流服务端一元拦截器
+//
+// 注意：限流键来源为 jwt 时，该拦截器须在 JWT 服务端一元拦截器之后执行，
+// 限流键来源为 appkey 时，该拦截器须在签名校验并通过 appsign.CtxWithAppKey 写入 AppKey 之后执行
+func RateLimitInterceptor(rl *limit.RouteLimit, opts ...RateLimitOption) grpc.UnaryServerInterceptor {
+	o := newRateLimitOptions(opts...)
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, err := allowRPC(ctx, rl, info.FullMethod, o)
+		if md != nil {
+			_ = grpc.SetHeader(ctx, md)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitStreamInterceptor 限流服务端流拦截器
+//
+// 注意：限流键来源为 jwt 时，该拦截器须在 JWT 服务端流拦截器之后执行，
+// 限流键来源为 appkey 时，该拦截器须在签名校验并通过 appsign.CtxWithAppKey 写入 AppKey 之后执行
+func RateLimitStreamInterceptor(rl *limit.RouteLimit, opts ...RateLimitOption) grpc.StreamServerInterceptor {
+	o := newRateLimitOptions(opts...)
+
+	return func(svr any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, err := allowRPC(stream.Context(), rl, info.FullMethod, o)
+		if md != nil {
+			_ = stream.SetHeader(md)
+		}
+		if err != nil {
+			return err
+		}
+
+		return handler(svr, stream)
+	}
+}
+
+// allowRPC 访问路由限流器，返回限流结果对应的响应头 metadata，请求被拒绝时返回 ErrTooManyRequests
+func allowRPC(ctx context.Context, rl *limit.RouteLimit, fullMethod string, o *rateLimitOptions) (metadata.MD, error) {
+	rule, ok := rl.Match("", fullMethod)
+	if !ok {
+		return nil, nil
+	}
+
+	// 未能提取限流键时不限流
+	key := rateLimitKey(ctx, rule, o)
+	if key == "" {
+		return nil, nil
+	}
+
+	// 限流器不可用时放行请求，避免影响业务
+	result, err := rl.Allow(rule, key)
+	if err != nil {
+		logx.WithContext(ctx).Errorf("rate limit interceptor allow err: %v", err)
+		return nil, nil
+	}
+
+	header := make(http.Header)
+	result.SetHeader(header)
+	md := metadata.MD{}
+	for k, v := range header {
+		md.Set(k, v...)
+	}
+	if !result.Allowed {
+		return md, ErrTooManyRequests
+	}
+
+	return md, nil
+}
+
+// rateLimitKey 按规则的限流键来源提取限流键
+func rateLimitKey(ctx context.Context, rule *limit.RouteRule, o *rateLimitOptions) string {
+	switch rule.KeyBy {
+	case limit.KeyByJWT:
+		payloads := make(map[string]any)
+		if err := jwt.ReadCtx(ctx, &payloads); err != nil {
+			return ""
+		}
+		if v, ok := payloads[rule.JWTField]; ok && v != nil {
+			return convert.ToString(v)
+		}
+		return ""
+	case limit.KeyByAppKey:
+		// 仅使用签名校验后的 AppKey，不信任请求 metadata 中未经校验的值
+		return appsign.AppKeyFromCtx(ctx)
+	case limit.KeyByRoute:
+		return rule.RouteKey()
+	default:
+		return clientIP(ctx, o.trustForwarded)
+	}
+}
+
+// clientIP 获取客户端的IP，trustForwarded 为 true 时优先使用网关传递的 X-Forwarded-For 和 X-Real-Ip，否则使用对端地址
+func clientIP(ctx context.Context, trustForwarded bool) string {
+	if trustForwarded {
+		if ip := strings.TrimSpace(strings.Split(firstMD(ctx, xhttp.HeaderXForwardedFor), ",")[0]); ip != "" {
+			return ip
+		}
+
+		if ip := strings.TrimSpace(firstMD(ctx, xhttp.HeaderXRealIP)); ip != "" {
+			return ip
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if ip, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return ip
+		}
+	}
+
+	return ""
+}
+
+// firstMD 获取请求 metadata 中指定 key 的首个值
+func firstMD(ctx context.Context, key string) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vs := md.Get(key); len(vs) > 0 {
+			return vs[0]
+		}
+	}
+
+	return ""
+}
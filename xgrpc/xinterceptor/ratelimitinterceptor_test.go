@@ -0,0 +1,154 @@
+package xinterceptor
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	miniredis "github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/core/stores/cache"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/sliveryou/micro-pkg/appsign"
+	"github.com/sliveryou/micro-pkg/errcode"
+	"github.com/sliveryou/micro-pkg/jwt"
+	"github.com/sliveryou/micro-pkg/limit"
+	"github.com/sliveryou/micro-pkg/xkv"
+)
+
+func getRouteLimit(t *testing.T) *limit.RouteLimit {
+	m := miniredis.RunT(t)
+	store := xkv.NewStore([]cache.NodeConf{
+		{
+			RedisConf: redis.RedisConf{
+				Host: m.Addr(),
+				Type: redis.NodeType,
+			},
+			Weight: 100,
+		},
+	})
+
+	return limit.MustNewRouteLimit(limit.RouteLimitConf{
+		Rules: []limit.RouteRule{
+			{Route: "/user.User/GetUser", KeyBy: limit.KeyByJWT, JWTField: "id", Rate: 1, Period: time.Minute},
+			{Route: "/user.User/Watch", KeyBy: limit.KeyByAppKey, Rate: 1, Period: time.Minute},
+			{Route: "/notice.Notice/*", KeyBy: limit.KeyByRoute, Rate: 1, Period: time.Minute},
+			{Route: "/user.User/*", Rate: 1, Period: time.Minute},
+		},
+	}, store)
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	rl := getRouteLimit(t)
+	interceptor := RateLimitInterceptor(rl)
+	handler := func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	}
+	call := func(ctx context.Context, method string) (*mockedTransportStream, error) {
+		sts := &mockedTransportStream{}
+		ctx = grpc.NewContextWithServerTransportStream(ctx, sts)
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return sts, err
+	}
+
+	// 按 JWT 载荷字段限流
+	ctx := jwt.WithCtx(context.Background(), map[string]any{"id": 1})
+	sts, err := call(ctx, "/user.User/GetUser")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, sts.header.Get("ratelimit-limit"))
+	assert.Equal(t, []string{"0"}, sts.header.Get("ratelimit-remaining"))
+	sts, err = call(ctx, "/user.User/GetUser")
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"60"}, sts.header.Get("retry-after"))
+	e, ok := errcode.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, uint32(156), e.Code)
+	assert.Equal(t, 429, e.HTTPCode)
+	_, err = call(jwt.WithCtx(context.Background(), map[string]any{"id": 2}), "/user.User/GetUser")
+	require.NoError(t, err)
+
+	// 按客户端 IP 限流，默认仅使用对端地址，不信任客户端传递的 X-Forwarded-For 和 X-Real-Ip
+	ctx = peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 8080}})
+	_, err = call(ctx, "/user.User/ListUser")
+	require.NoError(t, err)
+	_, err = call(ctx, "/user.User/ListUser")
+	require.Error(t, err)
+	_, err = call(metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", "10.0.0.2")), "/user.User/ListUser")
+	require.Error(t, err)
+	_, err = call(metadata.NewIncomingContext(ctx, metadata.Pairs("x-real-ip", "10.0.0.3")), "/user.User/ListUser")
+	require.Error(t, err)
+
+	// 信任网关时优先使用网关传递的 X-Forwarded-For 和 X-Real-Ip
+	interceptor = RateLimitInterceptor(rl, WithTrustForwarded())
+	_, err = call(metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", "10.0.0.2, 10.0.0.1")), "/user.User/ListUser")
+	require.NoError(t, err)
+	_, err = call(metadata.NewIncomingContext(ctx, metadata.Pairs("x-real-ip", "10.0.0.3")), "/user.User/ListUser")
+	require.NoError(t, err)
+	_, err = call(ctx, "/user.User/ListUser")
+	require.Error(t, err)
+
+	// 按路由限流，匹配同一规则的全部方法共享配额
+	_, err = call(ctx, "/notice.Notice/GetNotice")
+	require.NoError(t, err)
+	_, err = call(context.Background(), "/notice.Notice/ListNotice")
+	require.Error(t, err)
+
+	// 未匹配到规则时不限流
+	for i := 0; i < 3; i++ {
+		sts, err = call(ctx, "/order.Order/GetOrder")
+		require.NoError(t, err)
+		assert.Empty(t, sts.header)
+	}
+}
+
+func TestRateLimitStreamInterceptor(t *testing.T) {
+	interceptor := RateLimitStreamInterceptor(getRouteLimit(t))
+	info := &grpc.StreamServerInfo{FullMethod: "/user.User/Watch"}
+	handler := func(_ any, ss grpc.ServerStream) error {
+		return nil
+	}
+
+	// 按签名校验后的应用 AppKey 限流
+	ctx := appsign.CtxWithAppKey(context.Background(), "app1")
+	require.NoError(t, interceptor(nil, mockedStream{ctx: ctx}, info, handler))
+	err := interceptor(nil, mockedStream{ctx: ctx}, info, handler)
+	require.Error(t, err)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// 无法提取限流键时不限流，不使用请求 metadata 中未经校验的 AppKey
+	for i := 0; i < 3; i++ {
+		require.NoError(t, interceptor(nil, mockedStream{ctx: context.Background()}, info, handler))
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-appkey", "app1"))
+		require.NoError(t, interceptor(nil, mockedStream{ctx: ctx}, info, handler))
+	}
+}
+
+type mockedTransportStream struct {
+	header metadata.MD
+}
+
+func (m *mockedTransportStream) Method() string {
+	return ""
+}
+
+func (m *mockedTransportStream) SetHeader(md metadata.MD) error {
+	m.header = metadata.Join(m.header, md)
+	return nil
+}
+
+func (m *mockedTransportStream) SendHeader(md metadata.MD) error {
+	return nil
+}
+
+func (m *mockedTransportStream) SetTrailer(md metadata.MD) error {
+	return nil
+}
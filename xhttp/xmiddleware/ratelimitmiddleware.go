@@ -0,0 +1,111 @@
+package xmiddleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/zeromicro/go-zero/core/logx"
+
+	"github.com/sliveryou/go-tool/v2/convert"
+
+	"github.com/sliveryou/micro-pkg/appsign"
+	"github.com/sliveryou/micro-pkg/internal/bizerr"
+	"github.com/sliveryou/micro-pkg/jwt"
+	"github.com/sliveryou/micro-pkg/limit"
+	"github.com/sliveryou/micro-pkg/xhttp"
+)
+
+// -------------------- RateLimitMiddleware -------------------- //
+
+// ErrTooManyRequests 请求过于频繁错误
+var ErrTooManyRequests = bizerr.ErrTooManyRequests
+
+// RateLimitMiddleware 限流处理中间件
+type RateLimitMiddleware struct {
+	rl          *limit.RouteLimit
+	routePrefix string
+}
+
+// NewRateLimitMiddleware 新建限流处理中间件
+func NewRateLimitMiddleware(rl *limit.RouteLimit, routePrefix string) (*RateLimitMiddleware, error) {
+	if rl == nil {
+		return nil, errors.New("xmiddleware: illegal rate limit middleware config")
+	}
+
+	return &RateLimitMiddleware{rl: rl, routePrefix: routePrefix}, nil
+}
+
+// MustNewRateLimitMiddleware 新建限流处理中间件
+func MustNewRateLimitMiddleware(rl *limit.RouteLimit, routePrefix string) *RateLimitMiddleware {
+	m, err := NewRateLimitMiddleware(rl, routePrefix)
+	if err != nil {
+		panic(err)
+	}
+
+	return m
+}
+
+// Handle 限流处理
+//
+// 注意：限流键来源为 jwt 或 appkey 时，该中间件须在 JWT 认证处理或签名校验处理中间件之后执行
+func (m *RateLimitMiddleware) Handle(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		// 去除路径前缀
+		api := strings.TrimPrefix(r.URL.Path, m.routePrefix)
+
+		rule, ok := m.rl.Match(r.Method, api)
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		// 未能提取限流键时不限流
+		key := rateLimitKey(ctx, rule, func() string {
+			return xhttp.GetClientIP(r)
+		})
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		// 限流器不可用时放行请求，避免影响业务
+		result, err := m.rl.Allow(rule, key)
+		if err != nil {
+			logx.WithContext(ctx).Errorf("rate limit middleware allow err: %v", err)
+			next(w, r)
+			return
+		}
+
+		result.SetHeader(w.Header())
+		if !result.Allowed {
+			xhttp.ErrorCtx(ctx, w, ErrTooManyRequests)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// rateLimitKey 按规则的限流键来源提取限流键
+func rateLimitKey(ctx context.Context, rule *limit.RouteRule, clientIP func() string) string {
+	switch rule.KeyBy {
+	case limit.KeyByJWT:
+		payloads := make(map[string]any)
+		if err := jwt.ReadCtx(ctx, &payloads); err != nil {
+			return ""
+		}
+		if v, ok := payloads[rule.JWTField]; ok && v != nil {
+			return convert.ToString(v)
+		}
+		return ""
+	case limit.KeyByAppKey:
+		return appsign.AppKeyFromCtx(ctx)
+	case limit.KeyByRoute:
+		return rule.RouteKey()
+	default:
+		return clientIP()
+	}
+}
@@ -0,0 +1,128 @@
+package xmiddleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sliveryou/micro-pkg/appsign"
+	"github.com/sliveryou/micro-pkg/jwt"
+	"github.com/sliveryou/micro-pkg/limit"
+	"github.com/sliveryou/micro-pkg/xhttp"
+)
+
+func getRateLimitMiddleware() *RateLimitMiddleware {
+	rl := limit.MustNewRouteLimit(limit.RouteLimitConf{
+		Rules: []limit.RouteRule{
+			{Route: "POST:/api/user/{id}", KeyBy: limit.KeyByJWT, Rate: 1, Period: time.Minute},
+			{Route: "/api/file", KeyBy: limit.KeyByAppKey, Rate: 1, Period: time.Minute},
+			{Route: "/api/notice", KeyBy: limit.KeyByRoute, Rate: 1, Period: time.Minute},
+			{Route: "GET:/api/notice/{id}", KeyBy: limit.KeyByRoute, Rate: 1, Period: time.Minute},
+			{Route: "/api/login", Rate: 2, Period: time.Minute},
+		},
+	}, getStore())
+
+	return MustNewRateLimitMiddleware(rl, "/v1")
+}
+
+func TestNewRateLimitMiddleware(t *testing.T) {
+	_, err := NewRateLimitMiddleware(nil, "")
+	require.Error(t, err)
+	assert.Panics(t, func() {
+		MustNewRateLimitMiddleware(nil, "")
+	})
+}
+
+func TestRateLimitMiddleware_Handle(t *testing.T) {
+	m := getRateLimitMiddleware()
+	handler := m.Handle(func(w http.ResponseWriter, r *http.Request) {
+		xhttp.OkJsonCtx(r.Context(), w, nil)
+	})
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	// 按客户端 IP 限流
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "https://test.com/v1/api/login", http.NoBody)
+		req.Header.Set(xhttp.HeaderXForwardedFor, "10.0.0.1, 10.0.0.2")
+		resp := serve(req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "2", resp.Header().Get("RateLimit-Limit"))
+	}
+	req := httptest.NewRequest(http.MethodPost, "https://test.com/v1/api/login", http.NoBody)
+	req.Header.Set(xhttp.HeaderXForwardedFor, "10.0.0.1")
+	resp := serve(req)
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "0", resp.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "30", resp.Header().Get("Retry-After"))
+	result := resp.Result()
+	defer result.Body.Close()
+	d, err := io.ReadAll(result.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"code\":156,\"msg\":\"请求过于频繁，请稍后再试\"}", string(d))
+	req = httptest.NewRequest(http.MethodPost, "https://test.com/v1/api/login", http.NoBody)
+	req.Header.Set(xhttp.HeaderXRealIP, "10.0.0.3")
+	assert.Equal(t, http.StatusOK, serve(req).Code)
+
+	// 按 JWT 载荷字段限流，无法提取限流键时不限流
+	for _, c := range []struct {
+		sub    string
+		expect int
+	}{
+		{sub: "u1", expect: http.StatusOK},
+		{sub: "u1", expect: http.StatusTooManyRequests},
+		{sub: "u2", expect: http.StatusOK},
+		{sub: "", expect: http.StatusOK},
+		{sub: "", expect: http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "https://test.com/v1/api/user/1", http.NoBody)
+		if c.sub != "" {
+			req = req.WithContext(jwt.WithCtx(req.Context(), map[string]any{"sub": c.sub}))
+		}
+		assert.Equal(t, c.expect, serve(req).Code, c)
+	}
+
+	// 按应用 AppKey 限流
+	for _, c := range []struct {
+		appKey string
+		expect int
+	}{
+		{appKey: "app1", expect: http.StatusOK},
+		{appKey: "app1", expect: http.StatusTooManyRequests},
+		{appKey: "app2", expect: http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "https://test.com/v1/api/file", http.NoBody)
+		req = req.WithContext(appsign.CtxWithAppKey(req.Context(), c.appKey))
+		assert.Equal(t, c.expect, serve(req).Code, c)
+	}
+
+	// 按路由限流，全部客户端共享配额
+	req = httptest.NewRequest(http.MethodGet, "https://test.com/v1/api/notice", http.NoBody)
+	req.Header.Set(xhttp.HeaderXForwardedFor, "10.0.0.1")
+	assert.Equal(t, http.StatusOK, serve(req).Code)
+	req = httptest.NewRequest(http.MethodGet, "https://test.com/v1/api/notice", http.NoBody)
+	req.Header.Set(xhttp.HeaderXForwardedFor, "10.0.0.2")
+	assert.Equal(t, http.StatusTooManyRequests, serve(req).Code)
+
+	// 按路由限流时限流键为规则的路由，而不是具体的请求路径
+	req = httptest.NewRequest(http.MethodGet, "https://test.com/v1/api/notice/1", http.NoBody)
+	assert.Equal(t, http.StatusOK, serve(req).Code)
+	req = httptest.NewRequest(http.MethodGet, "https://test.com/v1/api/notice/2", http.NoBody)
+	assert.Equal(t, http.StatusTooManyRequests, serve(req).Code)
+
+	// 未匹配到规则时不限流
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "https://test.com/v1/api/other", http.NoBody)
+		resp := serve(req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Empty(t, resp.Header().Get("RateLimit-Limit"))
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"reflect"
 
 	"github.com/pkg/errors"
+	"github.com/zeromicro/go-zero/core/hash"
 	"github.com/zeromicro/go-zero/core/stores/kv"
+	"github.com/zeromicro/go-zero/core/stores/redis"
 
 	"github.com/sliveryou/go-tool/v2/convert"
 )
@@ -22,13 +24,70 @@ return current;`
 
 // Store 键值存取器
 type Store struct {
-	c kv.KvConf
+	c          kv.KvConf
+	dispatcher *hash.ConsistentHash // 与 kv.Store 相同的一致性哈希节点选取器
 	kv.Store
 }
 
 // NewStore 新建键值存取器
 func NewStore(c kv.KvConf) *Store {
-	return &Store{c: c, Store: kv.NewStore(c)}
+	store := kv.NewStore(c)
+
+	// 与 kv.Store 使用相同的节点和权重构建一致性哈希，保证同一 key 路由到相同的 redis 节点
+	dispatcher := hash.NewConsistentHash()
+	for _, node := range c {
+		dispatcher.AddWithWeight(redis.MustNewRedis(node.RedisConf), node.Weight)
+	}
+
+	return &Store{c: c, dispatcher: dispatcher, Store: store}
+}
+
+// Node redis 节点
+type Node struct {
+	*redis.Redis
+	Weight int // 权重，小于 0 时视为 0
+}
+
+// Nodes 获取全部 redis 节点，可用于需要在多个节点上独立执行命令的场景，如 Redlock
+func (s *Store) Nodes() ([]Node, error) {
+	nodes := make([]Node, 0, len(s.c))
+	for _, c := range s.c {
+		rds, err := redis.NewRedis(c.RedisConf)
+		if err != nil {
+			return nil, errors.WithMessage(err, "new redis node err")
+		}
+
+		weight := c.Weight
+		if weight < 0 {
+			weight = 0
+		}
+		nodes = append(nodes, Node{Redis: rds, Weight: weight})
+	}
+
+	return nodes, nil
+}
+
+// EvalKeys 在 keys[0] 路由到的 redis 节点上执行可以访问全部 keys 的 lua 脚本
+//
+// 全部 keys 均保存在 keys[0] 路由到的节点上，使用 redis 集群时全部 keys 需要包含相同的 hashtag，如 {tag}
+func (s *Store) EvalKeys(script string, keys []string, args ...any) (any, error) {
+	return s.EvalKeysCtx(context.Background(), script, keys, args...)
+}
+
+// EvalKeysCtx 在 keys[0] 路由到的 redis 节点上执行可以访问全部 keys 的 lua 脚本
+//
+// 全部 keys 均保存在 keys[0] 路由到的节点上，使用 redis 集群时全部 keys 需要包含相同的 hashtag，如 {tag}
+func (s *Store) EvalKeysCtx(ctx context.Context, script string, keys []string, args ...any) (any, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("xkv: empty eval keys")
+	}
+
+	val, ok := s.dispatcher.Get(keys[0])
+	if !ok {
+		return nil, kv.ErrNoRedisNode
+	}
+
+	return val.(*redis.Redis).EvalCtx(ctx, script, keys, args...)
 }
 
 // GetInt 返回给定 key 所关联的 int 值
@@ -1,6 +1,7 @@
 package xkv
 
 import (
+	"strconv"
 	"testing"
 
 	miniredis "github.com/alicebob/miniredis/v2"
@@ -81,6 +82,51 @@ func TestStore(t *testing.T) {
 	})
 }
 
+func TestStore_Nodes(t *testing.T) {
+	runOnCluster(func(s *Store) {
+		nodes, err := s.Nodes()
+		require.NoError(t, err)
+		require.Len(t, nodes, 2)
+		assert.Equal(t, 100, nodes[0].Weight)
+
+		// 每个节点可以独立执行命令
+		require.NoError(t, nodes[0].Set("test_key_for_nodes", "1"))
+		assert.True(t, s1.Exists("test_key_for_nodes"))
+		assert.False(t, s2.Exists("test_key_for_nodes"))
+	})
+
+	s := &Store{c: []cache.NodeConf{{RedisConf: redis.RedisConf{Host: "", Type: redis.NodeType}, Weight: 100}}}
+	_, err := s.Nodes()
+	require.Error(t, err)
+}
+
+func TestStore_EvalKeys(t *testing.T) {
+	runOnCluster(func(s *Store) {
+		script := `redis.call("SET", KEYS[2], ARGV[1]);
+return redis.call("GET", KEYS[1]);`
+
+		for i := 0; i < 10; i++ {
+			key := "test_key_for_eval_keys:" + strconv.Itoa(i)
+			require.NoError(t, s.Set(key, "v"))
+
+			// 与 EvalCtx 路由到相同的节点，全部 keys 保存在该节点上
+			resp, err := s.EvalKeys(script, []string{key, key + ":other"}, "other")
+			require.NoError(t, err)
+			assert.Equal(t, "v", resp)
+			node := s1
+			if !node.Exists(key) {
+				node = s2
+			}
+			v, err := node.Get(key + ":other")
+			require.NoError(t, err)
+			assert.Equal(t, "other", v)
+		}
+
+		_, err := s.EvalKeys(script, nil)
+		require.Error(t, err)
+	})
+}
+
 func runOnCluster(fn func(cluster *Store)) {
 	s1.FlushAll()
 	s2.FlushAll()